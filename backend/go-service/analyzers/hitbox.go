@@ -0,0 +1,178 @@
+package analyzers
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"cs2-demo-service/pkg/geometry"
+	"cs2-demo-service/pkg/maps"
+
+	"github.com/golang/geo/r3"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
+)
+
+// ============================================================================
+// HITBOX VISIBILITY
+// En vez de un único rayo ojos→centro, se lanzan rayos a varios puntos del
+// esqueleto (ajustados a la altura de agachado) y se devuelve la fracción
+// visible (ponderada). Así se detectan jugadores parcialmente expuestos.
+// ============================================================================
+
+// Alturas de ojos de CS2 (m_vecViewOffset de pie / agachado)
+const (
+	eyeHeightStanding = 64.06
+	eyeHeightDucking  = 46.04
+)
+
+// HitboxPoint es un punto del esqueleto usado para el test de visibilidad
+type HitboxPoint struct {
+	Name       string
+	Height     float64 // Altura sobre el origen de pie
+	DuckHeight float64 // Altura sobre el origen agachado
+	Side       float64 // Desplazamiento lateral (perpendicular a la línea de visión); <0 = izquierda
+	Weight     float64 // Peso en la fracción visible
+}
+
+// DefaultHitboxPoints son los puntos usados si no se configura otra cosa
+var DefaultHitboxPoints = []HitboxPoint{
+	{Name: "head", Height: 62, DuckHeight: 45, Weight: 0.25},
+	{Name: "chest", Height: 48, DuckHeight: 34, Weight: 0.20},
+	{Name: "pelvis", Height: 36, DuckHeight: 24, Weight: 0.15},
+	{Name: "left_shoulder", Height: 55, DuckHeight: 39, Side: -11, Weight: 0.10},
+	{Name: "right_shoulder", Height: 55, DuckHeight: 39, Side: 11, Weight: 0.10},
+	{Name: "left_foot", Height: 4, DuckHeight: 4, Side: -6, Weight: 0.10},
+	{Name: "right_foot", Height: 4, DuckHeight: 4, Side: 6, Weight: 0.10},
+}
+
+// SelectHitboxPoints devuelve los puntos por nombre (vacío = DefaultHitboxPoints)
+func SelectHitboxPoints(names []string) ([]HitboxPoint, error) {
+	if len(names) == 0 {
+		return DefaultHitboxPoints, nil
+	}
+	points := make([]HitboxPoint, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, p := range DefaultHitboxPoints {
+			if strings.EqualFold(p.Name, name) {
+				points = append(points, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown hitbox point %q", name)
+		}
+	}
+	return points, nil
+}
+
+// duckAmount devuelve 0 (de pie) a 1 (agachado), interpolado durante la transición
+func duckAmount(player *common.Player) float64 {
+	if pawn := player.PlayerPawnEntity(); pawn != nil {
+		if v, ok := pawn.PropertyValue("m_pMovementServices.m_flDuckAmount"); ok {
+			if f, ok := v.Any.(float32); ok {
+				return math.Max(0, math.Min(1, float64(f)))
+			}
+		}
+	}
+	if player.IsDucking() {
+		return 1
+	}
+	return 0
+}
+
+// eyePosition devuelve la posición de los ojos usando el view offset de la demo
+// (fallback: interpolación de pie/agachado según duck amount)
+func eyePosition(player *common.Player) r3.Vector {
+	pos := player.Position()
+	if pawn := player.PlayerPawnEntity(); pawn != nil {
+		if v, ok := pawn.PropertyValue("m_vecViewOffset.m_vecZ"); ok {
+			if f, ok := v.Any.(float32); ok && f > 0 {
+				pos.Z += float64(f)
+				return pos
+			}
+		}
+	}
+	pos.Z += lerp(eyeHeightStanding, eyeHeightDucking, duckAmount(player))
+	return pos
+}
+
+// hitboxTargets calcula los puntos del esqueleto del enemigo vistos desde 'from'
+func hitboxTargets(enemy *common.Player, from r3.Vector, points []HitboxPoint) []r3.Vector {
+	origin := enemy.Position()
+	duck := duckAmount(enemy)
+
+	// Eje lateral: perpendicular (horizontal) a la línea de visión
+	side := r3.Vector{X: -(origin.Y - from.Y), Y: origin.X - from.X}
+	if side.Norm() > 0 {
+		side = side.Normalize()
+	}
+
+	targets := make([]r3.Vector, len(points))
+	for i, p := range points {
+		t := origin.Add(side.Mul(p.Side))
+		t.Z += lerp(p.Height, p.DuckHeight, duck)
+		targets[i] = t
+	}
+	return targets
+}
+
+// visibleFraction devuelve la fracción (ponderada) de puntos visibles desde eyes.
+// Antes de lanzar un rayo por punto descarta, sin perder precisión, los cuerpos tapados
+// enteros (la mayoría de pares en cada tick):
+//   - PVS del centro: todos los puntos están a menos de un vóxel del centro y el PVS
+//     marca los vecinos de cada vóxel visible, así que si el centro no es potencialmente
+//     visible ningún punto lo es.
+//   - Un rayo al centro: si la superficie que lo corta corta también los rayos a todos
+//     los puntos, el cuerpo está tapado entero.
+//
+// Un solo rayo no demuestra que el resto de puntos se vean, así que los cuerpos visibles
+// (o a medias) siguen necesitando un rayo por punto.
+func visibleFraction(checker maps.VisibilityChecker, eyes r3.Vector, targets []r3.Vector, points []HitboxPoint) float64 {
+	if len(targets) == 0 {
+		return 0
+	}
+	if oc, ok := checker.(maps.OcclusionChecker); ok && checker.IsLoaded() {
+		var center r3.Vector
+		for _, t := range targets {
+			center = center.Add(t)
+		}
+		center = center.Mul(1 / float64(len(targets)))
+
+		if !oc.PotentiallyVisible(eyes, center) {
+			return 0
+		}
+		if tri, blocked := oc.Blocker(eyes, center); blocked && blocksAll(tri, eyes, targets) {
+			return 0
+		}
+	}
+
+	visible, total := 0.0, 0.0
+	for i, t := range targets {
+		total += points[i].Weight
+		if checker.IsVisible(eyes, t) {
+			visible += points[i].Weight
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return visible / total
+}
+
+// blocksAll indica si tri corta los segmentos de eyes a cada uno de los targets
+func blocksAll(tri geometry.Triangle, eyes r3.Vector, targets []r3.Vector) bool {
+	for _, t := range targets {
+		dir := t.Sub(eyes)
+		dist := dir.Norm()
+		if dist == 0 || !geometry.RayIntersectsTriangle(eyes, dir.Mul(1/dist), tri, dist) {
+			return false
+		}
+	}
+	return true
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
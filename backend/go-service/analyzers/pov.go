@@ -0,0 +1,441 @@
+package analyzers
+
+import (
+	"math"
+	"sort"
+
+	"cs2-demo-service/models"
+
+	"github.com/golang/geo/r3"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+)
+
+// ============================================================================
+// POV RECORDER
+// Reconstruye el punto de vista de un jugador en una ronda a tick completo:
+// ojos, ángulos, arma, flash y, para cada enemigo vivo, si estaba dentro del
+// FOV (proyección en pantalla) y si era visible a través del mesh (hitbox
+// points + IsVisible) y de los humos. Al terminar junta los ticks en pantalla
+// en exposiciones y mide cuánto tardó el jugador en reaccionar.
+// ============================================================================
+
+// Valores por defecto del FOV de CS2 (fov_cs_debug 90 sobre 4:3, pantallas 16:9)
+const (
+	DefaultPOVFOV         = 90.0
+	DefaultPOVAspectRatio = 16.0 / 9.0
+
+	// Un enemigo que desaparece menos de esto (jiggle, un poste) sigue en la misma exposición
+	povExposureGapTicks = 8
+
+	// El blanco del flash se desvanece durante los últimos segundos de la ceguera
+	flashFadeSeconds = 3.0
+)
+
+// POVOptions selecciona el jugador, la ronda y la pantalla simulada
+type POVOptions struct {
+	SteamID     uint64
+	Round       int           // 1-based, como ActualRoundNumber
+	FOV         float64       // FOV horizontal sobre 4:3 (0 = DefaultPOVFOV)
+	AspectRatio float64       // 0 = DefaultPOVAspectRatio
+	Points      []HitboxPoint // Vacío = DefaultHitboxPoints
+}
+
+// POVRecorder acumula los frames del jugador seleccionado
+type POVRecorder struct {
+	ctx  *models.DemoContext
+	opts POVOptions
+
+	round     int
+	recording bool
+	done      bool
+	export    models.POVExport
+
+	shotTicks   []int
+	damageTicks map[uint64][]int // enemigo -> ticks en que el jugador le hizo daño
+}
+
+// RegisterPOVRecorder registra el recorder. Graba desde el fin del freeze time de la
+// ronda pedida hasta RoundEnd; Done() indica que ya se puede cancelar el parse.
+func RegisterPOVRecorder(ctx *models.DemoContext, opts POVOptions) *POVRecorder {
+	if opts.FOV <= 0 {
+		opts.FOV = DefaultPOVFOV
+	}
+	if opts.AspectRatio <= 0 {
+		opts.AspectRatio = DefaultPOVAspectRatio
+	}
+	if len(opts.Points) == 0 {
+		opts.Points = DefaultHitboxPoints
+	}
+	r := &POVRecorder{
+		ctx:         ctx,
+		opts:        opts,
+		damageTicks: make(map[uint64][]int),
+	}
+	r.export = models.POVExport{
+		SteamID:     opts.SteamID,
+		Round:       opts.Round,
+		FOV:         horizontalFOV(opts.FOV, opts.AspectRatio),
+		AspectRatio: opts.AspectRatio,
+		Frames:      []models.POVFrame{},
+		Exposures:   []models.POVExposure{},
+	}
+	if checker, ok := ctx.MapManager.(interface{ IsLoaded() bool }); ok {
+		r.export.MeshLoaded = checker.IsLoaded()
+	}
+
+	ctx.Parser.RegisterEventHandler(func(e events.RoundStart) {
+		gs := ctx.Parser.GameState()
+		if !gs.IsWarmupPeriod() {
+			r.round = gs.TotalRoundsPlayed() + 1
+		}
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.RoundFreezetimeEnd) {
+		if r.done || r.round != opts.Round {
+			return
+		}
+		r.recording = true
+		r.export.StartTick = ctx.Parser.GameState().IngameTick()
+		r.export.TickRate = ctx.Parser.TickRate()
+		if checker, ok := ctx.MapManager.(interface{ IsLoaded() bool }); ok {
+			r.export.MeshLoaded = checker.IsLoaded()
+		}
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.RoundEnd) {
+		if r.recording {
+			r.finish()
+		}
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.WeaponFire) {
+		if r.recording && e.Shooter != nil && e.Shooter.SteamID64 == opts.SteamID {
+			r.shotTicks = append(r.shotTicks, ctx.Parser.GameState().IngameTick())
+		}
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.PlayerHurt) {
+		if !r.recording || e.Attacker == nil || e.Player == nil || e.Attacker.SteamID64 != opts.SteamID {
+			return
+		}
+		victim := e.Player.SteamID64
+		r.damageTicks[victim] = append(r.damageTicks[victim], ctx.Parser.GameState().IngameTick())
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.FrameDone) {
+		if r.recording {
+			r.sample()
+		}
+	})
+
+	return r
+}
+
+// Done indica que la ronda pedida ya terminó (el resto de la demo sobra)
+func (r *POVRecorder) Done() bool {
+	return r.done
+}
+
+// Export devuelve el POV grabado (con las exposiciones calculadas si la ronda terminó)
+func (r *POVRecorder) Export() *models.POVExport {
+	if r.recording {
+		r.finish() // Demo cortada a mitad de ronda
+	}
+	return &r.export
+}
+
+func (r *POVRecorder) sample() {
+	gs := r.ctx.Parser.GameState()
+	tick := gs.IngameTick()
+	if n := len(r.export.Frames); n > 0 && r.export.Frames[n-1].Tick == tick {
+		return
+	}
+
+	var player *common.Player
+	for _, p := range gs.Participants().Playing() {
+		if p.SteamID64 == r.opts.SteamID {
+			player = p
+			break
+		}
+	}
+	if player == nil {
+		return
+	}
+	if r.export.PlayerName == "" {
+		r.export.PlayerName = player.Name
+		r.export.Team = teamName(player.Team)
+	}
+
+	eyes := eyePosition(player)
+	pitch := normalizePitch(player.ViewDirectionY())
+	frame := models.POVFrame{
+		Tick:        tick,
+		Alive:       player.IsAlive(),
+		EyeX:        eyes.X,
+		EyeY:        eyes.Y,
+		EyeZ:        eyes.Z,
+		Yaw:         player.ViewDirectionX(),
+		Pitch:       float32(pitch),
+		Health:      player.Health(),
+		IsReloading: player.IsReloading,
+		IsScoped:    player.IsScoped(),
+		IsDucking:   player.IsDucking(),
+		FlashAlpha:  flashAlpha(player),
+	}
+	if n := len(r.shotTicks); n > 0 && r.shotTicks[n-1] == tick {
+		frame.Fired = true
+	}
+
+	fov := r.opts.FOV
+	if w := player.ActiveWeapon(); w != nil {
+		frame.Weapon = w.String()
+		frame.AmmoClip = w.AmmoInMagazine()
+		frame.AmmoReserve = w.AmmoReserve()
+		frame.ZoomLevel = int(w.ZoomLevel())
+		fov = zoomedFOV(w, fov)
+	}
+
+	if frame.Alive {
+		view := newViewFrame(pitch, float64(frame.Yaw), horizontalFOV(fov, r.opts.AspectRatio), r.opts.AspectRatio)
+		for _, enemy := range gs.Participants().Playing() {
+			if enemy.Team == player.Team || !enemy.IsAlive() || enemy.SteamID64 == 0 {
+				continue
+			}
+			frame.Enemies = append(frame.Enemies, r.enemyView(enemy, eyes, view, tick))
+		}
+	}
+
+	r.export.Frames = append(r.export.Frames, frame)
+	r.export.EndTick = tick
+}
+
+// enemyView calcula FOV, pantalla y visibilidad de un enemigo
+func (r *POVRecorder) enemyView(enemy *common.Player, eyes r3.Vector, view viewFrame, tick int) models.POVEnemy {
+	pos := enemy.Position()
+	head := pos
+	head.Z += lerp(62, 45, duckAmount(enemy))
+
+	targets := hitboxTargets(enemy, eyes, r.opts.Points)
+	inFOV := false
+	for _, t := range targets {
+		if _, _, ok := view.project(t.Sub(eyes)); ok {
+			inFOV = true
+			break
+		}
+	}
+
+	ev := models.POVEnemy{
+		SteamID:  enemy.SteamID64,
+		Name:     enemy.Name,
+		X:        pos.X,
+		Y:        pos.Y,
+		Z:        pos.Z,
+		Distance: head.Sub(eyes).Norm(),
+		AngleOff: calculateAngle(view.forward, head.Sub(eyes).Normalize()),
+		InFOV:    inFOV,
+	}
+	if inFOV {
+		ev.ScreenX, ev.ScreenY, _ = view.project(head.Sub(eyes))
+	}
+
+	ev.VisibleFraction = visibleFraction(r.ctx.MapManager, eyes, targets, r.opts.Points)
+	ev.Visible = ev.VisibleFraction > 0
+	if ev.Visible && r.ctx.Smokes != nil {
+		ev.Smoked = true
+		for _, t := range targets {
+			if !r.ctx.Smokes.Blocks(eyes, t, tick) {
+				ev.Smoked = false
+				break
+			}
+		}
+	}
+	ev.OnScreen = ev.InFOV && ev.Visible && !ev.Smoked
+	return ev
+}
+
+// finish cierra la grabación y calcula las exposiciones
+func (r *POVRecorder) finish() {
+	r.recording = false
+	r.done = true
+	r.export.Exposures = r.exposures()
+}
+
+// exposures junta los ticks con cada enemigo en pantalla (tolerando huecos cortos)
+// y busca la primera reacción (disparo o daño a ese enemigo) dentro de cada una
+func (r *POVRecorder) exposures() []models.POVExposure {
+	tickRate := r.export.TickRate
+	if tickRate <= 0 {
+		tickRate = 64
+	}
+	toMs := func(ticks int) float64 { return float64(ticks) * 1000 / tickRate }
+
+	open := make(map[uint64]*models.POVExposure)
+	var order []uint64
+	result := []models.POVExposure{}
+	closeExposure := func(id uint64) {
+		exp := open[id]
+		delete(open, id)
+		exp.DurationMs = toMs(exp.EndTick - exp.StartTick + 1)
+		r.react(exp, toMs)
+		result = append(result, *exp)
+	}
+
+	for _, f := range r.export.Frames {
+		for _, e := range f.Enemies {
+			if !e.OnScreen {
+				continue
+			}
+			if exp, ok := open[e.SteamID]; ok && f.Tick-exp.EndTick <= povExposureGapTicks {
+				exp.EndTick = f.Tick
+				continue
+			}
+			if _, ok := open[e.SteamID]; ok {
+				closeExposure(e.SteamID)
+			} else {
+				order = append(order, e.SteamID)
+			}
+			open[e.SteamID] = &models.POVExposure{EnemyID: e.SteamID, EnemyName: e.Name, StartTick: f.Tick, EndTick: f.Tick}
+		}
+		// Cerrar las que llevan más del hueco permitido sin verse
+		for _, id := range order {
+			if exp, ok := open[id]; ok && f.Tick-exp.EndTick > povExposureGapTicks {
+				closeExposure(id)
+			}
+		}
+	}
+	for _, id := range order {
+		if _, ok := open[id]; ok {
+			closeExposure(id)
+		}
+	}
+
+	// Orden cronológico (las exposiciones se cierran en otro orden)
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].StartTick != result[j].StartTick {
+			return result[i].StartTick < result[j].StartTick
+		}
+		return result[i].EnemyID < result[j].EnemyID
+	})
+	return result
+}
+
+// react rellena la primera reacción del jugador durante la exposición
+func (r *POVRecorder) react(exp *models.POVExposure, toMs func(int) float64) {
+	first := func(ticks []int, reaction string) {
+		for _, t := range ticks {
+			if t < exp.StartTick || t > exp.EndTick {
+				continue
+			}
+			if exp.Reaction == "" || t < exp.ReactionTick {
+				exp.Reaction, exp.ReactionTick = reaction, t
+			}
+			return
+		}
+	}
+	first(r.shotTicks, "shot")
+	first(r.damageTicks[exp.EnemyID], "damage")
+	if exp.Reaction != "" {
+		exp.ReactionMs = toMs(exp.ReactionTick - exp.StartTick)
+	}
+}
+
+// --- Geometría de la pantalla ---
+
+// viewFrame es la base de la cámara (forward/right/up) y los semiejes de la pantalla
+type viewFrame struct {
+	forward, right, up r3.Vector
+	tanX, tanY         float64
+}
+
+func newViewFrame(pitch, yaw, hfov, aspect float64) viewFrame {
+	p := pitch * math.Pi / 180
+	y := yaw * math.Pi / 180
+	forward := r3.Vector{X: math.Cos(p) * math.Cos(y), Y: math.Cos(p) * math.Sin(y), Z: -math.Sin(p)}
+	right := r3.Vector{X: math.Sin(y), Y: -math.Cos(y)}
+	tanX := math.Tan(hfov * math.Pi / 360)
+	return viewFrame{
+		forward: forward,
+		right:   right,
+		up:      right.Cross(forward),
+		tanX:    tanX,
+		tanY:    tanX / aspect,
+	}
+}
+
+// project devuelve la posición en pantalla (-1..1) de una dirección relativa a los ojos
+func (v viewFrame) project(d r3.Vector) (float64, float64, bool) {
+	depth := d.Dot(v.forward)
+	if depth <= 0 {
+		return 0, 0, false
+	}
+	x := d.Dot(v.right) / depth / v.tanX
+	y := d.Dot(v.up) / depth / v.tanY
+	return x, y, math.Abs(x) <= 1 && math.Abs(y) <= 1
+}
+
+// horizontalFOV convierte un FOV de 4:3 (convención de CS) al horizontal de la pantalla
+func horizontalFOV(fov43, aspect float64) float64 {
+	half := math.Tan(fov43*math.Pi/360) * aspect / (4.0 / 3.0)
+	return 2 * math.Atan(half) * 180 / math.Pi
+}
+
+// zoomedFOV aplica el zoom de las miras (FOV 4:3 de cada nivel de zoom)
+func zoomedFOV(w *common.Equipment, fov float64) float64 {
+	level := w.ZoomLevel()
+	if level == common.ZoomNone {
+		return fov
+	}
+	switch w.Type {
+	case common.EqAWP:
+		if level == common.ZoomFull {
+			return 10
+		}
+		return 40
+	case common.EqSSG08, common.EqScar20, common.EqG3SG1:
+		if level == common.ZoomFull {
+			return 15
+		}
+		return 40
+	case common.EqAUG, common.EqSG553:
+		return 45
+	}
+	return fov
+}
+
+// normalizePitch lleva el pitch de demoinfocs (270 = mirando arriba) a -90..90
+func normalizePitch(pitch float32) float64 {
+	p := float64(pitch)
+	if p > 180 {
+		p -= 360
+	}
+	return p
+}
+
+// flashAlpha aproxima la opacidad del blanco del flash: máxima mientras dura la
+// ceguera y desvaneciéndose linealmente en los últimos flashFadeSeconds
+func flashAlpha(player *common.Player) float64 {
+	remaining := player.FlashDurationTimeRemaining().Seconds()
+	if remaining <= 0 {
+		return 0
+	}
+	maxAlpha := 1.0
+	if pawn := player.PlayerPawnEntity(); pawn != nil {
+		if v, ok := pawn.PropertyValue("m_flFlashMaxAlpha"); ok {
+			if f, ok := v.Any.(float32); ok && f > 0 {
+				maxAlpha = math.Min(1, float64(f)/255)
+			}
+		}
+	}
+	return maxAlpha * math.Min(1, remaining/flashFadeSeconds)
+}
+
+func teamName(team common.Team) string {
+	switch team {
+	case common.TeamCounterTerrorists:
+		return "CT"
+	case common.TeamTerrorists:
+		return "T"
+	}
+	return ""
+}
@@ -18,15 +18,34 @@ type visibilityJob struct {
 	shooter     *common.Player
 	enemy       *common.Player
 	shooterEyes r3.Vector
-	enemyCenter r3.Vector
-	enemyHead   r3.Vector
+	targets     []r3.Vector // Puntos del esqueleto del enemigo (ver hitbox.go)
 	shooterID   uint64
 	enemyID     uint64
 	currentTick int
 }
 
-// RegisterReactionAnalyzer registra el analizador de reaction time
+// Resultado de un visibility check
+type visibilityResult struct {
+	shooterID       uint64
+	enemyID         uint64
+	isVisible       bool
+	visibleFraction float64 // 0-1, fracción ponderada de hitbox points visibles
+	shooter         *common.Player
+	enemy           *common.Player
+}
+
+// RegisterReactionAnalyzer registra el analizador de reaction time (DefaultHitboxPoints)
 func RegisterReactionAnalyzer(ctx *models.DemoContext) {
+	RegisterReactionAnalyzerWithPoints(ctx, DefaultHitboxPoints)
+}
+
+// RegisterReactionAnalyzerWithPoints registra el analizador usando los hitbox points dados
+// para el test de visibilidad (un enemigo es visible si alguno de sus puntos lo es)
+func RegisterReactionAnalyzerWithPoints(ctx *models.DemoContext, points []HitboxPoint) {
+	if len(points) == 0 {
+		points = DefaultHitboxPoints
+	}
+
 	// Detectar cuando un enemigo se vuelve visible
 	ctx.Parser.RegisterEventHandler(func(e events.FrameDone) {
 		currentTick := ctx.Parser.GameState().IngameTick()
@@ -37,40 +56,7 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 			return
 		}
 
-		// Actualizar smokes activos en el contexto
-		ctx.ActiveSmokes = []r3.Vector{}
-
-		// Detectar smokes/molotovs activos mediante Infernos
-		for _, inferno := range ctx.Parser.GameState().Infernos() {
-			// Obtener fires activos como lista
-			fires := inferno.Fires().Active().List()
-			if len(fires) > 0 {
-				// Calcular centroid de los fires activos
-				var sumX, sumY, sumZ float64
-				for _, fire := range fires {
-					sumX += fire.X
-					sumY += fire.Y
-					sumZ += fire.Z
-				}
-				centroid := r3.Vector{
-					X: sumX / float64(len(fires)),
-					Y: sumY / float64(len(fires)),
-					Z: sumZ / float64(len(fires)),
-				}
-				ctx.ActiveSmokes = append(ctx.ActiveSmokes, centroid)
-			}
-		}
-
-		// También revisar proyectiles de smoke que aún no detonaron
-		for _, proj := range ctx.Parser.GameState().GrenadeProjectiles() {
-			if proj.WeaponInstance != nil {
-				weapon := proj.WeaponInstance.Type
-				// Smoke grenades: 45 = weapon_smokegrenade
-				if weapon == 45 {
-					ctx.ActiveSmokes = append(ctx.ActiveSmokes, proj.Position())
-				}
-			}
-		}
+		// Humos: mantenidos por handlers.RegisterSmokeHandlers (ctx.Smokes); el fuego no tapa
 
 		// PARALELIZACIÓN: Preparar jobs de visibility checking
 		var jobs []visibilityJob
@@ -82,8 +68,7 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 
 			shooterID := shooter.SteamID64
 			shooterTeam := shooter.Team
-			shooterEyes := shooter.Position()
-			shooterEyes.Z += 64
+			shooterEyes := eyePosition(shooter) // Ajustado a agachado / view offset
 
 			// Inicializar mapas si no existen
 			if ctx.EnemyFirstSeenTick[shooterID] == nil {
@@ -133,17 +118,12 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 					continue
 				}
 
-				// Calculate Heat Position for Dual Raycast
-				enemyHead := enemy.Position()
-				enemyHead.Z += 62
-
 				// Añadir job a la cola
 				jobs = append(jobs, visibilityJob{
 					shooter:     shooter,
 					enemy:       enemy,
 					shooterEyes: shooterEyes,
-					enemyCenter: enemyCenter,
-					enemyHead:   enemyHead,
+					targets:     hitboxTargets(enemy, shooterEyes, points),
 					shooterID:   shooterID,
 					enemyID:     enemyID,
 					currentTick: currentTick,
@@ -151,16 +131,12 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 			}
 		}
 
-		// Procesar jobs en paralelo con worker pool limitado
+		// Procesar jobs en paralelo con worker pool limitado.
+		// Cada worker escribe en su índice: los resultados se aplican en el orden de
+		// los jobs, independientemente de qué goroutine termine antes (determinista).
 		var wg sync.WaitGroup
-		jobsChan := make(chan visibilityJob, len(jobs))
-		resultsChan := make(chan struct {
-			shooterID uint64
-			enemyID   uint64
-			isVisible bool
-			shooter   *common.Player
-			enemy     *common.Player
-		}, len(jobs))
+		jobsChan := make(chan int, len(jobs))
+		fractions := make([]float64, len(jobs))
 
 		// Lanzar workers (máximo 6 para no saturar CPU)
 		numWorkers := maxWorkers
@@ -172,48 +148,39 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				for job := range jobsChan {
-					// DUAL RAYCAST STRATEGY
-					// 1. Check HEAD first (Z+62). This catches head-peeks (common in CS).
-					// 2. If Head is blocked, check CHEST (Z+40).
-					// This ensures we catch all visibility cases.
-
-					isVisibleHead := ctx.MapManager.IsVisible(job.shooterEyes, job.enemyHead)
-					isVisibleChest := false
-					if !isVisibleHead {
-						isVisibleChest = ctx.MapManager.IsVisible(job.shooterEyes, job.enemyCenter)
-					}
-					isVisible := isVisibleHead || isVisibleChest
+				for i := range jobsChan {
+					job := jobs[i]
+					// MULTI-POINT RAYCAST: un rayo por hitbox point (cabeza, pecho, pelvis,
+					// hombros, pies). Captura head-peeks y jugadores parcialmente expuestos.
 
 					// REMOVED HeuristicIsVisible (Smoke Check)
 					// We want purely geometric visibility for FirstSeen.
 					// SmokeInPath is calculated later in the event metadata.
 
-					resultsChan <- struct {
-						shooterID uint64
-						enemyID   uint64
-						isVisible bool
-						shooter   *common.Player
-						enemy     *common.Player
-					}{job.shooterID, job.enemyID, isVisible, job.shooter, job.enemy}
+					fractions[i] = visibleFraction(ctx.MapManager, job.shooterEyes, job.targets, points)
 				}
 			}()
 		}
 
 		// Enviar jobs a workers
-		for _, job := range jobs {
-			jobsChan <- job
+		for i := range jobs {
+			jobsChan <- i
 		}
 		close(jobsChan)
 
 		// Esperar resultados
-		go func() {
-			wg.Wait()
-			close(resultsChan)
-		}()
-
-		// Procesar resultados y actualizar estado
-		for result := range resultsChan {
+		wg.Wait()
+
+		// Procesar resultados (en orden de jobs) y actualizar estado
+		for i, job := range jobs {
+			result := visibilityResult{
+				shooterID:       job.shooterID,
+				enemyID:         job.enemyID,
+				isVisible:       fractions[i] > 0,
+				visibleFraction: fractions[i],
+				shooter:         job.shooter,
+				enemy:           job.enemy,
+			}
 			wasVisible := ctx.LastVisibleEnemies[result.shooterID][result.enemyID]
 
 			if result.isVisible {
@@ -221,6 +188,7 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 				if wasVisible {
 					if data, ok := ctx.EnemyFirstSeenTick[result.shooterID][result.enemyID]; ok {
 						data.LastSeenTick = currentTick
+						data.PeakVisibleFraction = math.Max(data.PeakVisibleFraction, result.visibleFraction)
 						ctx.EnemyFirstSeenTick[result.shooterID][result.enemyID] = data
 					}
 				} else {
@@ -233,6 +201,7 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 						if currentTick-data.LastSeenTick < 64 {
 							// Es un jiggle peek, mantenemos el FirstSeenTick original
 							data.LastSeenTick = currentTick
+							data.PeakVisibleFraction = math.Max(data.PeakVisibleFraction, result.visibleFraction)
 							ctx.EnemyFirstSeenTick[result.shooterID][result.enemyID] = data
 							isJigglePeek = true
 						}
@@ -243,6 +212,7 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 						// Calcular Crosshair Placement Error
 						// Determinar objetivo (Cabeza vs Cuerpo) según arma
 						targetPos := result.enemy.Position()
+						enemyDuck := duckAmount(result.enemy)
 						activeWeapon := result.shooter.ActiveWeapon()
 						isSniper := false
 						if activeWeapon != nil {
@@ -253,13 +223,12 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 						}
 
 						if isSniper {
-							targetPos.Z += 40.0 // Altura aproximada pecho/estómago
+							targetPos.Z += lerp(40.0, 28.0, enemyDuck) // Altura aproximada pecho/estómago
 						} else {
-							targetPos.Z += 62.0 // Altura aproximada cabeza
+							targetPos.Z += lerp(62.0, 45.0, enemyDuck) // Altura aproximada cabeza
 						}
 
-						playerEyePos := result.shooter.Position()
-						playerEyePos.Z += 64.0 // Altura ojos
+						playerEyePos := eyePosition(result.shooter) // Altura ojos
 
 						// Vector Ideal (Desde ojos a objetivo)
 						vecIdeal := r3.Vector{
@@ -308,6 +277,8 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 							PitchError:              pitchError,
 							YawError:                yawError,
 							ShooterVelocity:         shooterSpeed,
+							VisibleFraction:         result.visibleFraction,
+							PeakVisibleFraction:     result.visibleFraction,
 						}
 					}
 				}
@@ -362,7 +333,9 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 
 		// Verificar si hay un enemigo visible recién detectado
 		if ctx.EnemyFirstSeenTick[shooterID] != nil {
-			for enemyID, firstSeenData := range ctx.EnemyFirstSeenTick[shooterID] {
+			// Orden por SteamID: los ReactionTimes se añaden siempre en el mismo orden
+			for _, enemyID := range models.SortedKeys(ctx.EnemyFirstSeenTick[shooterID]) {
+				firstSeenData := ctx.EnemyFirstSeenTick[shooterID][enemyID]
 				ticksSinceVisible := currentTick - firstSeenData.Tick
 
 				// Reaction time válido: entre 0 y 320 ticks (0ms - ~2500ms at 128tick)
@@ -398,21 +371,15 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 						}
 
 						// Posiciones
-						shooterPos := e.Shooter.Position()
-						shooterPos.Z += 64 // Eye level
+						shooterPos := eyePosition(e.Shooter) // Eye level
 						enemyPos := enemy.Position()
 						enemyPos.Z += 40 // Chest level
 
 						// Distancia
 						distance = shooterPos.Sub(enemyPos).Norm()
 
-						// Smoke check
-						for _, smokePos := range ctx.ActiveSmokes {
-							if distancePointToSegment(smokePos, shooterPos, enemyPos) < 140.0 {
-								smokeInPath = true
-								break
-							}
-						}
+						// Smoke check (opacidad volumétrica, los infernos no cuentan)
+						smokeInPath = ctx.Smokes.Blocks(shooterPos, enemyPos, currentTick)
 					}
 
 					// Obtener/crear PlayerData
@@ -440,6 +407,8 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 						PitchError:              firstSeenData.PitchError,
 						YawError:                firstSeenData.YawError,
 						ShooterVelocity:         firstSeenData.ShooterVelocity,
+						VisibleFraction:         firstSeenData.VisibleFraction,
+						PeakVisibleFraction:     firstSeenData.PeakVisibleFraction,
 					}
 					playerData.ReactionTimes = append(playerData.ReactionTimes, reactionEvent)
 
@@ -549,20 +518,6 @@ func RegisterReactionAnalyzer(ctx *models.DemoContext) {
 	})
 }
 
-// Helper function
-func distancePointToSegment(p, a, b r3.Vector) float64 {
-	ab := b.Sub(a)
-	ap := p.Sub(a)
-	t := ap.Dot(ab) / ab.Dot(ab)
-	if t < 0.0 {
-		t = 0.0
-	} else if t > 1.0 {
-		t = 1.0
-	}
-	nearest := a.Add(ab.Mul(t))
-	return p.Sub(nearest).Norm()
-}
-
 func anglesToR3Vector(pitch, yaw float32) r3.Vector {
 	// Normalize pitch from demoinfocs range (270 to 90, where 270 = -90) to standard -90 to 90
 	// demoinfocs: 270° = looking up (-90°), 90° = looking down (+90°), 0° = horizontal
@@ -2,14 +2,18 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"time"
+	"sync"
 
+	"cs2-demo-service/analyzers"
 	"cs2-demo-service/db"
+	"cs2-demo-service/handlers"
 	"cs2-demo-service/parser"
+	"cs2-demo-service/scheduler"
 
 	"github.com/gorilla/mux"
 )
@@ -21,6 +25,44 @@ type ProcessDemoRequest struct {
 	MatchID       string `json:"match_id"`
 	MatchDate     string `json:"match_date"`     // ISO 8601 date from Steam GC
 	MatchDuration int    `json:"match_duration"` // Duration in seconds from GC
+
+	// Optional: analyzers to run (empty = all). Also used to estimate memory
+	Analyzers []string `json:"analyzers,omitempty"`
+
+	// Optional: guarda match_<id>/intermediate.bin para re-exportar sin re-parsear
+	KeepIntermediate bool `json:"keep_intermediate,omitempty"`
+
+	// Optional: hitbox points para la visibilidad (head, chest, pelvis, left_shoulder...). Vacío = todos
+	HitboxPoints []string `json:"hitbox_points,omitempty"`
+
+	// Optional: frames por segundo del replay (1-64, 0 = 16 Hz)
+	ReplaySampleRateHz int `json:"replay_sample_rate_hz,omitempty"`
+}
+
+// demoScheduler limita cuántas demos se parsean a la vez (memoria). Se crea una sola
+// vez (SetScheduler o el default en la primera petición) aunque lleguen a la vez.
+var (
+	demoScheduler     *scheduler.Scheduler
+	demoSchedulerOnce sync.Once
+)
+
+// SetScheduler configura el scheduler usado por los handlers (antes de servir peticiones)
+func SetScheduler(s *scheduler.Scheduler) {
+	set := false
+	demoSchedulerOnce.Do(func() {
+		demoScheduler = s
+		set = true
+	})
+	if !set {
+		log.Printf("⚠️  SetScheduler ignorado: el scheduler ya estaba creado")
+	}
+}
+
+func getScheduler() *scheduler.Scheduler {
+	demoSchedulerOnce.Do(func() {
+		demoScheduler = scheduler.New(scheduler.DefaultConfig())
+	})
+	return demoScheduler
 }
 
 // HandleProcessDemo procesa una demo y devuelve el JSON
@@ -72,33 +114,58 @@ func HandleProcessDemo(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("📊 Demo file size: %.2f MB", float64(fileInfo.Size())/(1024*1024))
 
-	// ⏱️ TIMING: ParseDemo
-	parseStart := time.Now()
-	// Parsear demo usando nueva arquitectura
-	ctx, err := parser.ParseDemo(req.DemoPath)
-	if err != nil {
-		log.Printf("❌ Error parseando demo: %v", err)
-		http.Error(w, fmt.Sprintf("Error parseando: %v", err), http.StatusInternalServerError)
+	exportBaseDir := "../data/exports"
+
+	opts := parser.DefaultParseOptions()
+	if len(req.Analyzers) > 0 {
+		opts.Analyzers = req.Analyzers
+	}
+	if req.KeepIntermediate {
+		opts.IntermediatePath = parser.IntermediatePath(exportBaseDir, matchID)
+	}
+	if _, err := analyzers.SelectHitboxPoints(req.HitboxPoints); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.HitboxPoints = req.HitboxPoints
+	if err := handlers.ValidateReplaySampleRate(req.ReplaySampleRateHz); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	log.Printf("⏱️ ParseDemo took: %v", time.Since(parseStart))
+	opts.ReplaySampleRateHz = req.ReplaySampleRateHz
+
+	// Parse + export via scheduler (espera turno si se supera el presupuesto de memoria)
+	result := getScheduler().Run(scheduler.Job{
+		DemoPath:  req.DemoPath,
+		MatchID:   matchID,
+		MatchDate: req.MatchDate,
+		OutputDir: exportBaseDir,
+		Options:   opts,
+	})
+	defer result.Release() // El DemoContext cuenta en el presupuesto hasta que se guarda
+	if errors.Is(result.Err, scheduler.ErrQueueFull) {
+		log.Printf("⏳ Cola de demos llena, rechazando %s", matchID)
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "Demo queue is full, retry later", http.StatusServiceUnavailable)
+		return
+	}
+	if result.Context == nil {
+		log.Printf("❌ Error parseando demo: %v", result.Err)
+		http.Error(w, fmt.Sprintf("Error parseando: %v", result.Err), http.StatusInternalServerError)
+		return
+	}
+	if result.Err != nil {
+		log.Printf("⚠️  Error exportando AI models: %v", result.Err)
+	}
+	log.Printf("⏱️ Queue wait: %v, ParseDemo took: %v, ExportAIModels took: %v",
+		result.QueueWait, result.ParseDuration, result.ExportDuration)
 
+	ctx := result.Context
 	matchData := ctx.MatchData
 
 	// Asignar matchID
 	matchData.MatchID = matchID
 
-	exportBaseDir := "../data/exports"
-
-	// ⏱️ TIMING: ExportAIModels
-	exportStart := time.Now()
-	// Exportar AI Models (pass match_date directly)
-	err = parser.ExportAIModels(ctx, matchID, exportBaseDir, req.MatchDate)
-	if err != nil {
-		log.Printf("⚠️  Error exportando AI models: %v", err)
-	}
-	log.Printf("⏱️ ExportAIModels took: %v", time.Since(exportStart))
-
 	// Guardar en Redis
 
 	err = db.SaveMatchData(matchID, matchData)
@@ -113,6 +180,10 @@ func HandleProcessDemo(w http.ResponseWriter, r *http.Request) {
 		"match_id": matchID,
 		"kills":    len(matchData.Kills),
 		"rounds":   len(matchData.Rounds),
+		"memory": map[string]interface{}{
+			"estimated_bytes":      result.EstimatedBytes,
+			"estimated_peak_bytes": result.EstimatedPeakBytes, // Reparto del heap del proceso, no medido por demo
+		},
 	})
 
 	log.Printf("✅ Demo procesada: %s (%d kills, %d rounds)", matchID, len(matchData.Kills), len(matchData.Rounds))
@@ -127,6 +198,12 @@ func HandleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleSchedulerStatus devuelve el estado del scheduler de demos
+func HandleSchedulerStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(getScheduler().Status())
+}
+
 // HandleGetMatchDetails obtiene detalles de un match desde Redis
 func HandleGetMatchDetails(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"cs2-demo-service/parser"
+	"cs2-demo-service/pkg/render"
+	"cs2-demo-service/plots"
+	"cs2-demo-service/scheduler"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleMatchPlot devuelve un heatmap / plot de posiciones del match en PNG.
+// GET /match/{matchID}/plot/{kind}?mode=heatmap|points&steam_id=&side=CT|T&round=&grenade=&level=&size=
+// kind: deaths, kills, grenades, positions
+func HandleMatchPlot(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	matchID := vars["matchID"]
+	if matchID == "" || filepath.Base(matchID) != matchID || matchID == ".." {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	opts := plots.Options{
+		MatchDir: filepath.Join("../data/exports", fmt.Sprintf("match_%s", matchID)),
+		MapsDir:  parser.DefaultMapsDir,
+		Kind:     vars["kind"],
+		Mode:     q.Get("mode"),
+		SteamID:  q.Get("steam_id"),
+		Side:     q.Get("side"),
+		Grenade:  q.Get("grenade"),
+		Level:    q.Get("level"),
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+		max  int
+	}{{"round", &opts.Round, 1000}, {"size", &opts.Size, 4096}} {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > p.max {
+				http.Error(w, fmt.Sprintf("Invalid %s", p.name), http.StatusBadRequest)
+				return
+			}
+			*p.dst = n
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := plots.Render(opts, &buf); err != nil {
+		switch {
+		case errors.Is(err, plots.ErrBadOptions):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, os.ErrNotExist):
+			http.Error(w, "Match exports not found", http.StatusNotFound)
+		default:
+			log.Printf("❌ Error rendering plot for %s: %v", matchID, err)
+			http.Error(w, fmt.Sprintf("Error rendering plot: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
+
+// HandleMatchRecap devuelve la animación de una ronda (GIF o APNG) desde replay.json.
+// GET /match/{matchID}/recap/{round}?format=gif|apng&speed=&fps=&size=&crop=true&level=
+func HandleMatchRecap(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	matchID := vars["matchID"]
+	if matchID == "" || filepath.Base(matchID) != matchID || matchID == ".." {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+	round, err := strconv.Atoi(vars["round"])
+	if err != nil || round < 1 {
+		http.Error(w, "Invalid round", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	opts := plots.RecapOptions{
+		MatchDir: filepath.Join("../data/exports", fmt.Sprintf("match_%s", matchID)),
+		MapsDir:  parser.DefaultMapsDir,
+		Round:    round,
+		Format:   q.Get("format"),
+		Level:    q.Get("level"),
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"fps", &opts.FPS}, {"size", &opts.Size}} {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid %s", p.name), http.StatusBadRequest)
+				return
+			}
+			*p.dst = n
+		}
+	}
+	if v := q.Get("speed"); v != "" {
+		if opts.Speed, err = strconv.ParseFloat(v, 64); err != nil || opts.Speed <= 0 {
+			http.Error(w, "Invalid speed", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("crop"); v != "" {
+		if opts.Crop, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "Invalid crop", http.StatusBadRequest)
+			return
+		}
+	}
+
+	recap, err := plots.LoadRecap(opts)
+	if err != nil {
+		writeRecapError(w, matchID, round, err)
+		return
+	}
+
+	// El render puede ocupar cientos de MB: pasa por el scheduler como una demo más
+	var buf bytes.Buffer
+	result := getScheduler().Run(scheduler.Job{
+		MatchID:        fmt.Sprintf("%s/recap-%d", matchID, round),
+		EstimatedBytes: recap.EstimatedBytes(),
+		Work:           func() error { return recap.Render(&buf) },
+	})
+	if result.Err != nil {
+		writeRecapError(w, matchID, round, result.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", render.ContentType(opts.Format))
+	w.Write(buf.Bytes())
+}
+
+// writeRecapError traduce el error de un recap a su código HTTP
+func writeRecapError(w http.ResponseWriter, matchID string, round int, err error) {
+	switch {
+	case errors.Is(err, scheduler.ErrQueueFull):
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "Render queue is full, retry later", http.StatusServiceUnavailable)
+	case errors.Is(err, plots.ErrBadOptions):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, os.ErrNotExist):
+		http.Error(w, "Replay export not found", http.StatusNotFound)
+	default:
+		log.Printf("❌ Error rendering recap for %s round %d: %v", matchID, round, err)
+		http.Error(w, fmt.Sprintf("Error rendering recap: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// HandleMapImage devuelve el fondo del mapa en PNG: el radar si está en data/maps o,
+// si no, la planta generada del physics mesh. GET /map-image/{mapName}?level=lower
+func HandleMapImage(w http.ResponseWriter, r *http.Request) {
+	mapName := mux.Vars(r)["mapName"]
+	if mapName == "" || filepath.Base(mapName) != mapName || mapName == ".." {
+		http.Error(w, "Invalid map name", http.StatusBadRequest)
+		return
+	}
+
+	img, source, err := plots.MapImage(parser.DefaultMapsDir, mapName, r.URL.Query().Get("level"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if img == nil {
+		http.Error(w, "No radar or map mesh for this map", http.StatusNotFound)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		http.Error(w, fmt.Sprintf("Error encoding image: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("X-Map-Image-Source", source)
+	w.Write(buf.Bytes())
+}
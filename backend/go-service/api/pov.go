@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"cs2-demo-service/models"
+	"cs2-demo-service/parser"
+	"cs2-demo-service/scheduler"
+)
+
+// POVRequest pide el punto de vista de un jugador en una ronda
+type POVRequest struct {
+	DemoPath string `json:"demo_path"`
+	SteamID  string `json:"steam_id"`
+	Round    int    `json:"round"`
+
+	// Optional: si se indica, el export se guarda también en match_<id>/pov_<steam>_round<n>.json
+	MatchID string `json:"match_id,omitempty"`
+
+	// Optional: FOV 4:3 del jugador (default 90) y relación de aspecto de su pantalla (default 16:9)
+	FOV         float64 `json:"fov,omitempty"`
+	AspectRatio float64 `json:"aspect_ratio,omitempty"`
+}
+
+// HandlePOV parsea la demo hasta el final de la ronda y devuelve el POV del jugador
+// a tick completo (ojos, ángulos, arma, flash, enemigos en pantalla y exposiciones).
+// POST /pov
+func HandlePOV(w http.ResponseWriter, r *http.Request) {
+	var req POVRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Error decodificando JSON", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	steamID, err := strconv.ParseUint(req.SteamID, 10, 64)
+	if err != nil || steamID == 0 {
+		http.Error(w, "Invalid steam_id", http.StatusBadRequest)
+		return
+	}
+	if req.DemoPath == "" || req.Round < 1 {
+		http.Error(w, "Faltan demo_path o round", http.StatusBadRequest)
+		return
+	}
+	if req.MatchID != "" && (filepath.Base(req.MatchID) != req.MatchID || req.MatchID == "..") {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+	info, err := os.Stat(req.DemoPath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Demo file not found: %s", req.DemoPath), http.StatusNotFound)
+		return
+	}
+
+	log.Printf("🎥 POV de %d, ronda %d: %s", steamID, req.Round, req.DemoPath)
+	// El parse hasta la ronda pasa por el scheduler como el de /process-demo
+	var export *models.POVExport
+	result := getScheduler().Run(scheduler.Job{
+		DemoPath:       req.DemoPath,
+		MatchID:        fmt.Sprintf("pov-%d-round%d", steamID, req.Round),
+		EstimatedBytes: scheduler.EstimatePOVMemory(info.Size()),
+		Work: func() (err error) {
+			export, err = parser.ExportPOV(req.DemoPath, parser.POVOptions{
+				SteamID:     steamID,
+				Round:       req.Round,
+				MapsDir:     parser.DefaultMapsDir,
+				FOV:         req.FOV,
+				AspectRatio: req.AspectRatio,
+			})
+			return err
+		},
+	})
+	err = result.Err
+	if errors.Is(err, scheduler.ErrQueueFull) {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "Demo queue is full, retry later", http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, parser.ErrPOVNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error exportando POV: %v", err)
+		http.Error(w, fmt.Sprintf("Error exportando POV: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if req.MatchID != "" {
+		export.MatchID = req.MatchID
+		if err := parser.WritePOV(parser.POVPath("../data/exports", req.MatchID, steamID, req.Round), export); err != nil {
+			log.Printf("⚠️  Error guardando POV: %v", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(export)
+}
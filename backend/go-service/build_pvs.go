@@ -0,0 +1,87 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/pkg/geometry"
+	"cs2-demo-service/pkg/maps"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Precalcula el PVS (potentially visible set) de un mapa y lo guarda junto al mesh
+// (<mapa>/<mapa>_physics.pvs). MapManager.IsVisible lo usa para descartar pares
+// imposibles antes de lanzar rayos.
+// Uso:
+//
+//	go run build_pvs.go -map de_mirage
+//	go run build_pvs.go -map de_nuke -cell 96 -height 48
+func main() {
+	mapName := flag.String("map", "", "map name (e.g. de_mirage)")
+	mapsDir := flag.String("maps", "../data/maps", "maps directory (mesh + nav)")
+	cell := flag.Float64("cell", 128, "voxel size in XY (units)")
+	height := flag.Float64("height", 64, "voxel height (units)")
+	maxDist := flag.Float64("max-dist", 4000, "pairs farther than this are kept visible without raycasting")
+	workers := flag.Int("workers", 0, "goroutines (0 = GOMAXPROCS)")
+	flag.Parse()
+
+	if *mapName == "" {
+		log.Fatal("-map is required")
+	}
+
+	// 1. Mesh (desde la caché de BVH si está al día) + nav
+	meshPath := maps.FindMeshSource(*mapsDir, *mapName)
+	if meshPath == "" {
+		log.Fatalf("No mesh found for %s in %s", *mapName, *mapsDir)
+	}
+	mesh, _, err := geometry.LoadMeshCached(meshPath, filepath.Join(*mapsDir, maps.CacheDirName))
+	if err != nil {
+		log.Fatalf("Error loading mesh: %v", err)
+	}
+	hash, err := geometry.HashMeshSource(meshPath)
+	if err != nil {
+		log.Fatalf("Error hashing mesh: %v", err)
+	}
+
+	navPath := filepath.Join(*mapsDir, *mapName, *mapName+".nav")
+	nav, err := maps.LoadNavMesh(navPath)
+	if err != nil {
+		log.Fatalf("Error loading nav mesh %s: %v", navPath, err)
+	}
+	fmt.Printf("Mesh: %d triangles | Nav: %d areas\n", len(mesh.Triangles), len(nav.Areas))
+
+	// 2. Build
+	start := time.Now()
+	lastReport := time.Now()
+	var mu sync.Mutex // Progress se llama desde los workers
+	pvs, err := maps.BuildPVS(mesh, nav, maps.PVSOptions{
+		CellSize:    *cell,
+		CellHeight:  *height,
+		MaxDistance: *maxDist,
+		Workers:     *workers,
+		Progress: func(done, total int) {
+			mu.Lock()
+			defer mu.Unlock()
+			if time.Since(lastReport) > 5*time.Second || done == total {
+				lastReport = time.Now()
+				fmt.Printf("  %d/%d voxels (%.0f%%)\n", done, total, 100*float64(done)/float64(total))
+			}
+		},
+	})
+	if err != nil {
+		log.Fatalf("Error building PVS: %v", err)
+	}
+	fmt.Printf("PVS built in %v: %d voxels (%dx%dx%d grid)\n", time.Since(start).Round(time.Millisecond),
+		pvs.VoxelCount(), pvs.Dims[0], pvs.Dims[1], pvs.Dims[2])
+
+	// 3. Guardar junto al mesh
+	outPath := maps.PVSPath(meshPath)
+	if err := maps.SavePVS(outPath, pvs, hash); err != nil {
+		log.Fatalf("Error saving PVS: %v", err)
+	}
+	fmt.Printf("✅ Saved %s\n", outPath)
+}
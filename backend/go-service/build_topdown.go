@@ -0,0 +1,72 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/pkg/geometry"
+	"cs2-demo-service/pkg/maps"
+	"cs2-demo-service/pkg/render"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// Genera la planta (top-down) del physics mesh de un mapa, una imagen por sección
+// vertical del overview, y la cachea junto al mesh. El servicio la usa de fondo
+// cuando no hay radar (plots y /map-image); si el mesh cambia se regenera sola,
+// este comando solo sirve para generarla por adelantado.
+// Uso:
+//
+//	go run build_topdown.go -map de_nuke
+//	go run build_topdown.go -map de_vertigo -size 2048
+func main() {
+	mapName := flag.String("map", "", "map name (e.g. de_nuke)")
+	mapsDir := flag.String("maps", "../data/maps", "maps directory")
+	size := flag.Int("size", render.RadarSize, "image size in pixels")
+	flag.Parse()
+
+	if *mapName == "" {
+		log.Fatal("-map is required")
+	}
+	meshPath := maps.FindMeshSource(*mapsDir, *mapName)
+	if meshPath == "" {
+		log.Fatalf("No mesh for %s in %s", *mapName, *mapsDir)
+	}
+
+	overview, err := maps.LoadOverview(*mapsDir, *mapName)
+	if err != nil {
+		fmt.Printf("⚠️  No overview (%v): using builtin map config\n", err)
+		overview = nil
+	}
+	config := maps.ResolveMapConfig(*mapName, overview)
+	fmt.Printf("Map config (%s): pos_x=%g pos_y=%g scale=%g\n", config.Source, config.PosX, config.PosY, config.Scale)
+
+	hash, err := geometry.HashMeshSource(meshPath)
+	if err != nil {
+		log.Fatalf("Error hashing %s: %v", meshPath, err)
+	}
+
+	t := render.Transform{PosX: config.PosX, PosY: config.PosY, Scale: config.Scale}
+	levels := []render.Level{render.AllLevels}
+	if len(config.Levels) > 0 {
+		levels = levels[:0]
+		for _, l := range config.Levels {
+			levels = append(levels, render.Level{Name: l.Name, Min: l.AltitudeMin, Max: l.AltitudeMax})
+		}
+	}
+
+	for _, level := range levels {
+		start := time.Now()
+		_, generated, err := render.LoadTopDown(meshPath, filepath.Join(*mapsDir, maps.CacheDirName), t, *size, level)
+		if err != nil {
+			log.Fatalf("Error generating %s: %v", level.Name, err)
+		}
+		state := "up to date"
+		if generated {
+			state = fmt.Sprintf("generated in %v", time.Since(start).Round(time.Millisecond))
+		}
+		fmt.Printf("🗺️  %s: %s (%s)\n", level.Name, render.TopDownPath(meshPath, t, *size, level, hash), state)
+	}
+}
@@ -0,0 +1,72 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/parser"
+	"flag"
+	"fmt"
+	"log"
+)
+
+// Exporta el punto de vista de un jugador en una ronda (un frame por tick) y resume
+// cuánto tiempo tuvo a cada enemigo en pantalla antes de reaccionar.
+//
+// Uso:
+//
+//	go run export_pov.go -demo ../data/demos/match.dem -steam 76561198000000000 -round 5
+//	go run export_pov.go -demo ../data/demos/match.dem -steam 76561198000000000 -round 5 -match <match_id>
+//	go run export_pov.go -demo ../data/demos/match.dem -steam 76561198000000000 -round 5 -out pov.json -fov 90 -aspect 1.333
+func main() {
+	demoPath := flag.String("demo", "", "demo file")
+	steamID := flag.Uint64("steam", 0, "player steam id 64")
+	round := flag.Int("round", 0, "round number (1-based)")
+	matchID := flag.String("match", "", "save to ../data/exports/match_<id>/pov_<steam>_round<n>.json")
+	out := flag.String("out", "", "output JSON path (default: pov_<steam>_round<n>.json)")
+	mapsDir := flag.String("maps", parser.DefaultMapsDir, "maps directory")
+	fov := flag.Float64("fov", 0, "player FOV on 4:3 (default 90)")
+	aspect := flag.Float64("aspect", 0, "screen aspect ratio (default 16:9)")
+	flag.Parse()
+
+	if *demoPath == "" || *steamID == 0 || *round < 1 {
+		log.Fatal("-demo, -steam and -round are required")
+	}
+
+	export, err := parser.ExportPOV(*demoPath, parser.POVOptions{
+		SteamID:     *steamID,
+		Round:       *round,
+		MapsDir:     *mapsDir,
+		FOV:         *fov,
+		AspectRatio: *aspect,
+	})
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	path := *out
+	switch {
+	case path != "":
+	case *matchID != "":
+		export.MatchID = *matchID
+		path = parser.POVPath("../data/exports", *matchID, *steamID, *round)
+	default:
+		path = fmt.Sprintf("pov_%d_round%d.json", *steamID, *round)
+	}
+	if err := parser.WritePOV(path, export); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	fmt.Printf("🎥 %s (%s) round %d on %s: %d ticks (%d-%d), FOV %.1f°\n", export.PlayerName, export.Team, export.Round,
+		export.MapName, len(export.Frames), export.StartTick, export.EndTick, export.FOV)
+	if !export.MeshLoaded {
+		fmt.Println("⚠️  No map mesh: visibility ignores walls")
+	}
+	for _, e := range export.Exposures {
+		reaction := "no reaction"
+		if e.Reaction != "" {
+			reaction = fmt.Sprintf("%s after %.0f ms", e.Reaction, e.ReactionMs)
+		}
+		fmt.Printf("   tick %6d  %-20s on screen %5.0f ms  %s\n", e.StartTick, e.EnemyName, e.DurationMs, reaction)
+	}
+	fmt.Printf("✅ %s\n", path)
+}
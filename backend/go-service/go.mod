@@ -5,13 +5,13 @@ go 1.23.4
 require (
 	github.com/gorilla/mux v1.8.1
 	github.com/markus-wa/demoinfocs-golang/v4 v4.4.0
+	github.com/qmuntal/gltf v0.28.0
 	github.com/redis/go-redis/v9 v9.17.0
 )
 
 require (
 	github.com/galaco/bsp v0.3.1 // indirect
 	github.com/go-gl/mathgl v1.0.0 // indirect
-	github.com/qmuntal/gltf v0.28.0 // indirect
 	golang.org/x/image v0.18.0 // indirect
 )
 
@@ -23,7 +23,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/markus-wa/go-unassert v0.1.3 // indirect
 	github.com/markus-wa/gobitread v0.2.4 // indirect
-	github.com/markus-wa/godispatch v1.4.1 // indirect
+	github.com/markus-wa/godispatch v1.4.1
 	github.com/markus-wa/ice-cipher-go v0.0.0-20230901094113-348096939ba7 // indirect
 	github.com/markus-wa/quickhull-go/v2 v2.2.0 // indirect
 	github.com/oklog/ulid/v2 v2.1.0 // indirect
@@ -0,0 +1,87 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/parser"
+	"cs2-demo-service/pkg/golden"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Regenera el baseline (golden files) de una demo para TestGoldenExports (parser/golden_test.go).
+// Parsea la demo -runs veces, se niega a escribir si las ejecuciones no son idénticas
+// byte a byte y guarda la primera en parser/testdata/golden/<demo>/. La comparación
+// contra el baseline la hace go test ./parser.
+//
+// Uso:
+//
+//	go run golden_check.go -demo ../data/demos/<demo>.dem [-maps ../data/maps] [-runs 2]
+func main() {
+	demoPath := flag.String("demo", "", "demo file (required)")
+	goldenDir := flag.String("golden", "", "golden baseline directory (default parser/testdata/golden/<demo>)")
+	mapsDir := flag.String("maps", parser.DefaultMapsDir, "maps directory")
+	runs := flag.Int("runs", 2, "parse runs (>1 also checks run-to-run determinism)")
+	maxDiffs := flag.Int("max-diffs", 50, "max differences to print")
+	flag.Parse()
+
+	if *demoPath == "" {
+		log.Fatal("-demo is required")
+	}
+	if *goldenDir == "" {
+		name := strings.TrimSuffix(filepath.Base(*demoPath), filepath.Ext(*demoPath))
+		*goldenDir = filepath.Join("parser", "testdata", "golden", name)
+	}
+	if *runs < 1 {
+		*runs = 1
+	}
+
+	tmpDir, err := os.MkdirTemp("", "golden-*")
+	if err != nil {
+		log.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// 1. Parse + export N veces (mismo match id que parser/golden_test.go)
+	const matchID = "golden"
+	var outputs []string
+	for i := 0; i < *runs; i++ {
+		outputDir := filepath.Join(tmpDir, fmt.Sprintf("run%d", i))
+
+		opts := parser.DefaultParseOptions()
+		opts.MapsDir = *mapsDir
+		opts.FlushDir = parser.PartialDir(outputDir, matchID)
+
+		fmt.Printf("Run %d/%d: parsing %s\n", i+1, *runs, *demoPath)
+		result, err := parser.ParseDemoWithOptions(*demoPath, opts)
+		if err != nil {
+			log.Fatalf("Error parsing demo: %v", err)
+		}
+		if err := parser.ExportAIModels(result.Context, matchID, outputDir, ""); err != nil {
+			log.Fatalf("Error exporting AI models: %v", err)
+		}
+		outputs = append(outputs, filepath.Join(outputDir, "match_"+matchID))
+	}
+
+	// 2. Determinismo: un baseline no determinista haría fallar el test sin motivo
+	for i := 1; i < len(outputs); i++ {
+		report, err := golden.CompareDirs(outputs[0], outputs[i])
+		if err != nil {
+			log.Fatalf("Error comparing runs: %v", err)
+		}
+		if !report.OK() {
+			fmt.Printf("❌ Run %d differs from run 1 (non-deterministic output)\n%s", i+1, report.Summary(*maxDiffs))
+			os.Exit(1)
+		}
+	}
+
+	// 3. Escribir el baseline
+	if err := golden.Update(*goldenDir, outputs[0]); err != nil {
+		log.Fatalf("Error updating golden files: %v", err)
+	}
+	fmt.Printf("✅ Golden baseline updated: %s\n", *goldenDir)
+}
@@ -0,0 +1,292 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/pkg/grenade"
+	"cs2-demo-service/pkg/maps"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/r3"
+	dem "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+)
+
+// Simulador de granadas (pkg/grenade) contra el physics mesh del mapa.
+//
+// Lineup: dónde cae una granada lanzada desde una posición (la de getpos, a la altura
+// de los pies) con unos ángulos y un tipo de lanzamiento:
+//
+//	go run grenade_sim.go -map de_mirage -getpos "setpos -1200 -1300 -100;setang -40.5 145 0" -kind smoke -style jump
+//	go run grenade_sim.go -map de_mirage -pos -1200,-1300,-100 -angles -40.5,145 -kind flash -strength 0.5
+//
+// Validación: simula cada granada de una demo y la compara con la trayectoria
+// observada, desde el estado inicial del proyectil (solo física) y desde el
+// lanzamiento del jugador (ojos + ángulos + velocidad):
+//
+//	go run grenade_sim.go -demo ../data/demos/match.dem
+//	go run grenade_sim.go -demo ../data/demos/match.dem -v
+func main() {
+	mapsDir := flag.String("maps", "../data/maps", "maps directory")
+	demoPath := flag.String("demo", "", "validate against every grenade of this demo")
+	verbose := flag.Bool("v", false, "print every grenade (validation)")
+	mapName := flag.String("map", "", "map name (lineup)")
+	getpos := flag.String("getpos", "", `output of getpos ("setpos x y z;setang pitch yaw roll")`)
+	posFlag := flag.String("pos", "", "player position x,y,z (feet, like setpos)")
+	anglesFlag := flag.String("angles", "", "pitch,yaw in degrees")
+	kindFlag := flag.String("kind", "smoke", "smoke, flash, he, molotov, decoy")
+	style := flag.String("style", "stand", "stand, walk, run, jump, runjump")
+	strength := flag.Float64("strength", grenade.StrengthLeft, "throw strength: 1 left click, 0.5 both, 0 right click")
+	crouch := flag.Bool("crouch", false, "crouched throw (lower eye height)")
+	flag.Parse()
+
+	if *demoPath != "" {
+		validate(*demoPath, *mapsDir, *verbose)
+		return
+	}
+
+	if *mapName == "" {
+		log.Fatal("-map or -demo is required")
+	}
+	kind, ok := grenade.ParseKind(*kindFlag)
+	if !ok {
+		log.Fatalf("Unknown grenade kind %q", *kindFlag)
+	}
+	pos, pitch, yaw, err := parseLineup(*getpos, *posFlag, *anglesFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	vel, ok := grenade.StyleVelocity(grenade.Style(*style), yaw)
+	if !ok {
+		log.Fatalf("Unknown throw style %q", *style)
+	}
+
+	mm := maps.NewMapManager(*mapsDir)
+	if err := mm.LoadMap(*mapName); err != nil {
+		log.Fatalf("Error loading %s: %v", *mapName, err)
+	}
+	throw := grenade.Throw{Eye: eyePosition(pos, *crouch), Pitch: pitch, Yaw: yaw, Strength: *strength, PlayerVelocity: vel}
+	res := grenade.Predict(mm, kind, throw)
+
+	fmt.Printf("💣 %s %s from (%.0f, %.0f, %.0f) pitch=%.2f yaw=%.2f strength=%.1f\n", kind, *style, pos.X, pos.Y, pos.Z, pitch, yaw, *strength)
+	for i, b := range res.Bounces {
+		fmt.Printf("   bounce %d at %.2fs: (%.0f, %.0f, %.0f) %s\n", i+1, float64(b.Tick)/grenade.TickRate,
+			b.Position.X, b.Position.Y, b.Position.Z, mm.GetCallout(b.Position))
+	}
+	where := "lands"
+	if res.Airborne {
+		where = "detonates in the air"
+	}
+	fmt.Printf("✅ %s at (%.0f, %.0f, %.0f) %s after %.2fs (%s)\n", where, res.Land.X, res.Land.Y, res.Land.Z,
+		mm.GetCallout(res.Land), res.Seconds(), res.End)
+}
+
+// parseLineup lee la posición (pies) y los ángulos de -getpos o de -pos/-angles
+func parseLineup(getpos, pos, angles string) (r3.Vector, float64, float64, error) {
+	if getpos != "" {
+		var p r3.Vector
+		var pitch, yaw, roll float64
+		s := strings.NewReplacer(";", " ", "\"", " ").Replace(getpos)
+		if _, err := fmt.Sscanf(s, "setpos %g %g %g setang %g %g %g", &p.X, &p.Y, &p.Z, &pitch, &yaw, &roll); err != nil {
+			return p, 0, 0, fmt.Errorf("invalid -getpos %q: %w", getpos, err)
+		}
+		return p, pitch, yaw, nil
+	}
+	xyz, err := parseFloats(pos, 3)
+	if err != nil {
+		return r3.Vector{}, 0, 0, fmt.Errorf("invalid -pos: %w", err)
+	}
+	ang, err := parseFloats(angles, 2)
+	if err != nil {
+		return r3.Vector{}, 0, 0, fmt.Errorf("invalid -angles: %w", err)
+	}
+	return r3.Vector{X: xyz[0], Y: xyz[1], Z: xyz[2]}, ang[0], ang[1], nil
+}
+
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected %d comma-separated values, got %q", n, s)
+	}
+	out := make([]float64, n)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// eyePosition suma la altura de los ojos de CS2 (64 de pie, 46 agachado)
+func eyePosition(feet r3.Vector, crouched bool) r3.Vector {
+	if crouched {
+		return feet.Add(r3.Vector{Z: 46})
+	}
+	return feet.Add(r3.Vector{Z: 64})
+}
+
+// observedGrenade es una granada de la demo con su lanzamiento y trayectoria
+type observedGrenade struct {
+	kind       grenade.Kind
+	thrower    string
+	tick       int
+	throw      grenade.Throw
+	initialPos r3.Vector
+	initialVel r3.Vector
+	hasInitial bool
+	positions  []r3.Vector
+	land       r3.Vector
+}
+
+// validate parsea la demo, simula cada granada y resume los errores
+func validate(demoPath, mapsDir string, verbose bool) {
+	f, err := os.Open(demoPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	p := dem.NewParser(f)
+	defer p.Close()
+
+	var mm *maps.MapManager
+	active := make(map[int]*observedGrenade)
+	var done []*observedGrenade
+
+	p.RegisterEventHandler(func(e events.GrenadeProjectileThrow) {
+		proj := e.Projectile
+		if proj == nil || proj.Thrower == nil || proj.WeaponInstance == nil {
+			return
+		}
+		kind, ok := grenade.ParseKind(proj.WeaponInstance.Type.String())
+		if !ok {
+			return
+		}
+		if mm == nil {
+			mm = maps.NewMapManager(mapsDir)
+			if err := mm.LoadMap(p.Header().MapName); err != nil {
+				log.Fatalf("Error loading %s: %v", p.Header().MapName, err)
+			}
+		}
+		thrower := proj.Thrower
+		g := &observedGrenade{
+			kind:    kind,
+			thrower: thrower.Name,
+			tick:    p.GameState().IngameTick(),
+			throw: grenade.Throw{
+				Eye:            eyePosition(thrower.Position(), thrower.IsDucking()),
+				Pitch:          float64(thrower.ViewDirectionY()),
+				Yaw:            float64(thrower.ViewDirectionX()),
+				Strength:       throwStrength(thrower),
+				PlayerVelocity: thrower.Velocity(),
+			},
+		}
+		if pos, vel, ok := initialState(proj); ok {
+			g.initialPos, g.initialVel, g.hasInitial = pos, vel, true
+		}
+		active[proj.Entity.ID()] = g
+	})
+
+	p.RegisterEventHandler(func(e events.FrameDone) {
+		for _, proj := range p.GameState().GrenadeProjectiles() {
+			if g, ok := active[proj.Entity.ID()]; ok {
+				g.positions = append(g.positions, proj.Position())
+			}
+		}
+	})
+
+	p.RegisterEventHandler(func(e events.GrenadeProjectileDestroy) {
+		if e.Projectile == nil {
+			return
+		}
+		g, ok := active[e.Projectile.Entity.ID()]
+		if !ok {
+			return
+		}
+		delete(active, e.Projectile.Entity.ID())
+		g.land = e.Projectile.Position()
+		if len(g.positions) > 1 {
+			done = append(done, g)
+		}
+	})
+
+	if err := p.ParseToEnd(); err != nil {
+		log.Fatalf("Error parsing demo: %v", err)
+	}
+	if len(done) == 0 {
+		log.Fatal("No grenades in the demo")
+	}
+
+	var physics, throws []grenade.Deviation
+	for _, g := range done {
+		start := g.positions[0]
+		var phys grenade.Deviation
+		if g.hasInitial {
+			phys = grenade.Compare(grenade.Simulate(mm, g.kind, g.initialPos, g.initialVel), g.positions, g.land)
+			physics = append(physics, phys)
+		}
+		thr := grenade.Compare(grenade.Predict(mm, g.kind, g.throw), g.positions, g.land)
+		throws = append(throws, thr)
+		if verbose {
+			fmt.Printf("tick %6d %-8s %-16s from (%.0f, %.0f, %.0f): physics land %6.1f path %6.1f | throw land %6.1f path %6.1f\n",
+				g.tick, g.kind, g.thrower, start.X, start.Y, start.Z, phys.LandError, phys.MeanError, thr.LandError, thr.MeanError)
+		}
+	}
+
+	fmt.Printf("Grenades: %d\n", len(done))
+	printSummary("Physics (initial state)", physics)
+	printSummary("Throw (eye + angles)", throws)
+}
+
+// initialState lee m_vInitialPosition / m_vInitialVelocity del proyectil (CS2)
+func initialState(proj *common.GrenadeProjectile) (r3.Vector, r3.Vector, bool) {
+	pos := proj.Entity.Property("m_vInitialPosition")
+	vel := proj.Entity.Property("m_vInitialVelocity")
+	if pos == nil || vel == nil || pos.Value().Any == nil || vel.Value().Any == nil {
+		return r3.Vector{}, r3.Vector{}, false
+	}
+	return pos.Value().R3Vec(), vel.Value().R3Vec(), true
+}
+
+// throwStrength lee m_flThrowStrength de la granada en la mano (1 si no está)
+func throwStrength(p *common.Player) float64 {
+	w := p.ActiveWeapon()
+	if w == nil || w.Entity == nil {
+		return grenade.StrengthLeft
+	}
+	if prop := w.Entity.Property("m_flThrowStrength"); prop != nil && prop.Value().Any != nil {
+		return float64(prop.Value().Float())
+	}
+	return grenade.StrengthLeft
+}
+
+func printSummary(name string, devs []grenade.Deviation) {
+	if len(devs) == 0 {
+		fmt.Printf("%s: no data\n", name)
+		return
+	}
+	land := make([]float64, len(devs))
+	path := 0.0
+	within := 0
+	for i, d := range devs {
+		land[i] = d.LandError
+		path += d.MeanError
+		if d.LandError <= 50 {
+			within++
+		}
+	}
+	sort.Float64s(land)
+	fmt.Printf("%s: land error median %.1f p90 %.1f | mean path error %.1f | %.0f%% within 50u\n", name,
+		land[len(land)/2], land[int(math.Min(float64(len(land)-1), float64(len(land))*0.9))],
+		path/float64(len(devs)), 100*float64(within)/float64(len(devs)))
+}
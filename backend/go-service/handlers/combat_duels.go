@@ -56,8 +56,21 @@ func ConsolidateDuels(ctx *models.DemoContext) {
 		return
 	}
 
-	// Sort events by tick
-	sort.Slice(ctx.RawCombatEvents, func(i, j int) bool {
+	// Keep a copy of the input so duels can be rebuilt from the intermediate cache
+	if ctx.KeepRawCombat {
+		batch := models.RawCombatBatch{
+			Round:            ctx.ActualRoundNumber,
+			Events:           append([]models.RawCombatEvent{}, ctx.RawCombatEvents...),
+			LastActiveWeapon: make(map[uint64]string, len(ctx.LastActiveWeapon)),
+		}
+		for id, w := range ctx.LastActiveWeapon {
+			batch.LastActiveWeapon[id] = w
+		}
+		ctx.RawCombatHistory = append(ctx.RawCombatHistory, batch)
+	}
+
+	// Sort events by tick (stable: same-tick events keep their arrival order)
+	sort.SliceStable(ctx.RawCombatEvents, func(i, j int) bool {
 		return ctx.RawCombatEvents[i].Tick < ctx.RawCombatEvents[j].Tick
 	})
 
@@ -120,6 +133,7 @@ func ConsolidateDuels(ctx *models.DemoContext) {
 	// PASS 2: Process NORMAL WEAPON events (group by player PAIR - bidirectional)
 	// All damage between two players forms ONE duel, winner determined by who got the kill
 	duelGroups := make(map[string][]models.RawCombatEvent)
+	var duelKeys []string // Orden de primera aparición (determinista, no depende del map)
 
 	for i, event := range ctx.RawCombatEvents {
 		if processed[i] {
@@ -127,12 +141,15 @@ func ConsolidateDuels(ctx *models.DemoContext) {
 		}
 		// Use canonical key: both directions grouped together
 		key := makeDuelKey(event.AttackerSteamID, event.VictimSteamID)
+		if _, exists := duelGroups[key]; !exists {
+			duelKeys = append(duelKeys, key)
+		}
 		duelGroups[key] = append(duelGroups[key], event)
 	}
 
 	// Split each pair's events by timeout and build duels
-	for _, events := range duelGroups {
-		duels := splitByTimeout(events, DuelTimeoutTicks)
+	for _, key := range duelKeys {
+		duels := splitByTimeout(duelGroups[key], DuelTimeoutTicks)
 		for _, duelEvents := range duels {
 			duel := buildMultiVictimDuel(ctx, duelEvents, false)
 			if duel != nil {
@@ -192,25 +209,22 @@ func buildMultiVictimDuel(ctx *models.DemoContext, events []models.RawCombatEven
 	// For normal weapons (not grenades), we need to handle bidirectional 1v1 duels
 	// Collect all unique participants (both as attackers and victims)
 	if !isGrenadeEvent {
-		// Get all unique player IDs involved
+		// Get all unique player IDs involved (in order of appearance, so player1 is
+		// always the first attacker and damage ties resolve the same way every run)
 		participantIDs := make(map[uint64]bool)
+		var participantOrder []uint64
 		for _, e := range events {
-			participantIDs[e.AttackerSteamID] = true
-			participantIDs[e.VictimSteamID] = true
+			for _, id := range [2]uint64{e.AttackerSteamID, e.VictimSteamID} {
+				if !participantIDs[id] {
+					participantIDs[id] = true
+					participantOrder = append(participantOrder, id)
+				}
+			}
 		}
 
 		// If exactly 2 participants, this is a 1v1 duel - need to determine winner/loser
 		if len(participantIDs) == 2 {
-			var player1ID, player2ID uint64
-			i := 0
-			for id := range participantIDs {
-				if i == 0 {
-					player1ID = id
-				} else {
-					player2ID = id
-				}
-				i++
-			}
+			player1ID, player2ID := participantOrder[0], participantOrder[1]
 
 			// Calculate damage dealt BY each player (when they are attacker)
 			p1DamageDealt := 0
@@ -459,6 +473,10 @@ func buildMultiVictimDuel(ctx *models.DemoContext, events []models.RawCombatEven
 					ThroughSmoke:       anyThroughSmoke(events),
 					IsWallbang:         anyWallbang(events),
 					PenetratedObjects:  maxPenetratedObjects(events),
+					Wallbangable:       anyWallbangable(events),
+					WallbangDamage:     maxWallbangDamage(events),
+					TradeTime:          killTradeTime(events),
+					TradePossible:      killTradePossible(events),
 					NoScope:            getNoScopePtr(winnerStats.Weapon, contextEvent.NoScope),
 					ZoomLevel:          getZoomLevelPtr(winnerStats.Weapon, contextEvent.ZoomLevel),
 					BombPlanted:        contextEvent.BombPlanted,
@@ -476,6 +494,7 @@ func buildMultiVictimDuel(ctx *models.DemoContext, events []models.RawCombatEven
 
 	// Collect unique victims
 	victimMap := make(map[uint64]*playerDuelStats)
+	var victimOrder []uint64 // Orden de primera aparición
 	for _, e := range events {
 		if e.VictimSteamID == 0 {
 			continue
@@ -484,6 +503,7 @@ func buildMultiVictimDuel(ctx *models.DemoContext, events []models.RawCombatEven
 			victimMap[e.VictimSteamID] = &playerDuelStats{
 				SteamID: e.VictimSteamID,
 			}
+			victimOrder = append(victimOrder, e.VictimSteamID)
 		}
 	}
 
@@ -493,7 +513,7 @@ func buildMultiVictimDuel(ctx *models.DemoContext, events []models.RawCombatEven
 	killCount := 0
 	var lastKillEvent *models.RawCombatEvent
 
-	for victimID := range victimMap {
+	for _, victimID := range victimOrder {
 		stats := aggregateVictimStats(events, victimID)
 		if stats.HealthBefore == 0 && stats.TotalDamage == 0 {
 			continue // Skip if no actual damage
@@ -705,6 +725,10 @@ func buildMultiVictimDuel(ctx *models.DemoContext, events []models.RawCombatEven
 			ThroughSmoke:       anyThroughSmoke(events),
 			IsWallbang:         anyWallbang(events),
 			PenetratedObjects:  maxPenetratedObjects(events),
+			Wallbangable:       anyWallbangable(events),
+			WallbangDamage:     maxWallbangDamage(events),
+			TradeTime:          killTradeTime(events),
+			TradePossible:      killTradePossible(events),
 			NoScope:            getNoScopePtr(attackerStats.Weapon, contextEvent.NoScope),
 			ZoomLevel:          getZoomLevelPtr(attackerStats.Weapon, contextEvent.ZoomLevel),
 			BombPlanted:        contextEvent.BombPlanted,
@@ -945,6 +969,47 @@ func maxPenetratedObjects(events []models.RawCombatEvent) int {
 	return max
 }
 
+// anyWallbangable returns true if the victim was wallbangable in any event of the duel
+func anyWallbangable(events []models.RawCombatEvent) bool {
+	for _, e := range events {
+		if e.Wallbangable {
+			return true
+		}
+	}
+	return false
+}
+
+// maxWallbangDamage returns the max estimated wallbang damage from all events
+func maxWallbangDamage(events []models.RawCombatEvent) int {
+	max := 0
+	for _, e := range events {
+		if e.WallbangDamage > max {
+			max = e.WallbangDamage
+		}
+	}
+	return max
+}
+
+// killTradeTime returns the trade time estimated at the kill of the duel (0 = unknown)
+func killTradeTime(events []models.RawCombatEvent) float64 {
+	for _, e := range events {
+		if e.IsKill {
+			return e.TradeTime
+		}
+	}
+	return 0
+}
+
+// killTradePossible returns true if a teammate of the victim could have traded the kill in time
+func killTradePossible(events []models.RawCombatEvent) bool {
+	for _, e := range events {
+		if e.IsKill {
+			return e.TradePossible
+		}
+	}
+	return false
+}
+
 // ============================================================================
 // RAW EVENT CAPTURE FUNCTIONS
 // Capture kill/damage events for later consolidation
@@ -1067,6 +1132,9 @@ func captureRawKillEvent(ctx *models.DemoContext, e *events.Kill) {
 	killerMapArea := getAreaName(ctx, e.Killer)
 	victimMapArea := getAreaName(ctx, e.Victim)
 
+	wallbangable, wallbangDamage := estimateWallbang(ctx, e.Killer, e.Victim, e.Weapon)
+	tradeTime, hasTradeTime := estimateTradeTime(ctx, e.Killer, e.Victim)
+
 	rawEvent := models.RawCombatEvent{
 		Tick:   ctx.Parser.GameState().IngameTick(),
 		Round:  ctx.ActualRoundNumber,
@@ -1113,6 +1181,10 @@ func captureRawKillEvent(ctx *models.DemoContext, e *events.Kill) {
 		ThroughSmoke:      e.ThroughSmoke,
 		IsWallbang:        e.IsWallBang(),
 		PenetratedObjects: e.PenetratedObjects,
+		Wallbangable:      wallbangable,
+		WallbangDamage:    wallbangDamage,
+		TradeTime:         tradeTime,
+		TradePossible:     hasTradeTime && tradeTime <= TradeWindowSeconds,
 		IsHeadshot:        e.IsHeadshot,
 		NoScope:           e.NoScope,
 		ZoomLevel:         getZoomLevel(e.Killer),
@@ -1239,6 +1311,8 @@ func captureRawDamageEvent(ctx *models.DemoContext, e *events.PlayerHurt) {
 	attackerMapArea := getAreaName(ctx, e.Attacker)
 	victimMapArea := getAreaName(ctx, e.Player)
 
+	wallbangable, wallbangDamage := estimateWallbang(ctx, e.Attacker, e.Player, e.Weapon)
+
 	rawEvent := models.RawCombatEvent{
 		Tick:   ctx.Parser.GameState().IngameTick(),
 		Round:  ctx.ActualRoundNumber,
@@ -1285,6 +1359,8 @@ func captureRawDamageEvent(ctx *models.DemoContext, e *events.PlayerHurt) {
 		ThroughSmoke:      isThroughSmoke(ctx, attackerPos, victimPos),
 		IsWallbang:        false, // Not available in PlayerHurt
 		PenetratedObjects: 0,
+		Wallbangable:      wallbangable,
+		WallbangDamage:    wallbangDamage,
 		IsHeadshot:        e.HitGroup == events.HitGroupHead,
 		NoScope:           false,
 		ZoomLevel:         getZoomLevel(e.Attacker),
@@ -1341,30 +1417,9 @@ func isFirstKillOfRound(ctx *models.DemoContext) bool {
 	return true
 }
 
-// isThroughSmoke checks if a shot went through smoke
+// isThroughSmoke checks if a shot went through smoke (volumetric opacity, see pkg/smoke)
 func isThroughSmoke(ctx *models.DemoContext, from, to r3.Vector) bool {
-	for _, smokePos := range ctx.ActiveSmokes {
-		// Simple check: if smoke center is within ~144 units of the line between shooter and target
-		dist := distancePointToSegment(smokePos, from, to)
-		if dist < 144 {
-			return true
-		}
-	}
-	return false
-}
-
-// distancePointToSegment calculates distance from point P to line segment AB
-func distancePointToSegment(p, a, b r3.Vector) float64 {
-	ab := b.Sub(a)
-	ap := p.Sub(a)
-	t := ap.Dot(ab) / ab.Dot(ab)
-	if t < 0.0 {
-		t = 0.0
-	} else if t > 1.0 {
-		t = 1.0
-	}
-	nearest := a.Add(ab.Mul(t))
-	return p.Sub(nearest).Norm()
+	return ctx.Smokes.Blocks(from, to, ctx.Parser.GameState().IngameTick())
 }
 
 // countVisibleEnemies counts how many enemies are visible to the player at this moment
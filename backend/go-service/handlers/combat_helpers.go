@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"cs2-demo-service/models"
+	"cs2-demo-service/pkg/geometry"
+	"cs2-demo-service/pkg/maps"
 	"math"
 
+	"github.com/golang/geo/r3"
 	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
 )
 
@@ -114,6 +117,16 @@ func getZoomLevelPtr(weapon string, zoomLevel int) *int {
 	return nil
 }
 
+// intPtr returns a pointer to v, for fields that must keep their zero value in JSON
+func intPtr(v int) *int {
+	return &v
+}
+
+// floatPtr returns a pointer to v, for fields that must keep their zero value in JSON
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
 // --- NEW ENRICHMENT HELPERS ---
 
 // getPlayerVelocityVector returns the 3D velocity components
@@ -304,3 +317,97 @@ func calculateHeightDiff(killer, victim *common.Player) float64 {
 	}
 	return killer.Position().Z - victim.Position().Z
 }
+
+// Eye / chest offsets over the player origin (standing vs. ducking)
+const (
+	eyeHeightStanding   = 64.0
+	eyeHeightDucking    = 46.0
+	chestHeightStanding = 45.0
+	chestHeightDucking  = 32.0
+)
+
+// estimateWallbang checks whether the victim was behind geometry the attacker's
+// weapon could shoot through (attacker eyes -> victim chest).
+// Returns the estimated damage per bullet; false if the line is clear, the bullet
+// is stopped, the weapon cannot penetrate or no map mesh is loaded.
+func estimateWallbang(ctx *models.DemoContext, attacker, victim *common.Player, weapon *common.Equipment) (bool, int) {
+	if attacker == nil || victim == nil || weapon == nil || ctx.MapManager == nil {
+		return false, 0
+	}
+	checker, ok := ctx.MapManager.(maps.PenetrationChecker)
+	if !ok {
+		return false, 0
+	}
+	ballistics, ok := models.GetWeaponBallistics(weapon.String())
+	if !ok {
+		return false, 0
+	}
+
+	eyes := attacker.Position()
+	if isPlayerDucking(attacker) {
+		eyes.Z += eyeHeightDucking
+	} else {
+		eyes.Z += eyeHeightStanding
+	}
+	chest := victim.Position()
+	if isPlayerDucking(victim) {
+		chest.Z += chestHeightDucking
+	} else {
+		chest.Z += chestHeightStanding
+	}
+
+	result, ok := checker.EstimatePenetration(eyes, chest, geometry.Ballistics{
+		Damage:        ballistics.Damage,
+		Penetration:   ballistics.Penetration,
+		RangeModifier: ballistics.RangeModifier,
+	})
+	if !ok || !result.Wallbang() {
+		return false, 0
+	}
+	return true, int(math.Round(result.Damage))
+}
+
+// TradeWindowSeconds is the time a teammate has to trade a death (same window as player stats)
+const TradeWindowSeconds = 5.0
+
+// pathDistance returns the nav mesh path length from -> to. Without nav mesh (or
+// without path) it falls back to the straight line and byPath=false.
+func pathDistance(ctx *models.DemoContext, from, to r3.Vector) (dist float64, byPath bool) {
+	if finder, ok := ctx.MapManager.(maps.PathFinder); ok {
+		if path, ok := finder.FindPath(from, to); ok {
+			return path.Length, true
+		}
+	}
+	return from.Sub(to).Norm(), false
+}
+
+// estimateTradeTime returns the seconds the closest alive teammate of the victim
+// needs to reach the attacker by nav path (running with their active weapon).
+// false if no teammate is alive or there is no nav mesh.
+func estimateTradeTime(ctx *models.DemoContext, attacker, victim *common.Player) (float64, bool) {
+	if attacker == nil || victim == nil || ctx.MapManager == nil {
+		return 0, false
+	}
+	finder, ok := ctx.MapManager.(maps.PathFinder)
+	if !ok {
+		return 0, false
+	}
+
+	best := math.Inf(1)
+	for _, mate := range ctx.Parser.GameState().Participants().TeamMembers(victim.Team) {
+		if mate == nil || mate.SteamID64 == victim.SteamID64 || !mate.IsAlive() {
+			continue
+		}
+		path, ok := finder.FindPath(mate.Position(), attacker.Position())
+		if !ok {
+			continue
+		}
+		if t := models.EstimateRunTime(path.Length, getActiveWeapon(mate)); t < best {
+			best = t
+		}
+	}
+	if math.IsInf(best, 1) {
+		return 0, false
+	}
+	return best, true
+}
@@ -473,8 +473,10 @@ func RegisterGrenadeHandlers(ctx *models.DemoContext) {
 		// Molotov/Incendiary Damage
 		if e.Weapon != nil && (e.Weapon.Type == common.EqMolotov || e.Weapon.Type == common.EqIncendiary) {
 			// Find active inferno for this attacker
-			// Since we don't have direct link, we iterate active infernos
-			for _, idx := range ctx.ActiveInfernos {
+			// Since we don't have direct link, we iterate active infernos (sorted, so the
+			// first match is always the same one)
+			for _, id := range models.SortedKeys(ctx.ActiveInfernos) {
+				idx := ctx.ActiveInfernos[id]
 				if ctx.AI_GrenadeEvents[idx].Thrower == e.Attacker.Name {
 					ctx.AI_GrenadeEvents[idx].DamageDealt += e.HealthDamage
 					isEnemy := e.Player.Team != e.Attacker.Team
@@ -641,8 +643,10 @@ func findGrenadeTrajectory(ctx *models.DemoContext, throwerID uint64, grenadeTyp
 	minDist := 1000.0 // Max distance threshold
 
 	// Helper function to check a map of trajectories
+	// (sorted by entity ID so distance ties always resolve to the same trajectory)
 	checkTrajectories := func(trajectories map[int]*models.GrenadeTrajectoryEvent) {
-		for id, traj := range trajectories {
+		for _, id := range models.SortedKeys(trajectories) {
+			traj := trajectories[id]
 			if traj.ThrowerID != throwerID {
 				continue
 			}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"cs2-demo-service/models"
+
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+)
+
+// RegisterLifecycleHandlers registra el tracking de conexiones, desconexiones,
+// control de bots y equipo por ronda de cada jugador humano.
+// Debe registrarse DESPUÉS de RegisterTimelineHandlers (usa ActualRoundNumber).
+func RegisterLifecycleHandlers(ctx *models.DemoContext) {
+	// Connect / Reconnect
+	ctx.Parser.RegisterEventHandler(func(e events.PlayerConnect) {
+		if e.Player == nil || e.Player.SteamID64 == 0 {
+			return // Bots llegan como BotConnect
+		}
+		tick := ctx.Parser.GameState().IngameTick()
+		lc := ctx.GetOrCreateLifecycle(e.Player.SteamID64, e.Player.Name)
+
+		if lc.Connected {
+			return // Evento duplicado
+		}
+		lc.Connected = true
+		lc.ConnectTicks = append(lc.ConnectTicks, tick)
+		if len(lc.DisconnectTicks) > 0 {
+			lc.ReconnectTicks = append(lc.ReconnectTicks, tick)
+		}
+
+		// Si reconecta con la ronda en curso, cuenta como presente desde este tick
+		markLatePresence(ctx, e.Player, tick)
+	})
+
+	// Disconnect
+	ctx.Parser.RegisterEventHandler(func(e events.PlayerDisconnected) {
+		if e.Player == nil || e.Player.SteamID64 == 0 {
+			return
+		}
+		tick := ctx.Parser.GameState().IngameTick()
+		lc := ctx.GetOrCreateLifecycle(e.Player.SteamID64, e.Player.Name)
+
+		if !lc.Connected && len(lc.ConnectTicks) > 0 {
+			return // Evento duplicado
+		}
+		lc.Connected = false
+		lc.DisconnectTicks = append(lc.DisconnectTicks, tick)
+		lc.DisconnectRounds = append(lc.DisconnectRounds, ctx.ActualRoundNumber)
+
+		if rp, exists := lc.Rounds[ctx.ActualRoundNumber]; exists && ctx.InRound {
+			rp.LeftTick = tick
+		}
+	})
+
+	// Team switches (halftime, coach, reconnect al otro equipo...)
+	ctx.Parser.RegisterEventHandler(func(e events.PlayerTeamChange) {
+		if e.Player == nil || e.Player.SteamID64 == 0 || e.OldTeam == e.NewTeam {
+			return
+		}
+		tick := ctx.Parser.GameState().IngameTick()
+		lc := ctx.GetOrCreateLifecycle(e.Player.SteamID64, e.Player.Name)
+		lc.TeamChanges = append(lc.TeamChanges, models.TeamChange{
+			Tick:  tick,
+			Round: ctx.ActualRoundNumber,
+			From:  getTeamString(e.OldTeam),
+			To:    getTeamString(e.NewTeam),
+		})
+
+		markLatePresence(ctx, e.Player, tick)
+	})
+
+	// Round start: snapshot de quién está presente y en qué equipo
+	ctx.Parser.RegisterEventHandler(func(e events.RoundStart) {
+		gs := ctx.Parser.GameState()
+		if gs.IsWarmupPeriod() || ctx.ActualRoundNumber == 0 {
+			return
+		}
+
+		for _, p := range gs.Participants().Playing() {
+			if p == nil || p.SteamID64 == 0 || !p.IsConnected {
+				continue
+			}
+			team := getTeamString(p.Team)
+			if team == "" {
+				continue
+			}
+			lc := ctx.GetOrCreateLifecycle(p.SteamID64, p.Name)
+			if !lc.Connected && len(lc.ConnectTicks) == 0 {
+				// Conectado antes del inicio de la demo
+				lc.Connected = true
+				lc.ConnectTicks = append(lc.ConnectTicks, 0)
+			}
+			lc.MarkPresent(ctx.ActualRoundNumber, team, 0)
+		}
+	})
+
+	// Bot takeover: el humano controla un bot durante el resto de la ronda
+	ctx.Parser.RegisterEventHandler(func(e events.BotTakenOver) {
+		if e.Taker == nil || e.Taker.SteamID64 == 0 || ctx.ActualRoundNumber == 0 {
+			return
+		}
+		lc := ctx.GetOrCreateLifecycle(e.Taker.SteamID64, e.Taker.Name)
+		rp := lc.MarkPresent(ctx.ActualRoundNumber, getTeamString(e.Taker.Team), ctx.Parser.GameState().IngameTick())
+		rp.BotControlled = true
+		rp.BotTakeoverTick = ctx.Parser.GameState().IngameTick()
+		if bot := e.Taker.ControlledBot(); bot != nil {
+			rp.ControlledBot = bot.Name
+		}
+	})
+
+	// Kills hechas controlando un bot se atribuyen por separado
+	ctx.Parser.RegisterEventHandler(func(e events.Kill) {
+		if e.Killer == nil || e.Killer.SteamID64 == 0 || ctx.ActualRoundNumber == 0 {
+			return
+		}
+		if !e.Killer.IsControllingBot() {
+			return
+		}
+		lc := ctx.GetOrCreateLifecycle(e.Killer.SteamID64, e.Killer.Name)
+		rp := lc.MarkPresent(ctx.ActualRoundNumber, getTeamString(e.Killer.Team), ctx.Parser.GameState().IngameTick())
+		rp.BotControlled = true
+		rp.BotKills++
+	})
+}
+
+// markLatePresence marca presente a un jugador que se une (o reconecta) con la ronda en curso
+func markLatePresence(ctx *models.DemoContext, player *common.Player, tick int) {
+	if !ctx.InRound || ctx.ActualRoundNumber == 0 {
+		return
+	}
+	team := getTeamString(player.Team)
+	if team == "" {
+		return
+	}
+	lc := ctx.GetOrCreateLifecycle(player.SteamID64, player.Name)
+	lc.MarkPresent(ctx.ActualRoundNumber, team, tick)
+}
+
+// FinalizeLifecycles marca como abandono las desconexiones sin reconexión
+// ocurridas antes de la última ronda jugada. Llamar tras ParseToEnd.
+func FinalizeLifecycles(ctx *models.DemoContext) {
+	lastRound := ctx.CurrentRound
+	for _, lc := range ctx.PlayerLifecycles {
+		if lc.Connected || len(lc.DisconnectTicks) == 0 {
+			continue
+		}
+		idx := len(lc.DisconnectTicks) - 1
+		disconnectRound := lc.DisconnectRounds[idx]
+		if disconnectRound < lastRound {
+			lc.Abandoned = true
+			lc.AbandonRound = disconnectRound
+			lc.AbandonTick = lc.DisconnectTicks[idx]
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"cs2-demo-service/models"
+
+	dem "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+	dp "github.com/markus-wa/godispatch"
+)
+
+// fakeParser implementa lo que usan los lifecycle handlers (registro de handlers y
+// GameState); el resto de métodos del interface embebido no se usan (nil).
+type fakeParser struct {
+	dem.Parser
+	handlers []reflect.Value
+	state    fakeGameState
+}
+
+func (p *fakeParser) RegisterEventHandler(handler any) dp.HandlerIdentifier {
+	p.handlers = append(p.handlers, reflect.ValueOf(handler))
+	return nil
+}
+
+func (p *fakeParser) GameState() dem.GameState { return &p.state }
+
+// dispatch llama a los handlers registrados para el tipo del evento
+func (p *fakeParser) dispatch(event any) {
+	for _, h := range p.handlers {
+		if h.Type().In(0) == reflect.TypeOf(event) {
+			h.Call([]reflect.Value{reflect.ValueOf(event)})
+		}
+	}
+}
+
+type fakeGameState struct {
+	dem.GameState
+	tick    int
+	warmup  bool
+	playing []*common.Player
+}
+
+func (s *fakeGameState) IngameTick() int                { return s.tick }
+func (s *fakeGameState) IsWarmupPeriod() bool           { return s.warmup }
+func (s *fakeGameState) Participants() dem.Participants { return fakeParticipants{playing: s.playing} }
+
+type fakeParticipants struct {
+	dem.Participants
+	playing []*common.Player
+}
+
+func (s fakeParticipants) Playing() []*common.Player { return s.playing }
+
+// lifecycleSim reproduce el estado de ronda que mantiene RegisterTimelineHandlers
+// (ActualRoundNumber, CurrentRound, InRound) y despacha los eventos a los lifecycle handlers
+type lifecycleSim struct {
+	ctx    *models.DemoContext
+	parser *fakeParser
+}
+
+func newLifecycleSim() *lifecycleSim {
+	p := &fakeParser{}
+	ctx := models.NewDemoContext(p)
+	RegisterLifecycleHandlers(ctx)
+	return &lifecycleSim{ctx: ctx, parser: p}
+}
+
+// lifecycleStep es una acción en el tick indicado
+type lifecycleStep func(s *lifecycleSim)
+
+func at(tick int, step lifecycleStep) lifecycleStep {
+	return func(s *lifecycleSim) {
+		s.parser.state.tick = tick
+		step(s)
+	}
+}
+
+// roundStart empieza la ronda con los jugadores indicados en el servidor
+func roundStart(round int, playing ...*common.Player) lifecycleStep {
+	return func(s *lifecycleSim) {
+		s.parser.state.playing = playing
+		if !s.parser.state.warmup {
+			s.ctx.ActualRoundNumber = round
+			s.ctx.CurrentRound = round
+			s.ctx.InRound = true
+		}
+		s.parser.dispatch(events.RoundStart{})
+	}
+}
+
+func roundEnd() lifecycleStep {
+	return func(s *lifecycleSim) { s.ctx.InRound = false }
+}
+
+func warmup(on bool) lifecycleStep {
+	return func(s *lifecycleSim) { s.parser.state.warmup = on }
+}
+
+func connect(p *common.Player) lifecycleStep {
+	return func(s *lifecycleSim) { s.parser.dispatch(events.PlayerConnect{Player: p}) }
+}
+
+func disconnect(p *common.Player) lifecycleStep {
+	return func(s *lifecycleSim) { s.parser.dispatch(events.PlayerDisconnected{Player: p}) }
+}
+
+func teamChange(p *common.Player, from, to common.Team) lifecycleStep {
+	return func(s *lifecycleSim) {
+		p.Team = to
+		s.parser.dispatch(events.PlayerTeamChange{Player: p, OldTeam: from, NewTeam: to})
+	}
+}
+
+func botTakeover(p *common.Player) lifecycleStep {
+	return func(s *lifecycleSim) { s.parser.dispatch(events.BotTakenOver{Taker: p}) }
+}
+
+func finalize() lifecycleStep {
+	return func(s *lifecycleSim) { FinalizeLifecycles(s.ctx) }
+}
+
+func TestLifecycleTransitions(t *testing.T) {
+	const steamID = 76561198000000001
+	newPlayer := func() *common.Player {
+		return &common.Player{SteamID64: steamID, Name: "alpha", Team: common.TeamCounterTerrorists, IsConnected: true}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		steps func(p *common.Player) []lifecycleStep
+		check func(t *testing.T, lc *models.PlayerLifecycle)
+	}{
+		{
+			name: "connect before the first round start",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{at(100, connect(p)), at(200, roundStart(1, p))}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				wantTicks(t, "connect", lc.ConnectTicks, 100)
+				wantRounds(t, lc, 1)
+				if rp := lc.Rounds[1]; rp.JoinedTick != 0 || rp.Team != "CT" {
+					t.Errorf("round 1 presence = %+v, want joined at round start as CT", rp)
+				}
+			},
+		},
+		{
+			name: "connected before the demo started",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{at(300, roundStart(1, p))}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				wantTicks(t, "connect", lc.ConnectTicks, 0)
+				if !lc.Connected {
+					t.Error("player should be connected")
+				}
+				wantRounds(t, lc, 1)
+			},
+		},
+		{
+			name: "warmup round start is ignored",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{at(50, connect(p)), warmup(true), at(60, roundStart(1, p)), warmup(false), at(400, roundStart(1, p))}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				wantRounds(t, lc, 1)
+			},
+		},
+		{
+			name: "events before the first round do not mark presence",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{
+					at(10, connect(p)),
+					at(20, teamChange(p, common.TeamUnassigned, common.TeamTerrorists)),
+					at(30, botTakeover(p)),
+					at(40, disconnect(p)),
+				}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				wantRounds(t, lc)
+				wantTicks(t, "disconnect", lc.DisconnectTicks, 40)
+				if len(lc.TeamChanges) != 1 || lc.TeamChanges[0].Round != 0 || lc.TeamChanges[0].To != "T" {
+					t.Errorf("team changes = %+v, want one change to T in round 0", lc.TeamChanges)
+				}
+			},
+		},
+		{
+			name: "duplicate connect and disconnect events",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{
+					at(100, connect(p)), at(110, connect(p)), at(200, roundStart(1, p)),
+					at(500, disconnect(p)), at(510, disconnect(p)),
+				}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				wantTicks(t, "connect", lc.ConnectTicks, 100)
+				wantTicks(t, "disconnect", lc.DisconnectTicks, 500)
+				wantTicks(t, "reconnect", lc.ReconnectTicks)
+				if lc.Rounds[1].LeftTick != 500 {
+					t.Errorf("LeftTick = %d, want the first disconnect", lc.Rounds[1].LeftTick)
+				}
+			},
+		},
+		{
+			name: "duplicate round start keeps the first presence",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{
+					at(100, roundStart(1, p)),
+					at(150, teamChange(p, common.TeamCounterTerrorists, common.TeamTerrorists)),
+					at(160, roundStart(1, p)),
+				}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				wantRounds(t, lc, 1)
+				if lc.Rounds[1].Team != "CT" {
+					t.Errorf("round 1 team = %q, want the team at the first round start", lc.Rounds[1].Team)
+				}
+			},
+		},
+		{
+			name: "disconnect after a duplicate round end does not set LeftTick",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{
+					at(100, roundStart(1, p)), at(900, roundEnd()), at(905, roundEnd()), at(950, disconnect(p)),
+				}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				if lc.Rounds[1].LeftTick != 0 {
+					t.Errorf("LeftTick = %d, want 0 (round already over)", lc.Rounds[1].LeftTick)
+				}
+				if !slices.Equal(lc.DisconnectRounds, []int{1}) {
+					t.Errorf("DisconnectRounds = %v, want [1]", lc.DisconnectRounds)
+				}
+			},
+		},
+		{
+			name: "reconnect mid-round",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{
+					at(100, roundStart(1, p)), at(300, disconnect(p)), at(400, connect(p)),
+				}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				wantTicks(t, "connect", lc.ConnectTicks, 0, 400)
+				wantTicks(t, "reconnect", lc.ReconnectTicks, 400)
+				if rp := lc.Rounds[1]; rp.JoinedTick != 0 || rp.LeftTick != 300 {
+					t.Errorf("round 1 presence = %+v, want joined 0 / left 300", rp)
+				}
+			},
+		},
+		{
+			name: "late join mid-round",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{at(100, roundStart(1)), at(700, connect(p))}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				wantRounds(t, lc, 1)
+				if lc.Rounds[1].JoinedTick != 700 {
+					t.Errorf("JoinedTick = %d, want 700", lc.Rounds[1].JoinedTick)
+				}
+			},
+		},
+		{
+			name: "team change between rounds",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{
+					at(100, roundStart(1, p)), at(900, roundEnd()),
+					at(950, teamChange(p, common.TeamCounterTerrorists, common.TeamTerrorists)),
+					at(960, teamChange(p, common.TeamTerrorists, common.TeamTerrorists)), // Sin cambio: se ignora
+					at(1000, roundStart(2, p)),
+				}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				want := []models.TeamChange{{Tick: 950, Round: 1, From: "CT", To: "T"}}
+				if !slices.Equal(lc.TeamChanges, want) {
+					t.Errorf("team changes = %+v, want %+v", lc.TeamChanges, want)
+				}
+				wantRounds(t, lc, 1, 2)
+				if lc.Rounds[1].Team != "CT" || lc.Rounds[2].Team != "T" {
+					t.Errorf("teams = %q/%q, want CT/T", lc.Rounds[1].Team, lc.Rounds[2].Team)
+				}
+			},
+		},
+		{
+			name: "bot takeover marks the round",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{at(100, roundStart(1, p)), at(600, botTakeover(p))}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				if rp := lc.Rounds[1]; !rp.BotControlled || rp.BotTakeoverTick != 600 || rp.JoinedTick != 0 {
+					t.Errorf("round 1 presence = %+v, want bot takeover at 600", rp)
+				}
+			},
+		},
+		{
+			name: "disconnect before the last round is an abandon",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{
+					at(100, roundStart(1, p)), at(500, disconnect(p)), at(900, roundEnd()),
+					at(1000, roundStart(2)), at(2000, roundStart(3)), finalize(),
+				}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				if !lc.Abandoned || lc.AbandonRound != 1 || lc.AbandonTick != 500 {
+					t.Errorf("abandon = %v round %d tick %d, want round 1 tick 500", lc.Abandoned, lc.AbandonRound, lc.AbandonTick)
+				}
+				wantRounds(t, lc, 1)
+			},
+		},
+		{
+			name: "disconnect in the last round is not an abandon",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{at(100, roundStart(1, p)), at(1000, roundStart(2, p)), at(1500, disconnect(p)), finalize()}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				if lc.Abandoned {
+					t.Error("disconnect in the last round should not be an abandon")
+				}
+			},
+		},
+		{
+			name: "reconnected player is not an abandon",
+			steps: func(p *common.Player) []lifecycleStep {
+				return []lifecycleStep{
+					at(100, roundStart(1, p)), at(500, disconnect(p)), at(900, roundEnd()),
+					at(1000, roundStart(2)), at(1100, connect(p)), at(2000, roundStart(3, p)), finalize(),
+				}
+			},
+			check: func(t *testing.T, lc *models.PlayerLifecycle) {
+				if lc.Abandoned {
+					t.Error("reconnected player should not be an abandon")
+				}
+				wantRounds(t, lc, 1, 2, 3)
+				if lc.Rounds[2].JoinedTick != 1100 {
+					t.Errorf("round 2 JoinedTick = %d, want 1100", lc.Rounds[2].JoinedTick)
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sim := newLifecycleSim()
+			for _, step := range tc.steps(newPlayer()) {
+				step(sim)
+			}
+			lc := sim.ctx.PlayerLifecycles[steamID]
+			if lc == nil {
+				t.Fatal("no lifecycle for the player")
+			}
+			tc.check(t, lc)
+		})
+	}
+}
+
+// TestLifecycleIgnoresBots: los bots (SteamID 0) no tienen lifecycle
+func TestLifecycleIgnoresBots(t *testing.T) {
+	sim := newLifecycleSim()
+	bot := &common.Player{Name: "BOT Eli", Team: common.TeamTerrorists, IsConnected: true}
+	for _, step := range []lifecycleStep{
+		at(10, connect(bot)), at(100, roundStart(1, bot)), at(200, botTakeover(bot)), at(300, disconnect(bot)), finalize(),
+	} {
+		step(sim)
+	}
+	if len(sim.ctx.PlayerLifecycles) != 0 {
+		t.Errorf("bots should not have lifecycles, got %d", len(sim.ctx.PlayerLifecycles))
+	}
+}
+
+func wantTicks(t *testing.T, kind string, got []int, want ...int) {
+	t.Helper()
+	if !slices.Equal(got, want) && !(len(got) == 0 && len(want) == 0) {
+		t.Errorf("%s ticks = %v, want %v", kind, got, want)
+	}
+}
+
+func wantRounds(t *testing.T, lc *models.PlayerLifecycle, want ...int) {
+	t.Helper()
+	var got []int
+	for _, rp := range lc.SortedRounds() {
+		got = append(got, rp.Round)
+	}
+	if !slices.Equal(got, want) && !(len(got) == 0 && len(want) == 0) {
+		t.Errorf("rounds present = %v, want %v", got, want)
+	}
+}
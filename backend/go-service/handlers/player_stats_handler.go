@@ -22,6 +22,9 @@ type PlayerStatsHandler struct {
 	roundSurvived map[uint64]bool
 	roundTraded   map[uint64]bool // If player died and was traded
 
+	// Presence tracking (rounds the player actually played, see lifecycle.go)
+	roundsPresent map[uint64]int
+
 	// Side tracking (for CT/T stats)
 	roundSide      map[uint64]common.Team // Player's side this round
 	ctRoundDamage  map[uint64]int         // Damage dealt as CT
@@ -73,6 +76,7 @@ func NewPlayerStatsHandler() *PlayerStatsHandler {
 		roundDamage:       make(map[uint64]int),
 		roundSurvived:     make(map[uint64]bool),
 		roundTraded:       make(map[uint64]bool),
+		roundsPresent:     make(map[uint64]int),
 		recentDeaths:      make([]deathEvent, 0),
 		roundSide:         make(map[uint64]common.Team),
 		ctRoundDamage:     make(map[uint64]int),
@@ -91,6 +95,94 @@ func NewPlayerStatsHandler() *PlayerStatsHandler {
 	}
 }
 
+// PlayerStatsState is the accumulated (pre-final) state of the handler.
+// Persisted in the intermediate cache so final stats can be recomputed without re-parsing.
+type PlayerStatsState struct {
+	Stats          map[uint64]*models.AI_PlayerStats
+	CurrentRound   int
+	RoundsPresent  map[uint64]int
+	RoundSide      map[uint64]common.Team
+	CTRoundDamage  map[uint64]int
+	TRoundDamage   map[uint64]int
+	CTKills        map[uint64]int
+	TKills         map[uint64]int
+	CTDeaths       map[uint64]int
+	TDeaths        map[uint64]int
+	CTAssists      map[uint64]int
+	TAssists       map[uint64]int
+	CTRoundsPlayed map[uint64]int
+	TRoundsPlayed  map[uint64]int
+	CTKAST         map[uint64]float64
+	TKAST          map[uint64]float64
+}
+
+// State returns the accumulated state (call BEFORE GetStats, which finalizes the stats in place)
+func (h *PlayerStatsHandler) State() PlayerStatsState {
+	return PlayerStatsState{
+		Stats:          h.stats,
+		CurrentRound:   h.currentRound,
+		RoundsPresent:  h.roundsPresent,
+		RoundSide:      h.roundSide,
+		CTRoundDamage:  h.ctRoundDamage,
+		TRoundDamage:   h.tRoundDamage,
+		CTKills:        h.ctKills,
+		TKills:         h.tKills,
+		CTDeaths:       h.ctDeaths,
+		TDeaths:        h.tDeaths,
+		CTAssists:      h.ctAssists,
+		TAssists:       h.tAssists,
+		CTRoundsPlayed: h.ctRoundsPlayed,
+		TRoundsPlayed:  h.tRoundsPlayed,
+		CTKAST:         h.ctKAST,
+		TKAST:          h.tKAST,
+	}
+}
+
+// RestorePlayerStatsHandler rebuilds a handler from a persisted state (no event handlers registered)
+func RestorePlayerStatsHandler(state PlayerStatsState) *PlayerStatsHandler {
+	h := NewPlayerStatsHandler()
+	h.currentRound = state.CurrentRound
+	restoreMap(&h.stats, state.Stats)
+	restoreMap(&h.roundsPresent, state.RoundsPresent)
+	restoreMap(&h.roundSide, state.RoundSide)
+	restoreMap(&h.ctRoundDamage, state.CTRoundDamage)
+	restoreMap(&h.tRoundDamage, state.TRoundDamage)
+	restoreMap(&h.ctKills, state.CTKills)
+	restoreMap(&h.tKills, state.TKills)
+	restoreMap(&h.ctDeaths, state.CTDeaths)
+	restoreMap(&h.tDeaths, state.TDeaths)
+	restoreMap(&h.ctAssists, state.CTAssists)
+	restoreMap(&h.tAssists, state.TAssists)
+	restoreMap(&h.ctRoundsPlayed, state.CTRoundsPlayed)
+	restoreMap(&h.tRoundsPlayed, state.TRoundsPlayed)
+	restoreMap(&h.ctKAST, state.CTKAST)
+	restoreMap(&h.tKAST, state.TKAST)
+
+	// Stats maps are always initialized by getOrCreateStats
+	for _, s := range h.stats {
+		if s.MultiKills == nil {
+			s.MultiKills = make(map[string]int)
+		}
+		if s.GrenadeDamage == nil {
+			s.GrenadeDamage = make(map[string]int)
+		}
+		if s.BodyPartHits == nil {
+			s.BodyPartHits = make(map[string]int)
+		}
+		if s.WeaponStats == nil {
+			s.WeaponStats = make(map[string]models.AI_WeaponStat)
+		}
+	}
+	return h
+}
+
+// restoreMap keeps the initialized empty map when the persisted one is nil
+func restoreMap[K comparable, V any](dst *map[K]V, src map[K]V) {
+	if src != nil {
+		*dst = src
+	}
+}
+
 // RegisterPlayerStatsHandler registers the handler and returns it
 func RegisterPlayerStatsHandler(ctx *models.DemoContext) *PlayerStatsHandler {
 	h := NewPlayerStatsHandler()
@@ -106,6 +198,7 @@ func RegisterPlayerStatsHandler(ctx *models.DemoContext) *PlayerStatsHandler {
 	return h
 }
 
+// GetStats returns the final stats of every player, sorted by SteamID (deterministic output)
 func (h *PlayerStatsHandler) GetStats() []models.AI_PlayerStats {
 	var result []models.AI_PlayerStats
 	for _, s := range h.stats {
@@ -113,6 +206,9 @@ func (h *PlayerStatsHandler) GetStats() []models.AI_PlayerStats {
 		h.calculateFinalStats(s)
 		result = append(result, *s)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SteamID < result[j].SteamID
+	})
 	return result
 }
 
@@ -121,15 +217,35 @@ func (h *PlayerStatsHandler) GetStatsWithContext(ctx *models.DemoContext) []mode
 	// First calculate TTD and crosshair error averages from combat data
 	h.calculateCombatMetrics(ctx)
 
+	// Bot-controlled rounds from the lifecycle tracker
+	h.applyLifecycle(ctx)
+
 	// Then return the stats with final calculations
 	return h.GetStats()
 }
 
+// applyLifecycle copies bot-control info from the player lifecycle tracker into the stats
+func (h *PlayerStatsHandler) applyLifecycle(ctx *models.DemoContext) {
+	for steamID, lc := range ctx.PlayerLifecycles {
+		playerStats, exists := h.stats[steamID]
+		if !exists {
+			continue
+		}
+		playerStats.BotControlledRounds = 0
+		for _, rp := range lc.Rounds {
+			if rp.BotControlled {
+				playerStats.BotControlledRounds++
+			}
+		}
+	}
+}
+
 // calculateCombatMetrics aggregates time_to_damage and crosshair_placement from ReactionTimes
 // Uses MEDIAN instead of MEAN to exclude outliers (matching Leetify methodology)
 func (h *PlayerStatsHandler) calculateCombatMetrics(ctx *models.DemoContext) {
-	// Iterate through all players in match data
-	for steamID, playerData := range ctx.MatchData.Players {
+	// Iterate through all players in match data (sorted, deterministic)
+	for _, steamID := range ctx.MatchData.SortedPlayerIDs() {
+		playerData := ctx.MatchData.Players[steamID]
 		if playerData == nil {
 			continue
 		}
@@ -239,7 +355,7 @@ func (h *PlayerStatsHandler) HandleRoundStart(e events.RoundStart, ctx *models.D
 	gs := ctx.Parser.GameState()
 	if gs != nil {
 		for _, p := range gs.Participants().Playing() {
-			if p != nil {
+			if p != nil && ctx.IsPlayerPresent(p.SteamID64, ctx.ActualRoundNumber) {
 				h.roundSide[p.SteamID64] = p.Team
 				// Count rounds played per side
 				if p.Team == common.TeamCounterTerrorists {
@@ -253,7 +369,11 @@ func (h *PlayerStatsHandler) HandleRoundStart(e events.RoundStart, ctx *models.D
 }
 
 func (h *PlayerStatsHandler) HandleKill(e events.Kill, ctx *models.DemoContext) {
-	if e.Killer != nil {
+	// Kills made while controlling a bot are attributed separately (not the player's own kills)
+	if e.Killer != nil && e.Killer.IsControllingBot() {
+		s := h.getOrCreateStats(e.Killer)
+		s.BotControlledKills++
+	} else if e.Killer != nil {
 		s := h.getOrCreateStats(e.Killer)
 		s.Kills++
 		h.roundKills[e.Killer.SteamID64]++
@@ -315,15 +435,18 @@ func (h *PlayerStatsHandler) HandleKill(e events.Kill, ctx *models.DemoContext)
 	}
 
 	if e.Victim != nil {
-		s := h.getOrCreateStats(e.Victim)
-		s.Deaths++
-		h.roundDeaths[e.Victim.SteamID64] = true
-
-		// Side-specific deaths
-		if e.Victim.Team == common.TeamCounterTerrorists {
-			h.ctDeaths[e.Victim.SteamID64]++
-		} else if e.Victim.Team == common.TeamTerrorists {
-			h.tDeaths[e.Victim.SteamID64]++
+		// A controlled bot dying is not a death of the player (their own death was already counted)
+		if !e.Victim.IsControllingBot() {
+			s := h.getOrCreateStats(e.Victim)
+			s.Deaths++
+			h.roundDeaths[e.Victim.SteamID64] = true
+
+			// Side-specific deaths
+			if e.Victim.Team == common.TeamCounterTerrorists {
+				h.ctDeaths[e.Victim.SteamID64]++
+			} else if e.Victim.Team == common.TeamTerrorists {
+				h.tDeaths[e.Victim.SteamID64]++
+			}
 		}
 
 		// Record death for potential trade
@@ -563,6 +686,12 @@ func (h *PlayerStatsHandler) HandleRoundEnd(e events.RoundEnd, ctx *models.DemoC
 
 	// Calculate KAST for this round
 	for steamID, playerStats := range h.stats {
+		// Skip players that were absent this round (disconnected / not yet joined)
+		if !ctx.IsPlayerPresent(steamID, ctx.ActualRoundNumber) {
+			continue
+		}
+		h.roundsPresent[steamID]++
+
 		hasKill := h.roundKills[steamID] > 0
 		hasAssist := h.roundAssists[steamID]
 		survived := !h.roundDeaths[steamID]
@@ -631,12 +760,17 @@ func (h *PlayerStatsHandler) getOrCreateStats(player *common.Player) *models.AI_
 }
 
 func (h *PlayerStatsHandler) calculateFinalStats(s *models.AI_PlayerStats) {
-	rounds := float64(h.currentRound)
+	steamID, _ := strconv.ParseUint(s.SteamID, 10, 64)
+
+	// Per-round stats only count rounds where the player was present
+	rounds := float64(h.roundsPresent[steamID])
+	if rounds == 0 {
+		rounds = float64(h.currentRound)
+	}
 	if rounds == 0 {
 		return
 	}
-
-	steamID, _ := strconv.ParseUint(s.SteamID, 10, 64)
+	s.RoundsPlayed = int(rounds)
 
 	// Set Team based on the LAST ROUND (roundSide tracks the team at each round start)
 	// This is the team the player was on when the match ended
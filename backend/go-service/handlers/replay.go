@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"cs2-demo-service/models"
+	"cs2-demo-service/pkg/maps"
+	"fmt"
 	"math"
 
 	dem "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs"
@@ -10,13 +12,16 @@ import (
 )
 
 const (
-	// ReplaySampleRateHz is how many times per second we sample player positions
+	// DefaultReplaySampleRateHz is how many times per second we sample player positions
 	// 16 Hz = 62.5ms between samples, enables hyper-fluid 60FPS playback
 	// Frontend interpolates between these samples for smooth animation
-	ReplaySampleRateHz = 16
+	DefaultReplaySampleRateHz = 16
 
-	// ShotVisibilityTicks is how many ticks a shot line stays visible
-	ShotVisibilityTicks = 32 // ~500ms at 64 tick - visible longer for better visualization
+	// MaxReplaySampleRateHz is one frame per tick at 64 tick
+	MaxReplaySampleRateHz = 64
+
+	// DefaultShotVisibilityTicks is how many ticks a shot line stays visible
+	DefaultShotVisibilityTicks = 32 // ~500ms at 64 tick - visible longer for better visualization
 
 	// SmokeRadius is the standard smoke grenade radius in game units
 	SmokeRadius = 144.0
@@ -25,9 +30,43 @@ const (
 	TrajectoryHistorySize = 20 // More points for smoother grenade curves
 )
 
+// ReplayOptions configures the replay export of one parse (zero values = defaults)
+type ReplayOptions struct {
+	SampleRateHz        int // Frames per second (4 Hz previews ... 64 Hz = every tick)
+	ShotVisibilityTicks int // How many ticks a shot line stays in the frames
+}
+
+// DefaultReplayOptions returns the 16 Hz replay options
+func DefaultReplayOptions() ReplayOptions {
+	return ReplayOptions{SampleRateHz: DefaultReplaySampleRateHz, ShotVisibilityTicks: DefaultShotVisibilityTicks}
+}
+
+// ValidateReplaySampleRate checks a requested sample rate (0 = default)
+func ValidateReplaySampleRate(hz int) error {
+	if hz < 0 || hz > MaxReplaySampleRateHz {
+		return fmt.Errorf("replay sample rate must be 0 (default) or 1..%d Hz, got %d", MaxReplaySampleRateHz, hz)
+	}
+	return nil
+}
+
+func (o ReplayOptions) withDefaults() ReplayOptions {
+	def := DefaultReplayOptions()
+	if o.SampleRateHz <= 0 {
+		o.SampleRateHz = def.SampleRateHz
+	}
+	if o.SampleRateHz > MaxReplaySampleRateHz {
+		o.SampleRateHz = MaxReplaySampleRateHz
+	}
+	if o.ShotVisibilityTicks <= 0 {
+		o.ShotVisibilityTicks = def.ShotVisibilityTicks
+	}
+	return o
+}
+
 // ReplayHandler manages replay data collection
 type ReplayHandler struct {
-	ctx *models.DemoContext
+	ctx  *models.DemoContext
+	opts ReplayOptions
 
 	// Current round data
 	currentRound *models.ReplayRoundData
@@ -45,6 +84,12 @@ type ReplayHandler struct {
 	// Bomb state
 	bombState *models.ReplayBombState
 
+	// Buy tracking: money spent this round per player at the last check (baseline
+	// taken at RoundStart), and the items picked up in freeze time waiting for the
+	// spent money to update
+	moneySpent  map[uint64]int
+	pendingBuys map[uint64][]string
+
 	// Output: all rounds
 	Rounds []models.ReplayRound
 
@@ -63,21 +108,29 @@ type shotWithTick struct {
 }
 
 // NewReplayHandler creates a new replay handler
-func NewReplayHandler(ctx *models.DemoContext) *ReplayHandler {
+func NewReplayHandler(ctx *models.DemoContext, opts ReplayOptions) *ReplayHandler {
 	return &ReplayHandler{
 		ctx:               ctx,
+		opts:              opts.withDefaults(),
 		activeProjectiles: make(map[int64]*models.ReplayProjectile),
 		activeSmokes:      make(map[int64]*models.ReplayActiveEffect),
 		activeInfernos:    make(map[int64]*models.ReplayActiveEffect),
 		recentShots:       []shotWithTick{},
+		moneySpent:        make(map[uint64]int),
+		pendingBuys:       make(map[uint64][]string),
 		Rounds:            []models.ReplayRound{},
 		roundPhase:        "none",
 	}
 }
 
-// RegisterReplayHandlers registers all handlers needed for replay data collection
+// RegisterReplayHandlers registers all handlers needed for replay data collection (16 Hz)
 func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
-	handler := NewReplayHandler(ctx)
+	return RegisterReplayHandlersWithOptions(ctx, DefaultReplayOptions())
+}
+
+// RegisterReplayHandlersWithOptions registers the replay handlers with a custom sample rate
+func RegisterReplayHandlersWithOptions(ctx *models.DemoContext, opts ReplayOptions) *ReplayHandler {
+	handler := NewReplayHandler(ctx, opts)
 
 	// ========================================
 	// ROUND LIFECYCLE
@@ -97,6 +150,14 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 		handler.activeInfernos = make(map[int64]*models.ReplayActiveEffect)
 		handler.activeProjectiles = make(map[int64]*models.ReplayProjectile)
 		handler.recentShots = []shotWithTick{}
+		handler.pendingBuys = make(map[uint64][]string)
+
+		// Baseline of the money spent: whatever was spent before the round start is not
+		// a buy of this round (taken here so buys in the first freeze time frame count)
+		handler.moneySpent = make(map[uint64]int)
+		for _, p := range ctx.Parser.GameState().Participants().Playing() {
+			handler.moneySpent[p.SteamID64] = p.MoneySpentThisRound()
+		}
 
 		// Initialize bomb state
 		handler.bombState = &models.ReplayBombState{State: "carried"}
@@ -165,13 +226,7 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 
 		gameState := ctx.Parser.GameState()
 		currentTick := gameState.IngameTick()
-		tickRate := ctx.Parser.TickRate()
-
-		// Calculate ticks per sample
-		ticksPerSample := int(tickRate / float64(ReplaySampleRateHz))
-		if ticksPerSample == 0 {
-			ticksPerSample = 4 // Fallback for 16Hz at 64 tick
-		}
+		ticksPerSample := handler.ticksPerSample()
 
 		// Determine if we should sample based on phase
 		shouldSample := false
@@ -375,9 +430,8 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 			throwerID = e.Thrower.SteamID64
 		}
 
-		handler.currentRound.Events = append(handler.currentRound.Events, models.ReplayEvent{
-			Tick:        ctx.Parser.GameState().IngameTick(),
-			Type:        "flash_explode",
+		handler.addEvent(models.ReplayEvent{
+			Type:        models.ReplayEventFlashExplode,
 			GrenadeType: "flashbang",
 			X:           e.Position.X,
 			Y:           e.Position.Y,
@@ -420,24 +474,36 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 			weapon = e.Weapon.String()
 		}
 
-		handler.currentRound.Events = append(handler.currentRound.Events, models.ReplayEvent{
-			Tick:       ctx.Parser.GameState().IngameTick(),
-			Type:       "kill",
-			KillerID:   killerID,
-			VictimID:   victimID,
-			KillerName: killerName,
-			VictimName: victimName,
-			KillerTeam: killerTeam,
-			VictimTeam: victimTeam,
-			KillerX:    killerX,
-			KillerY:    killerY,
-			VictimX:    victimX,
-			VictimY:    victimY,
-			Weapon:     weapon,
-			Headshot:   e.IsHeadshot,
-			Wallbang:   e.PenetratedObjects > 0,
-			NoScope:    e.NoScope,
-		})
+		kill := models.ReplayEvent{
+			Type:              models.ReplayEventKill,
+			KillerID:          killerID,
+			VictimID:          victimID,
+			KillerName:        killerName,
+			VictimName:        victimName,
+			KillerTeam:        killerTeam,
+			VictimTeam:        victimTeam,
+			KillerX:           killerX,
+			KillerY:           killerY,
+			VictimX:           victimX,
+			VictimY:           victimY,
+			Weapon:            weapon,
+			Headshot:          e.IsHeadshot,
+			Wallbang:          e.PenetratedObjects > 0,
+			NoScope:           e.NoScope,
+			PenetratedObjects: e.PenetratedObjects,
+			ThroughSmoke:      e.ThroughSmoke,
+			AttackerBlind:     e.AttackerBlind,
+			Distance:          floatPtr(math.Round(float64(e.Distance)*10) / 10),
+			AssistedFlash:     e.AssistedFlash,
+			Suicide:           killerID != 0 && killerID == victimID,
+			TeamKill:          killerID != victimID && killerTeam != "" && killerTeam == victimTeam,
+		}
+		if e.Assister != nil {
+			kill.AssisterID = e.Assister.SteamID64
+			kill.AssisterName = e.Assister.Name
+		}
+		handler.markKillContext(&kill)
+		handler.addEvent(kill)
 
 		// Mark the shot that killed as a hit
 		for i := len(handler.recentShots) - 1; i >= 0; i-- {
@@ -448,6 +514,127 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 		}
 	})
 
+	// ========================================
+	// DAMAGE & FLASHES
+	// ========================================
+
+	ctx.Parser.RegisterEventHandler(func(e events.PlayerHurt) {
+		if e.Player == nil {
+			return
+		}
+
+		var attackerID uint64
+		if e.Attacker != nil {
+			attackerID = e.Attacker.SteamID64
+		}
+		weapon := ""
+		if e.Weapon != nil {
+			weapon = e.Weapon.String()
+		}
+
+		handler.addEvent(models.ReplayEvent{
+			Type:        models.ReplayEventDamage,
+			AttackerID:  attackerID,
+			VictimID:    e.Player.SteamID64,
+			Weapon:      weapon,
+			Damage:      e.HealthDamageTaken,
+			ArmorDamage: e.ArmorDamageTaken,
+			Health:      intPtr(e.Health),
+			HitGroup:    hitgroupToString(e.HitGroup),
+			X:           e.Player.Position().X,
+			Y:           e.Player.Position().Y,
+		})
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.PlayerFlashed) {
+		if e.Player == nil {
+			return
+		}
+
+		var attackerID uint64
+		if e.Attacker != nil {
+			attackerID = e.Attacker.SteamID64
+		}
+
+		handler.addEvent(models.ReplayEvent{
+			Type:        models.ReplayEventPlayerFlashed,
+			GrenadeType: "flashbang",
+			AttackerID:  attackerID,
+			VictimID:    e.Player.SteamID64,
+			Duration:    e.FlashDuration().Seconds(),
+			TeamFlash:   e.Attacker != nil && e.Attacker != e.Player && e.Attacker.Team == e.Player.Team,
+		})
+	})
+
+	// ========================================
+	// RELOADS & WEAPON SWITCHES
+	// ========================================
+
+	ctx.Parser.RegisterEventHandler(func(e events.WeaponReload) {
+		if e.Player == nil {
+			return
+		}
+
+		handler.addEvent(models.ReplayEvent{
+			Type:     models.ReplayEventReload,
+			PlayerID: e.Player.SteamID64,
+			Weapon:   getActiveWeapon(e.Player),
+		})
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.ItemEquip) {
+		// Freeze time weapon swaps (buying) have no frames to overlay
+		if e.Player == nil || e.Weapon == nil || handler.roundPhase == "freezetime" {
+			return
+		}
+
+		handler.addEvent(models.ReplayEvent{
+			Type:     models.ReplayEventWeaponSwitch,
+			PlayerID: e.Player.SteamID64,
+			Weapon:   e.Weapon.String(),
+		})
+	})
+
+	// ========================================
+	// BUYS & CHAT
+	// ========================================
+
+	// Freeze time pickups are buys only if the money spent this round went up: spawn
+	// pistols and weapons dropped by teammates don't cost anything. The spent money
+	// prop can update after the pickup event, so buys are resolved at FrameDone.
+	ctx.Parser.RegisterEventHandler(func(e events.ItemPickup) {
+		if handler.currentRound == nil || handler.roundPhase != "freezetime" || e.Player == nil || e.Weapon == nil {
+			return
+		}
+		id := e.Player.SteamID64
+		handler.pendingBuys[id] = append(handler.pendingBuys[id], e.Weapon.String())
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.FrameDone) {
+		if handler.currentRound == nil || handler.roundPhase != "freezetime" {
+			return
+		}
+		handler.resolveBuys(ctx.Parser.GameState())
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.ChatMessage) {
+		if e.Text == "" {
+			return
+		}
+
+		chat := models.ReplayEvent{
+			Type:     models.ReplayEventChat,
+			Text:     e.Text,
+			TeamChat: !e.IsChatAll,
+		}
+		if e.Sender != nil {
+			chat.PlayerID = e.Sender.SteamID64
+			chat.PlayerName = e.Sender.Name
+			chat.Team = getTeamString(e.Sender.Team)
+		}
+		handler.addEvent(chat)
+	})
+
 	// ========================================
 	// BOMB EVENTS
 	// ========================================
@@ -458,6 +645,13 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 			handler.bombState.X = e.Player.Position().X
 			handler.bombState.Y = e.Player.Position().Y
 			handler.bombState.CarrierID = 0
+
+			handler.addEvent(models.ReplayEvent{
+				Type:     models.ReplayEventBombDrop,
+				PlayerID: e.Player.SteamID64,
+				X:        e.Player.Position().X,
+				Y:        e.Player.Position().Y,
+			})
 		}
 	})
 
@@ -465,7 +659,37 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 		if handler.bombState != nil && e.Player != nil {
 			handler.bombState.State = "carried"
 			handler.bombState.CarrierID = e.Player.SteamID64
+
+			handler.addEvent(models.ReplayEvent{
+				Type:     models.ReplayEventBombPickup,
+				PlayerID: e.Player.SteamID64,
+				X:        e.Player.Position().X,
+				Y:        e.Player.Position().Y,
+			})
+		}
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.BombPlantBegin) {
+		if e.Player == nil {
+			return
 		}
+		handler.addEvent(models.ReplayEvent{
+			Type:     models.ReplayEventBombPlantBegin,
+			Site:     string(e.Site),
+			PlayerID: e.Player.SteamID64,
+			X:        e.Player.Position().X,
+			Y:        e.Player.Position().Y,
+		})
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.BombPlantAborted) {
+		if e.Player == nil {
+			return
+		}
+		handler.addEvent(models.ReplayEvent{
+			Type:     models.ReplayEventBombPlantAbort,
+			PlayerID: e.Player.SteamID64,
+		})
 	})
 
 	ctx.Parser.RegisterEventHandler(func(e events.BombPlanted) {
@@ -481,9 +705,8 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 			handler.bombState.X = e.Player.Position().X
 			handler.bombState.Y = e.Player.Position().Y
 
-			handler.currentRound.Events = append(handler.currentRound.Events, models.ReplayEvent{
-				Tick:     ctx.Parser.GameState().IngameTick(),
-				Type:     "bomb_plant",
+			handler.addEvent(models.ReplayEvent{
+				Type:     models.ReplayEventBombPlant,
 				Site:     string(e.Site),
 				PlayerID: e.Player.SteamID64,
 				X:        e.Player.Position().X,
@@ -496,6 +719,13 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 		if handler.bombState != nil && e.Player != nil {
 			handler.bombState.State = "defusing"
 			handler.bombState.DefuserID = e.Player.SteamID64
+
+			handler.addEvent(models.ReplayEvent{
+				Type:     models.ReplayEventBombDefuseStart,
+				Site:     handler.bombState.Site,
+				PlayerID: e.Player.SteamID64,
+				HasKit:   e.HasKit,
+			})
 		}
 	})
 
@@ -503,6 +733,16 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 		if handler.bombState != nil {
 			handler.bombState.State = "planted"
 			handler.bombState.DefuserID = 0
+
+			var playerID uint64
+			if e.Player != nil {
+				playerID = e.Player.SteamID64
+			}
+			handler.addEvent(models.ReplayEvent{
+				Type:     models.ReplayEventBombDefuseAbort,
+				Site:     handler.bombState.Site,
+				PlayerID: playerID,
+			})
 		}
 	})
 
@@ -514,9 +754,8 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 		handler.bombState.State = "defused"
 
 		if e.Player != nil {
-			handler.currentRound.Events = append(handler.currentRound.Events, models.ReplayEvent{
-				Tick:     ctx.Parser.GameState().IngameTick(),
-				Type:     "bomb_defuse",
+			handler.addEvent(models.ReplayEvent{
+				Type:     models.ReplayEventBombDefuse,
 				Site:     handler.bombState.Site,
 				PlayerID: e.Player.SteamID64,
 			})
@@ -530,9 +769,8 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 
 		handler.bombState.State = "exploded"
 
-		handler.currentRound.Events = append(handler.currentRound.Events, models.ReplayEvent{
-			Tick: ctx.Parser.GameState().IngameTick(),
-			Type: "bomb_explode",
+		handler.addEvent(models.ReplayEvent{
+			Type: models.ReplayEventBombExplode,
 			Site: handler.bombState.Site,
 			X:    handler.bombState.X,
 			Y:    handler.bombState.Y,
@@ -546,6 +784,15 @@ func RegisterReplayHandlers(ctx *models.DemoContext) *ReplayHandler {
 // HELPER METHODS
 // ========================================
 
+// addEvent records an event of the current round at its exact game tick
+func (h *ReplayHandler) addEvent(e models.ReplayEvent) {
+	if h.currentRound == nil {
+		return
+	}
+	e.Tick = h.ctx.Parser.GameState().IngameTick()
+	h.currentRound.Events = append(h.currentRound.Events, e)
+}
+
 func (h *ReplayHandler) collectPlayerStates(gs dem.GameState) []models.ReplayPlayerState {
 	players := []models.ReplayPlayerState{}
 
@@ -603,8 +850,9 @@ func (h *ReplayHandler) collectProjectiles() []models.ReplayProjectile {
 		}
 	}
 
-	// Collect all active projectiles
-	for _, proj := range h.activeProjectiles {
+	// Collect all active projectiles (sorted by entity ID, deterministic frames)
+	for _, id := range models.SortedKeys(h.activeProjectiles) {
+		proj := h.activeProjectiles[id]
 		projectiles = append(projectiles, *proj)
 	}
 
@@ -615,7 +863,8 @@ func (h *ReplayHandler) collectActiveEffects(gs dem.GameState) []models.ReplayAc
 	effects := []models.ReplayActiveEffect{}
 
 	// Collect smokes
-	for _, smoke := range h.activeSmokes {
+	for _, id := range models.SortedKeys(h.activeSmokes) {
+		smoke := h.activeSmokes[id]
 		effects = append(effects, *smoke)
 	}
 
@@ -632,7 +881,8 @@ func (h *ReplayHandler) collectActiveEffects(gs dem.GameState) []models.ReplayAc
 	}
 
 	// Collect infernos
-	for _, inferno := range h.activeInfernos {
+	for _, id := range models.SortedKeys(h.activeInfernos) {
+		inferno := h.activeInfernos[id]
 		effects = append(effects, *inferno)
 	}
 
@@ -643,7 +893,7 @@ func (h *ReplayHandler) collectRecentShots(currentTick int) []models.ReplayShot
 	// Clean old shots
 	validShots := []shotWithTick{}
 	for _, s := range h.recentShots {
-		if currentTick-s.tick <= ShotVisibilityTicks {
+		if currentTick-s.tick <= h.opts.ShotVisibilityTicks {
 			validShots = append(validShots, s)
 		}
 	}
@@ -658,23 +908,140 @@ func (h *ReplayHandler) collectRecentShots(currentTick int) []models.ReplayShot
 	return shots
 }
 
+// markKillContext sets the round context flags of a kill before it is added: opening
+// kill (first enemy kill of the round; team kills, suicides and world kills don't count)
+// and trades (the victim had killed a teammate of the killer within the trade
+// window; that earlier kill gets traded_by_id)
+func (h *ReplayHandler) markKillContext(kill *models.ReplayEvent) {
+	if h.currentRound == nil {
+		return
+	}
+	tick := h.ctx.Parser.GameState().IngameTick()
+	tickRate := h.ctx.Parser.TickRate()
+	if tickRate <= 0 {
+		tickRate = 64
+	}
+	window := int(TradeWindowSeconds * tickRate)
+
+	opening := true
+	for i := len(h.currentRound.Events) - 1; i >= 0; i-- {
+		prev := &h.currentRound.Events[i]
+		if prev.Type != models.ReplayEventKill {
+			continue
+		}
+		if prev.KillerID != 0 && !prev.TeamKill && !prev.Suicide {
+			opening = false // Only an enemy kill takes the opening
+		}
+		if kill.TradeOfID != 0 || kill.TeamKill || kill.Suicide || kill.VictimID == 0 || tick-prev.Tick > window {
+			continue
+		}
+		if prev.KillerID == kill.VictimID && prev.VictimTeam == kill.KillerTeam && prev.VictimID != kill.KillerID && prev.TradedByID == 0 {
+			kill.TradeOfID = prev.VictimID
+			prev.TradedByID = kill.KillerID
+		}
+	}
+	kill.OpeningKill = opening && kill.KillerID != 0 && !kill.TeamKill && !kill.Suicide
+}
+
+// resolveBuys turns the pending freeze time pickups into buy events using the change
+// in money spent this round (one item: the whole difference; several in the same
+// frame: the list price of each)
+func (h *ReplayHandler) resolveBuys(gs dem.GameState) {
+	if len(h.pendingBuys) == 0 {
+		return
+	}
+
+	for _, p := range gs.Participants().Playing() {
+		items, ok := h.pendingBuys[p.SteamID64]
+		if !ok {
+			continue
+		}
+		spent := p.MoneySpentThisRound()
+		diff := spent - h.moneySpent[p.SteamID64]
+		h.moneySpent[p.SteamID64] = spent
+		if diff <= 0 {
+			continue
+		}
+		for _, item := range items {
+			cost := diff
+			if len(items) > 1 {
+				cost = getWeaponPrice(item)
+				if cost == 0 {
+					continue
+				}
+			}
+			h.addEvent(models.ReplayEvent{
+				Type:       models.ReplayEventBuy,
+				PlayerID:   p.SteamID64,
+				PlayerName: p.Name,
+				Team:       getTeamString(p.Team),
+				Weapon:     item,
+				Cost:       cost,
+				MoneyLeft:  intPtr(p.Money()),
+			})
+		}
+	}
+	h.pendingBuys = make(map[uint64][]string)
+}
+
+// ticksPerSample converts the sample rate to ticks (at least one frame per tick)
+func (h *ReplayHandler) ticksPerSample() int {
+	tickRate := h.ctx.Parser.TickRate()
+	if tickRate <= 0 {
+		tickRate = 64 // Fallback until the tick rate is known
+	}
+	ticks := int(math.Round(tickRate / float64(h.opts.SampleRateHz)))
+	if ticks < 1 {
+		return 1
+	}
+	return ticks
+}
+
+// sampleIntervalMs is the real spacing of the frames: whole ticks, so 10 Hz at
+// 64 tick is 6 ticks = 93.75 ms, not 100
+func (h *ReplayHandler) sampleIntervalMs(tickRate float64) float64 {
+	if tickRate <= 0 {
+		tickRate = 64
+	}
+	return float64(h.ticksPerSample()) * 1000 / tickRate
+}
+
 // GetReplayData builds the final replay data structure
 func (h *ReplayHandler) GetReplayData(matchID string) models.ReplayData {
 	mapName := h.ctx.MatchData.MapName
 	tickRate := h.ctx.Parser.TickRate()
 
+	var overview *maps.Overview
+	if provider, ok := h.ctx.MapManager.(maps.OverviewProvider); ok {
+		overview = provider.Overview()
+	}
+	mapConfig := maps.ResolveMapConfig(mapName, overview)
+	if mapConfig.Source == maps.MapConfigSourceDefault {
+		fmt.Printf("⚠️  No radar overview for %s: replay positions use a default transform\n", mapName)
+	}
+
 	return models.ReplayData{
 		Metadata: models.ReplayMetadata{
-			MatchID:    matchID,
-			MapName:    mapName,
-			TickRate:   tickRate,
-			SampleRate: 1000 / ReplaySampleRateHz, // Convert Hz to ms
-			MapConfig:  models.GetMapConfig(mapName),
+			SchemaVersion: models.ReplaySchemaVersion,
+			MatchID:       matchID,
+			MapName:       mapName,
+			TickRate:      tickRate,
+			SampleRate:    h.sampleIntervalMs(tickRate),
+			SampleRateHz:  h.opts.SampleRateHz,
+			MapConfig:     mapConfig,
 		},
 		Rounds: h.Rounds,
 	}
 }
 
+// TakeRounds returns the completed rounds and releases them from the handler
+// (used by the per-round flush to keep memory bounded)
+func (h *ReplayHandler) TakeRounds() []models.ReplayRound {
+	rounds := h.Rounds
+	h.Rounds = []models.ReplayRound{}
+	return rounds
+}
+
 func normalizeGrenadeType(t string) string {
 	switch t {
 	case "Smoke Grenade", "SmokeGrenade":
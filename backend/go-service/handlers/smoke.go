@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"cs2-demo-service/models"
+
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+)
+
+// RegisterSmokeHandlers mantiene ctx.Smokes (humos volumétricos, ver pkg/smoke).
+// Un humo existe desde su detonación (SmokeStart): las granadas de humo en vuelo no
+// se registran porque no tapan nada (antes contaban como un humo completo en su
+// posición). Los infernos no se siguen aquí: el fuego no bloquea la visión.
+func RegisterSmokeHandlers(ctx *models.DemoContext) {
+	ctx.Parser.RegisterEventHandler(func(e events.RoundStart) {
+		ctx.Smokes.Reset()
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.SmokeStart) {
+		ctx.Smokes.TickRate = getTickRate(ctx)
+		ctx.Smokes.Start(e.GrenadeEntityID, e.Position, ctx.Parser.GameState().IngameTick())
+	})
+
+	ctx.Parser.RegisterEventHandler(func(e events.SmokeExpired) {
+		ctx.Smokes.Expire(e.GrenadeEntityID)
+	})
+
+	// Las HE abren un hueco temporal en los humos
+	ctx.Parser.RegisterEventHandler(func(e events.HeExplode) {
+		ctx.Smokes.HEExplode(e.Position, ctx.Parser.GameState().IngameTick())
+	})
+}
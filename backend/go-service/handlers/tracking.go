@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"cs2-demo-service/models"
+	"cs2-demo-service/pkg/maps"
 	"math"
 
+	"github.com/golang/geo/r3"
 	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
 	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
 )
@@ -55,23 +57,36 @@ func RegisterTrackingHandler(ctx *models.DemoContext) {
 				continue
 			}
 
-			// Calculate nearby teammates
+			// Calculate nearby teammates (por camino del nav mesh si está cargado, no en línea recta)
 			nearbyTeammates := 0
+			nearestTeammate := 0.0
+			allByPath := false
 			if player.IsAlive() {
+				allByPath = true
 				for _, teammate := range gameState.Participants().TeamMembers(player.Team) {
 					if teammate == nil || teammate.SteamID64 == player.SteamID64 || !teammate.IsAlive() {
 						continue
 					}
 
-					dist := distance(player.Position().X, player.Position().Y, player.Position().Z,
-						teammate.Position().X, teammate.Position().Y, teammate.Position().Z)
+					dist, byPath := pathDistance(ctx, player.Position(), teammate.Position())
+					allByPath = allByPath && byPath
 
 					if dist <= NearbyDistanceUnits {
 						nearbyTeammates++
 					}
+					if nearestTeammate == 0 || dist < nearestTeammate {
+						nearestTeammate = dist
+					}
 				}
 			}
 
+			// Rotation time to each bombsite (solo con nav mesh)
+			var rotationA, rotationB float64
+			if player.IsAlive() {
+				rotationA = rotationTime(ctx, player, "A")
+				rotationB = rotationTime(ctx, player, "B")
+			}
+
 			// Get Area Name
 			areaName := player.LastPlaceName()
 			if ctx.MapManager != nil {
@@ -90,19 +105,23 @@ func RegisterTrackingHandler(ctx *models.DemoContext) {
 					Y: player.Position().Y,
 					Z: player.Position().Z,
 				},
-				AreaName:           areaName,
-				ViewAngleYaw:       player.ViewDirectionX(), // Yaw = horizontal rotation
-				ViewAnglePitch:     player.ViewDirectionY(), // Pitch = vertical rotation
-				VelocityLen:        math.Sqrt(player.Velocity().X*player.Velocity().X + player.Velocity().Y*player.Velocity().Y),
-				IsWalking:          player.IsWalking(),
-				IsDucking:          player.IsDucking(),
-				ActiveWeapon:       getActiveWeapon(player),
-				HasC4:              hasC4(player),
-				Health:             player.Health(),
-				Armor:              player.Armor(),
-				NearbyTeammates:    nearbyTeammates,
-				IsAlive:            player.IsAlive(),
-				RoundTimeRemaining: calculateRoundTimeRemaining(ctx),
+				AreaName:            areaName,
+				ViewAngleYaw:        player.ViewDirectionX(), // Yaw = horizontal rotation
+				ViewAnglePitch:      player.ViewDirectionY(), // Pitch = vertical rotation
+				VelocityLen:         math.Sqrt(player.Velocity().X*player.Velocity().X + player.Velocity().Y*player.Velocity().Y),
+				IsWalking:           player.IsWalking(),
+				IsDucking:           player.IsDucking(),
+				ActiveWeapon:        getActiveWeapon(player),
+				HasC4:               hasC4(player),
+				Health:              player.Health(),
+				Armor:               player.Armor(),
+				NearbyTeammates:     nearbyTeammates,
+				NearestTeammateDist: nearestTeammate,
+				TeammateDistByPath:  allByPath && nearestTeammate > 0,
+				RotationTimeA:       rotationA,
+				RotationTimeB:       rotationB,
+				IsAlive:             player.IsAlive(),
+				RoundTimeRemaining:  calculateRoundTimeRemaining(ctx),
 			}
 
 			// Store with round number for later grouping
@@ -114,8 +133,57 @@ func RegisterTrackingHandler(ctx *models.DemoContext) {
 	})
 }
 
-func distance(x1, y1, z1, x2, y2, z2 float64) float64 {
-	return math.Sqrt(math.Pow(x2-x1, 2) + math.Pow(y2-y1, 2) + math.Pow(z2-z1, 2))
+// rotationTime returns the seconds the player needs to reach the bombsite by nav path
+// with their active weapon (0 if there is no nav mesh or the site is unknown)
+func rotationTime(ctx *models.DemoContext, player *common.Player, site string) float64 {
+	finder, ok := ctx.MapManager.(maps.PathFinder)
+	if !ok {
+		return 0
+	}
+	center, ok := bombsiteCenter(ctx, site)
+	if !ok {
+		return 0
+	}
+	path, ok := finder.FindPath(player.Position(), center)
+	if !ok {
+		return 0
+	}
+	return models.EstimateRunTime(path.Length, getActiveWeapon(player))
+}
+
+// bombsiteCenter returns the center of bombsite "A"/"B" from the player resource
+// entity (m_bombsiteCenterA/B). Se busca una sola vez por demo (el primer muestreo es
+// tras el freeze time, con la entidad ya inicializada) y se cachea también si no está.
+func bombsiteCenter(ctx *models.DemoContext, site string) (r3.Vector, bool) {
+	if ctx.BombsiteCenters == nil {
+		ctx.BombsiteCenters = findBombsiteCenters(ctx)
+	}
+	center, ok := ctx.BombsiteCenters[site]
+	return center, ok
+}
+
+// findBombsiteCenters lee los centros de A y B del CCSPlayerResource (mapa vacío si
+// la entidad no está o el mapa no tiene bombsites)
+func findBombsiteCenters(ctx *models.DemoContext) map[string]r3.Vector {
+	centers := make(map[string]r3.Vector, 2)
+	for _, ent := range ctx.Parser.GameState().Entities() {
+		if ent == nil || ent.ServerClass().Name() != "CCSPlayerResource" {
+			continue
+		}
+		for _, site := range []string{"A", "B"} {
+			val, ok := ent.PropertyValue("m_bombsiteCenter" + site)
+			fs, isVec := val.Any.([]float32)
+			if !ok || !isVec || len(fs) < 3 {
+				continue
+			}
+			center := r3.Vector{X: float64(fs[0]), Y: float64(fs[1]), Z: float64(fs[2])}
+			if center.X != 0 || center.Y != 0 || center.Z != 0 {
+				centers[site] = center
+			}
+		}
+		break
+	}
+	return centers
 }
 
 func hasC4(player *common.Player) bool {
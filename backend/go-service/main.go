@@ -3,32 +3,51 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 
 	"cs2-demo-service/api"
 	"cs2-demo-service/middlewares"
+	"cs2-demo-service/scheduler"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 )
 
 func main() {
-
 	// Cargar el fichero .env desde la raíz del proyecto
 	err := godotenv.Load("../.env")
 	if err != nil {
 		log.Println("No se pudo cargar el fichero .env (no es crítico):", err)
 	}
 
+	// Logs sin timestamp por defecto: el log de una demo es reproducible entre ejecuciones
+	// (LOG_TIMESTAMPS=1 para recuperarlos)
+	if os.Getenv("LOG_TIMESTAMPS") == "" {
+		log.SetFlags(0)
+	}
+
+	// Scheduler de demos: concurrencia y presupuesto de memoria (DEMO_MAX_CONCURRENT, DEMO_MEMORY_BUDGET_MB)
+	api.SetScheduler(scheduler.New(scheduler.ConfigFromEnv()))
+
 	// Crea el router.
 	router := mux.NewRouter()
 
 	// Endpoint simplificado: procesa una demo y devuelve el JSON directamente
 	router.HandleFunc("/process-demo", api.HandleProcessDemo).Methods("POST")
 	router.HandleFunc("/health", api.HandleHealth).Methods("GET")
+	router.HandleFunc("/scheduler/status", api.HandleSchedulerStatus).Methods("GET")
 
 	// Endpoint para obtener detalles de un match desde exports/
 	router.HandleFunc("/match-details/{matchID}", api.HandleGetMatchDetails).Methods("GET")
 
+	// Heatmaps y plots de posiciones en PNG y recaps animados de rondas (desde exports/)
+	router.HandleFunc("/match/{matchID}/plot/{kind}", api.HandleMatchPlot).Methods("GET")
+	router.HandleFunc("/match/{matchID}/recap/{round}", api.HandleMatchRecap).Methods("GET")
+	router.HandleFunc("/map-image/{mapName}", api.HandleMapImage).Methods("GET")
+
+	// POV de un jugador en una ronda (parsea la demo hasta el final de la ronda)
+	router.HandleFunc("/pov", api.HandlePOV).Methods("POST")
+
 	// Aplica el middleware de CORS.
 	handlerWithCors := middlewares.WithCors(router)
 
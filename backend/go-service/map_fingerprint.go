@@ -0,0 +1,150 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/pkg/maps"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	dem "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/msgs2"
+)
+
+// Gestiona <mapa>/<mapa>_fingerprint.json: la build del juego de la que se exportaron
+// GLTF/nav/places y el sha256 de cada fichero. El parser compara con él la versión
+// del mapa de cada demo y marca las métricas de visibilidad como no fiables si no
+// coincide (ver pkg/maps/fingerprint.go).
+// Uso:
+//
+//	go run map_fingerprint.go -demo match.dem -write          # Registrar los assets actuales con la build de la demo
+//	go run map_fingerprint.go -map de_mirage -build 10514 -write
+//	go run map_fingerprint.go -demo match.dem                 # Comprobar (exit 1 si no coincide)
+//	go run map_fingerprint.go -demo newer.dem -verify         # El parche no tocó el mapa: aceptar esa build
+func main() {
+	mapName := flag.String("map", "", "map name (default: from the demo)")
+	mapsDir := flag.String("maps", "../data/maps", "maps directory")
+	demoPath := flag.String("demo", "", "demo to read the map name and game build from")
+	build := flag.Int("build", 0, "game build number (instead of -demo)")
+	addons := flag.String("addons", "", "addons of the build (workshop maps, instead of -demo)")
+	write := flag.Bool("write", false, "record the current assets for this build (overwrites the fingerprint)")
+	verify := flag.Bool("verify", false, "accept this build for the current fingerprint (map unchanged)")
+	flag.Parse()
+
+	demo := maps.DemoMapFingerprint{MapName: *mapName, BuildNum: *build, Addons: *addons}
+	if *demoPath != "" {
+		fromDemo, err := readDemoMap(*demoPath)
+		if err != nil {
+			log.Fatalf("Error reading %s: %v", *demoPath, err)
+		}
+		if *mapName != "" && !strings.EqualFold(*mapName, fromDemo.BaseName()) {
+			log.Fatalf("Demo is on %s, not %s", fromDemo.BaseName(), *mapName)
+		}
+		demo = fromDemo
+	}
+	if demo.MapName == "" {
+		log.Fatal("-map or -demo is required")
+	}
+	name := demo.BaseName()
+	fmt.Printf("Demo map: %s build=%d protocol=%d addons=%q crc=%08x\n",
+		name, demo.BuildNum, demo.NetworkProtocol, demo.Addons, demo.CRC())
+
+	switch {
+	case *write:
+		if !demo.Known() {
+			log.Fatal("-write needs the game build (-build or -demo)")
+		}
+		fp, err := maps.NewFingerprint(*mapsDir, name, demo.BuildNum, demo.Addons)
+		if err != nil {
+			log.Fatalf("Error hashing map files: %v", err)
+		}
+		if len(fp.Files) == 0 {
+			log.Fatalf("No map files for %s in %s", name, *mapsDir)
+		}
+		if err := maps.SaveFingerprint(*mapsDir, name, fp); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("✅ Fingerprint written: %s (%d files, crc %08x)\n", maps.FingerprintPath(*mapsDir, name), len(fp.Files), fp.MapCRC)
+
+	case *verify:
+		if !demo.Known() {
+			log.Fatal("-verify needs the game build (-build or -demo)")
+		}
+		fp, err := maps.LoadFingerprint(*mapsDir, name)
+		if err != nil {
+			log.Fatalf("Error loading fingerprint (create it with -write): %v", err)
+		}
+		if fp.Accepts(demo.CRC()) {
+			fmt.Printf("Build %d already accepted\n", demo.BuildNum)
+			return
+		}
+		fp.Verified = append(fp.Verified, demo.CRC())
+		if err := maps.SaveFingerprint(*mapsDir, name, fp); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("✅ Build %d accepted for %s (%d verified builds)\n", demo.BuildNum, name, len(fp.Verified))
+
+	default:
+		fp, err := maps.LoadFingerprint(*mapsDir, name)
+		if err != nil && !errors.Is(err, maps.ErrNoFingerprint) {
+			log.Fatal(err)
+		}
+		files, err := maps.HashMapFiles(*mapsDir, name)
+		if err != nil {
+			log.Fatalf("Error hashing map files: %v", err)
+		}
+		check := maps.CompareFingerprint(demo, fp, files)
+		fmt.Printf("Map check: %s\n", check.Status)
+		for _, r := range check.Reasons {
+			fmt.Printf("   - %s\n", r)
+		}
+		if check.Status == maps.MapCheckMismatch {
+			os.Exit(1)
+		}
+	}
+}
+
+// readDemoMap lee frames hasta tener CDemoFileHeader (llega al principio de la demo)
+func readDemoMap(path string) (maps.DemoMapFingerprint, error) {
+	var fp maps.DemoMapFingerprint
+	f, err := os.Open(path)
+	if err != nil {
+		return fp, err
+	}
+	defer f.Close()
+
+	p := dem.NewParser(f)
+	defer p.Close()
+	if _, err := p.ParseHeader(); err != nil {
+		return fp, err
+	}
+	seen := false
+	p.RegisterNetMessageHandler(func(m *msgs2.CDemoFileHeader) {
+		fp.MapName = m.GetMapName()
+		fp.BuildNum = int(m.GetBuildNum())
+		fp.NetworkProtocol = int(m.GetNetworkProtocol())
+		fp.DemoVersion = m.GetDemoVersionName()
+		fp.Addons = m.GetAddons()
+		seen = true
+	})
+	for !seen {
+		more, err := p.ParseNextFrame()
+		if err != nil {
+			return fp, err
+		}
+		if !more {
+			break
+		}
+	}
+	if fp.MapName == "" {
+		fp.MapName = p.Header().MapName
+	}
+	if fp.MapName == "" {
+		return fp, errors.New("demo has no map name")
+	}
+	return fp, nil
+}
@@ -1,5 +1,7 @@
 package models
 
+import "cs2-demo-service/pkg/maps"
+
 // AI_Metadata represents the global context for the match
 type AI_Metadata struct {
 	MatchID               string  `json:"match_id"`
@@ -12,6 +14,43 @@ type AI_Metadata struct {
 	TickRate              float64 `json:"tick_rate"`                          // Server tick rate (64 or 128)
 	TotalRounds           int     `json:"total_rounds"`                       // Total rounds played
 	AverageRank           string  `json:"average_rank,omitempty"`             // e.g. "Faceit Lvl 8"
+
+	// Map version (see pkg/maps/fingerprint.go). CS2 demos carry no real map CRC:
+	// map_crc is a CRC32 of map name + game build + addons from the demo header.
+	MapCRC             uint32        `json:"map_crc"`
+	MapBuild           int           `json:"map_build,omitempty"` // Game build of the demo (CDemoFileHeader.build_num)
+	MapCheck           maps.MapCheck `json:"map_check"`           // Demo map vs local mesh/nav/callouts
+	VisibilityReliable bool          `json:"visibility_reliable"` // false when the local map assets don't match the demo
+	UnreliableMetrics  []string      `json:"unreliable_metrics,omitempty"`
+}
+
+// VisibilityDependentMetrics are the exported fields computed against the local map assets
+// (raycasts on the mesh, nav paths). They are listed in AI_Metadata.UnreliableMetrics when
+// the map check reports a mismatch.
+var VisibilityDependentMetrics = []string{
+	"combat.attacker.initial_crosshair_error",
+	"combat.attacker.pitch_error",
+	"combat.attacker.yaw_error",
+	"combat.attacker.time_to_reaction",
+	"combat.attacker.time_to_first_damage",
+	"combat.attacker.avg_time_to_reaction",
+	"combat.attacker.avg_time_to_first_damage",
+	"combat.exchanges.time_to_reaction",
+	"combat.exchanges.time_to_first_damage",
+	"combat.context.wallbangable",
+	"combat.context.wallbang_damage",
+	"combat.context.enemies_visible_to_loser",
+	"combat.context.trade_time",
+	"combat.context.trade_possible",
+	"players_summary.time_to_damage_avg_ms",
+	"players_summary.crosshair_placement_avg_error",
+	"players_summary.crosshair_placement_peek",
+	"players_summary.crosshair_placement_hold",
+	"players.reaction_times.visible_fraction",
+	"players.reaction_times.peak_visible_fraction",
+	"tracking.nearest_teammate_dist",
+	"tracking.rotation_time_a",
+	"tracking.rotation_time_b",
 }
 
 // AI_EconomyMatch represents the economy data for a match
@@ -298,8 +337,12 @@ type AI_DuelContext struct {
 	ThroughSmoke          bool    `json:"through_smoke"`
 	IsWallbang            bool    `json:"is_wallbang"`
 	PenetratedObjects     int     `json:"penetrated_objects"`
-	NoScope               *bool   `json:"no_scope,omitempty"`   // Only for scoped weapons (AWP, Scout, etc.)
-	ZoomLevel             *int    `json:"zoom_level,omitempty"` // 0=none, 1=first, 2=second (scoped weapons only)
+	Wallbangable          bool    `json:"wallbangable,omitempty"`    // Loser was behind geometry the winner's weapon could shoot through
+	WallbangDamage        int     `json:"wallbang_damage,omitempty"` // Estimated damage per bullet through that geometry
+	TradeTime             float64 `json:"trade_time,omitempty"`      // Seconds the closest alive teammate of the loser needed (nav path) to reach the winner
+	TradePossible         bool    `json:"trade_possible,omitempty"`  // TradeTime fits in the trade window
+	NoScope               *bool   `json:"no_scope,omitempty"`        // Only for scoped weapons (AWP, Scout, etc.)
+	ZoomLevel             *int    `json:"zoom_level,omitempty"`      // 0=none, 1=first, 2=second (scoped weapons only)
 	BombPlanted           bool    `json:"bomb_planted"`
 	AliveCT               int     `json:"alive_ct"`
 	AliveT                int     `json:"alive_t"`
@@ -367,6 +410,10 @@ type RawCombatEvent struct {
 	ThroughSmoke      bool
 	IsWallbang        bool
 	PenetratedObjects int
+	Wallbangable      bool    // Line attacker→victim blocked by geometry, but penetrable (geometry.Mesh.Penetrate)
+	WallbangDamage    int     // Estimated damage per bullet through that geometry
+	TradeTime         float64 // Seconds (nav path) for the victim's closest alive teammate to reach the attacker; 0 = unknown
+	TradePossible     bool    // TradeTime <= trade window
 	IsHeadshot        bool
 	NoScope           bool
 	ZoomLevel         int // 0=none, 1=first, 2=second (only for scoped weapons)
@@ -473,23 +520,27 @@ type AI_ViewAngle struct {
 
 // AI_TrackingEvent represents a sampled position snapshot (2Hz)
 type AI_TrackingEvent struct {
-	Tick               int       `json:"tick"`
-	PlayerSteamID      uint64    `json:"player_steam_id"`
-	Team               string    `json:"team"` // "CT" or "T"
-	Position           AI_Vector `json:"pos"`
-	AreaName           string    `json:"area_name"`
-	ViewAngleYaw       float32   `json:"view_yaw"`
-	ViewAnglePitch     float32   `json:"view_pitch"`
-	VelocityLen        float64   `json:"vel_len"`
-	IsWalking          bool      `json:"is_walking"`
-	IsDucking          bool      `json:"is_ducking"`
-	ActiveWeapon       string    `json:"active_weapon"`
-	HasC4              bool      `json:"has_c4"`
-	Health             int       `json:"health"`
-	Armor              int       `json:"armor"`
-	NearbyTeammates    int       `json:"nearby_teammates"`
-	IsAlive            bool      `json:"is_alive"`
-	RoundTimeRemaining float64   `json:"round_time_remaining"`
+	Tick                int       `json:"tick"`
+	PlayerSteamID       uint64    `json:"player_steam_id"`
+	Team                string    `json:"team"` // "CT" or "T"
+	Position            AI_Vector `json:"pos"`
+	AreaName            string    `json:"area_name"`
+	ViewAngleYaw        float32   `json:"view_yaw"`
+	ViewAnglePitch      float32   `json:"view_pitch"`
+	VelocityLen         float64   `json:"vel_len"`
+	IsWalking           bool      `json:"is_walking"`
+	IsDucking           bool      `json:"is_ducking"`
+	ActiveWeapon        string    `json:"active_weapon"`
+	HasC4               bool      `json:"has_c4"`
+	Health              int       `json:"health"`
+	Armor               int       `json:"armor"`
+	NearbyTeammates     int       `json:"nearby_teammates"`
+	NearestTeammateDist float64   `json:"nearest_teammate_dist,omitempty"` // Nav path distance, or straight line (see TeammateDistByPath)
+	TeammateDistByPath  bool      `json:"teammate_dist_by_path"`           // true = nearby/nearest by nav path; false = some teammate by straight line (no nav mesh or no path)
+	RotationTimeA       float64   `json:"rotation_time_a,omitempty"`       // Seconds to bombsite A by nav path with the active weapon
+	RotationTimeB       float64   `json:"rotation_time_b,omitempty"`
+	IsAlive             bool      `json:"is_alive"`
+	RoundTimeRemaining  float64   `json:"round_time_remaining"`
 }
 
 // AI_TrackingTick represents all player states at a specific tick
@@ -537,6 +588,11 @@ type AI_PlayerStats struct {
 	Name    string `json:"name"`
 	Team    string `json:"team"` // "CT", "T" or "Mixed"
 
+	// === PRESENCE ===
+	RoundsPlayed        int `json:"rounds_played"`         // Rounds the player was present (excludes disconnected rounds)
+	BotControlledRounds int `json:"bot_controlled_rounds"` // Rounds where the player took over a bot
+	BotControlledKills  int `json:"bot_controlled_kills"`  // Kills made while controlling a bot (not in Kills)
+
 	// === CORE STATS ===
 	Kills        int     `json:"kills"`
 	Deaths       int     `json:"deaths"`
@@ -626,3 +682,49 @@ type AI_WeaponStat struct {
 	ShotsHit   int     `json:"shots_hit"`
 	Accuracy   float64 `json:"accuracy"` // (ShotsHit / ShotsFired) * 100
 }
+
+// ============================================================================
+// PRESENCE MODELS
+// ============================================================================
+
+// AI_PresenceExport represents the root structure for presence.json
+type AI_PresenceExport struct {
+	MatchID string              `json:"match_id"`
+	Players []AI_PlayerPresence `json:"players"`
+}
+
+// AI_PlayerPresence represents the connection lifecycle of a single player
+type AI_PlayerPresence struct {
+	SteamID         string             `json:"steam_id"`
+	Name            string             `json:"name"`
+	ConnectTicks    []int              `json:"connect_ticks"`
+	DisconnectTicks []int              `json:"disconnect_ticks"`
+	ReconnectTicks  []int              `json:"reconnect_ticks"`
+	TeamChanges     []AI_TeamChange    `json:"team_changes"`
+	RoundsPresent   int                `json:"rounds_present"`
+	RoundsAbsent    []int              `json:"rounds_absent"`
+	Abandoned       bool               `json:"abandoned"`
+	AbandonRound    int                `json:"abandon_round,omitempty"`
+	AbandonTick     int                `json:"abandon_tick,omitempty"`
+	Rounds          []AI_PresenceRound `json:"rounds"`
+}
+
+// AI_TeamChange represents a team switch of a player
+type AI_TeamChange struct {
+	Tick  int    `json:"tick"`
+	Round int    `json:"round"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// AI_PresenceRound represents the presence of a player in one round
+type AI_PresenceRound struct {
+	Round           int    `json:"round"`
+	Team            string `json:"team"`
+	JoinedTick      int    `json:"joined_tick,omitempty"` // Set if the player joined mid-round
+	LeftTick        int    `json:"left_tick,omitempty"`   // Set if the player disconnected mid-round
+	BotControlled   bool   `json:"bot_controlled"`
+	ControlledBot   string `json:"controlled_bot,omitempty"`
+	BotTakeoverTick int    `json:"bot_takeover_tick,omitempty"`
+	BotKills        int    `json:"bot_kills"`
+}
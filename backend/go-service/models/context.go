@@ -2,6 +2,7 @@ package models
 
 import (
 	"cs2-demo-service/pkg/maps"
+	"cs2-demo-service/pkg/smoke"
 
 	"github.com/golang/geo/r3"
 	dem "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs"
@@ -40,6 +41,9 @@ type DemoContext struct {
 	BombPlanted bool
 	BombSite    string
 	BombTick    int
+	// Centros de los bombsites (CCSPlayerResource), para tiempos de rotación.
+	// nil = aún sin buscar; un sitio ausente del mapa = no encontrado (no se vuelve a buscar)
+	BombsiteCenters map[string]r3.Vector
 
 	// Buy tracking (NEW)
 	FreezeTimeEnded   bool
@@ -61,7 +65,7 @@ type DemoContext struct {
 	EnemyFirstSeenTick map[uint64]map[uint64]FirstSeenData
 	FirstDamageTick    map[uint64]map[uint64]int // Attacker -> Victim -> Tick of first damage
 	LastVisibleEnemies map[uint64]map[uint64]bool
-	ActiveSmokes       []r3.Vector // Posiciones de humos activos
+	Smokes             *smoke.Tracker // Humos volumétricos activos (ver pkg/smoke)
 
 	// Mechanics tracking per shot
 	LastShotMechanics map[uint64]*ShotMechanics
@@ -131,8 +135,38 @@ type DemoContext struct {
 	// FIX: Track active weapon name per player each tick (for victim weapon in kills)
 	LastActiveWeapon map[uint64]string // SteamID -> Active weapon name (updated each tick)
 
+	// Player lifecycle: conexiones, desconexiones, bots y equipo por ronda
+	PlayerLifecycles map[uint64]*PlayerLifecycle
+
 	// 2D Replay Data (for frontend visualization)
 	ReplayData *ReplayData
+
+	// Info del header/parser necesaria para exportar sin parser (rebuild desde caché intermedia)
+	DemoInfo DemoInfo
+
+	// Caché intermedia: lotes de raw combat events tal y como entran en ConsolidateDuels
+	KeepRawCombat    bool
+	RawCombatHistory []RawCombatBatch
+
+	// Per-round flush (ver parser/round_flush.go): directorio de volcado y rondas ya volcadas
+	FlushDir          string
+	FlushedRounds     []int
+	GrenadeFlushCount int
+}
+
+// DemoInfo guarda los datos del header usados por el export
+type DemoInfo struct {
+	TickRate        float64
+	DurationSeconds float64
+	Map             maps.DemoMapFingerprint // Mapa según el header de la demo
+	MapCheck        maps.MapCheck           // Comparación con los assets locales del mapa
+}
+
+// RawCombatBatch es el input de una llamada a ConsolidateDuels (una ronda)
+type RawCombatBatch struct {
+	Round            int
+	Events           []RawCombatEvent
+	LastActiveWeapon map[uint64]string // Fallback de arma usado al consolidar
 }
 
 // FirstSeenData stores metadata when an enemy is first seen
@@ -143,6 +177,8 @@ type FirstSeenData struct {
 	PitchError              float64
 	YawError                float64
 	ShooterVelocity         float64 // Velocity at first sight (u/s) for peek/hold classification
+	VisibleFraction         float64 // Fracción (0-1) de hitbox points visibles al primer avistamiento
+	PeakVisibleFraction     float64 // Máxima fracción visible mientras siguió visible
 }
 
 // NewDemoContext crea un nuevo contexto inicializado
@@ -197,7 +233,7 @@ func NewDemoContext(p dem.Parser) *DemoContext {
 		EnemyFirstSeenTick:           make(map[uint64]map[uint64]FirstSeenData, 16),
 		FirstDamageTick:              make(map[uint64]map[uint64]int, 16),
 		LastVisibleEnemies:           make(map[uint64]map[uint64]bool, 16),
-		ActiveSmokes:                 []r3.Vector{},
+		Smokes:                       smoke.NewTracker(0),
 		LastShotMechanics:            make(map[uint64]*ShotMechanics, 16),
 		CrosshairStats:               make(map[uint64]*CrosshairStats, 16),
 		ActiveGrenadeTrajectories:    make(map[int]*GrenadeTrajectoryEvent, 32),
@@ -216,6 +252,7 @@ func NewDemoContext(p dem.Parser) *DemoContext {
 		RoundDamage:            make(map[uint64]map[uint64]int),
 		LastKnownHealth:        make(map[uint64]int),
 		LastActiveWeapon:       make(map[uint64]string),
+		PlayerLifecycles:       make(map[uint64]*PlayerLifecycle),
 	}
 }
 
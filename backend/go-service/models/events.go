@@ -179,6 +179,20 @@ type MovementLog struct {
 	Yaw       float32 `json:"yaw"`
 }
 
+// MovementRound agrupa los movement logs de una ronda por jugador (movement.json y
+// volcado por ronda, ver parser.registerRoundFlush)
+type MovementRound struct {
+	Round   int              `json:"round"`
+	Players []PlayerMovement `json:"players"`
+}
+
+// PlayerMovement son los movement logs de un jugador en una ronda
+type PlayerMovement struct {
+	SteamID  uint64        `json:"steam_id"`
+	Name     string        `json:"name"`
+	Movement []MovementLog `json:"movement"`
+}
+
 // RoundEconomyStats representa la economía de un equipo en una ronda
 type RoundEconomyStats struct {
 	Round          int    `json:"round"`
@@ -266,6 +280,8 @@ type ReactionTimeEvent struct {
 	Distance                float64 `json:"distance"`                  // Distancia al enemigo en unidades
 	PenetratedObjects       int     `json:"penetrated_objects"`        // Objetos penetrados en la kill (0 = visión clara)
 	ShooterVelocity         float64 `json:"shooter_velocity"`          // Velocity at first sight (u/s) for peek/hold classification
+	VisibleFraction         float64 `json:"visible_fraction"`          // Exposición del enemigo (0-1) al primer avistamiento
+	PeakVisibleFraction     float64 `json:"peak_visible_fraction"`     // Máxima exposición hasta el disparo
 }
 
 // CrosshairStats estadísticas de crosshair placement
@@ -339,3 +355,8 @@ type MatchData struct {
 	// Player stats (para scoreboard final)
 	PlayerStats []PlayerStats `json:"player_stats"`
 }
+
+// SortedPlayerIDs devuelve los SteamIDs de Players ordenados (iteración determinista)
+func (m *MatchData) SortedPlayerIDs() []uint64 {
+	return SortedKeys(m.Players)
+}
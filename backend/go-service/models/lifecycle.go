@@ -0,0 +1,109 @@
+package models
+
+import "sort"
+
+// PlayerLifecycle registra la presencia de un jugador humano durante toda la partida
+// (conexiones, desconexiones, reconexiones, rondas controlando bots y equipo por ronda)
+type PlayerLifecycle struct {
+	SteamID uint64
+	Name    string
+
+	ConnectTicks    []int // Primera conexión y reconexiones
+	DisconnectTicks []int
+	ReconnectTicks  []int // Subconjunto de ConnectTicks posteriores a una desconexión
+	TeamChanges     []TeamChange
+
+	DisconnectRounds []int // Ronda en la que ocurrió cada desconexión
+	Connected        bool  // Estado actual durante el parsing
+
+	Rounds map[int]*RoundPresence // Round -> presencia en esa ronda
+
+	// Abandono: se desconectó y no volvió antes de que terminara la partida
+	Abandoned    bool
+	AbandonRound int
+	AbandonTick  int
+}
+
+// TeamChange registra un cambio de equipo de un jugador
+type TeamChange struct {
+	Tick  int
+	Round int
+	From  string
+	To    string
+}
+
+// RoundPresence describe la participación de un jugador en una ronda concreta
+type RoundPresence struct {
+	Round      int
+	Team       string // "CT" o "T"
+	JoinedTick int    // 0 si estaba presente al inicio de la ronda
+	LeftTick   int    // 0 si no se desconectó durante la ronda
+
+	// Control de bots (CS2 permite tomar un bot tras morir)
+	BotControlled   bool
+	ControlledBot   string
+	BotTakeoverTick int
+	BotKills        int // Kills hechas con el bot (no cuentan como kills propias)
+}
+
+// GetOrCreateLifecycle devuelve el tracker de un jugador, creándolo si no existe
+func (ctx *DemoContext) GetOrCreateLifecycle(steamID uint64, name string) *PlayerLifecycle {
+	lc, exists := ctx.PlayerLifecycles[steamID]
+	if !exists {
+		lc = &PlayerLifecycle{
+			SteamID: steamID,
+			Name:    name,
+			Rounds:  make(map[int]*RoundPresence),
+		}
+		ctx.PlayerLifecycles[steamID] = lc
+	}
+	if name != "" {
+		lc.Name = name
+	}
+	return lc
+}
+
+// IsPlayerPresent indica si el jugador participó en la ronda indicada.
+// Si no hay información de lifecycle para el jugador se asume presente (compatibilidad).
+func (ctx *DemoContext) IsPlayerPresent(steamID uint64, round int) bool {
+	lc, exists := ctx.PlayerLifecycles[steamID]
+	if !exists {
+		return true
+	}
+	_, present := lc.Rounds[round]
+	return present
+}
+
+// MarkPresent registra al jugador como presente en la ronda con el equipo dado
+func (lc *PlayerLifecycle) MarkPresent(round int, team string, joinedTick int) *RoundPresence {
+	rp, exists := lc.Rounds[round]
+	if !exists {
+		rp = &RoundPresence{
+			Round:      round,
+			Team:       team,
+			JoinedTick: joinedTick,
+		}
+		lc.Rounds[round] = rp
+	}
+	if rp.Team == "" {
+		rp.Team = team
+	}
+	return rp
+}
+
+// RoundsPresent devuelve el número de rondas en las que el jugador estuvo presente
+func (lc *PlayerLifecycle) RoundsPresent() int {
+	return len(lc.Rounds)
+}
+
+// SortedRounds devuelve las rondas de presencia ordenadas por número de ronda
+func (lc *PlayerLifecycle) SortedRounds() []*RoundPresence {
+	rounds := make([]*RoundPresence, 0, len(lc.Rounds))
+	for _, rp := range lc.Rounds {
+		rounds = append(rounds, rp)
+	}
+	sort.Slice(rounds, func(i, j int) bool {
+		return rounds[i].Round < rounds[j].Round
+	})
+	return rounds
+}
@@ -0,0 +1,82 @@
+package models
+
+// ========================================
+// POV EXPORT
+// Punto de vista de un jugador en una ronda, un frame por tick: posición de los
+// ojos, ángulos, estado del arma, flash y qué enemigos tenía en pantalla
+// ========================================
+
+// POVExport is the point-of-view reconstruction of one player in one round
+type POVExport struct {
+	MatchID     string  `json:"match_id,omitempty"`
+	MapName     string  `json:"map_name"`
+	SteamID     uint64  `json:"steam_id"`
+	PlayerName  string  `json:"player_name"`
+	Team        string  `json:"team"`
+	Round       int     `json:"round"`
+	TickRate    float64 `json:"tick_rate"`
+	StartTick   int     `json:"start_tick"`
+	EndTick     int     `json:"end_tick"`
+	FOV         float64 `json:"fov"`          // Horizontal FOV in degrees (for AspectRatio)
+	AspectRatio float64 `json:"aspect_ratio"` // Screen width / height used for the FOV test
+	MeshLoaded  bool    `json:"mesh_loaded"`  // false: Visible is always true (no map mesh)
+
+	Frames    []POVFrame    `json:"frames"`
+	Exposures []POVExposure `json:"exposures"`
+}
+
+// POVFrame is the player's view at one tick
+type POVFrame struct {
+	Tick        int     `json:"tick"`
+	Alive       bool    `json:"alive"`
+	EyeX        float64 `json:"eye_x"`
+	EyeY        float64 `json:"eye_y"`
+	EyeZ        float64 `json:"eye_z"`
+	Yaw         float32 `json:"yaw"`
+	Pitch       float32 `json:"pitch"` // -90 (up) to 90 (down)
+	Health      int     `json:"health"`
+	Weapon      string  `json:"weapon"`
+	AmmoClip    int     `json:"ammo_clip"`
+	AmmoReserve int     `json:"ammo_reserve"`
+	ZoomLevel   int     `json:"zoom_level,omitempty"`
+	IsReloading bool    `json:"is_reloading,omitempty"`
+	IsScoped    bool    `json:"is_scoped,omitempty"`
+	IsDucking   bool    `json:"is_ducking,omitempty"`
+	Fired       bool    `json:"fired,omitempty"`       // Shot fired this tick
+	FlashAlpha  float64 `json:"flash_alpha,omitempty"` // 0-1 opacity of the flashbang overlay
+
+	Enemies []POVEnemy `json:"enemies,omitempty"` // Alive enemies (only while the player is alive)
+}
+
+// POVEnemy is an enemy as seen from the player's eyes at one tick
+type POVEnemy struct {
+	SteamID         uint64  `json:"steam_id"`
+	Name            string  `json:"name"`
+	X               float64 `json:"x"`
+	Y               float64 `json:"y"`
+	Z               float64 `json:"z"`
+	Distance        float64 `json:"distance"`
+	AngleOff        float64 `json:"angle_off"` // Degrees between the crosshair and the enemy's head
+	InFOV           bool    `json:"in_fov"`
+	ScreenX         float64 `json:"screen_x,omitempty"` // Head on screen: -1 (left) to 1 (right), only when InFOV
+	ScreenY         float64 `json:"screen_y,omitempty"` // -1 (bottom) to 1 (top); beyond ±1 if only the body is in view
+	Visible         bool    `json:"visible"`            // Any hitbox point visible through the mesh
+	VisibleFraction float64 `json:"visible_fraction"`   // Weighted fraction of visible hitbox points
+	Smoked          bool    `json:"smoked,omitempty"`   // Line of sight blocked by a smoke
+	OnScreen        bool    `json:"on_screen"`          // InFOV && Visible && !Smoked
+}
+
+// POVExposure is a continuous period with an enemy on the player's screen
+type POVExposure struct {
+	EnemyID    uint64  `json:"enemy_id"`
+	EnemyName  string  `json:"enemy_name"`
+	StartTick  int     `json:"start_tick"`
+	EndTick    int     `json:"end_tick"`
+	DurationMs float64 `json:"duration_ms"`
+
+	// First reaction while the enemy was on screen: "shot" (player fired) or
+	// "damage" (player hit that enemy); empty if the player never reacted
+	Reaction     string  `json:"reaction,omitempty"`
+	ReactionTick int     `json:"reaction_tick,omitempty"`
+	ReactionMs   float64 `json:"reaction_ms,omitempty"` // On screen before reacting
+}
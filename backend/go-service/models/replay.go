@@ -1,10 +1,16 @@
 package models
 
+import "cs2-demo-service/pkg/maps"
+
 // ========================================
 // REPLAY 2D DATA STRUCTURES
 // For high-fidelity 2D replay visualization
 // ========================================
 
+// ReplaySchemaVersion is bumped whenever a field of replay.json changes meaning or
+// is removed (new optional fields and event types keep the version)
+const ReplaySchemaVersion = 2
+
 // ReplayData is the main structure for 2D replay export
 type ReplayData struct {
 	Metadata ReplayMetadata `json:"metadata"`
@@ -13,19 +19,13 @@ type ReplayData struct {
 
 // ReplayMetadata contains map info for coordinate translation
 type ReplayMetadata struct {
-	MatchID    string    `json:"match_id"`
-	MapName    string    `json:"map_name"`
-	TickRate   float64   `json:"tick_rate"`
-	SampleRate int       `json:"sample_rate_ms"` // Milliseconds between frames
-	MapConfig  MapConfig `json:"map_config"`
-}
-
-// MapConfig contains the coordinate transformation values
-// From demoinfocs-golang examples/_assets/metadata/*.txt
-type MapConfig struct {
-	PosX  float64 `json:"pos_x"`
-	PosY  float64 `json:"pos_y"`
-	Scale float64 `json:"scale"`
+	SchemaVersion int            `json:"schema_version"` // ReplaySchemaVersion of the exporter
+	MatchID       string         `json:"match_id"`
+	MapName       string         `json:"map_name"`
+	TickRate      float64        `json:"tick_rate"`
+	SampleRate    float64        `json:"sample_rate_ms"` // Milliseconds between frames (whole ticks: 93.75 for 10 Hz at 64 tick)
+	SampleRateHz  int            `json:"sample_rate_hz"` // Frames per second requested for this export
+	MapConfig     maps.MapConfig `json:"map_config"`
 }
 
 // ReplayRound contains all frames and events for one round
@@ -117,10 +117,48 @@ type ReplayBombState struct {
 	DefuserID uint64  `json:"defuser_id,omitempty"`
 }
 
-// ReplayEvent is a discrete event for timeline markers
+// Replay event types
+const (
+	ReplayEventKill            = "kill"
+	ReplayEventDamage          = "damage"
+	ReplayEventFlashExplode    = "flash_explode"
+	ReplayEventPlayerFlashed   = "player_flashed"
+	ReplayEventBombPlantBegin  = "bomb_plant_begin"
+	ReplayEventBombPlantAbort  = "bomb_plant_abort"
+	ReplayEventBombPlant       = "bomb_plant"
+	ReplayEventBombDefuseStart = "bomb_defuse_start"
+	ReplayEventBombDefuseAbort = "bomb_defuse_abort"
+	ReplayEventBombDefuse      = "bomb_defuse"
+	ReplayEventBombExplode     = "bomb_explode"
+	ReplayEventBombDrop        = "bomb_drop"
+	ReplayEventBombPickup      = "bomb_pickup"
+	ReplayEventReload          = "reload"
+	ReplayEventWeaponSwitch    = "weapon_switch"
+	ReplayEventBuy             = "buy"
+	ReplayEventChat            = "chat"
+)
+
+// ReplayEvent is a discrete event for timeline markers. Tick is the exact game
+// tick of the event (not the tick of the nearest frame), so the viewer can place
+// it between frames whatever the sample rate. Events of a round are in tick order.
+//
+// Fields set per type (the rest are omitted). distance, health and money_left are
+// pointers so a 0 (point blank, lethal hit, all money spent) is still written:
+//
+//	kill               killer_*/victim_*, weapon, headshot, wallbang, penetrated_objects, noscope,
+//	                   through_smoke, attacker_blind, distance, assister_id/assister_name,
+//	                   assisted_flash, opening_kill, team_kill, suicide, trade_of_id, traded_by_id
+//	damage             attacker_id, victim_id, weapon, damage, armor_damage, health, hitgroup, x, y
+//	player_flashed     attacker_id, victim_id, duration, team_flash
+//	flash_explode      player_id, grenade_type, x, y
+//	bomb_*             player_id, site, x, y (has_kit on bomb_defuse_start)
+//	reload             player_id, weapon
+//	weapon_switch      player_id, weapon
+//	buy                player_id, player_name, team, weapon, cost, money_left
+//	chat               player_id (0 = console), player_name, team, text, team_chat
 type ReplayEvent struct {
 	Tick int    `json:"tick"`
-	Type string `json:"type"` // "kill", "damage", "bomb_plant", "bomb_defuse", "grenade_explode"
+	Type string `json:"type"` // One of the ReplayEvent* constants
 
 	// For kills - includes position data for kill line visualization
 	KillerID   uint64  `json:"killer_id,omitempty"`
@@ -138,14 +176,46 @@ type ReplayEvent struct {
 	Wallbang   bool    `json:"wallbang,omitempty"`
 	NoScope    bool    `json:"noscope,omitempty"`
 
+	// Kill feed flags
+	PenetratedObjects int      `json:"penetrated_objects,omitempty"`
+	ThroughSmoke      bool     `json:"through_smoke,omitempty"`
+	AttackerBlind     bool     `json:"attacker_blind,omitempty"`
+	Distance          *float64 `json:"distance,omitempty"` // Killer to victim, game units (always set on kills)
+	AssisterID        uint64   `json:"assister_id,omitempty"`
+	AssisterName      string   `json:"assister_name,omitempty"`
+	AssistedFlash     bool     `json:"assisted_flash,omitempty"` // The assist was a flash
+	OpeningKill       bool     `json:"opening_kill,omitempty"`   // First kill of the round
+	TeamKill          bool     `json:"team_kill,omitempty"`
+	Suicide           bool     `json:"suicide,omitempty"`
+	TradeOfID         uint64   `json:"trade_of_id,omitempty"`  // This kill traded the death of that teammate
+	TradedByID        uint64   `json:"traded_by_id,omitempty"` // The victim's death was traded by that player
+
 	// For grenades
 	GrenadeType string  `json:"grenade_type,omitempty"`
 	X           float64 `json:"x,omitempty"`
 	Y           float64 `json:"y,omitempty"`
 
-	// For bomb events
+	// For bomb events (and the acting player of flash/reload/weapon_switch events)
 	Site     string `json:"site,omitempty"`
 	PlayerID uint64 `json:"player_id,omitempty"`
+	HasKit   bool   `json:"has_kit,omitempty"` // bomb_defuse_start
+
+	// For damage and player_flashed (VictimID = damaged/flashed player)
+	AttackerID  uint64  `json:"attacker_id,omitempty"`
+	Damage      int     `json:"damage,omitempty"`       // Health damage taken
+	ArmorDamage int     `json:"armor_damage,omitempty"` // Armor damage taken
+	Health      *int    `json:"health,omitempty"`       // Health left after the hit (always set on damage, 0 = lethal)
+	HitGroup    string  `json:"hitgroup,omitempty"`
+	Duration    float64 `json:"duration,omitempty"` // Flash blindness in seconds
+	TeamFlash   bool    `json:"team_flash,omitempty"`
+
+	// For buy and chat (PlayerID = buyer / sender)
+	PlayerName string `json:"player_name,omitempty"`
+	Team       string `json:"team,omitempty"`       // "CT", "T" or "" (spectators / console)
+	Cost       int    `json:"cost,omitempty"`       // Price paid
+	MoneyLeft  *int   `json:"money_left,omitempty"` // Money after the buy (always set on buys, 0 included)
+	Text       string `json:"text,omitempty"`
+	TeamChat   bool   `json:"team_chat,omitempty"`
 }
 
 // ========================================
@@ -162,32 +232,3 @@ type ReplayRoundData struct {
 	Frames    []ReplayFrame
 	Events    []ReplayEvent
 }
-
-// ========================================
-// MAP CONFIGS
-// Coordinate transformation values from demoinfocs-golang
-// ========================================
-
-// MapConfigs contains pre-defined map metadata for coordinate translation
-var MapConfigs = map[string]MapConfig{
-	"de_dust2":    {PosX: -2476, PosY: 3239, Scale: 4.4},
-	"de_mirage":   {PosX: -3230, PosY: 1713, Scale: 5.0},
-	"de_inferno":  {PosX: -2087, PosY: 3870, Scale: 4.9},
-	"de_ancient":  {PosX: -2953, PosY: 2164, Scale: 5.0},
-	"de_anubis":   {PosX: -2796, PosY: 3328, Scale: 5.22},
-	"de_nuke":     {PosX: -3453, PosY: 2887, Scale: 7.0},
-	"de_overpass": {PosX: -4831, PosY: 1781, Scale: 5.2},
-	"de_vertigo":  {PosX: -3168, PosY: 1762, Scale: 4.0},
-	"de_train":    {PosX: -2308, PosY: 2078, Scale: 4.082077},
-	"de_cache":    {PosX: -2000, PosY: 3250, Scale: 5.5},
-}
-
-// GetMapConfig returns the map config for a given map name
-// Returns a default config if map is not found
-func GetMapConfig(mapName string) MapConfig {
-	if config, ok := MapConfigs[mapName]; ok {
-		return config
-	}
-	// Default fallback
-	return MapConfig{PosX: -2500, PosY: 3000, Scale: 5.0}
-}
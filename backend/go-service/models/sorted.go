@@ -0,0 +1,19 @@
+package models
+
+import "sort"
+
+// orderedKey son los tipos de clave usados en los maps del contexto (SteamIDs, entity IDs)
+type orderedKey interface {
+	~int | ~int64 | ~uint64
+}
+
+// SortedKeys devuelve las claves de un map ordenadas, para iterar de forma
+// determinista cuando el orden afecta a lo que se exporta
+func SortedKeys[K orderedKey, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -64,8 +64,77 @@ func GetWeaponMaxSpeed(weaponName string) float64 {
 	return 250.0
 }
 
+// EstimateRunTime returns the seconds needed to run distance units holding weaponName
+// (full speed, no stops; use nav path lengths, not straight lines)
+func EstimateRunTime(distance float64, weaponName string) float64 {
+	return distance / GetWeaponMaxSpeed(weaponName)
+}
+
 // GetAccuracyThreshold returns the speed threshold (34% of MaxSpeed)
 // below which there is NO movement inaccuracy penalty
 func GetAccuracyThreshold(weaponName string) float64 {
 	return 0.34 * GetWeaponMaxSpeed(weaponName)
 }
+
+// WeaponBallistics holds the bullet values used to estimate wallbang damage
+type WeaponBallistics struct {
+	Damage        float64 // Base damage per bullet (per pellet for shotguns)
+	Penetration   float64 // Penetration power (1 = pistols/SMGs, 2 = rifles, 2.5 = snipers)
+	RangeModifier float64 // Damage multiplier per 500 units travelled
+}
+
+// WeaponPenetration defines the ballistic values for each weapon (same keys as WeaponMaxSpeed)
+// Source: CS2 game files (items_game.txt), rounded
+var WeaponPenetration = map[string]WeaponBallistics{
+	// Rifles
+	"AK-47":    {Damage: 36, Penetration: 2.0, RangeModifier: 0.98},
+	"M4A4":     {Damage: 33, Penetration: 2.0, RangeModifier: 0.97},
+	"M4A1":     {Damage: 38, Penetration: 2.0, RangeModifier: 0.99}, // M4A1-S
+	"M4A1-S":   {Damage: 38, Penetration: 2.0, RangeModifier: 0.99},
+	"Galil AR": {Damage: 30, Penetration: 2.0, RangeModifier: 0.98},
+	"FAMAS":    {Damage: 30, Penetration: 2.0, RangeModifier: 0.96},
+	"SG 553":   {Damage: 30, Penetration: 2.0, RangeModifier: 0.98},
+	"AUG":      {Damage: 28, Penetration: 2.0, RangeModifier: 0.98},
+
+	// Sniper Rifles
+	"AWP":     {Damage: 115, Penetration: 2.5, RangeModifier: 0.99},
+	"SSG 08":  {Damage: 88, Penetration: 2.5, RangeModifier: 0.98},
+	"G3SG1":   {Damage: 80, Penetration: 2.5, RangeModifier: 0.98},
+	"SCAR-20": {Damage: 80, Penetration: 2.5, RangeModifier: 0.98},
+
+	// SMGs
+	"MP9":      {Damage: 26, Penetration: 1.0, RangeModifier: 0.87},
+	"MAC-10":   {Damage: 29, Penetration: 1.0, RangeModifier: 0.80},
+	"MP7":      {Damage: 29, Penetration: 1.0, RangeModifier: 0.85},
+	"MP5-SD":   {Damage: 27, Penetration: 1.0, RangeModifier: 0.85},
+	"UMP-45":   {Damage: 35, Penetration: 1.0, RangeModifier: 0.75},
+	"PP-Bizon": {Damage: 27, Penetration: 1.0, RangeModifier: 0.80},
+	"P90":      {Damage: 26, Penetration: 1.0, RangeModifier: 0.86},
+
+	// Heavy
+	"Nova":      {Damage: 26, Penetration: 1.0, RangeModifier: 0.70},
+	"XM1014":    {Damage: 20, Penetration: 1.0, RangeModifier: 0.70},
+	"MAG-7":     {Damage: 30, Penetration: 1.0, RangeModifier: 0.45},
+	"Sawed-Off": {Damage: 32, Penetration: 1.0, RangeModifier: 0.45},
+	"M249":      {Damage: 32, Penetration: 2.0, RangeModifier: 0.97},
+	"Negev":     {Damage: 35, Penetration: 2.0, RangeModifier: 0.97},
+
+	// Pistols
+	"Glock-18":      {Damage: 30, Penetration: 1.0, RangeModifier: 0.85},
+	"USP-S":         {Damage: 35, Penetration: 1.0, RangeModifier: 0.91},
+	"P2000":         {Damage: 35, Penetration: 1.0, RangeModifier: 0.91},
+	"P250":          {Damage: 38, Penetration: 1.0, RangeModifier: 0.85},
+	"Five-SeveN":    {Damage: 32, Penetration: 1.0, RangeModifier: 0.81},
+	"Tec-9":         {Damage: 33, Penetration: 1.0, RangeModifier: 0.83},
+	"CZ75-Auto":     {Damage: 31, Penetration: 1.0, RangeModifier: 0.85},
+	"Desert Eagle":  {Damage: 53, Penetration: 2.0, RangeModifier: 0.81},
+	"Dual Berettas": {Damage: 38, Penetration: 1.0, RangeModifier: 0.75},
+	"R8 Revolver":   {Damage: 86, Penetration: 2.0, RangeModifier: 0.94},
+}
+
+// GetWeaponBallistics returns the ballistic values for a given weapon
+// Returns false for weapons that cannot wallbang (knife, grenades, Zeus...)
+func GetWeaponBallistics(weaponName string) (WeaponBallistics, bool) {
+	b, exists := WeaponPenetration[weaponName]
+	return b, exists
+}
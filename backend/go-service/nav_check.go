@@ -0,0 +1,47 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/pkg/maps"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Muestra el resumen de un .nav real (versión, áreas, enlaces, escaleras). Los
+// ficheros sintéticos del parser están en pkg/maps/testdata/nav (go test ./pkg/maps).
+// Uso:
+//
+//	go run nav_check.go -nav ../data/maps/de_nuke/de_nuke.nav
+func main() {
+	navPath := flag.String("nav", "", ".nav file to parse and summarize (required)")
+	flag.Parse()
+
+	if *navPath == "" {
+		log.Fatal("-nav is required")
+	}
+	nav, err := maps.LoadNavMesh(*navPath)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	conns, oneWay := 0, 0
+	for _, a := range nav.Areas {
+		for _, l := range nav.Neighbors(a.ID) {
+			conns++
+			if !nav.IsConnected(l.AreaID, a.ID) {
+				oneWay++
+			}
+		}
+	}
+	fmt.Printf("Version %d.%d | analyzed=%v\n", nav.Version, nav.SubVersion, nav.IsAnalyzed)
+	if nav.NewerThanKnown() {
+		fmt.Printf("⚠️  v%d is newer than the last known version (v%d): read with the v%d layout\n",
+			nav.Version, maps.NavMaxVersion, maps.NavMaxVersion)
+	}
+	fmt.Printf("Areas: %d | Links: %d (%d one-way) | Ladders: %d | Places: %d\n",
+		len(nav.Areas), conns, oneWay, len(nav.Ladders), len(nav.Places))
+}
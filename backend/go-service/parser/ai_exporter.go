@@ -4,9 +4,11 @@ import (
 	"cs2-demo-service/models"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -39,12 +41,9 @@ func ExportAIModels(ctx *models.DemoContext, matchID string, outputDir string, m
 		return fmt.Errorf("failed to create match directory: %w", err)
 	}
 
-	// Get header info for duration and tick rate
-	header := ctx.Parser.Header()
-	tickRate := ctx.Parser.TickRate()
-
-	// Calculate duration in seconds from PlaybackTime (time.Duration)
-	durationSeconds := header.PlaybackTime.Seconds()
+	// Get header info for duration and tick rate (filled by the parser or restored from the intermediate cache)
+	tickRate := ctx.DemoInfo.TickRate
+	durationSeconds := ctx.DemoInfo.DurationSeconds
 
 	// Use date from parameter if provided
 	dateStr := ""
@@ -66,6 +65,14 @@ func ExportAIModels(ctx *models.DemoContext, matchID string, outputDir string, m
 		DurationSeconds: durationSeconds,
 		TickRate:        tickRate,
 		TotalRounds:     ctx.CurrentRound,
+
+		MapCRC:             ctx.DemoInfo.Map.CRC(),
+		MapBuild:           ctx.DemoInfo.Map.BuildNum,
+		MapCheck:           ctx.DemoInfo.MapCheck,
+		VisibilityReliable: ctx.DemoInfo.MapCheck.Reliable(),
+	}
+	if !metadata.VisibilityReliable {
+		metadata.UnreliableMetrics = models.VisibilityDependentMetrics
 	}
 
 	if err := writeJSON(filepath.Join(matchDir, "metadata.json"), metadata); err != nil {
@@ -73,44 +80,13 @@ func ExportAIModels(ctx *models.DemoContext, matchID string, outputDir string, m
 	}
 
 	// 2. Export Tracking Data (Grouped by Round and Tick)
-	trackingExport := buildTrackingExport(ctx)
-	if err := writeJSON(filepath.Join(matchDir, "tracking.json"), trackingExport); err != nil {
+	// Rounds flushed during parsing are streamed from disk, then the in-memory remainder
+	if err := writeTrackingJSON(ctx, filepath.Join(matchDir, "tracking.json")); err != nil {
 		return err
 	}
 
 	// 3. Export Consolidated Duels (Grouped by Round) to combat.json
-	// Group duels by round
-	duelRoundMap := make(map[int][]models.AI_Duel)
-	for _, duel := range ctx.AI_Duels {
-		duelRoundMap[duel.Round] = append(duelRoundMap[duel.Round], duel)
-	}
-
-	// Create ordered list of duel rounds
-	var duelRounds []models.AI_DuelRound
-	duelCounter := 0 // Global counter for sequential duel IDs
-	for round := 1; round <= ctx.CurrentRound; round++ {
-		if duels, exists := duelRoundMap[round]; exists {
-			// Sort duels by tick_start
-			sort.Slice(duels, func(i, j int) bool {
-				return duels[i].TickStart < duels[j].TickStart
-			})
-			// Reassign duel_id sequentially after sorting
-			for i := range duels {
-				duelCounter++
-				duels[i].DuelID = fmt.Sprintf("duel_%d", duelCounter)
-			}
-			duelRounds = append(duelRounds, models.AI_DuelRound{
-				Round: round,
-				Duels: duels,
-			})
-		}
-	}
-
-	duelExport := models.AI_DuelExport{
-		Rounds: duelRounds,
-	}
-
-	if err := writeJSON(filepath.Join(matchDir, "combat.json"), duelExport); err != nil {
+	if err := writeCombatJSON(ctx, filepath.Join(matchDir, "combat.json")); err != nil {
 		return err
 	}
 	// 4. Export Economy Data
@@ -125,7 +101,16 @@ func ExportAIModels(ctx *models.DemoContext, matchID string, outputDir string, m
 	}
 
 	// 5. Group and Export Grenade Events by Round
-	sort.Slice(ctx.AI_GrenadeEvents, func(i, j int) bool {
+	// Flushed grenades are merged back (small enough to keep in memory)
+	if ctx.GrenadeFlushCount > 0 {
+		flushedGrenades, err := readFlushedGrenades(ctx.FlushDir, ctx.GrenadeFlushCount)
+		if err != nil {
+			return err
+		}
+		ctx.AI_GrenadeEvents = append(flushedGrenades, ctx.AI_GrenadeEvents...)
+		ctx.GrenadeFlushCount = 0
+	}
+	sort.SliceStable(ctx.AI_GrenadeEvents, func(i, j int) bool {
 		if ctx.AI_GrenadeEvents[i].Round != ctx.AI_GrenadeEvents[j].Round {
 			return ctx.AI_GrenadeEvents[i].Round < ctx.AI_GrenadeEvents[j].Round
 		}
@@ -200,19 +185,184 @@ func ExportAIModels(ctx *models.DemoContext, matchID string, outputDir string, m
 		return err
 	}
 
-	// 7. Export Replay Data (for 2D visualization)
+	// 7. Export Player Presence (connection lifecycle per player)
+	presenceExport := buildPresenceExport(ctx, matchID)
+	if err := writeJSON(filepath.Join(matchDir, "presence.json"), presenceExport); err != nil {
+		return err
+	}
+
+	// 8. Export Replay Data (for 2D visualization)
 	if ctx.ReplayData != nil {
 		// Update matchID in replay data
 		ctx.ReplayData.Metadata.MatchID = matchID
-		if err := writeJSON(filepath.Join(matchDir, "replay.json"), ctx.ReplayData); err != nil {
+		replayRounds, err := writeReplayJSON(ctx, filepath.Join(matchDir, "replay.json"))
+		if err != nil {
 			return err
 		}
-		fmt.Printf("📹 Replay data exported: %d rounds\n", len(ctx.ReplayData.Rounds))
+		fmt.Printf("📹 Replay data exported: %d rounds\n", replayRounds)
+	}
+
+	// 9. Export Movement logs (flushed rounds streamed from disk, then the in-memory remainder)
+	if err := writeMovementJSON(ctx, filepath.Join(matchDir, "movement.json")); err != nil {
+		return err
+	}
+
+	// Per-round flush chunks are no longer needed once the final files are written
+	if ctx.FlushDir != "" {
+		if err := os.RemoveAll(ctx.FlushDir); err != nil {
+			fmt.Printf("⚠️  Could not remove flush directory %s: %v\n", ctx.FlushDir, err)
+		}
 	}
 
 	return nil
 }
 
+// writeTrackingJSON streams tracking.json: flushed rounds first, then in-memory rounds
+func writeTrackingJSON(ctx *models.DemoContext, path string) error {
+	w, err := newRoundsWriter(path, true)
+	if err != nil {
+		return err
+	}
+
+	flushed, err := chunkRounds(ctx.FlushDir, "tracking")
+	if err != nil {
+		w.Close()
+		return err
+	}
+	for _, round := range flushed {
+		raw, err := readChunk(ctx.FlushDir, chunkName("tracking", round))
+		if err != nil {
+			w.Close()
+			return err
+		}
+		w.RoundRaw(raw)
+	}
+
+	for _, tr := range buildTrackingExport(ctx).Rounds {
+		w.Round(tr)
+	}
+
+	return w.Close()
+}
+
+// writeCombatJSON streams combat.json, assigning sequential duel IDs across all rounds
+func writeCombatJSON(ctx *models.DemoContext, path string) error {
+	w, err := newRoundsWriter(path, true)
+	if err != nil {
+		return err
+	}
+
+	duelCounter := 0 // Global counter for sequential duel IDs
+	assignIDs := func(duels []models.AI_Duel) {
+		for i := range duels {
+			duelCounter++
+			duels[i].DuelID = fmt.Sprintf("duel_%d", duelCounter)
+		}
+	}
+
+	// Flushed rounds (already sorted by tick_start at flush time)
+	flushed, err := chunkRounds(ctx.FlushDir, "combat")
+	if err != nil {
+		w.Close()
+		return err
+	}
+	for _, round := range flushed {
+		raw, err := readChunk(ctx.FlushDir, chunkName("combat", round))
+		if err != nil {
+			w.Close()
+			return err
+		}
+		var duelRound models.AI_DuelRound
+		if err := json.Unmarshal(raw, &duelRound); err != nil {
+			w.Close()
+			return fmt.Errorf("failed to decode combat chunk: %w", err)
+		}
+		assignIDs(duelRound.Duels)
+		w.Round(duelRound)
+	}
+
+	// Group in-memory duels by round
+	duelRoundMap := make(map[int][]models.AI_Duel)
+	for _, duel := range ctx.AI_Duels {
+		duelRoundMap[duel.Round] = append(duelRoundMap[duel.Round], duel)
+	}
+
+	for round := 1; round <= ctx.CurrentRound; round++ {
+		if duels, exists := duelRoundMap[round]; exists {
+			// Sort duels by tick_start
+			sort.SliceStable(duels, func(i, j int) bool {
+				return duels[i].TickStart < duels[j].TickStart
+			})
+			// Reassign duel_id sequentially after sorting
+			assignIDs(duels)
+			w.Round(models.AI_DuelRound{
+				Round: round,
+				Duels: duels,
+			})
+		}
+	}
+
+	return w.Close()
+}
+
+// writeReplayJSON streams replay.json and returns the number of rounds written
+func writeReplayJSON(ctx *models.DemoContext, path string) (int, error) {
+	w, err := newRoundsWriter(path, ctx.ReplayData.Rounds == nil && ctx.FlushDir == "")
+	if err != nil {
+		return 0, err
+	}
+	w.Field("metadata", ctx.ReplayData.Metadata)
+
+	flushed, err := chunkRounds(ctx.FlushDir, "replay")
+	if err != nil {
+		w.Close()
+		return 0, err
+	}
+	for _, round := range flushed {
+		raw, err := readChunk(ctx.FlushDir, chunkName("replay", round))
+		if err != nil {
+			w.Close()
+			return 0, err
+		}
+		w.RoundRaw(raw)
+	}
+
+	for _, rr := range ctx.ReplayData.Rounds {
+		w.Round(rr)
+	}
+
+	return len(flushed) + len(ctx.ReplayData.Rounds), w.Close()
+}
+
+// writeMovementJSON streams movement.json: flushed rounds first, then in-memory rounds
+// (the in-memory logs are kept in MatchData)
+func writeMovementJSON(ctx *models.DemoContext, path string) error {
+	w, err := newRoundsWriter(path, false)
+	if err != nil {
+		return err
+	}
+
+	flushed, err := chunkRounds(ctx.FlushDir, "movement")
+	if err != nil {
+		w.Close()
+		return err
+	}
+	for _, round := range flushed {
+		raw, err := readChunk(ctx.FlushDir, chunkName("movement", round))
+		if err != nil {
+			w.Close()
+			return err
+		}
+		w.RoundRaw(raw)
+	}
+
+	for _, mr := range movementRounds(ctx, math.MaxInt, false) {
+		w.Round(mr)
+	}
+
+	return w.Close()
+}
+
 // writeJSON writes any data structure to a JSON file with indentation
 func writeJSON(filepath string, data interface{}) error {
 	bytes, err := json.MarshalIndent(data, "", "  ")
@@ -310,10 +460,17 @@ func compactWeaponArrays(jsonStr string) string {
 
 // buildTrackingExport groups tracking events by round and tick
 func buildTrackingExport(ctx *models.DemoContext) models.AI_TrackingExport {
+	return models.AI_TrackingExport{
+		Rounds: buildTrackingRounds(ctx.AI_TrackingEventsWithRound),
+	}
+}
+
+// buildTrackingRounds groups a set of tracking events by round, then by tick (both sorted)
+func buildTrackingRounds(events []models.AI_TrackingEventWithRound) []models.AI_TrackingRound {
 	// Group events by round, then by tick
 	roundMap := make(map[int]map[int][]models.AI_TrackingEvent)
 
-	for _, e := range ctx.AI_TrackingEventsWithRound {
+	for _, e := range events {
 		round := e.Round
 		tick := e.Event.Tick
 
@@ -357,7 +514,65 @@ func buildTrackingExport(ctx *models.DemoContext) models.AI_TrackingExport {
 		})
 	}
 
-	return models.AI_TrackingExport{
-		Rounds: rounds,
+	return rounds
+}
+
+// buildPresenceExport builds the presence timeline of every human player, sorted by SteamID
+func buildPresenceExport(ctx *models.DemoContext, matchID string) models.AI_PresenceExport {
+	steamIDs := models.SortedKeys(ctx.PlayerLifecycles)
+
+	players := make([]models.AI_PlayerPresence, 0, len(steamIDs))
+	for _, id := range steamIDs {
+		lc := ctx.PlayerLifecycles[id]
+
+		presence := models.AI_PlayerPresence{
+			SteamID:         strconv.FormatUint(id, 10),
+			Name:            lc.Name,
+			ConnectTicks:    append([]int{}, lc.ConnectTicks...),
+			DisconnectTicks: append([]int{}, lc.DisconnectTicks...),
+			ReconnectTicks:  append([]int{}, lc.ReconnectTicks...),
+			TeamChanges:     []models.AI_TeamChange{},
+			RoundsPresent:   lc.RoundsPresent(),
+			RoundsAbsent:    []int{},
+			Abandoned:       lc.Abandoned,
+			AbandonRound:    lc.AbandonRound,
+			AbandonTick:     lc.AbandonTick,
+			Rounds:          []models.AI_PresenceRound{},
+		}
+
+		for _, tc := range lc.TeamChanges {
+			presence.TeamChanges = append(presence.TeamChanges, models.AI_TeamChange{
+				Tick:  tc.Tick,
+				Round: tc.Round,
+				From:  tc.From,
+				To:    tc.To,
+			})
+		}
+
+		for round := 1; round <= ctx.CurrentRound; round++ {
+			if _, present := lc.Rounds[round]; !present {
+				presence.RoundsAbsent = append(presence.RoundsAbsent, round)
+			}
+		}
+
+		for _, rp := range lc.SortedRounds() {
+			presence.Rounds = append(presence.Rounds, models.AI_PresenceRound{
+				Round:           rp.Round,
+				Team:            rp.Team,
+				JoinedTick:      rp.JoinedTick,
+				LeftTick:        rp.LeftTick,
+				BotControlled:   rp.BotControlled,
+				ControlledBot:   rp.ControlledBot,
+				BotTakeoverTick: rp.BotTakeoverTick,
+				BotKills:        rp.BotKills,
+			})
+		}
+
+		players = append(players, presence)
+	}
+
+	return models.AI_PresenceExport{
+		MatchID: matchID,
+		Players: players,
 	}
 }
@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"cs2-demo-service/handlers"
+	"cs2-demo-service/models"
+	"cs2-demo-service/pkg/golden"
+	"cs2-demo-service/pkg/maps"
+)
+
+// goldenMatchID es el match id de los exports de los baselines
+const goldenMatchID = "golden"
+
+// syntheticGolden es el baseline de TestGoldenSynthetic (no corresponde a ninguna demo).
+// Se regenera con: go test ./parser -run TestGoldenSynthetic -update
+const syntheticGolden = "synthetic"
+
+var updateGolden = flag.Bool("update", false, "regenerate the synthetic golden baseline in testdata/golden/synthetic")
+
+// TestGoldenExports compara los exports de cada demo con su baseline en
+// testdata/golden/<demo>/ (generado con golden_check.go) y comprueba que dos parses
+// seguidos dan el mismo resultado. Las demos no están en el repo: se buscan en
+// $GOLDEN_DEMOS_DIR (por defecto ../../data/demos) y se omiten si no están.
+func TestGoldenExports(t *testing.T) {
+	if testing.Short() {
+		t.Skip("golden exports parse full demos")
+	}
+	baselines, err := os.ReadDir(filepath.Join("testdata", "golden"))
+	if err != nil {
+		t.Skipf("no golden baselines: %v", err)
+	}
+	demosDir := os.Getenv("GOLDEN_DEMOS_DIR")
+	if demosDir == "" {
+		demosDir = filepath.Join("..", "..", "data", "demos")
+	}
+
+	for _, entry := range baselines {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if name == syntheticGolden {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			demoPath := filepath.Join(demosDir, name+".dem")
+			if _, err := os.Stat(demoPath); err != nil {
+				t.Skipf("demo not available: %s", demoPath)
+			}
+
+			first := exportGolden(t, demoPath)
+			second := exportGolden(t, demoPath)
+			if report := compareGolden(t, first, second); !report.OK() {
+				t.Errorf("non-deterministic output between runs:\n%s", report.Summary(50))
+			}
+			if report := compareGolden(t, filepath.Join("testdata", "golden", name), first); !report.OK() {
+				t.Errorf("output differs from golden baseline (regenerate with golden_check.go if intended):\n%s", report.Summary(50))
+			}
+		})
+	}
+}
+
+// TestGoldenSynthetic exporta dos veces un DemoContext sintético (rondas 1-2 volcadas a
+// disco, la 3 en memoria) y compara ambos exports entre sí y con testdata/golden/synthetic/.
+// A diferencia de TestGoldenExports no necesita demos, así que siempre corre en CI.
+func TestGoldenSynthetic(t *testing.T) {
+	first := exportSynthetic(t)
+	second := exportSynthetic(t)
+	if report := compareGolden(t, first, second); !report.OK() {
+		t.Errorf("non-deterministic output between runs:\n%s", report.Summary(50))
+	}
+
+	baselineDir := filepath.Join("testdata", "golden", syntheticGolden)
+	if *updateGolden {
+		if err := golden.Update(baselineDir, first); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("baseline regenerated in %s", baselineDir)
+	}
+	if report := compareGolden(t, baselineDir, first); !report.OK() {
+		t.Errorf("output differs from golden baseline (regenerate with -update if intended):\n%s", report.Summary(50))
+	}
+}
+
+// exportSynthetic construye el DemoContext sintético, vuelca las rondas 1 y 2 como el
+// parsing y lo exporta; devuelve el directorio match_<id>
+func exportSynthetic(t *testing.T) string {
+	t.Helper()
+	outputDir := t.TempDir()
+
+	ctx := syntheticContext()
+	ctx.FlushDir = PartialDir(outputDir, goldenMatchID)
+	if err := os.MkdirAll(ctx.FlushDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	replay := handlers.NewReplayHandler(ctx, handlers.DefaultReplayOptions())
+	replay.Rounds = ctx.ReplayData.Rounds[:2]
+	ctx.ReplayData.Rounds = ctx.ReplayData.Rounds[2:]
+	for round := 1; round <= 2; round++ {
+		if err := flushRound(ctx, replay, nil, round); err != nil {
+			t.Fatalf("flush round %d: %v", round, err)
+		}
+	}
+
+	if err := ExportAIModels(ctx, goldenMatchID, outputDir, "2024-01-02T15:04:05Z"); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	return filepath.Join(outputDir, "match_"+goldenMatchID)
+}
+
+// syntheticContext devuelve un DemoContext de 3 rondas y 2 jugadores con datos en cada
+// export (tracking, duelos, economía, granadas, resumen, presencia, replay y movement)
+func syntheticContext() *models.DemoContext {
+	const rounds, ticksPerRound = 3, 4
+	players := []struct {
+		id   uint64
+		name string
+		team string
+	}{{76561198000000001, "alpha", "CT"}, {76561198000000002, "bravo", "T"}}
+
+	ctx := models.NewDemoContext(nil)
+	ctx.CurrentRound = rounds
+	ctx.DemoInfo.TickRate = 64
+	ctx.DemoInfo.DurationSeconds = 180
+	ctx.MatchData.MapName = "de_synthetic"
+	ctx.MatchData.CTScore, ctx.MatchData.TScore, ctx.MatchData.Winner = 2, 1, "CT"
+	ctx.ReplayData = &models.ReplayData{Metadata: models.ReplayMetadata{
+		SchemaVersion: models.ReplaySchemaVersion,
+		MapName:       "de_synthetic",
+		TickRate:      64,
+		SampleRate:    62.5,
+		SampleRateHz:  16,
+		MapConfig:     maps.MapConfig{PosX: -2000, PosY: 2000, Scale: 4, Source: maps.MapConfigSourceDefault},
+	}}
+
+	for i, p := range players {
+		ctx.MatchData.Players[p.id] = &models.PlayerData{SteamID: p.id, Name: p.name, Team: p.team, Movement: []models.MovementLog{}}
+		lc := ctx.GetOrCreateLifecycle(p.id, p.name)
+		lc.ConnectTicks = []int{0}
+		for round := 1; round <= rounds; round++ {
+			if i == 1 && round == 2 {
+				continue // bravo se desconecta en la ronda 2
+			}
+			lc.MarkPresent(round, p.team, 0)
+		}
+		ctx.AI_PlayersSummary = append(ctx.AI_PlayersSummary, models.AI_PlayerStats{
+			SteamID: strconv.FormatUint(p.id, 10), Name: p.name, Team: p.team,
+			RoundsPlayed: rounds - i, Kills: 2 - i, Deaths: 1 + i, KDRatio: float64(2-i) / float64(1+i),
+		})
+	}
+	ctx.PlayerLifecycles[players[1].id].ConnectTicks = []int{0, 2100}
+	ctx.PlayerLifecycles[players[1].id].DisconnectTicks = []int{1500}
+	ctx.PlayerLifecycles[players[1].id].ReconnectTicks = []int{2100}
+
+	for round := 1; round <= rounds; round++ {
+		start := round * 1000
+		winner := "CT"
+		if round == 2 {
+			winner = "T"
+		}
+		rr := models.ReplayRound{Round: round, StartTick: start, EndTick: start + ticksPerRound*16, Winner: winner,
+			Events: []models.ReplayEvent{{Tick: start + 40, Type: "kill", KillerID: players[0].id, VictimID: players[1].id}}}
+		economy := models.AI_EconomyRound{Round: round, Teams: map[string]models.AI_EconomyTeam{
+			"CT": {TotalMoney: 4000 * round, AverageMoney: 4000 * round},
+			"T":  {TotalMoney: 3000 * round, AverageMoney: 3000 * round, LossBonus: 1400},
+		}}
+
+		for tick := 0; tick < ticksPerRound; tick++ {
+			frame := models.ReplayFrame{Tick: start + tick*16, TimeRemaining: 115 - float64(tick)/4}
+			for i, p := range players {
+				x, y := float64(100*i+10*tick), float64(-50*i+round)
+				ctx.AI_TrackingEventsWithRound = append(ctx.AI_TrackingEventsWithRound, models.AI_TrackingEventWithRound{
+					Round: round,
+					Event: models.AI_TrackingEvent{Tick: frame.Tick, PlayerSteamID: p.id, Team: p.team,
+						Position: models.AI_Vector{X: x, Y: y}, AreaName: "Mid", ActiveWeapon: "ak47",
+						Health: 100 - 10*tick*i, IsAlive: true, RoundTimeRemaining: frame.TimeRemaining},
+				})
+				pd := ctx.MatchData.Players[p.id]
+				pd.Movement = append(pd.Movement, models.MovementLog{Round: round, Tick: frame.Tick, X: x, Y: y, Speed: 250})
+				frame.Players = append(frame.Players, models.ReplayPlayerState{SteamID: p.id, Name: p.name, Team: p.team,
+					X: int(x), Y: int(y), Health: 100, Alive: true, Weapon: "ak47", Money: 800})
+			}
+			rr.Frames = append(rr.Frames, frame)
+		}
+		for _, p := range players {
+			economy.Players = append(economy.Players, models.AI_EconomyPlayer{SteamID: p.id, Name: p.name, Team: p.team,
+				InitialMoney: 800 * round, Outcome: winner, WinReason: "elimination", Survived: p.team == winner})
+		}
+
+		ctx.ReplayData.Rounds = append(ctx.ReplayData.Rounds, rr)
+		ctx.AI_EconomyRounds = append(ctx.AI_EconomyRounds, economy)
+		// Duelos desordenados por tick: el export los ordena y numera
+		for d := 1; d >= 0; d-- {
+			ctx.AI_Duels = append(ctx.AI_Duels, models.AI_Duel{Type: "duel", Outcome: "kill", VictimCount: 1, Round: round,
+				TickStart: start + 20*d, TickEnd: start + 20*d + 10,
+				Attacker: models.AI_DuelParticipant{SteamID: players[d].id, Name: players[d].name, Team: players[d].team, Weapon: "ak47", TotalDamageDealt: 100},
+				Victims:  []models.AI_DuelParticipant{{SteamID: players[1-d].id, Name: players[1-d].name, Team: players[1-d].team, HealthBefore: 100}},
+			})
+		}
+		ctx.AI_GrenadeEvents = append(ctx.AI_GrenadeEvents, models.AI_GrenadeEvent{Round: round, Type: "Smoke",
+			Thrower: players[round%2].name, TickThrow: start + 8, TickExplode: start + 90, LandArea: "Mid",
+			EndPosition: models.AI_Vector{X: 50, Y: float64(round)}, Duration: 18})
+	}
+	return ctx
+}
+
+// exportGolden parsea y exporta la demo como golden_check.go y devuelve el directorio match_<id>
+func exportGolden(t *testing.T, demoPath string) string {
+	t.Helper()
+	outputDir := t.TempDir()
+
+	opts := DefaultParseOptions()
+	opts.MapsDir = filepath.Join("..", DefaultMapsDir)
+	opts.FlushDir = PartialDir(outputDir, goldenMatchID)
+
+	result, err := ParseDemoWithOptions(demoPath, opts)
+	if err != nil {
+		t.Fatalf("parse %s: %v", demoPath, err)
+	}
+	if err := ExportAIModels(result.Context, goldenMatchID, outputDir, ""); err != nil {
+		t.Fatalf("export %s: %v", demoPath, err)
+	}
+	return filepath.Join(outputDir, "match_"+goldenMatchID)
+}
+
+func compareGolden(t *testing.T, baselineDir, candidateDir string) *golden.Report {
+	t.Helper()
+	report, err := golden.CompareDirs(baselineDir, candidateDir)
+	if err != nil {
+		t.Fatalf("compare %s: %v", baselineDir, err)
+	}
+	return report
+}
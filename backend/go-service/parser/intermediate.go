@@ -0,0 +1,446 @@
+package parser
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"cs2-demo-service/handlers"
+	"cs2-demo-service/models"
+)
+
+// ============================================================================
+// INTERMEDIATE CACHE
+// Snapshot of everything the exports are built from, so ExportAIModels /
+// ConsolidateDuels / GetStatsWithContext can be re-run without re-parsing the
+// demo. The container is binary but each section is a JSON payload (the models
+// only have JSON tags), all of it gzip-compressed.
+//
+// Format (gzip-compressed):
+//   magic "STRATAI\x00" | uint16 version | uint64 schema hash | sections...
+//   section = uint8 kind | uint32 length | JSON payload
+// The trailer section is always last.
+// ============================================================================
+
+// IntermediateVersion is the version of the container format. Changes to the
+// cached models are caught by the schema hash, no need to bump it for those.
+const IntermediateVersion uint16 = 2
+
+// IntermediateFileName is the cache file stored inside match_<id>/
+const IntermediateFileName = "intermediate.bin"
+
+var intermediateMagic = [8]byte{'S', 'T', 'R', 'A', 'T', 'A', 'I', 0}
+
+// intermediateSchema hashes the shape of every cached model (field names, types and
+// JSON tags, recursively), so a cache written before any struct change is rejected
+// instead of loading with the new fields silently missing
+var intermediateSchema = schemaHash(
+	reflect.TypeOf(intermediateTrailer{}),
+	reflect.TypeOf([]models.AI_TrackingEventWithRound{}),
+	reflect.TypeOf([]grenadeChunk{}),
+	reflect.TypeOf([]models.ReplayRound{}),
+	reflect.TypeOf([]models.MovementRound{}),
+)
+
+// ErrIntermediateVersion is returned when the cache was written by another version
+var ErrIntermediateVersion = errors.New("intermediate cache version mismatch")
+
+const (
+	sectionTracking uint8 = iota + 1
+	sectionGrenades
+	sectionReplay
+	sectionTrailer
+	sectionMovement
+)
+
+// IntermediatePath returns the cache path for a match
+func IntermediatePath(outputDir, matchID string) string {
+	return filepath.Join(outputDir, fmt.Sprintf("match_%s", matchID), IntermediateFileName)
+}
+
+// intermediateTrailer holds everything that is only complete at the end of the parse
+type intermediateTrailer struct {
+	MapName        string
+	CTScore        int
+	TScore         int
+	Winner         string
+	CurrentRound   int
+	DemoInfo       models.DemoInfo
+	ReplayMetadata models.ReplayMetadata
+
+	EconomyRounds []models.AI_EconomyRound
+	RawCombat     []models.RawCombatBatch
+	Stats         handlers.PlayerStatsState
+	Players       []cachedPlayerCombat
+	Lifecycles    map[uint64]*models.PlayerLifecycle
+}
+
+// cachedPlayerCombat is the per-player data used by GetStatsWithContext
+// (CounterStrafeValues is json:"-" in MechanicsStats, so it is stored apart)
+type cachedPlayerCombat struct {
+	SteamID             uint64
+	Name                string
+	ReactionTimes       []models.ReactionTimeEvent
+	Mechanics           *models.MechanicsStats
+	CounterStrafeValues []float64
+}
+
+// IntermediateWriter writes the cache incrementally (per-round flush + trailer).
+// All methods are no-ops on a nil writer.
+type IntermediateWriter struct {
+	f      *os.File
+	gz     *gzip.Writer
+	bw     *bufio.Writer
+	closed bool
+}
+
+// NewIntermediateWriter creates the cache file and writes the header
+func NewIntermediateWriter(path string) (*IntermediateWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create intermediate cache: %w", err)
+	}
+
+	gz, _ := gzip.NewWriterLevel(f, gzip.BestSpeed)
+	w := &IntermediateWriter{f: f, gz: gz, bw: bufio.NewWriter(gz)}
+
+	if _, err := w.bw.Write(intermediateMagic[:]); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("failed to write cache header: %w", err)
+	}
+	if err := binary.Write(w.bw, binary.LittleEndian, IntermediateVersion); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("failed to write cache header: %w", err)
+	}
+	if err := binary.Write(w.bw, binary.LittleEndian, intermediateSchema); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("failed to write cache header: %w", err)
+	}
+	return w, nil
+}
+
+func (w *IntermediateWriter) writeSection(kind uint8, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache section %d: %w", kind, err)
+	}
+	if err := w.bw.WriteByte(kind); err != nil {
+		return fmt.Errorf("failed to write cache section: %w", err)
+	}
+	if err := binary.Write(w.bw, binary.LittleEndian, uint32(len(payload))); err != nil {
+		return fmt.Errorf("failed to write cache section: %w", err)
+	}
+	if _, err := w.bw.Write(payload); err != nil {
+		return fmt.Errorf("failed to write cache section: %w", err)
+	}
+	return nil
+}
+
+// WriteTracking appends tracking samples
+func (w *IntermediateWriter) WriteTracking(events []models.AI_TrackingEventWithRound) error {
+	if w == nil || len(events) == 0 {
+		return nil
+	}
+	return w.writeSection(sectionTracking, events)
+}
+
+// WriteGrenades appends grenade events (with their round)
+func (w *IntermediateWriter) WriteGrenades(events []grenadeChunk) error {
+	if w == nil || len(events) == 0 {
+		return nil
+	}
+	return w.writeSection(sectionGrenades, events)
+}
+
+// WriteReplay appends finished replay rounds
+func (w *IntermediateWriter) WriteReplay(rounds []models.ReplayRound) error {
+	if w == nil || len(rounds) == 0 {
+		return nil
+	}
+	return w.writeSection(sectionReplay, rounds)
+}
+
+// WriteMovement appends the movement logs of finished rounds
+func (w *IntermediateWriter) WriteMovement(rounds []models.MovementRound) error {
+	if w == nil || len(rounds) == 0 {
+		return nil
+	}
+	return w.writeSection(sectionMovement, rounds)
+}
+
+// Finish writes whatever is still in memory plus the trailer, and closes the file.
+// Must be called before the stats are finalized (GetStats mutates them in place).
+func (w *IntermediateWriter) Finish(ctx *models.DemoContext, stats handlers.PlayerStatsState) error {
+	if w == nil {
+		return nil
+	}
+
+	if err := w.WriteTracking(ctx.AI_TrackingEventsWithRound); err != nil {
+		return err
+	}
+
+	grenades := make([]grenadeChunk, 0, len(ctx.AI_GrenadeEvents))
+	for _, g := range ctx.AI_GrenadeEvents {
+		grenades = append(grenades, grenadeChunk{Round: g.Round, Event: g})
+	}
+	if err := w.WriteGrenades(grenades); err != nil {
+		return err
+	}
+	if err := w.WriteMovement(movementRounds(ctx, math.MaxInt, false)); err != nil {
+		return err
+	}
+
+	trailer := intermediateTrailer{
+		MapName:       ctx.MatchData.MapName,
+		CTScore:       ctx.MatchData.CTScore,
+		TScore:        ctx.MatchData.TScore,
+		Winner:        ctx.MatchData.Winner,
+		CurrentRound:  ctx.CurrentRound,
+		DemoInfo:      ctx.DemoInfo,
+		EconomyRounds: ctx.AI_EconomyRounds,
+		RawCombat:     ctx.RawCombatHistory,
+		Stats:         stats,
+		Lifecycles:    ctx.PlayerLifecycles,
+	}
+	if ctx.ReplayData != nil {
+		trailer.ReplayMetadata = ctx.ReplayData.Metadata
+		if err := w.WriteReplay(ctx.ReplayData.Rounds); err != nil {
+			return err
+		}
+	}
+	for _, steamID := range ctx.MatchData.SortedPlayerIDs() {
+		pd := ctx.MatchData.Players[steamID]
+		if pd == nil || (len(pd.ReactionTimes) == 0 && pd.Mechanics == nil) {
+			continue
+		}
+		cached := cachedPlayerCombat{
+			SteamID:       steamID,
+			Name:          pd.Name,
+			ReactionTimes: pd.ReactionTimes,
+			Mechanics:     pd.Mechanics,
+		}
+		if pd.Mechanics != nil {
+			cached.CounterStrafeValues = pd.Mechanics.CounterStrafeValues
+		}
+		trailer.Players = append(trailer.Players, cached)
+	}
+
+	if err := w.writeSection(sectionTrailer, trailer); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// Close flushes and closes the cache file
+func (w *IntermediateWriter) Close() error {
+	if w == nil || w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err := w.bw.Flush()
+	if gzErr := w.gz.Close(); err == nil {
+		err = gzErr
+	}
+	if fErr := w.f.Close(); err == nil {
+		err = fErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to close intermediate cache: %w", err)
+	}
+	return nil
+}
+
+// RebuildFromIntermediate restores a DemoContext from the cache and re-runs the
+// post-processing (ConsolidateDuels, player stats). The result can be passed to ExportAIModels.
+func RebuildFromIntermediate(path string) (*models.DemoContext, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open intermediate cache: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read intermediate cache: %w", err)
+	}
+	defer gz.Close()
+	r := bufio.NewReader(gz)
+
+	var magic [8]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || magic != intermediateMagic {
+		return nil, fmt.Errorf("not an intermediate cache: %s", path)
+	}
+	var version uint16
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, fmt.Errorf("failed to read cache version: %w", err)
+	}
+	if version != IntermediateVersion {
+		return nil, fmt.Errorf("%w: file v%d, expected v%d", ErrIntermediateVersion, version, IntermediateVersion)
+	}
+	var schema uint64
+	if err := binary.Read(r, binary.LittleEndian, &schema); err != nil {
+		return nil, fmt.Errorf("failed to read cache version: %w", err)
+	}
+	if schema != intermediateSchema {
+		return nil, fmt.Errorf("%w: cached models changed (schema %016x, expected %016x)", ErrIntermediateVersion, schema, intermediateSchema)
+	}
+
+	ctx := models.NewDemoContext(nil)
+	replay := &models.ReplayData{Rounds: []models.ReplayRound{}}
+	var trailer *intermediateTrailer
+	var movement []models.MovementRound
+
+	for trailer == nil {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("intermediate cache truncated (no trailer): %w", err)
+		}
+		var length uint32
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, fmt.Errorf("failed to read cache section: %w", err)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, fmt.Errorf("failed to read cache section: %w", err)
+		}
+
+		switch kind {
+		case sectionTracking:
+			var events []models.AI_TrackingEventWithRound
+			if err := json.Unmarshal(payload, &events); err != nil {
+				return nil, fmt.Errorf("failed to decode tracking section: %w", err)
+			}
+			ctx.AI_TrackingEventsWithRound = append(ctx.AI_TrackingEventsWithRound, events...)
+		case sectionGrenades:
+			var chunks []grenadeChunk
+			if err := json.Unmarshal(payload, &chunks); err != nil {
+				return nil, fmt.Errorf("failed to decode grenades section: %w", err)
+			}
+			for _, c := range chunks {
+				c.Event.Round = c.Round
+				ctx.AI_GrenadeEvents = append(ctx.AI_GrenadeEvents, c.Event)
+			}
+		case sectionReplay:
+			var rounds []models.ReplayRound
+			if err := json.Unmarshal(payload, &rounds); err != nil {
+				return nil, fmt.Errorf("failed to decode replay section: %w", err)
+			}
+			replay.Rounds = append(replay.Rounds, rounds...)
+		case sectionMovement:
+			var rounds []models.MovementRound
+			if err := json.Unmarshal(payload, &rounds); err != nil {
+				return nil, fmt.Errorf("failed to decode movement section: %w", err)
+			}
+			movement = append(movement, rounds...)
+		case sectionTrailer:
+			trailer = &intermediateTrailer{}
+			if err := json.Unmarshal(payload, trailer); err != nil {
+				return nil, fmt.Errorf("failed to decode trailer: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown cache section %d", kind)
+		}
+	}
+
+	// Restore match-level state
+	ctx.MatchData.MapName = trailer.MapName
+	ctx.MatchData.CTScore = trailer.CTScore
+	ctx.MatchData.TScore = trailer.TScore
+	ctx.MatchData.Winner = trailer.Winner
+	ctx.CurrentRound = trailer.CurrentRound
+	ctx.DemoInfo = trailer.DemoInfo
+	ctx.AI_EconomyRounds = trailer.EconomyRounds
+	if trailer.Lifecycles != nil {
+		ctx.PlayerLifecycles = trailer.Lifecycles
+	}
+	replay.Metadata = trailer.ReplayMetadata
+	ctx.ReplayData = replay
+
+	for _, p := range trailer.Players {
+		pd := &models.PlayerData{
+			SteamID:       p.SteamID,
+			Name:          p.Name,
+			ReactionTimes: p.ReactionTimes,
+			Mechanics:     p.Mechanics,
+		}
+		if pd.Mechanics != nil {
+			pd.Mechanics.CounterStrafeValues = p.CounterStrafeValues
+		}
+		ctx.MatchData.Players[p.SteamID] = pd
+	}
+	for _, mr := range movement {
+		for _, pm := range mr.Players {
+			pd := ctx.MatchData.Players[pm.SteamID]
+			if pd == nil {
+				pd = &models.PlayerData{SteamID: pm.SteamID, Name: pm.Name}
+				ctx.MatchData.Players[pm.SteamID] = pd
+			}
+			pd.Movement = append(pd.Movement, pm.Movement...)
+		}
+	}
+
+	// Re-run duel consolidation round by round
+	for _, batch := range trailer.RawCombat {
+		ctx.ActualRoundNumber = batch.Round
+		ctx.RawCombatEvents = batch.Events
+		ctx.LastActiveWeapon = batch.LastActiveWeapon
+		if ctx.LastActiveWeapon == nil {
+			ctx.LastActiveWeapon = make(map[uint64]string)
+		}
+		handlers.ConsolidateDuels(ctx)
+	}
+
+	// Re-run final stats
+	statsHandler := handlers.RestorePlayerStatsHandler(trailer.Stats)
+	ctx.AI_PlayersSummary = statsHandler.GetStatsWithContext(ctx)
+
+	return ctx, nil
+}
+
+// schemaHash is an FNV-1a hash of the exported fields (name, JSON tag, type) of the
+// given types and everything they reference
+func schemaHash(types ...reflect.Type) uint64 {
+	h := fnv.New64a()
+	seen := map[reflect.Type]bool{}
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		fmt.Fprintf(h, "%s:%s;", t.Kind(), t.Name())
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array:
+			walk(t.Elem())
+		case reflect.Map:
+			walk(t.Key())
+			walk(t.Elem())
+		case reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				if !f.IsExported() {
+					continue
+				}
+				fmt.Fprintf(h, "%s `%s` ", f.Name, f.Tag.Get("json"))
+				walk(f.Type)
+			}
+		}
+	}
+	for _, t := range types {
+		walk(t)
+	}
+	return h.Sum64()
+}
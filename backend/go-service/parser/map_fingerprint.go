@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"cs2-demo-service/pkg/maps"
+
+	dem "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/msgs2"
+)
+
+// registerMapFingerprint guarda en fp la versión del mapa que anuncia la demo.
+// En CS2 CDemoFileHeader llega en el primer frame (no en ParseHeader), así que
+// fp solo está completo al terminar el parse.
+func registerMapFingerprint(p dem.Parser, fp *maps.DemoMapFingerprint) {
+	p.RegisterNetMessageHandler(func(m *msgs2.CDemoFileHeader) {
+		if name := m.GetMapName(); name != "" {
+			fp.MapName = name
+		}
+		fp.BuildNum = int(m.GetBuildNum())
+		fp.NetworkProtocol = int(m.GetNetworkProtocol())
+		fp.DemoVersion = m.GetDemoVersionName()
+		fp.Addons = m.GetAddons()
+	})
+	p.RegisterNetMessageHandler(func(m *msgs2.CSVCMsg_ServerInfo) {
+		if fp.MapName == "" {
+			fp.MapName = m.GetMapName()
+		}
+		if manifest := m.GetGameSessionManifest(); len(manifest) > 0 {
+			sum := sha256.Sum256(manifest)
+			fp.ManifestHash = hex.EncodeToString(sum[:6])
+		}
+	})
+}
+
+// checkMapFingerprint compara el mapa de la demo con los assets cargados y avisa si no coinciden
+func checkMapFingerprint(checker maps.VisibilityChecker, fp maps.DemoMapFingerprint) maps.MapCheck {
+	fc, ok := checker.(maps.FingerprintChecker)
+	if !ok {
+		return maps.MapCheck{Status: maps.MapCheckUnavailable, DemoCRC: fp.CRC()}
+	}
+	check := fc.CheckFingerprint(fp)
+	switch check.Status {
+	case maps.MapCheckMismatch:
+		fmt.Printf("⚠️  Map assets don't match the demo (%s, build %d): %s. Visibility metrics flagged as unreliable\n",
+			fp.BaseName(), fp.BuildNum, strings.Join(check.Reasons, "; "))
+	case maps.MapCheckUnknown, maps.MapCheckUnverified:
+		fmt.Printf("⚠️  Map version not verified (%s, build %d): %s\n", fp.BaseName(), fp.BuildNum, strings.Join(check.Reasons, "; "))
+	case maps.MapCheckMatch:
+		fmt.Printf("✅ Map assets match the demo (%s, crc %08x)\n", fp.BaseName(), check.DemoCRC)
+	}
+	return check
+}
@@ -19,6 +19,62 @@ type ParseDemoResult struct {
 	ReplayData *models.ReplayData
 }
 
+// Analyzer names usados en ParseOptions (y en la estimación de memoria del scheduler)
+const (
+	AnalyzerSpray     = "spray"
+	AnalyzerMechanics = "mechanics"
+	AnalyzerReaction  = "reaction"
+	AnalyzerCrosshair = "crosshair"
+)
+
+// AllAnalyzers lista todos los analyzers disponibles
+var AllAnalyzers = []string{AnalyzerSpray, AnalyzerMechanics, AnalyzerReaction, AnalyzerCrosshair}
+
+// DefaultMapsDir es el directorio de mapas (gltf, nav, places) relativo al servicio
+const DefaultMapsDir = "../data/maps"
+
+// ParseOptions controla qué analyzers se ejecutan durante el parsing
+type ParseOptions struct {
+	Analyzers []string // Vacío = todos los analyzers
+
+	// MapsDir es el directorio de mapas (vacío = DefaultMapsDir).
+	// Misma demo + mismo MapsDir = exports idénticos byte a byte.
+	MapsDir string
+
+	// FlushDir activa el volcado por ronda en RoundEndOfficial (ver round_flush.go).
+	// Debe ser PartialDir(outputDir, matchID) para que ExportAIModels lo recomponga.
+	FlushDir string
+
+	// IntermediatePath activa la caché intermedia binaria (ver intermediate.go)
+	IntermediatePath string
+
+	// HitboxPoints son los puntos del esqueleto usados por el reaction analyzer para
+	// la visibilidad (vacío = analyzers.DefaultHitboxPoints)
+	HitboxPoints []string
+
+	// ReplaySampleRateHz son los frames por segundo de replay.json
+	// (0 = handlers.DefaultReplaySampleRateHz; 4 Hz para previews, 32-64 Hz para análisis)
+	ReplaySampleRateHz int
+}
+
+// DefaultParseOptions devuelve las opciones por defecto (todos los analyzers activos)
+func DefaultParseOptions() ParseOptions {
+	return ParseOptions{Analyzers: AllAnalyzers}
+}
+
+// IsAnalyzerEnabled indica si un analyzer está activo con estas opciones
+func (o ParseOptions) IsAnalyzerEnabled(name string) bool {
+	if len(o.Analyzers) == 0 {
+		return true
+	}
+	for _, a := range o.Analyzers {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
 // ParseDemo es la función principal que procesa una demo completa
 // Devuelve el contexto completo para poder exportar timeline
 func ParseDemo(demoPath string) (*models.DemoContext, error) {
@@ -31,6 +87,11 @@ func ParseDemo(demoPath string) (*models.DemoContext, error) {
 
 // ParseDemoWithReplay parses a demo and returns full results including replay data
 func ParseDemoWithReplay(demoPath string) (*ParseDemoResult, error) {
+	return ParseDemoWithOptions(demoPath, DefaultParseOptions())
+}
+
+// ParseDemoWithOptions parses a demo running only the analyzers enabled in opts
+func ParseDemoWithOptions(demoPath string, opts ParseOptions) (*ParseDemoResult, error) {
 	// Abrir archivo demo
 	f, err := os.Open(demoPath)
 	if err != nil {
@@ -52,10 +113,12 @@ func ParseDemoWithReplay(demoPath string) (*ParseDemoResult, error) {
 	ctx := models.NewDemoContext(p)
 
 	// Initialize Map Manager
-	// Assuming maps are stored in backend/data/maps
-	// We need to construct the absolute path or relative to execution
-	// For now, hardcoded relative path
-	mapManager := maps.NewMapManager("../data/maps")
+	// Maps are stored in backend/data/maps unless opts.MapsDir says otherwise
+	mapsDir := opts.MapsDir
+	if mapsDir == "" {
+		mapsDir = DefaultMapsDir
+	}
+	mapManager := maps.NewMapManager(mapsDir)
 
 	// Attempt to load the map
 	mapName := p.Header().MapName
@@ -67,29 +130,64 @@ func ParseDemoWithReplay(demoPath string) (*ParseDemoResult, error) {
 
 	ctx.MapManager = mapManager
 
+	// Versión del mapa según la demo (se compara con los assets locales al terminar)
+	var demoMap maps.DemoMapFingerprint
+	registerMapFingerprint(p, &demoMap)
+
 	// Registrar todos los handlers
-	handlers.RegisterTimelineHandlers(ctx) // NEW: Timeline & GameState sampling
-	handlers.RegisterChatHandlers(ctx)     // NEW: Chat tracking
-	handlers.RegisterPlayerHandlers(ctx)   // Includes: Movement, Weapon State, Spotting, Zones (Phase 1)
+	handlers.RegisterTimelineHandlers(ctx)  // NEW: Timeline & GameState sampling
+	handlers.RegisterChatHandlers(ctx)      // NEW: Chat tracking
+	handlers.RegisterLifecycleHandlers(ctx) // NEW: Connect/disconnect/bot tracking (needs ActualRoundNumber)
+	handlers.RegisterPlayerHandlers(ctx)    // Includes: Movement, Weapon State, Spotting, Zones (Phase 1)
 	handlers.RegisterCombatHandlers(ctx)
 	handlers.RegisterGrenadeHandlers(ctx)
+	handlers.RegisterSmokeHandlers(ctx) // Humos volumétricos + infernos (visión)
 	handlers.RegisterRoundHandlers(ctx) // Includes: Zone reset (Phase 1)
 	handlers.RegisterEconomyHandlers(ctx)
 	handlers.RegisterBombHandlers(ctx)    // Includes: Defuse kit tracking (Phase 1)
 	handlers.RegisterTrackingHandler(ctx) // NEW: AI Tracking (2Hz sampling)
 
 	// NEW: 2D Replay data collection
-	replayHandler := handlers.RegisterReplayHandlers(ctx)
+	replayHandler := handlers.RegisterReplayHandlersWithOptions(ctx, handlers.ReplayOptions{SampleRateHz: opts.ReplaySampleRateHz})
 
 	// NEW: Advanced Player Stats (Phase 1 AI)
 	statsHandler := handlers.RegisterPlayerStatsHandler(ctx)
 
+	// Intermediate cache (re-export without re-parsing, see intermediate.go)
+	var cache *IntermediateWriter
+	if opts.IntermediatePath != "" {
+		cache, err = NewIntermediateWriter(opts.IntermediatePath)
+		if err != nil {
+			return nil, err
+		}
+		defer cache.Close()
+		ctx.KeepRawCombat = true
+	}
+
+	// Per-round flush (after replay handler, so the finished round is already in replayHandler.Rounds)
+	if opts.FlushDir != "" {
+		if err := registerRoundFlush(ctx, replayHandler, opts.FlushDir, cache); err != nil {
+			return nil, err
+		}
+	}
+
 	// Register analyzers
-	analyzers.RegisterSprayAnalyzer(ctx)
-	analyzers.RegisterMechanicsAnalyzer(ctx) // NEW: Counter-Strafe & Mechanics
-	// TODO: Reaction time analyzer deshabilitado - IsSpottedBy() no es confiable
-	analyzers.RegisterReactionAnalyzer(ctx)
-	analyzers.RegisterCrosshairAnalyzer(ctx)
+	if opts.IsAnalyzerEnabled(AnalyzerSpray) {
+		analyzers.RegisterSprayAnalyzer(ctx)
+	}
+	if opts.IsAnalyzerEnabled(AnalyzerMechanics) {
+		analyzers.RegisterMechanicsAnalyzer(ctx) // NEW: Counter-Strafe & Mechanics
+	}
+	if opts.IsAnalyzerEnabled(AnalyzerReaction) {
+		points, err := analyzers.SelectHitboxPoints(opts.HitboxPoints)
+		if err != nil {
+			return nil, err
+		}
+		analyzers.RegisterReactionAnalyzerWithPoints(ctx, points)
+	}
+	if opts.IsAnalyzerEnabled(AnalyzerCrosshair) {
+		analyzers.RegisterCrosshairAnalyzer(ctx)
+	}
 
 	// Ensure map is loaded (sometimes header map name is empty in CS2)
 	p.RegisterEventHandler(func(e events.RoundStart) {
@@ -107,8 +205,19 @@ func ParseDemoWithReplay(demoPath string) (*ParseDemoResult, error) {
 		return nil, fmt.Errorf("parsing failed: %w", err)
 	}
 
-	// Final Step: Collect aggregated player stats with combat metrics
-	ctx.AI_PlayersSummary = statsHandler.GetStatsWithContext(ctx)
+	// Mark abandonments (disconnected and never came back)
+	handlers.FinalizeLifecycles(ctx)
+
+	// Header info for the export (duration from PlaybackTime)
+	if demoMap.MapName == "" {
+		demoMap.MapName = p.Header().MapName
+	}
+	ctx.DemoInfo = models.DemoInfo{
+		TickRate:        p.TickRate(),
+		DurationSeconds: p.Header().PlaybackTime.Seconds(),
+		Map:             demoMap,
+		MapCheck:        checkMapFingerprint(mapManager, demoMap),
+	}
 
 	// Construir output final
 	matchData := BuildMatchData(ctx)
@@ -118,6 +227,16 @@ func ParseDemoWithReplay(demoPath string) (*ParseDemoResult, error) {
 	replayData := replayHandler.GetReplayData("")
 	ctx.ReplayData = &replayData
 
+	// Persist the intermediate cache BEFORE final stats (GetStats finalizes them in place)
+	if cache != nil {
+		if err := cache.Finish(ctx, statsHandler.State()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Final Step: Collect aggregated player stats with combat metrics
+	ctx.AI_PlayersSummary = statsHandler.GetStatsWithContext(ctx)
+
 	return &ParseDemoResult{
 		Context:    ctx,
 		ReplayData: &replayData,
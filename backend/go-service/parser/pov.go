@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"cs2-demo-service/analyzers"
+	"cs2-demo-service/handlers"
+	"cs2-demo-service/models"
+	"cs2-demo-service/pkg/maps"
+
+	dem "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs"
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+)
+
+// POVOptions selecciona el jugador y la ronda del export POV
+type POVOptions struct {
+	SteamID     uint64
+	Round       int     // 1-based (el mismo número de ronda que los exports)
+	MapsDir     string  // Vacío = DefaultMapsDir
+	FOV         float64 // FOV 4:3 del jugador (0 = 90)
+	AspectRatio float64 // 0 = 16:9
+}
+
+// ErrPOVNotFound indica que la ronda o el jugador no están en la demo
+var ErrPOVNotFound = errors.New("round or player not found in demo")
+
+// ExportPOV parsea la demo solo hasta el final de la ronda pedida y devuelve el
+// punto de vista del jugador a tick completo (ver analyzers.RegisterPOVRecorder)
+func ExportPOV(demoPath string, opts POVOptions) (*models.POVExport, error) {
+	if opts.SteamID == 0 || opts.Round < 1 {
+		return nil, fmt.Errorf("steam id and round are required")
+	}
+
+	f, err := os.Open(demoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open demo file: %w", err)
+	}
+	defer f.Close()
+
+	p := dem.NewParser(f)
+	defer p.Close()
+
+	if _, err := p.ParseHeader(); err != nil {
+		return nil, fmt.Errorf("failed to parse header: %w", err)
+	}
+
+	ctx := models.NewDemoContext(p)
+	mapsDir := opts.MapsDir
+	if mapsDir == "" {
+		mapsDir = DefaultMapsDir
+	}
+	mapManager := maps.NewMapManager(mapsDir)
+	if mapName := p.Header().MapName; mapName != "" {
+		_ = mapManager.LoadMap(mapName)
+	}
+	ctx.MapManager = mapManager
+
+	// Los humos bloquean la visión igual que en el reaction analyzer
+	handlers.RegisterSmokeHandlers(ctx)
+
+	// Map name vacío en el header (CS2): cargar en el primer RoundStart, antes que el recorder
+	p.RegisterEventHandler(func(e events.RoundStart) {
+		if !mapManager.IsLoaded() && p.Header().MapName != "" {
+			_ = mapManager.LoadMap(p.Header().MapName)
+		}
+	})
+
+	recorder := analyzers.RegisterPOVRecorder(ctx, analyzers.POVOptions{
+		SteamID:     opts.SteamID,
+		Round:       opts.Round,
+		FOV:         opts.FOV,
+		AspectRatio: opts.AspectRatio,
+	})
+
+	// El resto de la demo sobra cuando termina la ronda
+	p.RegisterEventHandler(func(e events.FrameDone) {
+		if recorder.Done() {
+			p.Cancel()
+		}
+	})
+
+	if err := p.ParseToEnd(); err != nil && !errors.Is(err, dem.ErrCancelled) {
+		return nil, fmt.Errorf("parsing failed: %w", err)
+	}
+
+	export := recorder.Export()
+	if len(export.Frames) == 0 {
+		return nil, fmt.Errorf("round %d, player %d: %w", opts.Round, opts.SteamID, ErrPOVNotFound)
+	}
+	export.MapName = p.Header().MapName
+	return export, nil
+}
+
+// POVPath es la ruta del export POV dentro de match_<id>/
+func POVPath(outputDir, matchID string, steamID uint64, round int) string {
+	return filepath.Join(outputDir, fmt.Sprintf("match_%s", matchID), fmt.Sprintf("pov_%d_round%d.json", steamID, round))
+}
+
+// WritePOV guarda el export POV como JSON
+func WritePOV(path string, export *models.POVExport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create match directory: %w", err)
+	}
+	return writeJSON(path, export)
+}
@@ -0,0 +1,351 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"cs2-demo-service/handlers"
+	"cs2-demo-service/models"
+
+	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+)
+
+// PartialDirName es el subdirectorio de match_<id>/ donde se vuelcan las rondas durante el parsing
+const PartialDirName = ".partial"
+
+// PartialDir devuelve el directorio de volcado por ronda para un match
+func PartialDir(outputDir, matchID string) string {
+	return filepath.Join(outputDir, fmt.Sprintf("match_%s", matchID), PartialDirName)
+}
+
+// registerRoundFlush vuelca a disco los datos de cada ronda en RoundEndOfficial
+// (tracking, combat, replay, granadas y movement logs) y los libera del DemoContext, de forma
+// que la memoria del parsing no crece con la duración de la demo.
+// ExportAIModels recompone después los JSON finales leyendo estos volcados.
+// Si cache no es nil, los datos volcados también se escriben en la caché intermedia.
+func registerRoundFlush(ctx *models.DemoContext, replayHandler *handlers.ReplayHandler, dir string, cache *IntermediateWriter) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clean flush directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create flush directory: %w", err)
+	}
+	ctx.FlushDir = dir
+
+	ctx.Parser.RegisterEventHandler(func(e events.RoundEndOfficial) {
+		if err := flushRound(ctx, replayHandler, cache, ctx.ActualRoundNumber); err != nil {
+			fmt.Printf("⚠️  Round flush failed (round %d): %v\n", ctx.ActualRoundNumber, err)
+		}
+	})
+
+	return nil
+}
+
+// flushRound escribe los datos de la ronda indicada (y anteriores pendientes) y los libera
+func flushRound(ctx *models.DemoContext, replayHandler *handlers.ReplayHandler, cache *IntermediateWriter, round int) error {
+	if round <= 0 {
+		return nil // Warmup
+	}
+
+	// 1. Tracking
+	var trackingEvents, keptTracking []models.AI_TrackingEventWithRound
+	for _, e := range ctx.AI_TrackingEventsWithRound {
+		if e.Round <= round {
+			trackingEvents = append(trackingEvents, e)
+		} else {
+			keptTracking = append(keptTracking, e)
+		}
+	}
+	if err := cache.WriteTracking(trackingEvents); err != nil {
+		return err
+	}
+	for _, tr := range buildTrackingRounds(trackingEvents) {
+		if err := writeChunk(ctx.FlushDir, chunkName("tracking", tr.Round), tr); err != nil {
+			return err
+		}
+	}
+	ctx.AI_TrackingEventsWithRound = keptTracking
+
+	// 2. Combat (duels ya consolidados en RoundEnd)
+	duelsByRound := make(map[int][]models.AI_Duel)
+	var keptDuels []models.AI_Duel
+	for _, d := range ctx.AI_Duels {
+		if d.Round <= round {
+			duelsByRound[d.Round] = append(duelsByRound[d.Round], d)
+		} else {
+			keptDuels = append(keptDuels, d)
+		}
+	}
+	for r, duels := range duelsByRound {
+		sort.SliceStable(duels, func(i, j int) bool {
+			return duels[i].TickStart < duels[j].TickStart
+		})
+		if err := writeChunk(ctx.FlushDir, chunkName("combat", r), models.AI_DuelRound{Round: r, Duels: duels}); err != nil {
+			return err
+		}
+	}
+	ctx.AI_Duels = keptDuels
+
+	// 3. Replay
+	replayRounds := replayHandler.TakeRounds()
+	if err := cache.WriteReplay(replayRounds); err != nil {
+		return err
+	}
+	for _, rr := range replayRounds {
+		if err := writeChunk(ctx.FlushDir, chunkName("replay", rr.Round), rr); err != nil {
+			return err
+		}
+	}
+
+	// 4. Grenades: las infernos activas siguen referenciadas por índice (ActiveInfernos),
+	// así que se quedan en memoria hasta un flush posterior o el export final
+	if err := flushGrenades(ctx, cache, round); err != nil {
+		return err
+	}
+
+	// 5. Movement logs (MatchData.Players[*].Movement se queda solo con las rondas posteriores)
+	movement := movementRounds(ctx, round, true)
+	if err := cache.WriteMovement(movement); err != nil {
+		return err
+	}
+	for _, mr := range movement {
+		if err := writeChunk(ctx.FlushDir, chunkName("movement", mr.Round), mr); err != nil {
+			return err
+		}
+	}
+
+	ctx.FlushedRounds = append(ctx.FlushedRounds, round)
+	return nil
+}
+
+// flushGrenades vuelca las granadas terminadas y reindexa ActiveInfernos / PendingHEs
+func flushGrenades(ctx *models.DemoContext, cache *IntermediateWriter, round int) error {
+	referenced := make(map[int]bool, len(ctx.ActiveInfernos))
+	for _, idx := range ctx.ActiveInfernos {
+		referenced[idx] = true
+	}
+
+	var flushed []grenadeChunk
+	var kept []models.AI_GrenadeEvent
+	remap := make(map[int]int)
+	for i, g := range ctx.AI_GrenadeEvents {
+		if g.Round <= round && !referenced[i] {
+			flushed = append(flushed, grenadeChunk{Round: g.Round, Event: g})
+			continue
+		}
+		remap[i] = len(kept)
+		kept = append(kept, g)
+	}
+	if len(flushed) == 0 {
+		return nil
+	}
+
+	if err := cache.WriteGrenades(flushed); err != nil {
+		return err
+	}
+	if err := writeChunk(ctx.FlushDir, fmt.Sprintf("grenades_%04d.json", ctx.GrenadeFlushCount), flushed); err != nil {
+		return err
+	}
+	ctx.GrenadeFlushCount++
+
+	for id, idx := range ctx.ActiveInfernos {
+		ctx.ActiveInfernos[id] = remap[idx]
+	}
+	for steamID, idx := range ctx.PendingHEs {
+		if newIdx, ok := remap[idx]; ok {
+			ctx.PendingHEs[steamID] = newIdx
+		} else {
+			delete(ctx.PendingHEs, steamID)
+		}
+	}
+	ctx.AI_GrenadeEvents = kept
+	return nil
+}
+
+// movementRounds agrupa por ronda los movement logs de las rondas <= upTo (jugadores
+// en orden de SteamID). Con release, esos logs se quitan de MatchData.Players.
+func movementRounds(ctx *models.DemoContext, upTo int, release bool) []models.MovementRound {
+	byRound := make(map[int][]models.PlayerMovement)
+	for _, steamID := range ctx.MatchData.SortedPlayerIDs() {
+		pd := ctx.MatchData.Players[steamID]
+		if pd == nil || len(pd.Movement) == 0 {
+			continue
+		}
+		perRound := make(map[int][]models.MovementLog)
+		kept := make([]models.MovementLog, 0)
+		for _, m := range pd.Movement {
+			if m.Round <= upTo {
+				perRound[m.Round] = append(perRound[m.Round], m)
+			} else {
+				kept = append(kept, m)
+			}
+		}
+		for r, logs := range perRound {
+			byRound[r] = append(byRound[r], models.PlayerMovement{SteamID: steamID, Name: pd.Name, Movement: logs})
+		}
+		if release {
+			pd.Movement = kept // Nuevo slice: el array con las rondas volcadas se libera
+		}
+	}
+
+	rounds := make([]models.MovementRound, 0, len(byRound))
+	for _, r := range models.SortedKeys(byRound) {
+		rounds = append(rounds, models.MovementRound{Round: r, Players: byRound[r]})
+	}
+	return rounds
+}
+
+// grenadeChunk guarda la ronda junto al evento (AI_GrenadeEvent.Round no se serializa)
+type grenadeChunk struct {
+	Round int                    `json:"round"`
+	Event models.AI_GrenadeEvent `json:"event"`
+}
+
+// readFlushedGrenades lee todas las granadas volcadas (son pocas, caben en memoria)
+func readFlushedGrenades(dir string, count int) ([]models.AI_GrenadeEvent, error) {
+	var result []models.AI_GrenadeEvent
+	for i := 0; i < count; i++ {
+		raw, err := readChunk(dir, fmt.Sprintf("grenades_%04d.json", i))
+		if err != nil {
+			return nil, err
+		}
+		var chunk []grenadeChunk
+		if err := json.Unmarshal(raw, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode grenade chunk: %w", err)
+		}
+		for _, c := range chunk {
+			c.Event.Round = c.Round
+			result = append(result, c.Event)
+		}
+	}
+	return result, nil
+}
+
+func chunkName(kind string, round int) string {
+	return fmt.Sprintf("%s_r%04d.json", kind, round)
+}
+
+// writeChunk escribe un volcado en JSON compacto (se indenta al recomponer el export)
+func writeChunk(dir, name string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chunk %s: %w", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+		return fmt.Errorf("failed to write chunk %s: %w", name, err)
+	}
+	return nil
+}
+
+// readChunk lee un volcado en crudo
+func readChunk(dir, name string) ([]byte, error) {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk %s: %w", name, err)
+	}
+	return b, nil
+}
+
+// chunkRounds devuelve los números de ronda volcados para un tipo, ordenados
+func chunkRounds(dir, kind string) ([]int, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, kind+"_r*.json"))
+	if err != nil {
+		return nil, err
+	}
+	rounds := make([]int, 0, len(matches))
+	for _, m := range matches {
+		var r int
+		if _, err := fmt.Sscanf(filepath.Base(m), kind+"_r%04d.json", &r); err == nil {
+			rounds = append(rounds, r)
+		}
+	}
+	sort.Ints(rounds)
+	return rounds, nil
+}
+
+// roundsWriter escribe un objeto JSON con campos de cabecera y un array "rounds"
+// elemento a elemento, con el mismo formato que json.MarshalIndent(v, "", "  ").
+type roundsWriter struct {
+	f           *os.File
+	count       int
+	emptyAsNull bool // nil slice -> null, slice vacío -> []
+	err         error
+}
+
+func newRoundsWriter(path string, emptyAsNull bool) (*roundsWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	w := &roundsWriter{f: f, emptyAsNull: emptyAsNull}
+	w.write([]byte("{\n"))
+	return w, nil
+}
+
+func (w *roundsWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.f.Write(b)
+}
+
+// Field escribe un campo de cabecera (debe llamarse antes del primer Round)
+func (w *roundsWriter) Field(key string, value interface{}) {
+	b, err := json.MarshalIndent(value, "  ", "  ")
+	if err != nil {
+		w.err = fmt.Errorf("failed to marshal JSON: %w", err)
+		return
+	}
+	w.write([]byte(fmt.Sprintf("  %q: ", key)))
+	w.write(b)
+	w.write([]byte(",\n"))
+}
+
+// Round añade un elemento ya serializado (compacto o indentado) al array "rounds"
+func (w *roundsWriter) RoundRaw(raw []byte) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "    ", "  "); err != nil {
+		w.err = fmt.Errorf("failed to indent JSON: %w", err)
+		return
+	}
+	if w.count == 0 {
+		w.write([]byte("  \"rounds\": [\n    "))
+	} else {
+		w.write([]byte(",\n    "))
+	}
+	w.write(buf.Bytes())
+	w.count++
+}
+
+// Round serializa y añade un elemento al array "rounds"
+func (w *roundsWriter) Round(v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		w.err = fmt.Errorf("failed to marshal JSON: %w", err)
+		return
+	}
+	w.RoundRaw(b)
+}
+
+// Close cierra el array y el objeto
+func (w *roundsWriter) Close() error {
+	switch {
+	case w.count > 0:
+		w.write([]byte("\n  ]\n}"))
+	case w.emptyAsNull:
+		w.write([]byte("  \"rounds\": null\n}"))
+	default:
+		w.write([]byte("  \"rounds\": []\n}"))
+	}
+	if err := w.f.Close(); err != nil && w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
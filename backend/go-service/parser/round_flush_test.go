@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cs2-demo-service/handlers"
+	"cs2-demo-service/models"
+)
+
+// TestRoundFlushMovement simula una demo larga: los movement logs en memoria no
+// crecen con las rondas y movement.json las recompone todas en orden
+func TestRoundFlushMovement(t *testing.T) {
+	const rounds, ticksPerRound = 30, 200
+
+	ctx := models.NewDemoContext(nil)
+	ctx.FlushDir = t.TempDir()
+	replay := handlers.NewReplayHandler(ctx, handlers.DefaultReplayOptions())
+	players := []uint64{76561198000000002, 76561198000000001}
+	for _, id := range players {
+		ctx.MatchData.Players[id] = &models.PlayerData{SteamID: id, Name: "p", Movement: []models.MovementLog{}}
+	}
+
+	for round := 1; round <= rounds; round++ {
+		for tick := 0; tick < ticksPerRound; tick++ {
+			for _, id := range players {
+				pd := ctx.MatchData.Players[id]
+				pd.Movement = append(pd.Movement, models.MovementLog{Round: round, Tick: round*1000 + tick})
+			}
+		}
+		if err := flushRound(ctx, replay, nil, round); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range players {
+			if held := len(ctx.MatchData.Players[id].Movement); held != 0 {
+				t.Fatalf("round %d: player %d still holds %d movement logs after the flush", round, id, held)
+			}
+		}
+	}
+
+	// Ronda sin volcar (p. ej. la demo termina antes de RoundEndOfficial): sale de memoria
+	last := ctx.MatchData.Players[players[0]]
+	last.Movement = append(last.Movement, models.MovementLog{Round: rounds + 1, Tick: 99999})
+
+	path := filepath.Join(t.TempDir(), "movement.json")
+	if err := writeMovementJSON(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export struct {
+		Rounds []models.MovementRound `json:"rounds"`
+	}
+	if err := json.Unmarshal(raw, &export); err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Rounds) != rounds+1 {
+		t.Fatalf("movement.json has %d rounds, want %d", len(export.Rounds), rounds+1)
+	}
+	for i, mr := range export.Rounds[:rounds] {
+		if mr.Round != i+1 || len(mr.Players) != len(players) {
+			t.Fatalf("round %d: got round %d with %d players", i+1, mr.Round, len(mr.Players))
+		}
+		if mr.Players[0].SteamID > mr.Players[1].SteamID {
+			t.Errorf("round %d: players not sorted by steam id", mr.Round)
+		}
+		if n := len(mr.Players[0].Movement); n != ticksPerRound {
+			t.Errorf("round %d: %d logs, want %d", mr.Round, n, ticksPerRound)
+		}
+	}
+	if len(last.Movement) != 1 {
+		t.Errorf("export should keep the in-memory logs in MatchData, got %d", len(last.Movement))
+	}
+}
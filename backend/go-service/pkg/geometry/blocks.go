@@ -0,0 +1,127 @@
+package geometry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// PHYSICS GROUPS
+// El physics mesh de CS2 separa la colisión en grupos (playerclip, grenadeclip,
+// passbullets, window...) y cada uno bloquea cosas distintas. Cada material del
+// mesh guarda qué bloquea (BlockMask), así el mismo mesh responde a consultas de
+// balas, jugadores y granadas. Los grupos se eligen por nombre (mesh, material u
+// objeto OBJ) con el manifest del mapa (ver loader.go).
+// ============================================================================
+
+// BlockMask indica qué tipos de consulta bloquea una superficie
+type BlockMask uint8
+
+const (
+	BlocksBullets  BlockMask = 1 << iota // Balas (wallbangs)
+	BlocksPlayers                        // Movimiento de jugadores
+	BlocksGrenades                       // Trayectorias de granadas
+	BlocksAll      = BlocksBullets | BlocksPlayers | BlocksGrenades
+)
+
+var blockNames = []struct {
+	name string
+	mask BlockMask
+}{
+	{"bullets", BlocksBullets},
+	{"players", BlocksPlayers},
+	{"grenades", BlocksGrenades},
+}
+
+// ParseBlockMask convierte ["bullets", "players", "grenades"] (o "all") en una máscara
+func ParseBlockMask(names []string) (BlockMask, error) {
+	var mask BlockMask
+	for _, n := range names {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if n == "all" {
+			mask |= BlocksAll
+			continue
+		}
+		found := false
+		for _, b := range blockNames {
+			if b.name == n {
+				mask |= b.mask
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown block type %q (bullets, players, grenades, all)", n)
+		}
+	}
+	return mask, nil
+}
+
+// String devuelve los tipos de la máscara separados por "+" ("none" si no bloquea nada)
+func (m BlockMask) String() string {
+	var parts []string
+	for _, b := range blockNames {
+		if m&b.mask != 0 {
+			parts = append(parts, b.name)
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, "+")
+}
+
+// PhysicsGroup asigna una máscara a los meshes/materiales cuyo nombre contiene Match
+type PhysicsGroup struct {
+	Match  string   `json:"match"`  // Subcadena del nombre (sin distinguir mayúsculas)
+	Blocks []string `json:"blocks"` // bullets, players, grenades, all
+}
+
+// DefaultPhysicsGroups son los grupos que se cargaban antes de existir el manifest.
+// Gana el primero que coincide, así que los nombres más específicos van antes
+// ("grenadeclip" también contiene "clip").
+var DefaultPhysicsGroups = []PhysicsGroup{
+	{Match: "grenadeclip", Blocks: []string{"grenades"}},
+	{Match: "passbullets", Blocks: []string{"bullets"}},
+	{Match: "window", Blocks: []string{"bullets", "players", "grenades"}},
+	{Match: "clip", Blocks: []string{"players", "bullets"}}, // playerclip
+}
+
+// groupRule es un PhysicsGroup ya validado
+type groupRule struct {
+	match string
+	mask  BlockMask
+}
+
+// compileGroups valida los grupos y pasa los nombres a minúsculas
+func compileGroups(groups []PhysicsGroup) ([]groupRule, error) {
+	rules := make([]groupRule, 0, len(groups))
+	for _, g := range groups {
+		if g.Match == "" {
+			return nil, fmt.Errorf("physics group without match")
+		}
+		mask, err := ParseBlockMask(g.Blocks)
+		if err != nil {
+			return nil, fmt.Errorf("physics group %q: %w", g.Match, err)
+		}
+		rules = append(rules, groupRule{match: strings.ToLower(g.Match), mask: mask})
+	}
+	return rules, nil
+}
+
+// matchGroup devuelve la máscara del primer grupo que aparece en alguno de los nombres
+// (se prueban en orden: mesh y luego material); ok=false si ninguno coincide
+func matchGroup(rules []groupRule, names ...string) (BlockMask, bool) {
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if name == "" {
+			continue
+		}
+		for _, r := range rules {
+			if strings.Contains(name, r.match) {
+				return r.mask, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,469 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+
+	"github.com/golang/geo/r3"
+)
+
+// ============================================================================
+// FLAT BVH (SAH)
+// Surface Area Heuristic build + nodes stored in a contiguous array
+// (depth-first order: the left child is always node+1), so traversal is a
+// loop over an index stack instead of chasing pointers.
+// ============================================================================
+
+const (
+	sahBins        = 16  // Buckets per axis when evaluating SAH splits
+	sahMinLeafSize = 2   // Always a leaf at or below this size
+	sahMaxLeafSize = 8   // Never a leaf above this size (no more huge fallback leaves)
+	sahTraversal   = 1.0 // Relative cost of visiting a node vs. testing a triangle
+	bvhStackSize   = 64  // Initial traversal stack (grows if a tree is deeper)
+)
+
+// FlatBVHNode is a node of the flattened BVH
+type FlatBVHNode struct {
+	Bounds AABB
+	// Leaf: index of the first triangle in FlatBVH.Triangles.
+	// Internal: index of the right child (left child is the next node).
+	Offset int32
+	Count  uint16 // Triangles in the leaf (0 = internal node)
+	Axis   uint8  // Split axis of internal nodes (0:X, 1:Y, 2:Z), used for ordered traversal
+}
+
+// IsLeaf returns true if the node holds triangles
+func (n *FlatBVHNode) IsLeaf() bool {
+	return n.Count > 0
+}
+
+// FlatBVH is an SAH-built BVH stored as a contiguous node array.
+// Triangles is the input slice reordered so every leaf references a contiguous range.
+type FlatBVH struct {
+	Nodes     []FlatBVHNode
+	Triangles []Triangle
+}
+
+// buildPrim holds the per-triangle data used only during the build
+type buildPrim struct {
+	bounds   AABB
+	centroid r3.Vector
+	index    int
+}
+
+// BuildFlatBVH builds an SAH BVH over triangles.
+// NOTE: triangles is reordered in place (leaf order) and referenced by the result.
+func BuildFlatBVH(triangles []Triangle) *FlatBVH {
+	bvh := &FlatBVH{Triangles: triangles}
+	if len(triangles) == 0 {
+		return bvh
+	}
+
+	prims := make([]buildPrim, len(triangles))
+	for i, tri := range triangles {
+		b := triangleBounds(tri)
+		prims[i] = buildPrim{
+			bounds:   b,
+			centroid: b.Min.Add(b.Max).Mul(0.5),
+			index:    i,
+		}
+	}
+
+	bvh.Nodes = make([]FlatBVHNode, 0, 2*len(triangles)/sahMaxLeafSize+1)
+	bvh.build(prims, 0)
+
+	// Reorder triangles to match leaf ranges
+	ordered := make([]Triangle, len(triangles))
+	for i, p := range prims {
+		ordered[i] = triangles[p.index]
+	}
+	copy(triangles, ordered)
+
+	return bvh
+}
+
+// build creates the subtree for prims (which start at triangle offset first) and returns its node index
+func (b *FlatBVH) build(prims []buildPrim, first int) int32 {
+	nodeIdx := int32(len(b.Nodes))
+	b.Nodes = append(b.Nodes, FlatBVHNode{})
+
+	bounds := emptyAABB()
+	centroidBounds := emptyAABB()
+	for i := range prims {
+		bounds = bounds.Union(prims[i].bounds)
+		centroidBounds = centroidBounds.Extend(prims[i].centroid)
+	}
+	b.Nodes[nodeIdx].Bounds = bounds
+
+	makeLeaf := func() int32 {
+		b.Nodes[nodeIdx].Offset = int32(first)
+		b.Nodes[nodeIdx].Count = uint16(len(prims))
+		return nodeIdx
+	}
+
+	if len(prims) <= sahMinLeafSize {
+		return makeLeaf()
+	}
+
+	axis, mid, leaf := b.findSplit(prims, bounds, centroidBounds)
+	if leaf {
+		return makeLeaf()
+	}
+	if mid <= 0 || mid >= len(prims) {
+		// No usable SAH split (all centroids in one bin): split by count along the
+		// longest axis instead of creating a huge leaf
+		axis = centroidBounds.LongestAxis()
+		sortPrimsByAxis(prims, axis)
+		mid = len(prims) / 2
+	}
+
+	b.Nodes[nodeIdx].Axis = uint8(axis)
+	b.build(prims[:mid], first)
+	right := b.build(prims[mid:], first+mid)
+	b.Nodes[nodeIdx].Offset = right
+	return nodeIdx
+}
+
+// findSplit evaluates binned SAH on the three axes and partitions prims in place.
+// Returns the chosen axis and the partition index (0 = no split found), or leaf=true
+// when a small node is cheaper to keep as a leaf.
+func (b *FlatBVH) findSplit(prims []buildPrim, bounds, centroidBounds AABB) (axis, mid int, leaf bool) {
+	type bin struct {
+		bounds AABB
+		count  int
+	}
+
+	bestAxis, bestBin := -1, -1
+	bestCost := math.Inf(1)
+
+	for axis := 0; axis < 3; axis++ {
+		cMin := axisValue(centroidBounds.Min, axis)
+		cMax := axisValue(centroidBounds.Max, axis)
+		if cMax-cMin <= 0 {
+			continue
+		}
+		scale := float64(sahBins) / (cMax - cMin)
+
+		var bins [sahBins]bin
+		for i := range bins {
+			bins[i].bounds = emptyAABB()
+		}
+		for i := range prims {
+			idx := binIndex(axisValue(prims[i].centroid, axis), cMin, scale)
+			bins[idx].count++
+			bins[idx].bounds = bins[idx].bounds.Union(prims[i].bounds)
+		}
+
+		// Sweep from the right to get the area/count of every right partition
+		var rightArea [sahBins]float64
+		var rightCount [sahBins]int
+		acc := emptyAABB()
+		count := 0
+		for i := sahBins - 1; i > 0; i-- {
+			acc = acc.Union(bins[i].bounds)
+			count += bins[i].count
+			rightArea[i] = acc.SurfaceArea()
+			rightCount[i] = count
+		}
+
+		acc = emptyAABB()
+		count = 0
+		for i := 0; i < sahBins-1; i++ {
+			acc = acc.Union(bins[i].bounds)
+			count += bins[i].count
+			if count == 0 || rightCount[i+1] == 0 {
+				continue
+			}
+			cost := acc.SurfaceArea()*float64(count) + rightArea[i+1]*float64(rightCount[i+1])
+			if cost < bestCost {
+				bestCost = cost
+				bestAxis = axis
+				bestBin = i
+			}
+		}
+	}
+
+	if bestAxis < 0 {
+		return 0, 0, len(prims) <= sahMaxLeafSize
+	}
+
+	// Leaf cost vs. split cost (both relative to the parent area)
+	if area := bounds.SurfaceArea(); area > 0 && len(prims) <= sahMaxLeafSize {
+		if sahTraversal+bestCost/area >= float64(len(prims)) {
+			return 0, 0, true
+		}
+	}
+
+	// Partition in place: bins [0..bestBin] to the left
+	cMin := axisValue(centroidBounds.Min, bestAxis)
+	scale := float64(sahBins) / (axisValue(centroidBounds.Max, bestAxis) - cMin)
+	for i := range prims {
+		if binIndex(axisValue(prims[i].centroid, bestAxis), cMin, scale) <= bestBin {
+			prims[i], prims[mid] = prims[mid], prims[i]
+			mid++
+		}
+	}
+	return bestAxis, mid, false
+}
+
+func binIndex(v, min, scale float64) int {
+	idx := int((v - min) * scale)
+	if idx < 0 {
+		return 0
+	}
+	if idx >= sahBins {
+		return sahBins - 1
+	}
+	return idx
+}
+
+// sortPrimsByAxis orders prims by centroid along axis (ties by original index, deterministic)
+func sortPrimsByAxis(prims []buildPrim, axis int) {
+	sort.Slice(prims, func(i, j int) bool {
+		ci, cj := axisValue(prims[i].centroid, axis), axisValue(prims[j].centroid, axis)
+		if ci != cj {
+			return ci < cj
+		}
+		return prims[i].index < prims[j].index
+	})
+}
+
+// rayFilter skips triangles whose material doesn't block the query (mask 0 = no filter)
+type rayFilter struct {
+	blocks []BlockMask // Mesh.Blocks (per material)
+	mask   BlockMask
+}
+
+func (f rayFilter) skip(tri *Triangle) bool {
+	if f.mask == 0 || int(tri.Material) >= len(f.blocks) {
+		return false
+	}
+	return f.blocks[tri.Material]&f.mask == 0
+}
+
+// RayIntersects returns true if the ray hits any triangle before maxDist (any-hit, early out)
+func (b *FlatBVH) RayIntersects(origin, dir r3.Vector, maxDist float64) bool {
+	return b.rayIntersects(origin, dir, maxDist, rayFilter{})
+}
+
+func (b *FlatBVH) rayIntersects(origin, dir r3.Vector, maxDist float64, filter rayFilter) bool {
+	return b.anyHit(origin, dir, maxDist, filter) != nil
+}
+
+// anyHit returns the first triangle found before maxDist (not necessarily the closest)
+func (b *FlatBVH) anyHit(origin, dir r3.Vector, maxDist float64, filter rayFilter) *Triangle {
+	if len(b.Nodes) == 0 {
+		return nil
+	}
+	ray := newRay(origin, dir)
+
+	var buf [bvhStackSize]int32
+	stack := append(buf[:0], 0)
+
+	for len(stack) > 0 {
+		nodeIdx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		node := &b.Nodes[nodeIdx]
+		if hit := ray.intersectAABB(node.Bounds, maxDist); !hit {
+			continue
+		}
+
+		if node.IsLeaf() {
+			tris := b.Triangles[node.Offset : int(node.Offset)+int(node.Count)]
+			for i := range tris {
+				if !filter.skip(&tris[i]) && RayIntersectsTriangle(origin, dir, tris[i], maxDist) {
+					return &tris[i]
+				}
+			}
+			continue
+		}
+
+		// Push far child first so the near one is visited next
+		near, far := ray.orderChildren(nodeIdx+1, node)
+		stack = append(stack, far, near)
+	}
+	return nil
+}
+
+// RayCast returns the distance to the closest hit and its surface normal, or -1 if none
+func (b *FlatBVH) RayCast(origin, dir r3.Vector, maxDist float64) (float64, r3.Vector) {
+	return b.rayCast(origin, dir, maxDist, rayFilter{})
+}
+
+func (b *FlatBVH) rayCast(origin, dir r3.Vector, maxDist float64, filter rayFilter) (float64, r3.Vector) {
+	best := -1.0
+	var bestNormal r3.Vector
+	if len(b.Nodes) == 0 {
+		return best, bestNormal
+	}
+	ray := newRay(origin, dir)
+	limit := maxDist // Shrinks with every closer hit (prunes farther nodes)
+
+	var buf [bvhStackSize]int32
+	stack := append(buf[:0], 0)
+
+	for len(stack) > 0 {
+		nodeIdx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		node := &b.Nodes[nodeIdx]
+		if hit := ray.intersectAABB(node.Bounds, limit); !hit {
+			continue
+		}
+
+		if node.IsLeaf() {
+			tris := b.Triangles[node.Offset : int(node.Offset)+int(node.Count)]
+			for i := range tris {
+				if filter.skip(&tris[i]) {
+					continue
+				}
+				if t := RayCastTriangle(origin, dir, tris[i], limit); t > 0 {
+					limit = t
+					best = t
+					bestNormal = tris[i].Normal
+				}
+			}
+			continue
+		}
+
+		near, far := ray.orderChildren(nodeIdx+1, node)
+		stack = append(stack, far, near)
+	}
+	return best, bestNormal
+}
+
+// bvhRay caches the inverse direction used by every slab test
+type bvhRay struct {
+	origin r3.Vector
+	invDir r3.Vector
+	negDir [3]bool
+}
+
+func newRay(origin, dir r3.Vector) bvhRay {
+	return bvhRay{
+		origin: origin,
+		invDir: r3.Vector{X: 1.0 / dir.X, Y: 1.0 / dir.Y, Z: 1.0 / dir.Z},
+		negDir: [3]bool{dir.X < 0, dir.Y < 0, dir.Z < 0},
+	}
+}
+
+// orderChildren returns (near, far) for an internal node based on the ray direction along its split axis
+func (r *bvhRay) orderChildren(left int32, node *FlatBVHNode) (int32, int32) {
+	if r.negDir[node.Axis] {
+		return node.Offset, left
+	}
+	return left, node.Offset
+}
+
+// intersectAABB is the slab test with a precomputed inverse direction.
+// Inclusive (tMax >= tMin): leaves holding a single axis-aligned face have flat boxes.
+func (r *bvhRay) intersectAABB(box AABB, maxDist float64) bool {
+	tMin := 0.0
+	tMax := maxDist
+
+	t0 := (box.Min.X - r.origin.X) * r.invDir.X
+	t1 := (box.Max.X - r.origin.X) * r.invDir.X
+	if r.negDir[0] {
+		t0, t1 = t1, t0
+	}
+	if t0 > tMin {
+		tMin = t0
+	}
+	if t1 < tMax {
+		tMax = t1
+	}
+	if tMax < tMin {
+		return false
+	}
+
+	t0 = (box.Min.Y - r.origin.Y) * r.invDir.Y
+	t1 = (box.Max.Y - r.origin.Y) * r.invDir.Y
+	if r.negDir[1] {
+		t0, t1 = t1, t0
+	}
+	if t0 > tMin {
+		tMin = t0
+	}
+	if t1 < tMax {
+		tMax = t1
+	}
+	if tMax < tMin {
+		return false
+	}
+
+	t0 = (box.Min.Z - r.origin.Z) * r.invDir.Z
+	t1 = (box.Max.Z - r.origin.Z) * r.invDir.Z
+	if r.negDir[2] {
+		t0, t1 = t1, t0
+	}
+	if t0 > tMin {
+		tMin = t0
+	}
+	if t1 < tMax {
+		tMax = t1
+	}
+	if tMax < tMin {
+		return false
+	}
+
+	return true
+}
+
+// --- AABB helpers ---
+
+func emptyAABB() AABB {
+	inf := math.Inf(1)
+	return AABB{
+		Min: r3.Vector{X: inf, Y: inf, Z: inf},
+		Max: r3.Vector{X: -inf, Y: -inf, Z: -inf},
+	}
+}
+
+// Union returns the AABB enclosing both boxes
+func (box AABB) Union(o AABB) AABB {
+	return AABB{
+		Min: r3.Vector{X: math.Min(box.Min.X, o.Min.X), Y: math.Min(box.Min.Y, o.Min.Y), Z: math.Min(box.Min.Z, o.Min.Z)},
+		Max: r3.Vector{X: math.Max(box.Max.X, o.Max.X), Y: math.Max(box.Max.Y, o.Max.Y), Z: math.Max(box.Max.Z, o.Max.Z)},
+	}
+}
+
+// Extend returns the AABB grown to contain p
+func (box AABB) Extend(p r3.Vector) AABB {
+	return box.Union(AABB{Min: p, Max: p})
+}
+
+// SurfaceArea returns the surface area of the box (0 for empty boxes)
+func (box AABB) SurfaceArea() float64 {
+	d := box.Max.Sub(box.Min)
+	if d.X < 0 || d.Y < 0 || d.Z < 0 {
+		return 0
+	}
+	return 2 * (d.X*d.Y + d.Y*d.Z + d.Z*d.X)
+}
+
+// LongestAxis returns 0, 1 or 2 for X, Y, Z
+func (box AABB) LongestAxis() int {
+	d := box.Max.Sub(box.Min)
+	if d.Y > d.X && d.Y >= d.Z {
+		return 1
+	}
+	if d.Z > d.X && d.Z > d.Y {
+		return 2
+	}
+	return 0
+}
+
+func triangleBounds(tri Triangle) AABB {
+	return AABB{Min: tri.V0, Max: tri.V0}.Extend(tri.V1).Extend(tri.V2)
+}
+
+func axisValue(v r3.Vector, axis int) float64 {
+	switch axis {
+	case 1:
+		return v.Y
+	case 2:
+		return v.Z
+	}
+	return v.X
+}
@@ -0,0 +1,137 @@
+package geometry
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/golang/geo/r3"
+)
+
+// Raycasts: BVH legacy (midpoint, punteros) vs. SAH aplanado sobre una malla sintética
+// de cajas repartidas en un área tipo mapa.
+//
+//	go test ./pkg/geometry -run '^$' -bench Ray -benchmem
+
+// rayCase es un segmento entre dos puntos a altura de jugador (como el reaction analyzer)
+type rayCase struct {
+	origin, dir r3.Vector
+	dist        float64
+}
+
+// benchScene construye la malla (~12 triángulos por caja), ambos BVH y los rayos.
+// BuildFlatBVH reordena en sitio, así que cada BVH tiene su copia de los triángulos.
+func benchScene(boxes, rays int) (tris []Triangle, legacy *BVHNode, flat *FlatBVH, cases []rayCase) {
+	rng := rand.New(rand.NewSource(1))
+	tris = syntheticBoxes(rng, boxes)
+	legacy = BuildBVH(append([]Triangle(nil), tris...), 0)
+	flat = BuildFlatBVH(append([]Triangle(nil), tris...))
+
+	bounds := CalculateBounds(tris)
+	cases = make([]rayCase, rays)
+	for i := range cases {
+		a, b := randomPoint(rng, bounds), randomPoint(rng, bounds)
+		d := b.Sub(a)
+		cases[i] = rayCase{origin: a, dir: d.Normalize(), dist: d.Norm()}
+	}
+	return tris, legacy, flat, cases
+}
+
+// TestFlatBVHMatchesBruteForce compara el BVH SAH con probar todos los triángulos.
+// El legacy no se comprueba: falla rayos (por eso se sustituyó), solo es referencia de rendimiento.
+func TestFlatBVHMatchesBruteForce(t *testing.T) {
+	tris, _, flat, cases := benchScene(300, 500)
+	for i, r := range cases {
+		want := -1.0
+		for _, tri := range tris {
+			if d := RayCastTriangle(r.origin, r.dir, tri, r.dist); d > 0 && (want < 0 || d < want) {
+				want = d
+			}
+		}
+		if got, _ := flat.RayCast(r.origin, r.dir, r.dist); !sameHit(got, want) {
+			t.Errorf("ray %d: SAH RayCast = %v, brute force %v", i, got, want)
+		}
+		if got := flat.RayIntersects(r.origin, r.dir, r.dist); got != (want > 0) {
+			t.Errorf("ray %d: SAH RayIntersects = %v, brute force hit %v", i, got, want > 0)
+		}
+	}
+}
+
+func BenchmarkRayIntersects(b *testing.B) {
+	_, legacy, flat, cases := benchScene(2000, 20000)
+	b.Run("legacy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r := &cases[i%len(cases)]
+			legacy.RayIntersects(r.origin, r.dir, r.dist)
+		}
+	})
+	b.Run("sah", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r := &cases[i%len(cases)]
+			flat.RayIntersects(r.origin, r.dir, r.dist)
+		}
+	})
+}
+
+func BenchmarkRayCast(b *testing.B) {
+	_, legacy, flat, cases := benchScene(2000, 20000)
+	b.Run("legacy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r := &cases[i%len(cases)]
+			legacy.RayCast(r.origin, r.dir, r.dist)
+		}
+	})
+	b.Run("sah", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r := &cases[i%len(cases)]
+			flat.RayCast(r.origin, r.dir, r.dist)
+		}
+	})
+}
+
+// randomPoint devuelve un punto dentro de los bounds, en la franja inferior (altura de jugador)
+func randomPoint(rng *rand.Rand, b AABB) r3.Vector {
+	return r3.Vector{
+		X: b.Min.X + rng.Float64()*(b.Max.X-b.Min.X),
+		Y: b.Min.Y + rng.Float64()*(b.Max.Y-b.Min.Y),
+		Z: b.Min.Z + rng.Float64()*(b.Max.Z-b.Min.Z)*0.3,
+	}
+}
+
+// syntheticBoxes genera n cajas (12 triángulos cada una) repartidas en un área tipo mapa
+func syntheticBoxes(rng *rand.Rand, n int) []Triangle {
+	var tris []Triangle
+	for i := 0; i < n; i++ {
+		min := r3.Vector{X: rng.Float64()*8000 - 4000, Y: rng.Float64()*8000 - 4000, Z: rng.Float64() * 200}
+		size := r3.Vector{X: 16 + rng.Float64()*300, Y: 16 + rng.Float64()*300, Z: 16 + rng.Float64()*250}
+		tris = append(tris, boxTriangles(min, min.Add(size))...)
+	}
+	return tris
+}
+
+func boxTriangles(min, max r3.Vector) []Triangle {
+	v := [8]r3.Vector{
+		{X: min.X, Y: min.Y, Z: min.Z}, {X: max.X, Y: min.Y, Z: min.Z},
+		{X: max.X, Y: max.Y, Z: min.Z}, {X: min.X, Y: max.Y, Z: min.Z},
+		{X: min.X, Y: min.Y, Z: max.Z}, {X: max.X, Y: min.Y, Z: max.Z},
+		{X: max.X, Y: max.Y, Z: max.Z}, {X: min.X, Y: max.Y, Z: max.Z},
+	}
+	faces := [12][3]int{
+		{0, 2, 1}, {0, 3, 2}, {4, 5, 6}, {4, 6, 7},
+		{0, 1, 5}, {0, 5, 4}, {1, 2, 6}, {1, 6, 5},
+		{2, 3, 7}, {2, 7, 6}, {3, 0, 4}, {3, 4, 7},
+	}
+	tris := make([]Triangle, 0, 12)
+	for _, f := range faces {
+		a, b, c := v[f[0]], v[f[1]], v[f[2]]
+		tris = append(tris, Triangle{V0: a, V1: b, V2: c, Normal: b.Sub(a).Cross(c.Sub(a)).Normalize()})
+	}
+	return tris
+}
+
+func sameHit(got, want float64) bool {
+	if got < 0 || want < 0 {
+		return (got < 0) == (want < 0)
+	}
+	return math.Abs(got-want) < 1e-6
+}
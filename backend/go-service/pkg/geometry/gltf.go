@@ -0,0 +1,535 @@
+package geometry
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/golang/geo/r3"
+	"github.com/qmuntal/gltf"
+)
+
+// ============================================================================
+// GLTF / GLB LOADER
+// Lee .gltf (buffers externos o data: URIs) y .glb. El JSON se decodifica con los
+// tipos de qmuntal/gltf pero los buffers se cargan aquí, para poder leer los
+// bufferViews comprimidos con meshopt (cuyo buffer "fallback" no tiene datos).
+// Draco no se decodifica: hay que convertir el GLTF antes (p.ej. gltf-transform copy).
+// ============================================================================
+
+// ErrDracoCompressed indica un GLTF con Draco (hay que descomprimirlo offline)
+var ErrDracoCompressed = errors.New("gltf uses KHR_draco_mesh_compression: convert it offline (e.g. gltf-transform copy in.glb out.glb)")
+
+const (
+	extDraco      = "KHR_draco_mesh_compression"
+	extMeshopt    = "EXT_meshopt_compression"
+	extMeshoptKHR = "KHR_meshopt_compression"
+	glbMagic      = "glTF"
+	glbChunkJSON  = 0x4E4F534A
+	glbChunkBIN   = 0x004E4942
+)
+
+// gltfFile es un documento con sus buffers cargados
+type gltfFile struct {
+	doc     *gltf.Document
+	buffers [][]byte
+	views   map[int][]byte // bufferViews ya descomprimidos
+}
+
+// LoadGLTF loads a .gltf or .glb file with the default physics groups and returns a Mesh
+func LoadGLTF(path string) (*Mesh, error) {
+	opts, err := defaultLoadOptions()
+	if err != nil {
+		return nil, err
+	}
+	b := newMeshBuilder()
+	if err := b.addGLTF(path, opts); err != nil {
+		return nil, err
+	}
+	return b.build(), nil
+}
+
+// addGLTF añade al builder los triángulos de los grupos físicos incluidos
+func (b *meshBuilder) addGLTF(path string, opts loadOptions) error {
+	f, err := openGLTF(path)
+	if err != nil {
+		return err
+	}
+	if f.uses(extDraco) {
+		return fmt.Errorf("%s: %w", filepath.Base(path), ErrDracoCompressed)
+	}
+
+	emit := func(mesh *gltf.Mesh, transform *[16]float64) error {
+		for _, prim := range mesh.Primitives {
+			if err := b.addPrimitive(f, mesh, prim, opts, transform); err != nil {
+				return fmt.Errorf("mesh %q: %w", mesh.Name, err)
+			}
+		}
+		return nil
+	}
+
+	if !opts.nodeTransforms {
+		// Exports de Source 2 Viewer: vértices ya en coordenadas del mundo, se ignoran los nodos
+		for _, mesh := range f.doc.Meshes {
+			if err := emit(mesh, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var walk func(node int, parent [16]float64, depth int) error
+	walk = func(node int, parent [16]float64, depth int) error {
+		if node < 0 || node >= len(f.doc.Nodes) || depth > 64 {
+			return fmt.Errorf("invalid node hierarchy at node %d", node)
+		}
+		n := f.doc.Nodes[node]
+		world := mat4Mul(parent, nodeMatrix(n))
+		if n.Mesh != nil && *n.Mesh < len(f.doc.Meshes) {
+			if err := emit(f.doc.Meshes[*n.Mesh], &world); err != nil {
+				return err
+			}
+		}
+		for _, child := range n.Children {
+			if err := walk(child, world, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range f.rootNodes() {
+		if err := walk(root, gltf.DefaultMatrix, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addPrimitive lee posiciones e índices de un primitive y añade sus triángulos
+func (b *meshBuilder) addPrimitive(f *gltfFile, mesh *gltf.Mesh, prim *gltf.Primitive, opts loadOptions, transform *[16]float64) error {
+	matName := ""
+	if prim.Material != nil && *prim.Material < len(f.doc.Materials) {
+		matName = f.doc.Materials[*prim.Material].Name
+	}
+	mask, ok := matchGroup(opts.rules, mesh.Name, matName)
+	if !ok {
+		mask = opts.unmatched(0) // Sin grupo: render, triggers...
+	}
+	if mask == 0 {
+		return nil // Grupo físico que no se carga
+	}
+
+	posIdx, ok := prim.Attributes[gltf.POSITION]
+	if !ok {
+		return nil
+	}
+	verts, err := f.readPositions(posIdx)
+	if err != nil {
+		return err
+	}
+	if transform != nil {
+		for i := range verts {
+			verts[i] = mat4Point(*transform, verts[i])
+		}
+	}
+	flip := transform != nil && mat4Det3(*transform) < 0 // Escala negativa: invierte el winding
+
+	var indices []uint32
+	if prim.Indices != nil {
+		if indices, err = f.readIndices(*prim.Indices); err != nil {
+			return err
+		}
+	} else {
+		indices = make([]uint32, len(verts))
+		for i := range indices {
+			indices[i] = uint32(i)
+		}
+	}
+
+	// Material id: GLTF material name, or the mesh name if the primitive has none
+	if matName == "" {
+		matName = mesh.Name
+	}
+	matID := b.material(matName, mask)
+
+	tri := func(i0, i1, i2 uint32) {
+		if int(i0) >= len(verts) || int(i1) >= len(verts) || int(i2) >= len(verts) {
+			return
+		}
+		if flip {
+			i1, i2 = i2, i1
+		}
+		b.addTriangle(verts[i0], verts[i1], verts[i2], matID)
+	}
+	switch prim.Mode {
+	case gltf.PrimitiveTriangles:
+		for i := 0; i+2 < len(indices); i += 3 {
+			tri(indices[i], indices[i+1], indices[i+2])
+		}
+	case gltf.PrimitiveTriangleStrip:
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				tri(indices[i], indices[i+1], indices[i+2])
+			} else {
+				tri(indices[i+1], indices[i], indices[i+2])
+			}
+		}
+	case gltf.PrimitiveTriangleFan:
+		for i := 1; i+1 < len(indices); i++ {
+			tri(indices[0], indices[i], indices[i+1])
+		}
+	}
+	return nil
+}
+
+// --- Lectura del documento ---
+
+// openGLTF lee el JSON (de un .gltf o del chunk JSON de un .glb) y sus buffers
+func openGLTF(path string) (*gltfFile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gltf: %w", err)
+	}
+
+	jsonChunk, binChunk := raw, []byte(nil)
+	if len(raw) >= 12 && string(raw[:4]) == glbMagic {
+		jsonChunk = nil
+		for pos := 12; pos+8 <= len(raw); {
+			n := int(binary.LittleEndian.Uint32(raw[pos:]))
+			typ := binary.LittleEndian.Uint32(raw[pos+4:])
+			if n < 0 || pos+8+n > len(raw) {
+				return nil, fmt.Errorf("failed to open gltf: truncated GLB chunk in %s", path)
+			}
+			switch chunk := raw[pos+8 : pos+8+n]; {
+			case typ == glbChunkJSON && jsonChunk == nil:
+				jsonChunk = chunk
+			case typ == glbChunkBIN && binChunk == nil:
+				binChunk = chunk
+			}
+			pos += 8 + n
+		}
+		if jsonChunk == nil {
+			return nil, fmt.Errorf("failed to open gltf: GLB without JSON chunk: %s", path)
+		}
+	}
+
+	var doc gltf.Document
+	if err := json.Unmarshal(jsonChunk, &doc); err != nil {
+		return nil, fmt.Errorf("failed to open gltf: %w", err)
+	}
+
+	f := &gltfFile{doc: &doc, buffers: make([][]byte, len(doc.Buffers)), views: make(map[int][]byte)}
+	for i, buf := range doc.Buffers {
+		switch {
+		case buf.URI == "" && i == 0 && binChunk != nil:
+			f.buffers[i] = binChunk
+		case buf.URI == "":
+			// Buffer fallback de meshopt (sin datos): solo se leen sus vistas comprimidas
+		case strings.HasPrefix(buf.URI, "data:"):
+			comma := strings.IndexByte(buf.URI, ',')
+			if comma < 0 {
+				return nil, fmt.Errorf("failed to open gltf: invalid data URI in buffer %d", i)
+			}
+			if f.buffers[i], err = base64.StdEncoding.DecodeString(buf.URI[comma+1:]); err != nil {
+				return nil, fmt.Errorf("failed to open gltf: buffer %d: %w", i, err)
+			}
+		default:
+			uri, err := url.PathUnescape(buf.URI)
+			if err != nil {
+				uri = buf.URI
+			}
+			data, err := os.ReadFile(filepath.Join(filepath.Dir(path), filepath.FromSlash(uri)))
+			if err != nil {
+				if isMeshoptFallback(buf) {
+					continue
+				}
+				return nil, fmt.Errorf("failed to open gltf: buffer %s: %w", buf.URI, err)
+			}
+			f.buffers[i] = data
+		}
+	}
+	return f, nil
+}
+
+func (f *gltfFile) uses(ext string) bool {
+	return slices.Contains(f.doc.ExtensionsUsed, ext) || slices.Contains(f.doc.ExtensionsRequired, ext)
+}
+
+// rootNodes devuelve los nodos raíz de la escena por defecto (o de todas si no hay escenas)
+func (f *gltfFile) rootNodes() []int {
+	if len(f.doc.Scenes) > 0 {
+		scene := 0
+		if f.doc.Scene != nil && *f.doc.Scene < len(f.doc.Scenes) {
+			scene = *f.doc.Scene
+		}
+		return f.doc.Scenes[scene].Nodes
+	}
+	isChild := make([]bool, len(f.doc.Nodes))
+	for _, n := range f.doc.Nodes {
+		for _, c := range n.Children {
+			if c >= 0 && c < len(isChild) {
+				isChild[c] = true
+			}
+		}
+	}
+	var roots []int
+	for i, child := range isChild {
+		if !child {
+			roots = append(roots, i)
+		}
+	}
+	return roots
+}
+
+// view devuelve los bytes de un bufferView (descomprimiendo meshopt si hace falta)
+func (f *gltfFile) view(idx int) ([]byte, *gltf.BufferView, error) {
+	if idx < 0 || idx >= len(f.doc.BufferViews) {
+		return nil, nil, fmt.Errorf("invalid bufferView %d", idx)
+	}
+	bv := f.doc.BufferViews[idx]
+	if data, ok := f.views[idx]; ok {
+		return data, bv, nil
+	}
+
+	if raw, ok := rawExtension(bv.Extensions, extMeshopt, extMeshoptKHR); ok {
+		var mv meshoptView
+		if err := json.Unmarshal(raw, &mv); err != nil {
+			return nil, nil, fmt.Errorf("bufferView %d: %w", idx, err)
+		}
+		src, err := f.slice(mv.Buffer, mv.ByteOffset, mv.ByteLength)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bufferView %d: %w", idx, err)
+		}
+		data, err := decodeMeshopt(mv, src, bv.ByteLength)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bufferView %d: %w", idx, err)
+		}
+		f.views[idx] = data
+		return data, bv, nil
+	}
+
+	data, err := f.slice(bv.Buffer, bv.ByteOffset, bv.ByteLength)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bufferView %d: %w", idx, err)
+	}
+	return data, bv, nil
+}
+
+func (f *gltfFile) slice(buffer, offset, length int) ([]byte, error) {
+	if buffer < 0 || buffer >= len(f.buffers) {
+		return nil, fmt.Errorf("invalid buffer %d", buffer)
+	}
+	data := f.buffers[buffer]
+	if data == nil {
+		return nil, fmt.Errorf("buffer %d has no data", buffer)
+	}
+	if offset < 0 || length < 0 || offset+length > len(data) {
+		return nil, fmt.Errorf("range %d+%d out of buffer %d (%d bytes)", offset, length, buffer, len(data))
+	}
+	return data[offset : offset+length], nil
+}
+
+// accessor devuelve los bytes del accessor, su stride y el tamaño de cada componente
+func (f *gltfFile) accessor(idx int) (*gltf.Accessor, []byte, int, error) {
+	if idx < 0 || idx >= len(f.doc.Accessors) {
+		return nil, nil, 0, fmt.Errorf("invalid accessor %d", idx)
+	}
+	acc := f.doc.Accessors[idx]
+	if acc.BufferView == nil {
+		return nil, nil, 0, fmt.Errorf("accessor %d has no bufferView (sparse-only accessors are not supported)", idx)
+	}
+	data, bv, err := f.view(*acc.BufferView)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	stride := bv.ByteStride
+	if stride == 0 {
+		stride = gltf.SizeOfElement(acc.ComponentType, acc.Type)
+	}
+	need := acc.Type.Components() * acc.ComponentType.ByteSize()
+	if acc.Count > 0 && acc.ByteOffset+(acc.Count-1)*stride+need > len(data) {
+		return nil, nil, 0, fmt.Errorf("accessor %d out of bounds", idx)
+	}
+	return acc, data[acc.ByteOffset:], stride, nil
+}
+
+// readPositions lee un accessor VEC3 (float o cuantizado, KHR_mesh_quantization)
+func (f *gltfFile) readPositions(idx int) ([]r3.Vector, error) {
+	acc, data, stride, err := f.accessor(idx)
+	if err != nil {
+		return nil, err
+	}
+	if acc.Type != gltf.AccessorVec3 {
+		return nil, fmt.Errorf("accessor %d: POSITION must be VEC3", idx)
+	}
+	size := acc.ComponentType.ByteSize()
+	verts := make([]r3.Vector, acc.Count)
+	for i := range verts {
+		e := data[i*stride:]
+		verts[i] = r3.Vector{
+			X: readComponent(e, acc.ComponentType, acc.Normalized),
+			Y: readComponent(e[size:], acc.ComponentType, acc.Normalized),
+			Z: readComponent(e[2*size:], acc.ComponentType, acc.Normalized),
+		}
+	}
+	return verts, nil
+}
+
+// readIndices lee un accessor SCALAR de índices (ubyte, ushort o uint)
+func (f *gltfFile) readIndices(idx int) ([]uint32, error) {
+	acc, data, stride, err := f.accessor(idx)
+	if err != nil {
+		return nil, err
+	}
+	indices := make([]uint32, acc.Count)
+	for i := range indices {
+		e := data[i*stride:]
+		switch acc.ComponentType {
+		case gltf.ComponentUbyte:
+			indices[i] = uint32(e[0])
+		case gltf.ComponentUshort:
+			indices[i] = uint32(uint16FromBytes(e))
+		case gltf.ComponentUint:
+			indices[i] = uint32FromBytes(e)
+		default:
+			return nil, fmt.Errorf("accessor %d: invalid index component type", idx)
+		}
+	}
+	return indices, nil
+}
+
+// readComponent lee un componente y lo pasa a float (normalizado si el accessor lo indica)
+func readComponent(b []byte, ct gltf.ComponentType, normalized bool) float64 {
+	switch ct {
+	case gltf.ComponentByte:
+		if normalized {
+			return float64(gltf.DenormalizeByte(int8(b[0])))
+		}
+		return float64(int8(b[0]))
+	case gltf.ComponentUbyte:
+		if normalized {
+			return float64(gltf.DenormalizeUbyte(b[0]))
+		}
+		return float64(b[0])
+	case gltf.ComponentShort:
+		v := int16(uint16FromBytes(b))
+		if normalized {
+			return float64(gltf.DenormalizeShort(v))
+		}
+		return float64(v)
+	case gltf.ComponentUshort:
+		v := uint16FromBytes(b)
+		if normalized {
+			return float64(gltf.DenormalizeUshort(v))
+		}
+		return float64(v)
+	case gltf.ComponentUint:
+		return float64(uint32FromBytes(b))
+	default:
+		return float64(float32FromBytes(b))
+	}
+}
+
+// rawExtension devuelve el JSON de la primera extensión presente de names
+func rawExtension(ext gltf.Extensions, names ...string) (json.RawMessage, bool) {
+	for _, name := range names {
+		switch v := ext[name].(type) {
+		case json.RawMessage:
+			return v, true
+		case nil:
+		default:
+			if raw, err := json.Marshal(v); err == nil {
+				return raw, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func isMeshoptFallback(buf *gltf.Buffer) bool {
+	raw, ok := rawExtension(buf.Extensions, extMeshopt, extMeshoptKHR)
+	return ok && isFallbackExtension(raw)
+}
+
+// gltfExtensions son las extensiones de un buffer leídas sin qmuntal/gltf (HashMeshSource)
+type gltfExtensions map[string]json.RawMessage
+
+func (e gltfExtensions) meshoptFallback() bool {
+	for _, name := range []string{extMeshopt, extMeshoptKHR} {
+		if raw, ok := e[name]; ok && isFallbackExtension(raw) {
+			return true
+		}
+	}
+	return false
+}
+
+func isFallbackExtension(raw json.RawMessage) bool {
+	var ext struct {
+		Fallback bool `json:"fallback"`
+	}
+	return json.Unmarshal(raw, &ext) == nil && ext.Fallback
+}
+
+// --- Transformaciones de nodos (matrices 4x4 column-major, como GLTF) ---
+
+func nodeMatrix(n *gltf.Node) [16]float64 {
+	if m := n.MatrixOrDefault(); m != gltf.DefaultMatrix {
+		return m
+	}
+	t, q, s := n.TranslationOrDefault(), n.RotationOrDefault(), n.ScaleOrDefault()
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	return [16]float64{
+		(1 - 2*(y*y+z*z)) * s[0], (2 * (x*y + z*w)) * s[0], (2 * (x*z - y*w)) * s[0], 0,
+		(2 * (x*y - z*w)) * s[1], (1 - 2*(x*x+z*z)) * s[1], (2 * (y*z + x*w)) * s[1], 0,
+		(2 * (x*z + y*w)) * s[2], (2 * (y*z - x*w)) * s[2], (1 - 2*(x*x+y*y)) * s[2], 0,
+		t[0], t[1], t[2], 1,
+	}
+}
+
+func mat4Mul(a, b [16]float64) [16]float64 {
+	var r [16]float64
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			var sum float64
+			for k := 0; k < 4; k++ {
+				sum += a[k*4+row] * b[col*4+k]
+			}
+			r[col*4+row] = sum
+		}
+	}
+	return r
+}
+
+func mat4Point(m [16]float64, v r3.Vector) r3.Vector {
+	return r3.Vector{
+		X: m[0]*v.X + m[4]*v.Y + m[8]*v.Z + m[12],
+		Y: m[1]*v.X + m[5]*v.Y + m[9]*v.Z + m[13],
+		Z: m[2]*v.X + m[6]*v.Y + m[10]*v.Z + m[14],
+	}
+}
+
+// mat4Det3 es el determinante de la parte 3x3 (negativo = transformación espejo)
+func mat4Det3(m [16]float64) float64 {
+	return m[0]*(m[5]*m[10]-m[9]*m[6]) - m[4]*(m[1]*m[10]-m[9]*m[2]) + m[8]*(m[1]*m[6]-m[5]*m[2])
+}
+
+// float32FromBytes, uint16FromBytes y uint32FromBytes leen little endian
+func float32FromBytes(b []byte) float32 {
+	return math.Float32frombits(binary.LittleEndian.Uint32(b))
+}
+
+func uint16FromBytes(b []byte) uint16 {
+	return binary.LittleEndian.Uint16(b)
+}
+
+func uint32FromBytes(b []byte) uint32 {
+	return binary.LittleEndian.Uint32(b)
+}
@@ -0,0 +1,352 @@
+package geometry
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/r3"
+)
+
+// ============================================================================
+// MAP ASSET LOADER
+// Un mapa puede venir como .gltf, .glb, .obj o un manifest <mapa>_assets.json que
+// lista varios ficheros y declara qué bloquea cada grupo físico. Todo se junta
+// en un único Mesh (un BVH) con una BlockMask por material.
+//
+// Manifest:
+//
+//	{
+//	  "files": ["de_nuke_physics.glb", "props/*.obj"],   // Relativos al manifest (admite globs)
+//	  "groups": [{"match": "grenadeclip", "blocks": ["grenades"]}, ...],
+//	  "default_blocks": [],                             // Meshes que no coinciden con ningún grupo
+//	  "node_transforms": false                          // Aplicar las transformaciones de los nodos GLTF
+//	}
+// ============================================================================
+
+// ManifestFileSuffix is the suffix of the per-map asset manifest (<map>_assets.json)
+const ManifestFileSuffix = "_assets.json"
+
+// AssetManifest describes the files and physics groups of a map mesh
+type AssetManifest struct {
+	// Files a cargar (relativos al manifest, admite globs). Vacío = <base>_physics.gltf/.glb
+	// o <base>.gltf/.glb/.obj junto al manifest
+	Files []string `json:"files,omitempty"`
+	// Groups sustituye a DefaultPhysicsGroups (gana el primero que coincide)
+	Groups []PhysicsGroup `json:"groups,omitempty"`
+	// DefaultBlocks para lo que no coincide con ningún grupo. Sin definir: GLTF se
+	// descarta (render, triggers...) y OBJ bloquea todo. [] = descartar siempre
+	DefaultBlocks []string `json:"default_blocks,omitempty"`
+	// NodeTransforms aplica las matrices de los nodos (exports que no están en coordenadas del mundo)
+	NodeTransforms bool `json:"node_transforms,omitempty"`
+}
+
+// loadOptions es el manifest ya validado
+type loadOptions struct {
+	rules          []groupRule
+	defaultMask    BlockMask
+	hasDefault     bool
+	nodeTransforms bool
+}
+
+// unmatched devuelve la máscara de lo que no coincide con ningún grupo (formatDefault si el manifest no la fija)
+func (o loadOptions) unmatched(formatDefault BlockMask) BlockMask {
+	if o.hasDefault {
+		return o.defaultMask
+	}
+	return formatDefault
+}
+
+func defaultLoadOptions() (loadOptions, error) {
+	return AssetManifest{}.options()
+}
+
+func (m AssetManifest) options() (loadOptions, error) {
+	groups := m.Groups
+	if len(groups) == 0 {
+		groups = DefaultPhysicsGroups
+	}
+	rules, err := compileGroups(groups)
+	if err != nil {
+		return loadOptions{}, err
+	}
+	opts := loadOptions{rules: rules, nodeTransforms: m.NodeTransforms}
+	if m.DefaultBlocks != nil {
+		if opts.defaultMask, err = ParseBlockMask(m.DefaultBlocks); err != nil {
+			return loadOptions{}, fmt.Errorf("default_blocks: %w", err)
+		}
+		opts.hasDefault = true
+	}
+	return opts, nil
+}
+
+// LoadManifest reads a map asset manifest
+func LoadManifest(path string) (*AssetManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m AssetManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// ManifestFiles resolves the mesh files of a manifest (paths relative to the manifest, globs expanded)
+func ManifestFiles(manifestPath string, m *AssetManifest) ([]string, error) {
+	dir := filepath.Dir(manifestPath)
+	if len(m.Files) == 0 {
+		base := strings.TrimSuffix(filepath.Base(manifestPath), ManifestFileSuffix)
+		for _, name := range []string{base + "_physics.gltf", base + "_physics.glb", base + ".gltf", base + ".glb", base + ".obj"} {
+			p := filepath.Join(dir, name)
+			if _, err := os.Stat(p); err == nil {
+				return []string{p}, nil
+			}
+		}
+		return nil, fmt.Errorf("manifest %s lists no files and no %s mesh was found", manifestPath, base)
+	}
+
+	var files []string
+	for _, f := range m.Files {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %q: %w", f, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("manifest file not found: %s", p)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// LoadMesh loads a map mesh from a .gltf, .glb, .obj or asset manifest (.json)
+func LoadMesh(path string) (*Mesh, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return LoadManifestMesh(path)
+	case ".gltf", ".glb":
+		return LoadGLTF(path)
+	case ".obj":
+		return LoadOBJ(path)
+	}
+	return nil, fmt.Errorf("unsupported mesh format: %s", path)
+}
+
+// LoadManifestMesh loads every file of a manifest into a single Mesh
+func LoadManifestMesh(path string) (*Mesh, error) {
+	m, err := LoadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := m.options()
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	files, err := ManifestFiles(path, m)
+	if err != nil {
+		return nil, err
+	}
+
+	b := newMeshBuilder()
+	for _, f := range files {
+		fmt.Printf("   Loading %s\n", filepath.Base(f))
+		switch strings.ToLower(filepath.Ext(f)) {
+		case ".gltf", ".glb":
+			err = b.addGLTF(f, opts)
+		case ".obj":
+			err = b.addOBJ(f, opts)
+		default:
+			err = fmt.Errorf("unsupported mesh format")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(f), err)
+		}
+	}
+	return b.build(), nil
+}
+
+// LoadOBJ loads a .obj file and returns a Mesh
+// NOTE: This is a simplified loader. It expects vertices (v) and faces (f).
+// It ignores normals/textures in the file and computes face normals.
+// Objects/groups (o, g) and materials (usemtl) are matched against the default
+// physics groups; faces that match none block everything.
+func LoadOBJ(path string) (*Mesh, error) {
+	opts, err := defaultLoadOptions()
+	if err != nil {
+		return nil, err
+	}
+	b := newMeshBuilder()
+	if err := b.addOBJ(path, opts); err != nil {
+		return nil, err
+	}
+	return b.build(), nil
+}
+
+// addOBJ añade las caras del OBJ (triangulando polígonos en abanico)
+func (b *meshBuilder) addOBJ(path string, opts loadOptions) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var vertices []r3.Vector
+	group, usemtl := "", ""
+	matID, skip := uint16(0), false
+	updateMaterial := func() {
+		mask, ok := matchGroup(opts.rules, group, usemtl)
+		if !ok {
+			mask = opts.unmatched(BlocksAll)
+		}
+		name := usemtl
+		if name == "" {
+			name = group
+		}
+		skip = mask == 0
+		if !skip {
+			matID = b.material(name, mask)
+		}
+	}
+	updateMaterial()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+
+		switch parts[0] {
+		case "v": // Vertex
+			if len(parts) < 4 {
+				continue
+			}
+			x, _ := strconv.ParseFloat(parts[1], 64)
+			y, _ := strconv.ParseFloat(parts[2], 64)
+			z, _ := strconv.ParseFloat(parts[3], 64)
+			vertices = append(vertices, r3.Vector{X: x, Y: y, Z: z})
+
+		case "o", "g": // Object / group
+			group = strings.Join(parts[1:], " ")
+			updateMaterial()
+
+		case "usemtl":
+			usemtl = strings.Join(parts[1:], " ")
+			updateMaterial()
+
+		case "f": // Face
+			// Supports "f v1 v2 v3" or "f v1/vt1/vn1 ..."
+			if len(parts) < 4 || skip {
+				continue
+			}
+			indices := make([]int, 0, len(parts)-1)
+			valid := true
+			for _, p := range parts[1:] {
+				// Handle v/vt/vn format
+				idx, err := strconv.Atoi(strings.Split(p, "/")[0])
+				if idx < 0 {
+					idx += len(vertices) + 1 // Relative index (-1 = last vertex)
+				}
+				// OBJ indices are 1-based
+				if err != nil || idx < 1 || idx > len(vertices) {
+					valid = false
+					break
+				}
+				indices = append(indices, idx-1)
+			}
+			if !valid {
+				continue
+			}
+
+			// Triangulate polygon (fan)
+			for i := 1; i < len(indices)-1; i++ {
+				b.addTriangle(vertices[indices[0]], vertices[indices[i]], vertices[indices[i+1]], matID)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// --- Mesh builder ---
+
+// meshBuilder junta los triángulos de uno o varios ficheros. Un material es el par
+// (nombre, máscara): el mismo material en dos grupos físicos distintos son dos ids.
+type meshBuilder struct {
+	triangles []Triangle
+	materials []string
+	blocks    []BlockMask
+	index     map[materialKey]uint16
+}
+
+type materialKey struct {
+	name string
+	mask BlockMask
+}
+
+func newMeshBuilder() *meshBuilder {
+	return &meshBuilder{
+		materials: []string{""},
+		blocks:    []BlockMask{BlocksAll},
+		index:     map[materialKey]uint16{{"", BlocksAll}: 0},
+	}
+}
+
+// material devuelve el id del material (0 = desconocido si se superan los 65536)
+func (b *meshBuilder) material(name string, mask BlockMask) uint16 {
+	key := materialKey{name, mask}
+	if id, ok := b.index[key]; ok {
+		return id
+	}
+	if len(b.materials) > math.MaxUint16 {
+		return 0
+	}
+	id := uint16(len(b.materials))
+	b.index[key] = id
+	b.materials = append(b.materials, name)
+	b.blocks = append(b.blocks, mask)
+	return id
+}
+
+func (b *meshBuilder) addTriangle(v0, v1, v2 r3.Vector, material uint16) {
+	// Compute Normal
+	normal := v1.Sub(v0).Cross(v2.Sub(v0)).Normalize()
+	b.triangles = append(b.triangles, Triangle{V0: v0, V1: v1, V2: v2, Normal: normal, Material: material})
+}
+
+func (b *meshBuilder) build() *Mesh {
+	counts := make(map[BlockMask]int)
+	for i := range b.triangles {
+		counts[b.blocks[b.triangles[i].Material]]++
+	}
+	for mask := BlockMask(1); mask <= BlocksAll; mask++ {
+		if n := counts[mask]; n > 0 {
+			fmt.Printf("   %s: %d triangles\n", mask, n)
+		}
+	}
+
+	// Build BVH
+	fmt.Println("Building BVH...")
+	bvh := BuildFlatBVH(b.triangles)
+	fmt.Printf("BVH Built (%d nodes).\n", len(bvh.Nodes))
+
+	return &Mesh{
+		Triangles: b.triangles,
+		BVH:       bvh,
+		Materials: b.materials,
+		Blocks:    b.blocks,
+	}
+}
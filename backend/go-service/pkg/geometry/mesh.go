@@ -1,21 +1,14 @@
 package geometry
 
 import (
-	"bufio"
-	"fmt"
-	"math"
-	"os"
-	"strconv"
-	"strings"
-
 	"github.com/golang/geo/r3"
-	"github.com/qmuntal/gltf"
 )
 
 // Triangle represents a single triangle in 3D space
 type Triangle struct {
 	V0, V1, V2 r3.Vector
 	Normal     r3.Vector
+	Material   uint16 // Index into Mesh.Materials (0 = unknown)
 }
 
 // AABB represents an Axis-Aligned Bounding Box
@@ -71,7 +64,8 @@ func (box AABB) Intersects(origin, dir r3.Vector, maxDist float64) bool {
 	return true
 }
 
-// BVHNode represents a node in the Bounding Volume Hierarchy
+// BVHNode represents a node in the pointer-based midpoint BVH.
+// Legacy: Mesh uses FlatBVH (bvh.go); kept for benchmark comparison (BenchmarkRayCast in bvh_test.go).
 type BVHNode struct {
 	AABB      AABB
 	Left      *BVHNode
@@ -81,11 +75,13 @@ type BVHNode struct {
 
 // Mesh represents a collection of triangles (the map geometry)
 type Mesh struct {
-	Triangles []Triangle
-	BVH       *BVHNode // Optimization: BVH Root
+	Triangles []Triangle  // Reordered in BVH leaf order by BuildFlatBVH
+	BVH       *FlatBVH    // Optimization: SAH BVH (flattened)
+	Materials []string    // Material names (GLTF material or mesh name), [0] = unknown
+	Blocks    []BlockMask // What each material blocks (same index as Materials, nil = everything)
 }
 
-// BuildBVH constructs a BVH from a list of triangles
+// BuildBVH constructs a longest-axis midpoint BVH from a list of triangles (legacy, see BVHNode)
 func BuildBVH(triangles []Triangle, depth int) *BVHNode {
 	node := &BVHNode{}
 	node.AABB = CalculateBounds(triangles)
@@ -178,325 +174,33 @@ func CalculateBounds(triangles []Triangle) AABB {
 	return AABB{Min: min, Max: max}
 }
 
-// LoadGLTF loads a .gltf or .glb file and returns a Mesh
-func LoadGLTF(path string) (*Mesh, error) {
-	doc, err := gltf.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open gltf: %w", err)
-	}
-
-	var triangles []Triangle
-	min := r3.Vector{X: 1e9, Y: 1e9, Z: 1e9}
-	max := r3.Vector{X: -1e9, Y: -1e9, Z: -1e9}
-
-	// Physics groups to INCLUDE (whitelist approach)
-	// Only load geometry from these specific physics groups for raycasting
-	includeGroups := []string{
-		"clip",        // playerclip - blocks players and bullets
-		"grenadeclip", // blocks grenades (included for completeness)
-		"passbullets", // explicitly blocks bullets
-		"window",      // glass/windows
-	}
-
-	for _, mesh := range doc.Meshes {
-		// Check if mesh name matches any of our included groups
-		meshName := strings.ToLower(mesh.Name)
-		meshIncluded := false
-		for _, group := range includeGroups {
-			if strings.Contains(meshName, group) {
-				meshIncluded = true
-				break
-			}
-		}
-
-		for _, primitive := range mesh.Primitives {
-			// We only support TRIANGLES (mode 4)
-			if primitive.Mode != gltf.PrimitiveTriangles {
-				continue
-			}
-
-			// Check material name as fallback
-			shouldInclude := meshIncluded
-			if !shouldInclude && primitive.Material != nil {
-				matIdx := int(*primitive.Material)
-				if matIdx < len(doc.Materials) {
-					matName := strings.ToLower(doc.Materials[matIdx].Name)
-					for _, group := range includeGroups {
-						if strings.Contains(matName, group) {
-							shouldInclude = true
-							break
-						}
-					}
-				}
-			}
-
-			if !shouldInclude {
-				continue
-			}
-
-			// Get Position Accessor
-			posIdx, ok := primitive.Attributes[gltf.POSITION]
-			if !ok {
-				continue
-			}
-			posAccessor := doc.Accessors[posIdx]
-
-			// Read positions
-			// This is a simplified reader assuming float32 vec3
-			// In a robust implementation we should handle sparse accessors, etc.
-			// qmuntal/gltf doesn't provide high-level geometry extraction out of the box easily
-			// without some boilerplate.
-
-			// Let's try to read the buffer view
-			if posAccessor.BufferView == nil {
-				continue
-			}
-			bufferView := doc.BufferViews[*posAccessor.BufferView]
-			buffer := doc.Buffers[bufferView.Buffer]
-
-			data := buffer.Data[bufferView.ByteOffset : bufferView.ByteOffset+bufferView.ByteLength]
-
-			// Parse vertices (assuming VEC3 FLOAT)
-			// Stride
-			stride := 12 // 3 * 4 bytes
-			if bufferView.ByteStride != 0 {
-				stride = int(bufferView.ByteStride)
-			}
-
-			count := int(posAccessor.Count)
-			verts := make([]r3.Vector, count)
-
-			for i := 0; i < count; i++ {
-				offset := i * stride
-				if offset+12 > len(data) {
-					break
-				}
-
-				// Read float32 x, y, z
-				// Little endian
-				x := float32FromBytes(data[offset : offset+4])
-				y := float32FromBytes(data[offset+4 : offset+8])
-				z := float32FromBytes(data[offset+8 : offset+12])
-
-				v := r3.Vector{X: float64(x), Y: float64(y), Z: float64(z)}
-				verts[i] = v
-
-				// Update bounds
-				if v.X < min.X {
-					min.X = v.X
-				}
-				if v.Y < min.Y {
-					min.Y = v.Y
-				}
-				if v.Z < min.Z {
-					min.Z = v.Z
-				}
-				if v.X > max.X {
-					max.X = v.X
-				}
-				if v.Y > max.Y {
-					max.Y = v.Y
-				}
-				if v.Z > max.Z {
-					max.Z = v.Z
-				}
-			}
-
-			// Indices
-			if primitive.Indices != nil {
-				indicesAccessor := doc.Accessors[*primitive.Indices]
-				if indicesAccessor.BufferView == nil {
-					continue
-				}
-				ibv := doc.BufferViews[*indicesAccessor.BufferView]
-				ib := doc.Buffers[ibv.Buffer]
-				idata := ib.Data[ibv.ByteOffset : ibv.ByteOffset+ibv.ByteLength]
-
-				icount := int(indicesAccessor.Count)
-
-				// Component type: 5121 (UBYTE), 5123 (USHORT), 5125 (UINT)
-				compType := indicesAccessor.ComponentType
-
-				for i := 0; i < icount; i += 3 {
-					var i0, i1, i2 int
-
-					if compType == gltf.ComponentUshort {
-						i0 = int(uint16FromBytes(idata[i*2 : i*2+2]))
-						i1 = int(uint16FromBytes(idata[(i+1)*2 : (i+1)*2+2]))
-						i2 = int(uint16FromBytes(idata[(i+2)*2 : (i+2)*2+2]))
-					} else if compType == gltf.ComponentUint {
-						i0 = int(uint32FromBytes(idata[i*4 : i*4+4]))
-						i1 = int(uint32FromBytes(idata[(i+1)*4 : (i+1)*4+4]))
-						i2 = int(uint32FromBytes(idata[(i+2)*4 : (i+2)*4+4]))
-					} else {
-						// Byte
-						i0 = int(idata[i])
-						i1 = int(idata[i+1])
-						i2 = int(idata[i+2])
-					}
-
-					if i0 >= len(verts) || i1 >= len(verts) || i2 >= len(verts) {
-						continue
-					}
-
-					v0 := verts[i0]
-					v1 := verts[i1]
-					v2 := verts[i2]
-
-					// Compute Normal
-					edge1 := v1.Sub(v0)
-					edge2 := v2.Sub(v0)
-					normal := edge1.Cross(edge2).Normalize()
-
-					triangles = append(triangles, Triangle{
-						V0:     v0,
-						V1:     v1,
-						V2:     v2,
-						Normal: normal,
-					})
-				}
-			}
-		}
+// RayCast returns the distance to the first intersection and the surface normal, or -1 if none
+func (m *Mesh) RayCast(origin, dir r3.Vector, maxDist float64) (float64, r3.Vector) {
+	if m.BVH == nil {
+		return -1, r3.Vector{}
 	}
-
-	// Build BVH
-	fmt.Println("Building BVH...")
-	bvhRoot := BuildBVH(triangles, 0)
-	fmt.Println("BVH Built.")
-
-	return &Mesh{
-		Triangles: triangles,
-		BVH:       bvhRoot,
-	}, nil
+	return m.BVH.RayCast(origin, dir, maxDist)
 }
 
-func float32FromBytes(b []byte) float32 {
-	bits := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
-	return math.Float32frombits(bits)
-}
-
-func uint16FromBytes(b []byte) uint16 {
-	return uint16(b[0]) | uint16(b[1])<<8
-}
-
-func uint32FromBytes(b []byte) uint32 {
-	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
-}
-
-// LoadOBJ loads a .obj file and returns a Mesh
-// NOTE: This is a simplified loader. It expects vertices (v) and faces (f).
-// It ignores normals/textures in the file and computes face normals.
-func LoadOBJ(path string) (*Mesh, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	var vertices []r3.Vector
-	var triangles []Triangle
-
-	// Initialize bounds inverted
-	min := r3.Vector{X: 1e9, Y: 1e9, Z: 1e9}
-	max := r3.Vector{X: -1e9, Y: -1e9, Z: -1e9}
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if len(line) == 0 || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		parts := strings.Fields(line)
-		if len(parts) == 0 {
-			continue
-		}
-
-		switch parts[0] {
-		case "v": // Vertex
-			if len(parts) < 4 {
-				continue
-			}
-			x, _ := strconv.ParseFloat(parts[1], 64)
-			y, _ := strconv.ParseFloat(parts[2], 64)
-			z, _ := strconv.ParseFloat(parts[3], 64)
-			v := r3.Vector{X: x, Y: y, Z: z}
-			vertices = append(vertices, v)
-
-			// Update bounds
-			if x < min.X {
-				min.X = x
-			}
-			if y < min.Y {
-				min.Y = y
-			}
-			if z < min.Z {
-				min.Z = z
-			}
-			if x > max.X {
-				max.X = x
-			}
-			if y > max.Y {
-				max.Y = y
-			}
-			if z > max.Z {
-				max.Z = z
-			}
-
-		case "f": // Face
-			// Supports "f v1 v2 v3" or "f v1/vt1/vn1 ..."
-			if len(parts) < 4 {
-				continue
-			}
-			var indices []int
-			for _, p := range parts[1:] {
-				// Handle v/vt/vn format
-				idxStr := strings.Split(p, "/")[0]
-				idx, _ := strconv.Atoi(idxStr)
-				// OBJ indices are 1-based
-				indices = append(indices, idx-1)
-			}
-
-			// Triangulate polygon (fan)
-			for i := 1; i < len(indices)-1; i++ {
-				v0 := vertices[indices[0]]
-				v1 := vertices[indices[i]]
-				v2 := vertices[indices[i+1]]
-
-				// Compute Normal
-				edge1 := v1.Sub(v0)
-				edge2 := v2.Sub(v0)
-				normal := edge1.Cross(edge2).Normalize()
-
-				triangles = append(triangles, Triangle{
-					V0:     v0,
-					V1:     v1,
-					V2:     v2,
-					Normal: normal,
-				})
-			}
-		}
+// RayCastFor is RayCast ignoring surfaces that don't block mask (e.g. grenadeclip for bullets)
+func (m *Mesh) RayCastFor(origin, dir r3.Vector, maxDist float64, mask BlockMask) (float64, r3.Vector) {
+	if m.BVH == nil {
+		return -1, r3.Vector{}
 	}
+	return m.BVH.rayCast(origin, dir, maxDist, m.filter(mask))
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+// filter returns the ray filter for mask (no filter if the mesh has no block masks)
+func (m *Mesh) filter(mask BlockMask) rayFilter {
+	if m.Blocks == nil {
+		return rayFilter{}
 	}
-
-	// Build BVH
-	bvhRoot := BuildBVH(triangles, 0)
-
-	return &Mesh{
-		Triangles: triangles,
-		BVH:       bvhRoot,
-	}, nil
+	return rayFilter{blocks: m.Blocks, mask: mask}
 }
 
-// RayCast returns the distance to the first intersection and the surface normal, or -1 if none
-func (m *Mesh) RayCast(origin, dir r3.Vector, maxDist float64) (float64, r3.Vector) {
-	if m.BVH == nil {
-		return -1, r3.Vector{}
-	}
-	return rayCastBVH(m.BVH, origin, dir, maxDist)
+// RayCast is the legacy recursive closest-hit traversal (see BVHNode)
+func (node *BVHNode) RayCast(origin, dir r3.Vector, maxDist float64) (float64, r3.Vector) {
+	return rayCastBVH(node, origin, dir, maxDist)
 }
 
 func rayCastBVH(node *BVHNode, origin, dir r3.Vector, maxDist float64) (float64, r3.Vector) {
@@ -597,7 +301,42 @@ func (m *Mesh) RayIntersects(start, end r3.Vector) bool {
 	dist := dir.Norm()
 	dir = dir.Normalize()
 
-	return intersectBVH(m.BVH, start, dir, dist)
+	return m.BVH.RayIntersects(start, dir, dist)
+}
+
+// RayBlocker returns a triangle between start and end (any hit, not necessarily the
+// closest); ok=false if the segment is clear
+func (m *Mesh) RayBlocker(start, end r3.Vector) (tri Triangle, ok bool) {
+	if m.BVH == nil {
+		return Triangle{}, false
+	}
+
+	dir := end.Sub(start)
+	dist := dir.Norm()
+	dir = dir.Normalize()
+
+	if hit := m.BVH.anyHit(start, dir, dist, rayFilter{}); hit != nil {
+		return *hit, true
+	}
+	return Triangle{}, false
+}
+
+// RayIntersectsFor is RayIntersects counting only the surfaces that block mask
+func (m *Mesh) RayIntersectsFor(start, end r3.Vector, mask BlockMask) bool {
+	if m.BVH == nil {
+		return false
+	}
+
+	dir := end.Sub(start)
+	dist := dir.Norm()
+	dir = dir.Normalize()
+
+	return m.BVH.rayIntersects(start, dir, dist, m.filter(mask))
+}
+
+// RayIntersects is the legacy recursive any-hit traversal (see BVHNode)
+func (node *BVHNode) RayIntersects(origin, dir r3.Vector, maxDist float64) bool {
+	return intersectBVH(node, origin, dir, maxDist)
 }
 
 func intersectBVH(node *BVHNode, origin, dir r3.Vector, maxDist float64) bool {
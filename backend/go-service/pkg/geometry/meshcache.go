@@ -0,0 +1,425 @@
+package geometry
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/geo/r3"
+)
+
+// ============================================================================
+// MESH CACHE
+// Prebuilt mesh + flat BVH serialised to disk, keyed by the hash of the mesh
+// source. Loading it skips GLTF/OBJ parsing, triangulation and the SAH build.
+//
+// Format (little endian):
+//   magic "CS2BVH\x00\x00" | uint16 version | [32]byte source hash
+//   uint32 triangle count | uint32 node count
+//   triangles (12 x float64, uint16 material) | nodes (6 x float64, int32, uint16, uint8, pad)
+//   uint32 material count | materials (uint16 length + name bytes + uint8 block mask)
+// ============================================================================
+
+// MeshCacheVersion must be bumped whenever Triangle, FlatBVHNode or the SAH build change
+// v2: per-triangle material id + material names (bullet penetration)
+// v3: block mask per material (physics groups, see BlockMask)
+const MeshCacheVersion uint16 = 3
+
+var meshCacheMagic = [8]byte{'C', 'S', '2', 'B', 'V', 'H', 0, 0}
+
+// ErrMeshCacheStale is returned when the cache was built from another source file or version
+var ErrMeshCacheStale = errors.New("mesh cache is stale")
+
+const (
+	triangleBytes = 12*8 + 2
+	nodeBytes     = 6*8 + 4 + 2 + 1 + 1 // +1 padding byte
+)
+
+// HashMeshSource hashes a mesh source: a .gltf/.glb/.obj file plus the external
+// buffers it references (.gltf files usually point to a separate .bin), or a
+// manifest plus every file it lists
+func HashMeshSource(path string) ([32]byte, error) {
+	var sum [32]byte
+	h := sha256.New()
+	if err := hashMeshSource(h, path); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+func hashMeshSource(h io.Writer, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	h.Write(data)
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var m AssetManifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("invalid manifest %s: %w", path, err)
+		}
+		files, err := ManifestFiles(path, &m)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			io.WriteString(h, filepath.Base(f))
+			if err := hashMeshSource(h, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".gltf") {
+		for _, b := range gltfExternalBuffers(path, data) {
+			buf, err := os.ReadFile(b.path)
+			if err != nil {
+				if b.optional {
+					continue // Fallback sin comprimir que no se llegó a exportar
+				}
+				return fmt.Errorf("failed to read buffer %s: %w", b.path, err)
+			}
+			h.Write(buf)
+		}
+	}
+	return nil
+}
+
+// MeshSourceFiles lists the files a mesh source is read from: the file itself, the
+// external buffers of a .gltf and, for a manifest, every file it lists (recursively).
+// Best effort: unreadable files are skipped (HashMeshSource reports those errors).
+func MeshSourceFiles(path string) []string {
+	files := []string{path}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		m, err := LoadManifest(path)
+		if err != nil {
+			return files
+		}
+		listed, _ := ManifestFiles(path, m)
+		for _, f := range listed {
+			files = append(files, MeshSourceFiles(f)...)
+		}
+	case ".gltf":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return files
+		}
+		for _, b := range gltfExternalBuffers(path, data) {
+			files = append(files, b.path)
+		}
+	}
+	return files
+}
+
+// gltfBufferFile is an external buffer of a .gltf; optional = meshopt fallback that may be missing
+type gltfBufferFile struct {
+	path     string
+	optional bool
+}
+
+// gltfExternalBuffers returns the buffers of a .gltf stored in separate files (embedded
+// data: URIs are part of the .gltf itself)
+func gltfExternalBuffers(path string, data []byte) []gltfBufferFile {
+	var doc struct {
+		Buffers []struct {
+			URI        string         `json:"uri"`
+			Extensions gltfExtensions `json:"extensions"`
+		} `json:"buffers"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	var files []gltfBufferFile
+	for _, b := range doc.Buffers {
+		if b.URI == "" || strings.HasPrefix(b.URI, "data:") {
+			continue
+		}
+		files = append(files, gltfBufferFile{
+			path:     filepath.Join(filepath.Dir(path), filepath.FromSlash(b.URI)),
+			optional: b.Extensions.meshoptFallback(),
+		})
+	}
+	return files
+}
+
+// SaveMeshCache writes the mesh and its BVH (written atomically via a temp file)
+func SaveMeshCache(path string, mesh *Mesh, sourceHash [32]byte) error {
+	if mesh == nil || mesh.BVH == nil {
+		return fmt.Errorf("mesh has no BVH")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create mesh cache: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	w := bufio.NewWriterSize(tmp, 1<<20)
+	err = writeMeshCache(w, mesh, sourceHash)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write mesh cache: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write mesh cache: %w", err)
+	}
+	return nil
+}
+
+func writeMeshCache(w io.Writer, mesh *Mesh, sourceHash [32]byte) error {
+	header := make([]byte, 0, 8+2+32+4+4)
+	header = append(header, meshCacheMagic[:]...)
+	header = binary.LittleEndian.AppendUint16(header, MeshCacheVersion)
+	header = append(header, sourceHash[:]...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(mesh.BVH.Triangles)))
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(mesh.BVH.Nodes)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, triangleBytes)
+	for i := range mesh.BVH.Triangles {
+		t := &mesh.BVH.Triangles[i]
+		buf = buf[:0]
+		for _, v := range [4]r3.Vector{t.V0, t.V1, t.V2, t.Normal} {
+			buf = appendVector(buf, v)
+		}
+		buf = binary.LittleEndian.AppendUint16(buf, t.Material)
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	buf = make([]byte, 0, nodeBytes)
+	for i := range mesh.BVH.Nodes {
+		n := &mesh.BVH.Nodes[i]
+		buf = buf[:0]
+		buf = appendVector(buf, n.Bounds.Min)
+		buf = appendVector(buf, n.Bounds.Max)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(n.Offset))
+		buf = binary.LittleEndian.AppendUint16(buf, n.Count)
+		buf = append(buf, n.Axis, 0)
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	buf = binary.LittleEndian.AppendUint32(buf[:0], uint32(len(mesh.Materials)))
+	for i, name := range mesh.Materials {
+		if len(name) > math.MaxUint16 {
+			name = name[:math.MaxUint16]
+		}
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(name)))
+		buf = append(buf, name...)
+		buf = append(buf, byte(mesh.blockMask(i)))
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// LoadMeshCache reads a mesh cache. Returns ErrMeshCacheStale if it was built
+// from a different source (hash) or by a different MeshCacheVersion.
+func LoadMeshCache(path string, sourceHash [32]byte) (*Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 1<<20)
+
+	header := make([]byte, 8+2+32+4+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read mesh cache header: %w", err)
+	}
+	if string(header[:8]) != string(meshCacheMagic[:]) {
+		return nil, fmt.Errorf("not a mesh cache: %s", path)
+	}
+	if version := binary.LittleEndian.Uint16(header[8:10]); version != MeshCacheVersion {
+		return nil, fmt.Errorf("%w: version %d, expected %d", ErrMeshCacheStale, version, MeshCacheVersion)
+	}
+	if string(header[10:42]) != string(sourceHash[:]) {
+		return nil, fmt.Errorf("%w: source hash %s changed", ErrMeshCacheStale, hex.EncodeToString(header[10:18]))
+	}
+	triCount := int64(binary.LittleEndian.Uint32(header[42:46]))
+	nodeCount := int64(binary.LittleEndian.Uint32(header[46:50]))
+
+	// Counts must fit in the file before allocating (a corrupt header could ask for GBs)
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat mesh cache: %w", err)
+	}
+	body := info.Size() - int64(len(header))
+	if triCount*triangleBytes+nodeCount*nodeBytes+4 > body {
+		return nil, fmt.Errorf("mesh cache corrupt: %d triangles and %d nodes do not fit in %d bytes", triCount, nodeCount, info.Size())
+	}
+
+	triangles := make([]Triangle, triCount)
+	buf := make([]byte, triangleBytes)
+	for i := range triangles {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("mesh cache truncated: %w", err)
+		}
+		triangles[i] = Triangle{
+			V0:       readVector(buf[0:]),
+			V1:       readVector(buf[24:]),
+			V2:       readVector(buf[48:]),
+			Normal:   readVector(buf[72:]),
+			Material: binary.LittleEndian.Uint16(buf[96:]),
+		}
+	}
+
+	nodes := make([]FlatBVHNode, nodeCount)
+	buf = make([]byte, nodeBytes)
+	for i := range nodes {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("mesh cache truncated: %w", err)
+		}
+		nodes[i] = FlatBVHNode{
+			Bounds: AABB{Min: readVector(buf[0:]), Max: readVector(buf[24:])},
+			Offset: int32(binary.LittleEndian.Uint32(buf[48:])),
+			Count:  binary.LittleEndian.Uint16(buf[52:]),
+			Axis:   buf[54],
+		}
+	}
+
+	if err := validateBVHNodes(nodes, len(triangles)); err != nil {
+		return nil, fmt.Errorf("mesh cache corrupt: %w", err)
+	}
+
+	materials, blocks, err := readMaterials(r, body-triCount*triangleBytes-nodeCount*nodeBytes)
+	if err != nil {
+		return nil, fmt.Errorf("mesh cache truncated: %w", err)
+	}
+
+	return &Mesh{
+		Triangles: triangles,
+		BVH:       &FlatBVH{Nodes: nodes, Triangles: triangles},
+		Materials: materials,
+		Blocks:    blocks,
+	}, nil
+}
+
+// validateBVHNodes checks that every leaf range is inside the triangles and that every
+// child index points forward inside the node array (so traversal can neither panic nor loop)
+func validateBVHNodes(nodes []FlatBVHNode, triCount int) error {
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Axis > 2 {
+			return fmt.Errorf("node %d: split axis %d", i, n.Axis)
+		}
+		if n.IsLeaf() {
+			if n.Offset < 0 || int(n.Offset)+int(n.Count) > triCount {
+				return fmt.Errorf("node %d: triangles [%d, +%d) out of %d", i, n.Offset, n.Count, triCount)
+			}
+			continue
+		}
+		if i+1 >= len(nodes) || int(n.Offset) <= i+1 || int(n.Offset) >= len(nodes) {
+			return fmt.Errorf("node %d: children %d/%d out of %d nodes", i, i+1, n.Offset, len(nodes))
+		}
+	}
+	return nil
+}
+
+// readMaterials reads the material table (remaining = bytes left in the file, bounds the count)
+func readMaterials(r io.Reader, remaining int64) ([]string, []BlockMask, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, nil, err
+	}
+	count := int64(binary.LittleEndian.Uint32(b[:]))
+	if count*3 > remaining-4 { // Each entry is at least a uint16 length and the block mask
+		return nil, nil, fmt.Errorf("%d materials do not fit in %d bytes", count, remaining)
+	}
+	materials := make([]string, count)
+	blocks := make([]BlockMask, len(materials))
+	for i := range materials {
+		if _, err := io.ReadFull(r, b[:2]); err != nil {
+			return nil, nil, err
+		}
+		name := make([]byte, int(binary.LittleEndian.Uint16(b[:2]))+1)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, nil, err
+		}
+		materials[i] = string(name[:len(name)-1])
+		blocks[i] = BlockMask(name[len(name)-1])
+	}
+	return materials, blocks, nil
+}
+
+// blockMask returns what material i blocks (everything if the mesh has no masks)
+func (m *Mesh) blockMask(i int) BlockMask {
+	if i < len(m.Blocks) {
+		return m.Blocks[i]
+	}
+	return BlocksAll
+}
+
+// LoadMeshCached loads a map mesh (see LoadMesh) using the binary cache in cacheDir when it
+// is up to date, and (re)writes the cache otherwise. fromCache reports which path was taken.
+func LoadMeshCached(path, cacheDir string) (mesh *Mesh, fromCache bool, err error) {
+	hash, err := HashMeshSource(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	cachePath := filepath.Join(cacheDir, fmt.Sprintf("%s-%s.bvh", base, hex.EncodeToString(hash[:8])))
+
+	if mesh, err := LoadMeshCache(cachePath, hash); err == nil {
+		return mesh, true, nil
+	} else if !os.IsNotExist(err) {
+		fmt.Printf("⚠️  Ignoring mesh cache %s: %v\n", cachePath, err)
+	}
+
+	mesh, err = LoadMesh(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Remove caches of previous versions of this source file
+	if old, _ := filepath.Glob(filepath.Join(cacheDir, base+"-*.bvh")); len(old) > 0 {
+		for _, p := range old {
+			os.Remove(p)
+		}
+	}
+	if err := SaveMeshCache(cachePath, mesh, hash); err != nil {
+		fmt.Printf("⚠️  Could not write mesh cache: %v\n", err)
+	}
+	return mesh, false, nil
+}
+
+func appendVector(buf []byte, v r3.Vector) []byte {
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.X))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.Y))
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.Z))
+}
+
+func readVector(b []byte) r3.Vector {
+	return r3.Vector{
+		X: math.Float64frombits(binary.LittleEndian.Uint64(b[0:])),
+		Y: math.Float64frombits(binary.LittleEndian.Uint64(b[8:])),
+		Z: math.Float64frombits(binary.LittleEndian.Uint64(b[16:])),
+	}
+}
@@ -0,0 +1,117 @@
+package geometry
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/golang/geo/r3"
+)
+
+// gridMesh crea un mesh sintético: un suelo de n x n celdas (dos triángulos cada una)
+// y una pared cada 4 celdas, con su BVH
+func gridMesh(n int) *Mesh {
+	const cell = 64.0
+	var tris []Triangle
+	quad := func(a, b, c, d r3.Vector, material uint16) {
+		normal := b.Sub(a).Cross(c.Sub(a)).Normalize()
+		tris = append(tris,
+			Triangle{V0: a, V1: b, V2: c, Normal: normal, Material: material},
+			Triangle{V0: a, V1: c, V2: d, Normal: normal, Material: material})
+	}
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			x0, y0 := float64(x)*cell, float64(y)*cell
+			quad(r3.Vector{X: x0, Y: y0}, r3.Vector{X: x0 + cell, Y: y0}, r3.Vector{X: x0 + cell, Y: y0 + cell}, r3.Vector{X: x0, Y: y0 + cell}, 0)
+		}
+		if x%4 == 3 {
+			xw := float64(x+1) * cell
+			quad(r3.Vector{X: xw, Y: 0}, r3.Vector{X: xw, Y: float64(n) * cell / 2}, r3.Vector{X: xw, Y: float64(n) * cell / 2, Z: 128}, r3.Vector{X: xw, Z: 128}, 1)
+		}
+	}
+	return &Mesh{Triangles: tris, BVH: BuildFlatBVH(tris), Materials: []string{"floor", "wall"}}
+}
+
+func TestMeshCacheRoundTrip(t *testing.T) {
+	mesh := gridMesh(16)
+	path := filepath.Join(t.TempDir(), "grid.bvh")
+	hash := [32]byte{7}
+	if err := SaveMeshCache(path, mesh, hash); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadMeshCache(path, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got.Triangles, mesh.BVH.Triangles) || !slices.Equal(got.BVH.Nodes, mesh.BVH.Nodes) || !slices.Equal(got.Materials, mesh.Materials) {
+		t.Error("loaded mesh differs from the saved one")
+	}
+}
+
+func TestLoadMeshCacheCorrupt(t *testing.T) {
+	mesh := gridMesh(8)
+	path := filepath.Join(t.TempDir(), "grid.bvh")
+	if err := SaveMeshCache(path, mesh, [32]byte{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cabecera: magic(8) version(2) hash(32) triCount(4) nodeCount(4)
+	const triCountOff, nodeCountOff, headerLen = 42, 46, 50
+	nodeOff := func(i int) int { return headerLen + len(mesh.BVH.Triangles)*triangleBytes + i*nodeBytes }
+	leaf := slices.IndexFunc(mesh.BVH.Nodes, func(n FlatBVHNode) bool { return n.IsLeaf() })
+	if mesh.BVH.Nodes[0].IsLeaf() || leaf < 0 {
+		t.Fatal("test mesh needs internal nodes and leaves")
+	}
+
+	for name, mutate := range map[string]func([]byte) []byte{
+		"huge triangle count": func(b []byte) []byte { binary.LittleEndian.PutUint32(b[triCountOff:], 1<<31); return b },
+		"huge node count":     func(b []byte) []byte { binary.LittleEndian.PutUint32(b[nodeCountOff:], 1<<31); return b },
+		"truncated":           func(b []byte) []byte { return b[:nodeOff(1)] },
+		"leaf out of range": func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[nodeOff(leaf)+48:], uint32(len(mesh.BVH.Triangles)))
+			return b
+		},
+		"child points back": func(b []byte) []byte { binary.LittleEndian.PutUint32(b[nodeOff(0)+48:], 0); return b },
+		"child out of range": func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[nodeOff(0)+48:], uint32(len(mesh.BVH.Nodes)))
+			return b
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			bad := filepath.Join(t.TempDir(), "bad.bvh")
+			if err := os.WriteFile(bad, mutate(slices.Clone(data)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadMeshCache(bad, [32]byte{}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestMeshSourceFiles(t *testing.T) {
+	dir := t.TempDir()
+	gltf := `{"buffers": [{"uri": "map.bin"}, {"uri": "data:application/octet-stream;base64,AAAA"}, {"uri": "sub/extra.bin"}]}`
+	manifest := `{"files": ["map.gltf"]}`
+	for name, content := range map[string]string{"map.gltf": gltf, "map" + ManifestFileSuffix: manifest} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{filepath.Join(dir, "map.gltf"), filepath.Join(dir, "map.bin"), filepath.Join(dir, "sub", "extra.bin")}
+	if got := MeshSourceFiles(want[0]); !slices.Equal(got, want) {
+		t.Errorf("gltf: got %v, want %v", got, want)
+	}
+	manifestPath := filepath.Join(dir, "map"+ManifestFileSuffix)
+	if got := MeshSourceFiles(manifestPath); !slices.Equal(got, append([]string{manifestPath}, want...)) {
+		t.Errorf("manifest: got %v", got)
+	}
+}
@@ -0,0 +1,433 @@
+package geometry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ============================================================================
+// MESHOPT DECODER
+// Decodificador de EXT_meshopt_compression / KHR_meshopt_compression (gltfpack)
+// en Go puro, siguiendo el formato de meshoptimizer (vertexcodec.cpp e
+// indexcodec.cpp): ATTRIBUTES (codec de vértices v0), TRIANGLES (codec de
+// índices v0/v1) e INDICES (secuencias). De los filtros solo hace falta
+// EXPONENTIAL (posiciones); OCTAHEDRAL/QUATERNION son de normales y rotaciones,
+// que el loader no lee.
+// ============================================================================
+
+// ErrMeshoptData indica un buffer meshopt corrupto o truncado
+var ErrMeshoptData = errors.New("invalid meshopt data")
+
+const (
+	meshoptVertexHeader   = 0xa0
+	meshoptIndexHeader    = 0xe0
+	meshoptSequenceHeader = 0xd0
+
+	meshoptVertexBlockSizeBytes = 8192
+	meshoptVertexBlockMaxSize   = 256
+	meshoptByteGroupSize        = 16
+	meshoptTailMaxSize          = 32
+
+	// meshoptMaxViewBytes limita lo que se reserva para un bufferView descomprimido
+	// (count y byteStride vienen del fichero)
+	meshoptMaxViewBytes = 512 << 20
+)
+
+// meshoptView es la extensión de un bufferView comprimido
+type meshoptView struct {
+	Buffer     int    `json:"buffer"`
+	ByteOffset int    `json:"byteOffset"`
+	ByteLength int    `json:"byteLength"`
+	ByteStride int    `json:"byteStride"`
+	Count      int    `json:"count"`
+	Mode       string `json:"mode"`   // ATTRIBUTES, TRIANGLES, INDICES
+	Filter     string `json:"filter"` // NONE, OCTAHEDRAL, QUATERNION, EXPONENTIAL
+}
+
+// decodeMeshopt descomprime el bufferView (count * byteStride bytes, que no puede pasar
+// de viewLength, el byteLength del bufferView sin comprimir)
+func decodeMeshopt(v meshoptView, src []byte, viewLength int) ([]byte, error) {
+	if v.ByteStride <= 0 || v.ByteStride > meshoptVertexBlockMaxSize || v.Count < 0 {
+		return nil, fmt.Errorf("%w: stride %d, count %d", ErrMeshoptData, v.ByteStride, v.Count)
+	}
+	limit := min(viewLength, meshoptMaxViewBytes)
+	if v.Count > limit/v.ByteStride {
+		return nil, fmt.Errorf("%w: %d x %d bytes exceed the bufferView (%d bytes)", ErrMeshoptData, v.Count, v.ByteStride, viewLength)
+	}
+	dst := make([]byte, v.Count*v.ByteStride)
+	var err error
+	switch v.Mode {
+	case "ATTRIBUTES":
+		err = decodeMeshoptVertices(dst, v.Count, v.ByteStride, src)
+	case "TRIANGLES":
+		err = decodeMeshoptTriangles(dst, v.Count, v.ByteStride, src)
+	case "INDICES":
+		err = decodeMeshoptSequence(dst, v.Count, v.ByteStride, src)
+	default:
+		return nil, fmt.Errorf("unsupported meshopt mode %q", v.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch v.Filter {
+	case "", "NONE":
+	case "EXPONENTIAL":
+		if v.ByteStride%4 != 0 {
+			return nil, fmt.Errorf("%w: exponential filter with stride %d", ErrMeshoptData, v.ByteStride)
+		}
+		for i := 0; i+4 <= len(dst); i += 4 {
+			bits := binary.LittleEndian.Uint32(dst[i:])
+			exp := int(int8(bits >> 24))
+			mantissa := int32(bits<<8) >> 8
+			f := float32(math.Ldexp(float64(mantissa), exp))
+			binary.LittleEndian.PutUint32(dst[i:], math.Float32bits(f))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported meshopt filter %q", v.Filter)
+	}
+	return dst, nil
+}
+
+// --- Vértices ---
+
+func decodeMeshoptVertices(dst []byte, count, size int, buf []byte) error {
+	if size > 256 || size%4 != 0 {
+		return fmt.Errorf("%w: vertex size %d", ErrMeshoptData, size)
+	}
+	if len(buf) < 1+size || buf[0]&0xf0 != meshoptVertexHeader {
+		return fmt.Errorf("%w: bad vertex header", ErrMeshoptData)
+	}
+	if version := buf[0] & 0x0f; version > 0 {
+		return fmt.Errorf("unsupported meshopt vertex codec version %d", version)
+	}
+
+	tailSize := max(size, meshoptTailMaxSize)
+	if len(buf)-1 < tailSize {
+		return fmt.Errorf("%w: truncated vertex data", ErrMeshoptData)
+	}
+	// El encoder guarda el primer vértice (base de los deltas) al final del stream
+	var last [256]byte
+	copy(last[:size], buf[len(buf)-size:])
+
+	blockSize := min((meshoptVertexBlockSizeBytes/size)&^(meshoptByteGroupSize-1), meshoptVertexBlockMaxSize)
+	data := buf[1 : len(buf)-tailSize]
+	pos := 0
+	for offset := 0; offset < count; offset += blockSize {
+		n := min(blockSize, count-offset)
+		var err error
+		pos, err = decodeVertexBlock(data, pos, dst[offset*size:(offset+n)*size], n, size, last[:size])
+		if err != nil {
+			return err
+		}
+	}
+	if pos != len(data) {
+		return fmt.Errorf("%w: %d trailing vertex bytes", ErrMeshoptData, len(data)-pos)
+	}
+	return nil
+}
+
+// decodeVertexBlock decodifica un bloque: cada byte del vértice va en su propio
+// stream de deltas (zigzag) respecto al vértice anterior
+func decodeVertexBlock(data []byte, pos int, dst []byte, count, size int, last []byte) (int, error) {
+	aligned := (count + meshoptByteGroupSize - 1) &^ (meshoptByteGroupSize - 1)
+	var deltas [meshoptVertexBlockMaxSize]byte
+
+	for k := 0; k < size; k++ {
+		var err error
+		pos, err = decodeByteGroups(data, pos, deltas[:aligned])
+		if err != nil {
+			return pos, err
+		}
+		p := last[k]
+		for i := 0; i < count; i++ {
+			d := deltas[i]
+			p += (d >> 1) ^ -(d & 1)
+			dst[i*size+k] = p
+		}
+	}
+	copy(last, dst[(count-1)*size:count*size])
+	return pos, nil
+}
+
+// decodeByteGroups lee grupos de 16 bytes con 0, 2, 4 u 8 bits por valor (cabecera de 2 bits por grupo)
+func decodeByteGroups(data []byte, pos int, out []byte) (int, error) {
+	groups := len(out) / meshoptByteGroupSize
+	headerSize := (groups + 3) / 4
+	if pos+headerSize > len(data) {
+		return pos, fmt.Errorf("%w: truncated vertex block", ErrMeshoptData)
+	}
+	header := data[pos : pos+headerSize]
+	pos += headerSize
+
+	for g := 0; g < groups; g++ {
+		bitsLog2 := (header[g/4] >> ((g % 4) * 2)) & 3
+		group := out[g*meshoptByteGroupSize : (g+1)*meshoptByteGroupSize]
+		switch bitsLog2 {
+		case 0:
+			clear(group)
+		case 3:
+			if pos+meshoptByteGroupSize > len(data) {
+				return pos, fmt.Errorf("%w: truncated vertex block", ErrMeshoptData)
+			}
+			copy(group, data[pos:])
+			pos += meshoptByteGroupSize
+		default:
+			bits := 1 << bitsLog2 // 2 o 4
+			packed := meshoptByteGroupSize * bits / 8
+			if pos+packed > len(data) {
+				return pos, fmt.Errorf("%w: truncated vertex block", ErrMeshoptData)
+			}
+			extra := pos + packed // Los valores "todo unos" se leen enteros a continuación
+			sentinel := byte(1<<bits - 1)
+			for i := 0; i < meshoptByteGroupSize; i++ {
+				b := data[pos+i*bits/8]
+				v := (b >> (8 - bits - (i*bits)%8)) & sentinel
+				if v == sentinel {
+					if extra >= len(data) {
+						return pos, fmt.Errorf("%w: truncated vertex block", ErrMeshoptData)
+					}
+					v = data[extra]
+					extra++
+				}
+				group[i] = v
+			}
+			pos = extra
+		}
+	}
+	return pos, nil
+}
+
+// --- Índices ---
+
+// meshoptReader lee varints del stream de índices
+type meshoptReader struct {
+	data []byte
+	pos  int
+	end  int // Límite de lectura (la tabla codeaux va detrás)
+	err  error
+}
+
+func (r *meshoptReader) byte() byte {
+	if r.pos >= r.end {
+		r.err = ErrMeshoptData
+		return 0
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *meshoptReader) vbyte() uint32 {
+	lead := r.byte()
+	if lead < 128 {
+		return uint32(lead)
+	}
+	result := uint32(lead & 127)
+	shift := 7
+	for i := 0; i < 4; i++ {
+		group := r.byte()
+		result |= uint32(group&127) << shift
+		shift += 7
+		if group < 128 {
+			break
+		}
+	}
+	return result
+}
+
+// index lee un índice codificado como delta (zigzag) respecto a last
+func (r *meshoptReader) index(last uint32) uint32 {
+	v := r.vbyte()
+	return last + ((v >> 1) ^ -(v & 1))
+}
+
+func putIndex(dst []byte, i, size int, v uint32) {
+	if size == 2 {
+		binary.LittleEndian.PutUint16(dst[i*2:], uint16(v))
+	} else {
+		binary.LittleEndian.PutUint32(dst[i*4:], v)
+	}
+}
+
+// decodeMeshoptTriangles decodifica el codec de triángulos (FIFOs de aristas y vértices)
+func decodeMeshoptTriangles(dst []byte, count, size int, buf []byte) error {
+	if count%3 != 0 || (size != 2 && size != 4) {
+		return fmt.Errorf("%w: %d indices of %d bytes", ErrMeshoptData, count, size)
+	}
+	if len(buf) < 1+count/3+16 || buf[0]&0xf0 != meshoptIndexHeader {
+		return fmt.Errorf("%w: bad index header", ErrMeshoptData)
+	}
+	version := buf[0] & 0x0f
+	if version > 1 {
+		return fmt.Errorf("unsupported meshopt index codec version %d", version)
+	}
+	fecMax := 15
+	if version >= 1 {
+		fecMax = 13
+	}
+
+	var edgeFifo [16][2]uint32
+	var vertexFifo [16]uint32
+	for i := range edgeFifo {
+		edgeFifo[i] = [2]uint32{math.MaxUint32, math.MaxUint32}
+		vertexFifo[i] = math.MaxUint32
+	}
+	edgeOffset, vertexOffset := 0, 0
+	pushEdge := func(a, b uint32) {
+		edgeFifo[edgeOffset] = [2]uint32{a, b}
+		edgeOffset = (edgeOffset + 1) & 15
+	}
+	pushVertex := func(v uint32, cond bool) {
+		vertexFifo[vertexOffset] = v
+		if cond {
+			vertexOffset = (vertexOffset + 1) & 15
+		}
+	}
+
+	var next, last uint32
+	code := buf[1 : 1+count/3]
+	codeaux := buf[len(buf)-16:]
+	r := &meshoptReader{data: buf, pos: 1 + count/3, end: len(buf) - 16}
+
+	for t := 0; t < count/3; t++ {
+		codetri := code[t]
+		var a, b, c uint32
+
+		if codetri < 0xf0 {
+			fe := int(codetri >> 4)
+			edge := edgeFifo[(edgeOffset-1-fe)&15]
+			a, b = edge[0], edge[1]
+			fec := int(codetri & 15)
+			if fec < fecMax {
+				if fec == 0 {
+					c = next
+					next++
+				} else {
+					c = vertexFifo[(vertexOffset-1-fec)&15]
+				}
+				pushVertex(c, fec == 0)
+			} else {
+				if fec != 15 {
+					// 13, 14 → -1, +1 respecto al último índice libre
+					c = last + uint32(fec-(fec^3))
+				} else {
+					c = r.index(last)
+				}
+				last = c
+				pushVertex(c, true)
+			}
+			pushEdge(c, b)
+			pushEdge(a, c)
+		} else if codetri < 0xfe {
+			aux := codeaux[codetri&15]
+			feb, fec := int(aux>>4), int(aux&15)
+			a = next
+			next++
+			if feb == 0 {
+				b = next
+				next++
+			} else {
+				b = vertexFifo[(vertexOffset-feb)&15]
+			}
+			if fec == 0 {
+				c = next
+				next++
+			} else {
+				c = vertexFifo[(vertexOffset-fec)&15]
+			}
+			pushVertex(a, true)
+			pushVertex(b, feb == 0)
+			pushVertex(c, fec == 0)
+			pushEdge(b, a)
+			pushEdge(c, b)
+			pushEdge(a, c)
+		} else {
+			aux := r.byte()
+			fea := 15
+			if codetri == 0xfe {
+				fea = 0
+			}
+			feb, fec := int(aux>>4), int(aux&15)
+			if aux == 0 {
+				next = 0 // Reset
+			}
+			if fea == 0 {
+				a = next
+				next++
+			}
+			if feb == 0 {
+				b = next
+				next++
+			} else {
+				b = vertexFifo[(vertexOffset-feb)&15]
+			}
+			if fec == 0 {
+				c = next
+				next++
+			} else {
+				c = vertexFifo[(vertexOffset-fec)&15]
+			}
+			if fea == 15 {
+				a = r.index(last)
+				last = a
+			}
+			if feb == 15 {
+				b = r.index(last)
+				last = b
+			}
+			if fec == 15 {
+				c = r.index(last)
+				last = c
+			}
+			pushVertex(a, true)
+			pushVertex(b, feb == 0 || feb == 15)
+			pushVertex(c, fec == 0 || fec == 15)
+			pushEdge(b, a)
+			pushEdge(c, b)
+			pushEdge(a, c)
+		}
+		if r.err != nil {
+			return fmt.Errorf("%w: truncated index data", ErrMeshoptData)
+		}
+		putIndex(dst, t*3, size, a)
+		putIndex(dst, t*3+1, size, b)
+		putIndex(dst, t*3+2, size, c)
+	}
+	if r.pos != r.end {
+		return fmt.Errorf("%w: %d trailing index bytes", ErrMeshoptData, r.end-r.pos)
+	}
+	return nil
+}
+
+// decodeMeshoptSequence decodifica una secuencia de índices (dos bases alternas, deltas zigzag)
+func decodeMeshoptSequence(dst []byte, count, size int, buf []byte) error {
+	if size != 2 && size != 4 {
+		return fmt.Errorf("%w: index size %d", ErrMeshoptData, size)
+	}
+	if len(buf) < 1+count+4 || buf[0]&0xf0 != meshoptSequenceHeader {
+		return fmt.Errorf("%w: bad index sequence header", ErrMeshoptData)
+	}
+	if version := buf[0] & 0x0f; version > 1 {
+		return fmt.Errorf("unsupported meshopt index sequence version %d", version)
+	}
+
+	r := &meshoptReader{data: buf, pos: 1, end: len(buf) - 4}
+	var last [2]uint32
+	for i := 0; i < count; i++ {
+		v := r.vbyte()
+		base := v & 1
+		v >>= 1
+		last[base] += (v >> 1) ^ -(v & 1)
+		putIndex(dst, i, size, last[base])
+	}
+	if r.err != nil {
+		return fmt.Errorf("%w: truncated index sequence", ErrMeshoptData)
+	}
+	if r.pos != r.end {
+		return fmt.Errorf("%w: %d trailing index bytes", ErrMeshoptData, r.end-r.pos)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/golang/geo/r3"
+)
+
+// ============================================================================
+// BULLET PENETRATION
+// Walks a ray through every triangle it crosses, pairs entry/exit faces into
+// solid surfaces (thickness per surface) and estimates the damage left after
+// going through them, following CS's HandleBulletPenetration.
+// ============================================================================
+
+const (
+	// MaxPenetrations is the number of surfaces a bullet can go through (CS: 4)
+	MaxPenetrations = 4
+	// MaxPenetrationThickness: thicker surfaces stop the bullet (CS exit trace limit)
+	MaxPenetrationThickness = 90.0
+	// rangeFalloffUnits: RangeModifier is applied once every 500 units
+	rangeFalloffUnits = 500.0
+	// hitMergeEpsilon merges duplicated hits on shared triangle edges
+	hitMergeEpsilon = 1e-3
+)
+
+// SurfaceMaterial holds the penetration values of a surface type
+type SurfaceMaterial struct {
+	Name                string
+	PenetrationModifier float64 // >1 = easier to go through, <1 = harder
+	DamageModifier      float64 // Fraction of the current damage lost when penetrating
+}
+
+// surfaceMaterials maps keywords found in GLTF material names to surface values.
+// First match wins, so more specific keywords go first.
+// Values are approximations of CS surfaceproperties.
+var surfaceMaterials = []struct {
+	keyword  string
+	material SurfaceMaterial
+}{
+	{"chainlink", SurfaceMaterial{Name: "grate", PenetrationModifier: 99, DamageModifier: 0.01}},
+	{"grate", SurfaceMaterial{Name: "grate", PenetrationModifier: 99, DamageModifier: 0.01}},
+	{"fence", SurfaceMaterial{Name: "grate", PenetrationModifier: 99, DamageModifier: 0.01}},
+	{"glass", SurfaceMaterial{Name: "glass", PenetrationModifier: 2.0, DamageModifier: 0.05}},
+	{"window", SurfaceMaterial{Name: "glass", PenetrationModifier: 2.0, DamageModifier: 0.05}},
+	{"cardboard", SurfaceMaterial{Name: "cardboard", PenetrationModifier: 2.0, DamageModifier: 0.05}},
+	{"paper", SurfaceMaterial{Name: "cardboard", PenetrationModifier: 2.0, DamageModifier: 0.05}},
+	{"plaster", SurfaceMaterial{Name: "plaster", PenetrationModifier: 1.6, DamageModifier: 0.1}},
+	{"drywall", SurfaceMaterial{Name: "plaster", PenetrationModifier: 1.6, DamageModifier: 0.1}},
+	{"wood", SurfaceMaterial{Name: "wood", PenetrationModifier: 1.0, DamageModifier: 0.16}},
+	{"plastic", SurfaceMaterial{Name: "plastic", PenetrationModifier: 0.8, DamageModifier: 0.16}},
+	{"metal", SurfaceMaterial{Name: "metal", PenetrationModifier: 0.5, DamageModifier: 0.3}},
+	{"vent", SurfaceMaterial{Name: "metal", PenetrationModifier: 0.5, DamageModifier: 0.3}},
+	{"brick", SurfaceMaterial{Name: "concrete", PenetrationModifier: 0.5, DamageModifier: 0.3}},
+	{"concrete", SurfaceMaterial{Name: "concrete", PenetrationModifier: 0.5, DamageModifier: 0.3}},
+	{"stone", SurfaceMaterial{Name: "concrete", PenetrationModifier: 0.5, DamageModifier: 0.3}},
+	{"rock", SurfaceMaterial{Name: "concrete", PenetrationModifier: 0.5, DamageModifier: 0.3}},
+	{"dirt", SurfaceMaterial{Name: "dirt", PenetrationModifier: 0.3, DamageModifier: 0.35}},
+	{"sand", SurfaceMaterial{Name: "dirt", PenetrationModifier: 0.3, DamageModifier: 0.35}},
+}
+
+// DefaultSurfaceMaterial is used when the material name matches no keyword
+var DefaultSurfaceMaterial = SurfaceMaterial{Name: "default", PenetrationModifier: 1.0, DamageModifier: 0.16}
+
+// ClassifyMaterial returns the surface values for a GLTF material name
+func ClassifyMaterial(name string) SurfaceMaterial {
+	lower := strings.ToLower(name)
+	for _, m := range surfaceMaterials {
+		if strings.Contains(lower, m.keyword) {
+			return m.material
+		}
+	}
+	return DefaultSurfaceMaterial
+}
+
+// RayHit is a single ray/triangle intersection
+type RayHit struct {
+	T        float64
+	Normal   r3.Vector
+	Material uint16
+}
+
+// PenetratedSurface is a solid section crossed by the ray
+type PenetratedSurface struct {
+	Material  SurfaceMaterial
+	EntryDist float64 // Distance from the ray start to the entry face
+	ExitDist  float64 // Distance from the ray start to the exit face
+	Thickness float64
+}
+
+// Ballistics are the bullet values of a weapon (see models.WeaponPenetration)
+type Ballistics struct {
+	Damage        float64
+	Penetration   float64
+	RangeModifier float64
+}
+
+// PenetrationResult is the outcome of a penetration query between two points
+type PenetrationResult struct {
+	Surfaces []PenetratedSurface
+	Damage   float64 // Estimated damage at the end point (0 if the bullet is stopped)
+	Stopped  bool    // Too many / too thick surfaces or out of damage
+}
+
+// Wallbang returns true if the segment goes through geometry and the bullet still deals damage
+func (r PenetrationResult) Wallbang() bool {
+	return len(r.Surfaces) > 0 && !r.Stopped && r.Damage >= 1
+}
+
+// RayHits returns every triangle hit before maxDist, sorted by distance
+func (b *FlatBVH) RayHits(origin, dir r3.Vector, maxDist float64) []RayHit {
+	return b.rayHits(origin, dir, maxDist, rayFilter{})
+}
+
+func (b *FlatBVH) rayHits(origin, dir r3.Vector, maxDist float64, filter rayFilter) []RayHit {
+	if len(b.Nodes) == 0 {
+		return nil
+	}
+	ray := newRay(origin, dir)
+	var hits []RayHit
+
+	var buf [bvhStackSize]int32
+	stack := append(buf[:0], 0)
+
+	for len(stack) > 0 {
+		nodeIdx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		node := &b.Nodes[nodeIdx]
+		if hit := ray.intersectAABB(node.Bounds, maxDist); !hit {
+			continue
+		}
+
+		if node.IsLeaf() {
+			tris := b.Triangles[node.Offset : int(node.Offset)+int(node.Count)]
+			for i := range tris {
+				if filter.skip(&tris[i]) {
+					continue
+				}
+				if t := RayCastTriangle(origin, dir, tris[i], maxDist); t > 0 {
+					hits = append(hits, RayHit{T: t, Normal: tris[i].Normal, Material: tris[i].Material})
+				}
+			}
+			continue
+		}
+
+		near, far := ray.orderChildren(nodeIdx+1, node)
+		stack = append(stack, far, near)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].T < hits[j].T })
+	return hits
+}
+
+// Penetrate returns the solid surfaces crossed by the segment start→end
+// (only surfaces that block bullets, see BlockMask).
+// Faces whose normal points against the ray are entries, the rest exits;
+// nested/overlapping solids are merged. A segment that starts or ends inside
+// a solid is clipped to the segment.
+func (m *Mesh) Penetrate(start, end r3.Vector) []PenetratedSurface {
+	if m.BVH == nil {
+		return nil
+	}
+	dir := end.Sub(start)
+	dist := dir.Norm()
+	if dist == 0 {
+		return nil
+	}
+	dir = dir.Mul(1 / dist)
+
+	var surfaces []PenetratedSurface
+	depth := 0
+	var open PenetratedSurface
+	lastT, lastEntering := -1.0, false
+
+	for _, hit := range m.BVH.rayHits(start, dir, dist, m.filter(BlocksBullets)) {
+		entering := dir.Dot(hit.Normal) < 0
+		if lastT >= 0 && hit.T-lastT < hitMergeEpsilon && entering == lastEntering {
+			continue // Same face split in two triangles (shared edge)
+		}
+		lastT, lastEntering = hit.T, entering
+
+		if entering {
+			if depth == 0 {
+				open = PenetratedSurface{Material: m.material(hit.Material), EntryDist: hit.T}
+			}
+			depth++
+			continue
+		}
+
+		if depth == 0 {
+			// Exit without entry: the segment starts inside this solid
+			surfaces = append(surfaces, closeSurface(PenetratedSurface{Material: m.material(hit.Material)}, hit.T))
+			continue
+		}
+		depth--
+		if depth == 0 {
+			surfaces = append(surfaces, closeSurface(open, hit.T))
+		}
+	}
+
+	if depth > 0 {
+		// The segment ends inside a solid
+		surfaces = append(surfaces, closeSurface(open, dist))
+	}
+	return surfaces
+}
+
+// EstimatePenetration runs Penetrate and estimates the damage left at end
+func (m *Mesh) EstimatePenetration(start, end r3.Vector, b Ballistics) PenetrationResult {
+	surfaces := m.Penetrate(start, end)
+	damage, stopped := EstimatePenetrationDamage(surfaces, b, start.Sub(end).Norm())
+	return PenetrationResult{Surfaces: surfaces, Damage: damage, Stopped: stopped}
+}
+
+// EstimatePenetrationDamage applies range falloff over dist and the damage lost on each surface:
+//
+//	lost = dmg*DamageModifier + max(0, 3/Penetration*1.25)*penMod*3 + penMod*thickness²/24
+//
+// with penMod = 1/PenetrationModifier
+func EstimatePenetrationDamage(surfaces []PenetratedSurface, b Ballistics, dist float64) (float64, bool) {
+	damage := b.Damage * math.Pow(b.RangeModifier, dist/rangeFalloffUnits)
+	if len(surfaces) == 0 {
+		return damage, false
+	}
+	if b.Penetration <= 0 || len(surfaces) > MaxPenetrations {
+		return 0, true
+	}
+
+	for _, s := range surfaces {
+		if s.Thickness > MaxPenetrationThickness {
+			return 0, true
+		}
+		penMod := 0.0
+		if s.Material.PenetrationModifier > 0 {
+			penMod = 1 / s.Material.PenetrationModifier
+		}
+		lost := damage*s.Material.DamageModifier +
+			math.Max(0, 3/b.Penetration*1.25)*penMod*3 +
+			penMod*s.Thickness*s.Thickness/24
+		damage -= lost
+		if damage < 1 {
+			return 0, true
+		}
+	}
+	return damage, false
+}
+
+func (m *Mesh) material(id uint16) SurfaceMaterial {
+	if int(id) < len(m.Materials) {
+		return ClassifyMaterial(m.Materials[id])
+	}
+	return DefaultSurfaceMaterial
+}
+
+func closeSurface(s PenetratedSurface, exit float64) PenetratedSurface {
+	s.ExitDist = exit
+	s.Thickness = exit - s.EntryDist
+	return s
+}
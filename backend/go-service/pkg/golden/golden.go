@@ -0,0 +1,291 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Diff kinds
+const (
+	DiffChanged = "changed" // Mismo campo, valor distinto
+	DiffMissing = "missing" // Está en el baseline pero no en el candidato
+	DiffAdded   = "added"   // Está en el candidato pero no en el baseline
+	DiffType    = "type"    // Mismo campo, tipo JSON distinto
+)
+
+// Diff es una diferencia entre un artefacto baseline y uno candidato
+type Diff struct {
+	File      string
+	Path      string // Ruta JSON del campo, p.ej. "rounds[3].duels[0].winner"
+	Kind      string
+	Baseline  interface{}
+	Candidate interface{}
+}
+
+func (d Diff) String() string {
+	path := d.Path
+	if path == "" {
+		path = "<root>"
+	}
+	switch d.Kind {
+	case DiffMissing:
+		return fmt.Sprintf("%s: %s missing (baseline: %s)", d.File, path, short(d.Baseline))
+	case DiffAdded:
+		return fmt.Sprintf("%s: %s added (candidate: %s)", d.File, path, short(d.Candidate))
+	default:
+		return fmt.Sprintf("%s: %s %s: %s -> %s", d.File, path, d.Kind, short(d.Baseline), short(d.Candidate))
+	}
+}
+
+// Report es el resultado de comparar dos directorios de exports
+type Report struct {
+	Files     int // Ficheros comparados
+	Identical int // Ficheros idénticos byte a byte
+	Diffs     []Diff
+}
+
+// OK indica que no hay diferencias
+func (r *Report) OK() bool {
+	return len(r.Diffs) == 0
+}
+
+// Summary devuelve un resumen legible con como máximo maxDiffs diferencias
+func (r *Report) Summary(maxDiffs int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d files compared, %d identical, %d differences\n", r.Files, r.Identical, len(r.Diffs))
+	for i, d := range r.Diffs {
+		if maxDiffs > 0 && i >= maxDiffs {
+			fmt.Fprintf(&b, "  ... %d more\n", len(r.Diffs)-maxDiffs)
+			break
+		}
+		fmt.Fprintf(&b, "  %s\n", d)
+	}
+	return b.String()
+}
+
+// CompareDirs compara todos los ficheros de baselineDir con los de candidateDir.
+// Los .json se comparan campo a campo; el resto, byte a byte.
+func CompareDirs(baselineDir, candidateDir string) (*Report, error) {
+	baseline, err := listFiles(baselineDir)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := listFiles(candidateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	for _, name := range baseline {
+		report.Files++
+		candidatePath := filepath.Join(candidateDir, name)
+		if _, err := os.Stat(candidatePath); os.IsNotExist(err) {
+			report.Diffs = append(report.Diffs, Diff{File: name, Kind: DiffMissing, Baseline: "file"})
+			continue
+		}
+
+		identical, diffs, err := CompareFiles(name, filepath.Join(baselineDir, name), candidatePath)
+		if err != nil {
+			return nil, err
+		}
+		if identical {
+			report.Identical++
+		}
+		report.Diffs = append(report.Diffs, diffs...)
+	}
+
+	inBaseline := make(map[string]bool, len(baseline))
+	for _, name := range baseline {
+		inBaseline[name] = true
+	}
+	for _, name := range candidate {
+		if !inBaseline[name] {
+			report.Diffs = append(report.Diffs, Diff{File: name, Kind: DiffAdded, Candidate: "file"})
+		}
+	}
+
+	return report, nil
+}
+
+// CompareFiles compara dos artefactos. Devuelve si son idénticos byte a byte y,
+// si no lo son, las diferencias campo a campo (o una única diferencia si no son JSON).
+func CompareFiles(name, baselinePath, candidatePath string) (bool, []Diff, error) {
+	a, err := os.ReadFile(baselinePath)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to read baseline %s: %w", name, err)
+	}
+	b, err := os.ReadFile(candidatePath)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to read candidate %s: %w", name, err)
+	}
+	if bytes.Equal(a, b) {
+		return true, nil, nil
+	}
+
+	if filepath.Ext(name) != ".json" {
+		return false, []Diff{{File: name, Kind: DiffChanged, Baseline: fmt.Sprintf("%d bytes", len(a)), Candidate: fmt.Sprintf("%d bytes", len(b))}}, nil
+	}
+
+	va, err := decode(a)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to decode baseline %s: %w", name, err)
+	}
+	vb, err := decode(b)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to decode candidate %s: %w", name, err)
+	}
+
+	var diffs []Diff
+	diffValues(name, "", va, vb, &diffs)
+	if len(diffs) == 0 {
+		// Mismo contenido con distinto formato (indentación, orden de claves...)
+		diffs = append(diffs, Diff{File: name, Kind: DiffChanged, Baseline: "formatting", Candidate: "formatting"})
+	}
+	return false, diffs, nil
+}
+
+// Update reemplaza el baseline por el contenido de candidateDir
+func Update(baselineDir, candidateDir string) error {
+	if err := os.RemoveAll(baselineDir); err != nil {
+		return fmt.Errorf("failed to clean golden directory: %w", err)
+	}
+	files, err := listFiles(candidateDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(candidateDir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		dst := filepath.Join(baselineDir, name)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("failed to create golden directory: %w", err)
+		}
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// diffValues compara recursivamente dos valores JSON decodificados
+func diffValues(file, path string, a, b interface{}, out *[]Diff) {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok {
+			*out = append(*out, Diff{File: file, Path: path, Kind: DiffType, Baseline: a, Candidate: b})
+			return
+		}
+		for _, key := range sortedKeys(va) {
+			child := joinKey(path, key)
+			bv, exists := vb[key]
+			if !exists {
+				*out = append(*out, Diff{File: file, Path: child, Kind: DiffMissing, Baseline: va[key]})
+				continue
+			}
+			diffValues(file, child, va[key], bv, out)
+		}
+		for _, key := range sortedKeys(vb) {
+			if _, exists := va[key]; !exists {
+				*out = append(*out, Diff{File: file, Path: joinKey(path, key), Kind: DiffAdded, Candidate: vb[key]})
+			}
+		}
+
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok {
+			*out = append(*out, Diff{File: file, Path: path, Kind: DiffType, Baseline: a, Candidate: b})
+			return
+		}
+		for i := 0; i < len(va) || i < len(vb); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(vb):
+				*out = append(*out, Diff{File: file, Path: child, Kind: DiffMissing, Baseline: va[i]})
+			case i >= len(va):
+				*out = append(*out, Diff{File: file, Path: child, Kind: DiffAdded, Candidate: vb[i]})
+			default:
+				diffValues(file, child, va[i], vb[i], out)
+			}
+		}
+
+	default:
+		// Escalares: json.Number, string, bool o nil
+		if fmt.Sprintf("%T", a) != fmt.Sprintf("%T", b) {
+			*out = append(*out, Diff{File: file, Path: path, Kind: DiffType, Baseline: a, Candidate: b})
+		} else if a != b {
+			*out = append(*out, Diff{File: file, Path: path, Kind: DiffChanged, Baseline: a, Candidate: b})
+		}
+	}
+}
+
+// decode usa json.Number para no perder precisión al comparar números
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// listFiles devuelve las rutas relativas de todos los ficheros de dir, ordenadas
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// short abrevia valores grandes (objetos/arrays) para el resumen
+func short(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	if len(b) > 80 {
+		return string(b[:77]) + "..."
+	}
+	return string(b)
+}
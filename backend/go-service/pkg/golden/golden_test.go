@@ -0,0 +1,60 @@
+package golden
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareDirs(t *testing.T) {
+	report, err := CompareDirs(filepath.Join("testdata", "baseline"), filepath.Join("testdata", "candidate"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Files != 4 || report.Identical != 1 {
+		t.Errorf("files = %d, identical = %d; want 4 and 1", report.Files, report.Identical)
+	}
+	want := []Diff{
+		{File: "metadata.json", Path: "rounds[1].kills", Kind: DiffType},
+		{File: "metadata.json", Path: "rounds[1].winner", Kind: DiffChanged},
+		{File: "metadata.json", Path: "rounds[1].clutch", Kind: DiffAdded},
+		{File: "raw.bin", Kind: DiffChanged},
+		{File: "sub/removed.json", Kind: DiffMissing},
+		{File: "added.json", Kind: DiffAdded},
+	}
+	if len(report.Diffs) != len(want) {
+		t.Fatalf("got %d diffs, want %d:\n%s", len(report.Diffs), len(want), report.Summary(0))
+	}
+	for i, w := range want {
+		d := report.Diffs[i]
+		if d.File != w.File || d.Path != w.Path || d.Kind != w.Kind {
+			t.Errorf("diff %d = %s %q %s, want %s %q %s", i, d.File, d.Path, d.Kind, w.File, w.Path, w.Kind)
+		}
+	}
+}
+
+func TestCompareDirsIdentical(t *testing.T) {
+	dir := filepath.Join("testdata", "baseline")
+	report, err := CompareDirs(dir, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Identical != report.Files {
+		t.Errorf("comparing a directory with itself: %s", report.Summary(0))
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "golden")
+	src := filepath.Join("testdata", "candidate")
+	if err := Update(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	report, err := CompareDirs(dst, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("updated baseline differs from its source:\n%s", report.Summary(0))
+	}
+}
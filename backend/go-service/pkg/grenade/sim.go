@@ -0,0 +1,233 @@
+package grenade
+
+import (
+	"math"
+
+	"cs2-demo-service/pkg/geometry"
+
+	"github.com/golang/geo/r3"
+)
+
+// ============================================================================
+// GRENADE SIMULATOR
+// Integra la trayectoria tick a tick (gravedad de granada, rebotes con la
+// normal de la superficie) contra las superficies del mesh que bloquean
+// granadas, hasta que detona o queda en reposo. Es determinista: el mismo
+// lanzamiento sobre el mismo mesh da siempre el mismo resultado.
+//
+// Sigue CBaseCSGrenadeProjectile (PhysicsAddGravityMove + ResolveFlyCollisionCustom).
+// Aproximaciones: el proyectil es un rayo (no la caja de 2x2x2), no hay
+// jugadores ni props dinámicos y los cristales no se rompen.
+// ============================================================================
+
+// Constantes físicas de las granadas de CS
+const (
+	TickRate           = 64.0
+	Gravity            = 800 * 0.4 // sv_gravity * gravedad de la granada
+	Elasticity         = 0.45
+	MaxElasticity      = 0.9
+	RestSpeed          = 30.0 // Por debajo de esto, al tocar suelo se para
+	FloorNormalZ       = 0.7  // Normal.Z mínima para considerar la superficie suelo
+	HullRadius         = 2.0  // Media caja del proyectil
+	FuseSeconds        = 1.5  // HE y flash
+	MolotovAirSeconds  = 2.0  // molotov_throw_detonate_time
+	MolotovMaxSlope    = 30.0 // weapon_molotov_maxdetonateslope (grados)
+	SmokeCheckSeconds  = 0.2  // La smoke comprueba cada 0.2s si está parada
+	MaxSimulateSeconds = 20.0
+	maxCollisionsTick  = 4 // Colisiones resueltas dentro de un mismo tick (esquinas)
+	surfaceOffset      = 0.1
+)
+
+const blocksGrenades = geometry.BlocksGrenades
+
+// Tracer lanza rayos contra las superficies de un tipo (maps.CollisionChecker lo cumple)
+type Tracer interface {
+	TraceFor(origin, dir r3.Vector, maxDist float64, mask geometry.BlockMask) (dist float64, normal r3.Vector, ok bool)
+}
+
+// MeshTracer adapta un geometry.Mesh a Tracer (herramientas sin MapManager)
+type MeshTracer struct {
+	Mesh *geometry.Mesh
+}
+
+// TraceFor implements Tracer
+func (m MeshTracer) TraceFor(origin, dir r3.Vector, maxDist float64, mask geometry.BlockMask) (float64, r3.Vector, bool) {
+	if m.Mesh == nil {
+		return -1, r3.Vector{}, false
+	}
+	dist, normal := m.Mesh.RayCastFor(origin, dir, maxDist, mask)
+	return dist, normal, true
+}
+
+// End indica por qué terminó la simulación
+type End string
+
+const (
+	EndRest    End = "rest"    // Parada en el suelo (smoke, decoy)
+	EndFuse    End = "fuse"    // Detonó por tiempo (HE, flash, molotov en el aire)
+	EndSurface End = "surface" // Molotov que tocó suelo
+	EndTimeout End = "timeout" // MaxSimulateSeconds sin detonar
+	EndNoMesh  End = "no_mesh" // Sin geometría (caída libre hasta el timeout)
+)
+
+// Bounce es un impacto contra la geometría
+type Bounce struct {
+	Tick     int // Ticks desde el lanzamiento
+	Position r3.Vector
+	Normal   r3.Vector
+}
+
+// Result es la trayectoria simulada
+type Result struct {
+	Kind     Kind
+	Points   []r3.Vector // Una posición por tick, empezando en la de lanzamiento
+	Bounces  []Bounce
+	Land     r3.Vector // Posición de detonación / reposo
+	Ticks    int       // Ticks hasta Land
+	End      End
+	Airborne bool // Land en el aire (HE/flash que detonan antes de caer)
+}
+
+// Seconds devuelve el tiempo de vuelo hasta Land
+func (r Result) Seconds() float64 {
+	return float64(r.Ticks) / TickRate
+}
+
+// Predict simula un lanzamiento completo
+func Predict(tracer Tracer, kind Kind, t Throw) Result {
+	pos, vel := t.Release(tracer)
+	return Simulate(tracer, kind, pos, vel)
+}
+
+// Simulate integra la granada desde pos/vel hasta que detona o se para.
+// tracer puede ser nil (sin mapa: solo gravedad).
+func Simulate(tracer Tracer, kind Kind, pos, vel r3.Vector) Result {
+	const dt = 1 / TickRate
+	res := Result{Kind: kind, Points: []r3.Vector{pos}}
+	maxTicks := int(MaxSimulateSeconds * TickRate)
+	fuseTicks := detonateTicks(kind)
+	smokeCheck := int(math.Round(SmokeCheckSeconds * TickRate))
+	molotovFloor := math.Cos(MolotovMaxSlope * math.Pi / 180)
+	resting := false
+
+	finish := func(tick int, end End) Result {
+		res.Land, res.Ticks, res.End = pos, tick, end
+		res.Airborne = !resting && end == EndFuse
+		return res
+	}
+
+	for tick := 1; tick <= maxTicks; tick++ {
+		if !resting {
+			// PhysicsAddGravityMove: velocidad media del tick en Z
+			newZ := vel.Z - Gravity*dt
+			move := r3.Vector{X: vel.X * dt, Y: vel.Y * dt, Z: (vel.Z + newZ) / 2 * dt}
+			vel.Z = newZ
+
+			for i := 0; i < maxCollisionsTick && move.Norm2() > 0; i++ {
+				length := move.Norm()
+				dir := move.Mul(1 / length)
+				hit, normal := -1.0, r3.Vector{}
+				if tracer != nil {
+					hit, normal, _ = tracer.TraceFor(pos, dir, length, blocksGrenades)
+				}
+				if hit < 0 {
+					pos = pos.Add(move)
+					break
+				}
+				fraction := hit / length
+				pos = pos.Add(dir.Mul(hit)).Add(normal.Mul(surfaceOffset))
+				res.Bounces = append(res.Bounces, Bounce{Tick: tick, Position: pos, Normal: normal})
+
+				if kind == KindMolotov && normal.Z >= molotovFloor {
+					res.Points = append(res.Points, pos)
+					return finish(tick, EndSurface)
+				}
+
+				// ResolveFlyCollisionCustom: reflejo (overbounce 2) y pérdida de energía
+				vel = vel.Sub(normal.Mul(2 * vel.Dot(normal))).Mul(math.Min(Elasticity, MaxElasticity))
+				if normal.Z > FloorNormalZ {
+					if vel.Norm2() < RestSpeed*RestSpeed {
+						vel, resting = r3.Vector{}, true
+						break
+					}
+					// Sigue con el resto del movimiento del tick
+					move = vel.Mul((1 - fraction) * dt)
+					continue
+				}
+				break // Pared: el resto del tick se pierde (como PhysicsPushEntity)
+			}
+		}
+		res.Points = append(res.Points, pos)
+
+		switch kind {
+		case KindHE, KindFlash, KindMolotov:
+			if tick >= fuseTicks {
+				return finish(tick, EndFuse)
+			}
+		case KindSmoke:
+			if resting && tick%smokeCheck == 0 {
+				return finish(tick, EndRest)
+			}
+		default:
+			if resting {
+				return finish(tick, EndRest)
+			}
+		}
+	}
+	if tracer == nil {
+		return finish(maxTicks, EndNoMesh)
+	}
+	return finish(maxTicks, EndTimeout)
+}
+
+// detonateTicks devuelve los ticks de la mecha (0 = detona al pararse)
+func detonateTicks(kind Kind) int {
+	switch kind {
+	case KindHE, KindFlash:
+		return int(math.Round(FuseSeconds * TickRate))
+	case KindMolotov:
+		return int(math.Round(MolotovAirSeconds * TickRate))
+	}
+	return 0
+}
+
+// Deviation compara una trayectoria simulada con la observada en la demo
+type Deviation struct {
+	LandError float64 `json:"land_error"` // Distancia entre el punto final simulado y el observado
+	MeanError float64 `json:"mean_error"` // Distancia media de cada punto observado a la trayectoria simulada
+	MaxError  float64 `json:"max_error"`
+	Samples   int     `json:"samples"`
+}
+
+// Compare mide la distancia de los puntos observados a la polilínea simulada (no
+// depende de la frecuencia de muestreo de la demo) y el error del punto final
+func Compare(sim Result, observed []r3.Vector, observedLand r3.Vector) Deviation {
+	d := Deviation{LandError: sim.Land.Sub(observedLand).Norm(), Samples: len(observed)}
+	if len(observed) == 0 || len(sim.Points) == 0 {
+		return d
+	}
+	total := 0.0
+	for _, p := range observed {
+		best := math.Inf(1)
+		for i := 1; i < len(sim.Points); i++ {
+			best = math.Min(best, segmentDistance(p, sim.Points[i-1], sim.Points[i]))
+		}
+		if len(sim.Points) == 1 {
+			best = p.Sub(sim.Points[0]).Norm()
+		}
+		total += best
+		d.MaxError = math.Max(d.MaxError, best)
+	}
+	d.MeanError = total / float64(len(observed))
+	return d
+}
+
+func segmentDistance(p, a, b r3.Vector) float64 {
+	ab := b.Sub(a)
+	l2 := ab.Norm2()
+	if l2 == 0 {
+		return p.Sub(a).Norm()
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/l2))
+	return p.Sub(a.Add(ab.Mul(t))).Norm()
+}
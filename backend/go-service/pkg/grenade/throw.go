@@ -0,0 +1,148 @@
+package grenade
+
+import (
+	"math"
+	"strings"
+
+	"github.com/golang/geo/r3"
+)
+
+// ============================================================================
+// GRENADE THROW
+// Estado inicial de una granada a partir del lanzamiento (posición de los ojos,
+// ángulos, fuerza y velocidad del jugador), siguiendo CBaseCSGrenade::ThrowGrenade
+// de CS:GO, que CS2 mantiene salvo el subtick (el lanzamiento se redondea al tick).
+// ============================================================================
+
+// Kind es el tipo de granada (determina cuándo detona)
+type Kind string
+
+const (
+	KindSmoke   Kind = "smoke"
+	KindFlash   Kind = "flash"
+	KindHE      Kind = "he"
+	KindMolotov Kind = "molotov" // Molotov e incendiaria
+	KindDecoy   Kind = "decoy"
+)
+
+// ParseKind acepta nombres de demoinfocs ("Smoke Grenade", "Incendiary Grenade"...) y cortos ("smoke", "he")
+func ParseKind(name string) (Kind, bool) {
+	n := strings.ToLower(name)
+	switch {
+	case strings.Contains(n, "smoke"):
+		return KindSmoke, true
+	case strings.Contains(n, "flash"):
+		return KindFlash, true
+	case n == "he" || strings.Contains(n, "hegrenade") || strings.Contains(n, "he grenade") || strings.Contains(n, "explosive"):
+		return KindHE, true
+	case strings.Contains(n, "molotov") || strings.Contains(n, "incendiary") || n == "inc":
+		return KindMolotov, true
+	case strings.Contains(n, "decoy"):
+		return KindDecoy, true
+	}
+	return "", false
+}
+
+// Constantes de lanzamiento de CS
+const (
+	ThrowSpeed          = 750.0 * 0.9 // Velocidad de lanzamiento de las granadas (750) * 0.9
+	ThrowForwardOffset  = 22.0        // El proyectil sale 22 unidades por delante de los ojos
+	ThrowHeightOffset   = 12.0        // Lanzamiento flojo (clic derecho): 12 unidades más abajo
+	PlayerVelocityScale = 1.25        // Parte de la velocidad del jugador que hereda la granada
+	RunSpeed            = 245.0       // Velocidad máxima con una granada en la mano
+	WalkSpeed           = RunSpeed * 0.52
+	JumpImpulse         = 301.993377 // sv_jump_impulse
+)
+
+// Fuerza del lanzamiento (m_flThrowStrength)
+const (
+	StrengthLeft   = 1.0 // Clic izquierdo
+	StrengthMiddle = 0.5 // Clic izquierdo + derecho
+	StrengthRight  = 0.0 // Clic derecho (lanzamiento flojo)
+)
+
+// Throw describe un lanzamiento
+type Throw struct {
+	Eye            r3.Vector // Posición de los ojos del jugador
+	Pitch          float64   // Grados, positivo = mirando hacia abajo (convención de Source)
+	Yaw            float64   // Grados
+	Strength       float64   // 0 (clic derecho) - 1 (clic izquierdo)
+	PlayerVelocity r3.Vector // Velocidad del jugador al soltar la granada
+}
+
+// Style es un tipo de lanzamiento habitual en lineups
+type Style string
+
+const (
+	StyleStand   Style = "stand"
+	StyleWalk    Style = "walk"
+	StyleRun     Style = "run"
+	StyleJump    Style = "jump"    // Jumpthrow (suelta en el tick del salto)
+	StyleRunJump Style = "runjump" // Corriendo + jumpthrow
+)
+
+// StyleVelocity devuelve la velocidad del jugador para un estilo de lanzamiento
+// (moviéndose hacia donde mira); ok=false si el estilo no existe
+func StyleVelocity(style Style, yaw float64) (r3.Vector, bool) {
+	y := yaw * math.Pi / 180
+	forward := r3.Vector{X: math.Cos(y), Y: math.Sin(y)}
+	switch style {
+	case StyleStand, "":
+		return r3.Vector{}, true
+	case StyleWalk:
+		return forward.Mul(WalkSpeed), true
+	case StyleRun:
+		return forward.Mul(RunSpeed), true
+	case StyleJump:
+		return r3.Vector{Z: JumpImpulse}, true
+	case StyleRunJump:
+		return forward.Mul(RunSpeed).Add(r3.Vector{Z: JumpImpulse}), true
+	}
+	return r3.Vector{}, false
+}
+
+// Release devuelve la posición y velocidad iniciales del proyectil. Con tracer, el
+// desplazamiento de 22 unidades hacia delante se corta contra la geometría (lanzar
+// pegado a una pared); tracer puede ser nil.
+func (t Throw) Release(tracer Tracer) (r3.Vector, r3.Vector) {
+	pitch := normalizeAngle(t.Pitch)
+	// CS corrige el pitch hacia arriba: 10º mirando al frente, 0º mirando recto arriba/abajo
+	pitch -= (90 - math.Abs(pitch)) * 10 / 90
+	forward := angleVector(pitch, t.Yaw)
+
+	strength := math.Max(0, math.Min(1, t.Strength))
+	speed := ThrowSpeed * (strength*0.7 + 0.3)
+
+	src := t.Eye.Add(r3.Vector{Z: strength*ThrowHeightOffset - ThrowHeightOffset})
+	dist := ThrowForwardOffset
+	if tracer != nil {
+		if hit, _, ok := tracer.TraceFor(src, forward, dist, blocksGrenades); ok && hit >= 0 {
+			dist = math.Max(0, hit-HullRadius)
+		}
+	}
+	pos := src.Add(forward.Mul(dist))
+	vel := forward.Mul(speed).Add(t.PlayerVelocity.Mul(PlayerVelocityScale))
+	return pos, vel
+}
+
+// angleVector convierte pitch/yaw (Source) en un vector unitario
+func angleVector(pitch, yaw float64) r3.Vector {
+	p := pitch * math.Pi / 180
+	y := yaw * math.Pi / 180
+	return r3.Vector{
+		X: math.Cos(p) * math.Cos(y),
+		Y: math.Cos(p) * math.Sin(y),
+		Z: -math.Sin(p),
+	}
+}
+
+// normalizeAngle lleva el pitch a (-180, 180] (demoinfocs puede dar 270 = mirando arriba)
+func normalizeAngle(a float64) float64 {
+	a = math.Mod(a, 360)
+	if a > 180 {
+		a -= 360
+	} else if a <= -180 {
+		a += 360
+	}
+	return a
+}
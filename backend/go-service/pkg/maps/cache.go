@@ -0,0 +1,230 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"cs2-demo-service/pkg/geometry"
+)
+
+// CacheDirName es el subdirectorio de mapsDir donde se guardan los BVH precalculados
+const CacheDirName = ".cache"
+
+// mapAssets son los datos de un mapa compartidos entre todos los parses del proceso.
+// Son de solo lectura una vez cargados (raycasts, callouts y nav no los modifican).
+type mapAssets struct {
+	mesh         *geometry.Mesh
+	nav          *NavMesh
+	callouts     []Callout
+	calloutIndex *CalloutIndex // Grid sobre callouts (ver spatial.go)
+	pvs          *PVS          // nil si no hay <mapa>.pvs (ver build_pvs.go)
+
+	fingerprint *MapFingerprint   // nil si no hay <mapa>_fingerprint.json (ver fingerprint.go)
+	fileHashes  map[string]string // sha256 actuales de gltf/nav/places (solo con fingerprint)
+}
+
+// assetEntry es una entrada de la cache; done se cierra cuando la carga termina
+type assetEntry struct {
+	stamp  string
+	done   chan struct{}
+	assets *mapAssets
+	err    error
+}
+
+// sharedAssets es la cache de mapas del proceso (clave: mapsDir + nombre de mapa)
+var sharedAssets = struct {
+	sync.Mutex
+	entries map[string]*assetEntry
+}{entries: make(map[string]*assetEntry)}
+
+// getSharedAssets devuelve los assets del mapa, cargándolos una sola vez por proceso.
+// Si alguno de los ficheros fuente cambia (tamaño/fecha), se recargan.
+func getSharedAssets(mapsDir, baseName string) (*mapAssets, error) {
+	meshPath := FindMeshSource(mapsDir, baseName)
+	if meshPath == "" {
+		return nil, fmt.Errorf("map file not found")
+	}
+	navPath := filepath.Join(mapsDir, baseName, baseName+".nav")
+	placesPath := findPlaces(mapsDir, baseName)
+	stamp := MeshSourceStamp(meshPath) + "|" + fileStamp(navPath) + "|" + fileStamp(placesPath) + "|" + fileStamp(PVSPath(meshPath)) +
+		"|" + fileStamp(FingerprintPath(mapsDir, baseName))
+	key := filepath.Clean(mapsDir) + "|" + baseName
+
+	sharedAssets.Lock()
+	entry, exists := sharedAssets.entries[key]
+	if exists && entry.stamp == stamp {
+		sharedAssets.Unlock()
+		<-entry.done // Otro parse puede estar cargándolo
+		return entry.assets, entry.err
+	}
+	entry = &assetEntry{stamp: stamp, done: make(chan struct{})}
+	sharedAssets.entries[key] = entry
+	sharedAssets.Unlock()
+
+	entry.assets, entry.err = loadMapAssets(mapsDir, baseName, meshPath, navPath, placesPath)
+	close(entry.done)
+
+	if entry.err != nil {
+		// No cachear errores: el siguiente parse lo reintenta
+		sharedAssets.Lock()
+		if sharedAssets.entries[key] == entry {
+			delete(sharedAssets.entries, key)
+		}
+		sharedAssets.Unlock()
+	}
+	return entry.assets, entry.err
+}
+
+// loadMapAssets carga mesh (desde el BVH en disco si está al día), nav y callouts
+func loadMapAssets(mapsDir, baseName, meshPath, navPath, placesPath string) (*mapAssets, error) {
+	fmt.Printf("Loading CS2 Mesh: %s\n", meshPath)
+	mesh, fromCache, err := geometry.LoadMeshCached(meshPath, filepath.Join(mapsDir, CacheDirName))
+	if err != nil {
+		fmt.Printf("Failed to load mesh: %v\n", err)
+		return nil, err
+	}
+	if fromCache {
+		fmt.Printf("CS2 Mesh loaded from BVH cache: %s (%d triangles)\n", baseName, len(mesh.Triangles))
+	} else {
+		fmt.Printf("CS2 Mesh loaded successfully: %s (%d triangles)\n", baseName, len(mesh.Triangles))
+	}
+	assets := &mapAssets{mesh: mesh}
+
+	// Try loading .nav file
+	if _, err := os.Stat(navPath); err == nil {
+		fmt.Printf("Loading Nav Mesh: %s\n", navPath)
+		nav, err := LoadNavMesh(navPath)
+		if err == nil {
+			assets.nav = nav
+			fmt.Printf("Nav Mesh loaded successfully: %d areas, %d places\n", len(nav.Areas), len(nav.Places))
+			if nav.NewerThanKnown() {
+				fmt.Printf("⚠️ Nav Mesh v%d is newer than the last known version (v%d): read with the v%d layout, check the areas\n",
+					nav.Version, NavMaxVersion, NavMaxVersion)
+			}
+		} else {
+			// Sin nav mesh se usa el LastPlaceName() de la demo como fallback
+			fmt.Printf("⚠️ Nav Mesh could not be parsed (using demo fallback): %v\n", err)
+		}
+	} else {
+		fmt.Printf("Nav file not found: %s\n", navPath)
+	}
+
+	// Try loading places.json (CS2 Callouts)
+	// No places.json - will use demo's LastPlaceName() as fallback
+	if placesPath != "" {
+		fmt.Printf("Loading Callouts JSON: %s\n", placesPath)
+		callouts, err := LoadCallouts(placesPath)
+		if err == nil {
+			assets.callouts = callouts
+			assets.calloutIndex = NewCalloutIndex(callouts)
+			fmt.Printf("Callouts loaded successfully: %d places\n", len(callouts))
+
+			// Map seeds to NavMesh if available (antes de compartir la nav)
+			if assets.nav != nil {
+				mapCalloutsToNavMesh(assets.nav, callouts)
+			}
+		} else {
+			fmt.Printf("Failed to load Callouts JSON: %v\n", err)
+		}
+	}
+
+	// Try loading the precomputed PVS (rejects impossible pairs before raycasting)
+	pvsPath := PVSPath(meshPath)
+	if _, err := os.Stat(pvsPath); err == nil {
+		hash, err := geometry.HashMeshSource(meshPath)
+		if err == nil {
+			assets.pvs, err = LoadPVS(pvsPath, hash)
+		}
+		if err != nil {
+			fmt.Printf("⚠️  Ignoring PVS %s: %v\n", pvsPath, err)
+		} else {
+			fmt.Printf("PVS loaded successfully: %d voxels\n", assets.pvs.VoxelCount())
+		}
+	}
+
+	// Fingerprint de la versión del mapa; los hashes se calculan aquí para pagarlos una vez por proceso
+	if fp, err := LoadFingerprint(mapsDir, baseName); err == nil {
+		if assets.fileHashes, err = HashMapFiles(mapsDir, baseName); err != nil {
+			fmt.Printf("⚠️  Could not hash map files for the fingerprint check: %v\n", err)
+		} else {
+			assets.fingerprint = fp
+			fmt.Printf("Map fingerprint loaded: build %d (crc %08x)\n", fp.BuildNum, fp.MapCRC)
+		}
+	} else if !errors.Is(err, ErrNoFingerprint) {
+		fmt.Printf("⚠️  Ignoring map fingerprint: %v\n", err)
+	}
+
+	return assets, nil
+}
+
+// FindMeshSource devuelve la ruta del mesh del mapa (o "" si no existe): el manifest
+// de assets, un GLTF/GLB o, como último recurso, un OBJ (ver geometry.LoadMesh)
+func FindMeshSource(mapsDir, baseName string) string {
+	candidates := []string{
+		// Priority 1: maps/mapName/mapName_assets.json (varios ficheros y grupos físicos)
+		filepath.Join(mapsDir, baseName, baseName+geometry.ManifestFileSuffix),
+		// Priority 2: maps/mapName/mapName_physics.gltf (Source 2 Viewer export)
+		filepath.Join(mapsDir, baseName, baseName+"_physics.gltf"),
+		filepath.Join(mapsDir, baseName, baseName+"_physics.glb"),
+		// Priority 3: maps/mapName/mapName.gltf
+		filepath.Join(mapsDir, baseName, baseName+".gltf"),
+		filepath.Join(mapsDir, baseName, baseName+".glb"),
+		// Priority 4: maps/mapName.gltf (Flat structure)
+		filepath.Join(mapsDir, baseName+".gltf"),
+		filepath.Join(mapsDir, baseName+".glb"),
+		// Priority 5: OBJ fallback
+		filepath.Join(mapsDir, baseName, baseName+"_physics.obj"),
+		filepath.Join(mapsDir, baseName, baseName+".obj"),
+		filepath.Join(mapsDir, baseName+".obj"),
+	}
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// findPlaces devuelve la ruta del places.json del mapa (o "" si no existe)
+func findPlaces(mapsDir, baseName string) string {
+	candidates := []string{
+		// Priority 1: maps/mapName/mapName_places.json
+		filepath.Join(mapsDir, baseName, baseName+"_places.json"),
+		// Priority 2: maps/mapName_places.json
+		filepath.Join(mapsDir, baseName+"_places.json"),
+	}
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// MeshSourceStamp es el fileStamp del mesh y de cada fichero del que se lee (buffers
+// .bin externos de un .gltf, ficheros de un manifest; ver geometry.MeshSourceFiles)
+func MeshSourceStamp(meshPath string) string {
+	var stamp string
+	for i, f := range geometry.MeshSourceFiles(meshPath) {
+		if i > 0 {
+			stamp += "+"
+		}
+		stamp += fileStamp(f)
+	}
+	return stamp
+}
+
+// fileStamp identifica la versión de un fichero (tamaño + fecha), "" si no existe
+func fileStamp(path string) string {
+	if path == "" {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d@%d", info.Size(), info.ModTime().UnixNano())
+}
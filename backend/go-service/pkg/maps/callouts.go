@@ -9,12 +9,23 @@ import (
 	"github.com/golang/geo/r3"
 )
 
-// Callout represents a named area in the map defined by a bounding box or a point
+// Callout represents a named area in the map. Three shapes are supported:
+//   - Polygon: XY polygon extruded between ZMin and ZMax (diagonal areas, stacked levels)
+//   - Bounding box: Min/Max (axis aligned)
+//   - Point seed: X/Y/Z (fallback, nearest seed within 500 units)
+//
+// When shapes overlap, the highest Priority wins (ties: first in the file).
 type Callout struct {
 	Name string `json:"name"`
 	// Bounding Box (Optional)
 	Min *Vector3 `json:"min,omitempty"`
 	Max *Vector3 `json:"max,omitempty"`
+	// Extruded polygon (Optional). ZMin/ZMax nil = unbounded
+	Polygon []Vector2 `json:"polygon,omitempty"`
+	ZMin    *float64  `json:"z_min,omitempty"`
+	ZMax    *float64  `json:"z_max,omitempty"`
+	// Priority resolves overlapping shapes (e.g. "Ramp" over "Lobby")
+	Priority int `json:"priority,omitempty"`
 	// Point Seed (Optional, for .vmap extraction)
 	X float64 `json:"x,omitempty"`
 	Y float64 `json:"y,omitempty"`
@@ -28,8 +39,61 @@ type Vector3 struct {
 	Z float64 `json:"z"`
 }
 
-// Contains checks if a point is inside the callout's bounding box
+// Vector2 is a polygon vertex (XY)
+type Vector2 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// IsPolygon returns true if the callout is an extruded polygon
+func (c *Callout) IsPolygon() bool {
+	return len(c.Polygon) >= 3
+}
+
+// IsBox returns true if the callout is an axis-aligned box
+func (c *Callout) IsBox() bool {
+	return !c.IsPolygon() && c.Min != nil && c.Max != nil
+}
+
+// IsSeed returns true if the callout is a point seed
+func (c *Callout) IsSeed() bool {
+	return !c.IsPolygon() && c.Min == nil
+}
+
+// Bounds returns the XY bounds and Z range of a shape (polygon or box)
+func (c *Callout) Bounds() (min, max r3.Vector) {
+	switch {
+	case c.IsPolygon():
+		min = r3.Vector{X: math.Inf(1), Y: math.Inf(1), Z: math.Inf(-1)}
+		max = r3.Vector{X: math.Inf(-1), Y: math.Inf(-1), Z: math.Inf(1)}
+		for _, v := range c.Polygon {
+			min.X, min.Y = math.Min(min.X, v.X), math.Min(min.Y, v.Y)
+			max.X, max.Y = math.Max(max.X, v.X), math.Max(max.Y, v.Y)
+		}
+		if c.ZMin != nil {
+			min.Z = *c.ZMin
+		}
+		if c.ZMax != nil {
+			max.Z = *c.ZMax
+		}
+	case c.IsBox():
+		min = r3.Vector{X: c.Min.X, Y: c.Min.Y, Z: c.Min.Z}
+		max = r3.Vector{X: c.Max.X, Y: c.Max.Y, Z: c.Max.Z}
+	default:
+		min = r3.Vector{X: c.X, Y: c.Y, Z: c.Z}
+		max = min
+	}
+	return
+}
+
+// Contains checks if a point is inside the callout's shape (polygon or bounding box)
 func (c *Callout) Contains(pos r3.Vector) bool {
+	if c.IsPolygon() {
+		if (c.ZMin != nil && pos.Z < *c.ZMin) || (c.ZMax != nil && pos.Z > *c.ZMax) {
+			return false
+		}
+		return pointInPolygon2D(pos.X, pos.Y, c.Polygon)
+	}
 	if c.Min == nil || c.Max == nil {
 		return false
 	}
@@ -38,6 +102,19 @@ func (c *Callout) Contains(pos r3.Vector) bool {
 		pos.Z >= c.Min.Z && pos.Z <= c.Max.Z
 }
 
+func pointInPolygon2D(x, y float64, poly []Vector2) bool {
+	inside := false
+	j := len(poly) - 1
+	for i := 0; i < len(poly); i++ {
+		if (poly[i].Y > y) != (poly[j].Y > y) &&
+			x < (poly[j].X-poly[i].X)*(y-poly[i].Y)/(poly[j].Y-poly[i].Y)+poly[i].X {
+			inside = !inside
+		}
+		j = i
+	}
+	return inside
+}
+
 // LoadCallouts loads callouts from a JSON file
 func LoadCallouts(filePath string) ([]Callout, error) {
 	file, err := os.Open(filePath)
@@ -54,24 +131,28 @@ func LoadCallouts(filePath string) ([]Callout, error) {
 	return callouts, nil
 }
 
-// FindCallout returns the name of the callout containing the position, or empty string
+// FindCallout returns the name of the callout containing the position, or empty string.
+// Linear scan: for repeated queries use CalloutIndex (same result, see spatial.go)
 func FindCallout(pos r3.Vector, callouts []Callout) string {
-	// 1. Check Bounding Boxes (Priority)
-	// TODO: Optimize this with a spatial index (Octree/Grid) if we have many callouts
-	for _, c := range callouts {
-		if c.Contains(pos) {
-			return c.Name
+	// 1. Check shapes (polygons / boxes): highest priority, then first in the file
+	best := -1
+	for i := range callouts {
+		c := &callouts[i]
+		if (c.IsPolygon() || c.IsBox()) && c.Contains(pos) && (best < 0 || c.Priority > callouts[best].Priority) {
+			best = i
 		}
 	}
+	if best >= 0 {
+		return callouts[best].Name
+	}
 
 	// 2. Check Nearest Point Seed (Fallback for VMap extraction)
-	// Only if we didn't find a bounding box match
+	// Only if we didn't find a shape match
 	var bestName string
-	minDist := 500.0 // Threshold units (approx 12 meters)
+	minDist := calloutSeedRadius
 
 	for _, c := range callouts {
-		// Skip if it's a bounding box (already checked)
-		if c.Min != nil {
+		if !c.IsSeed() {
 			continue
 		}
 
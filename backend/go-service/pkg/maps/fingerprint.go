@@ -0,0 +1,259 @@
+package maps
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cs2-demo-service/pkg/geometry"
+)
+
+// ============================================================================
+// MAP FINGERPRINT
+// CS2 no guarda un CRC del mapa en la demo (el map_crc de CS:GO desapareció), así
+// que la "versión" del mapa visto en la demo se aproxima con lo que sí trae:
+// nombre del mapa, build del juego (CDemoFileHeader.build_num) y addons (mapas
+// del workshop). MapCRC es un CRC32 de esos campos: identifica mapa + build, no
+// el contenido del BSP, así que cualquier parche del juego lo cambia aunque el
+// mapa no se haya tocado.
+//
+// Cada mapa local puede llevar <mapa>/<mapa>_fingerprint.json con el MapCRC de la
+// build de la que se exportaron GLTF/nav/places, los CRC de otras builds con las
+// que se han verificado y el sha256 de cada fichero (ver map_fingerprint.go).
+// ============================================================================
+
+// FingerprintFileSuffix es el sufijo del fichero de fingerprint junto a los assets del mapa
+const FingerprintFileSuffix = "_fingerprint.json"
+
+// ErrNoFingerprint indica que el mapa no tiene <mapa>_fingerprint.json
+var ErrNoFingerprint = errors.New("map fingerprint not found")
+
+// Estados de MapCheck
+const (
+	MapCheckMatch       = "match"       // La demo es de una build verificada y los ficheros no han cambiado
+	MapCheckMismatch    = "mismatch"    // Otro mapa o ficheros locales modificados
+	MapCheckUnverified  = "unverified"  // Ficheros intactos pero la build de la demo no está en el fingerprint (p.ej. tras un parche)
+	MapCheckUnknown     = "unknown"     // Sin fingerprint local o sin info del mapa en la demo
+	MapCheckUnavailable = "unavailable" // Sin mesh (modo heurístico): no hay nada que comparar
+)
+
+// Claves de MapFingerprint.Files
+const (
+	FingerprintGLTF   = "gltf"
+	FingerprintNav    = "nav"
+	FingerprintPlaces = "places"
+)
+
+// DemoMapFingerprint es el mapa tal y como lo anuncia la demo
+type DemoMapFingerprint struct {
+	MapName         string `json:"map_name"`
+	BuildNum        int    `json:"build_num,omitempty"`        // CDemoFileHeader.build_num
+	NetworkProtocol int    `json:"network_protocol,omitempty"` // CDemoFileHeader.network_protocol
+	DemoVersion     string `json:"demo_version,omitempty"`     // CDemoFileHeader.demo_version_name
+	Addons          string `json:"addons,omitempty"`           // Workshop / addons montados (CDemoFileHeader.addons)
+	ManifestHash    string `json:"manifest_hash,omitempty"`    // sha256 (12 hex) de CSVCMsg_ServerInfo.game_session_manifest
+}
+
+// BaseName devuelve el nombre del mapa sin ruta ni extensión (como LoadMap)
+func (d DemoMapFingerprint) BaseName() string {
+	base := filepath.Base(strings.ReplaceAll(d.MapName, "\\", "/"))
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// Known indica si la demo trae info suficiente para identificar la versión del mapa
+func (d DemoMapFingerprint) Known() bool {
+	return d.MapName != "" && d.BuildNum != 0
+}
+
+// CRC devuelve el MapCRC de la demo (0 si no se conoce la build)
+func (d DemoMapFingerprint) CRC() uint32 {
+	if !d.Known() {
+		return 0
+	}
+	return MapCRC(d.BaseName(), d.BuildNum, d.Addons)
+}
+
+// MapCRC calcula el fingerprint de mapa + build + addons
+func MapCRC(baseName string, buildNum int, addons string) uint32 {
+	return crc32.ChecksumIEEE([]byte(strings.ToLower(baseName) + "|" + strconv.Itoa(buildNum) + "|" + addons))
+}
+
+// MapFingerprint es el contenido de <mapa>_fingerprint.json
+type MapFingerprint struct {
+	Map       string            `json:"map"`
+	BuildNum  int               `json:"build_num"`               // Build de la que se exportaron los assets
+	Addons    string            `json:"addons,omitempty"`        // Addons de esa build (mapas del workshop)
+	MapCRC    uint32            `json:"map_crc"`                 // MapCRC(map, build_num, addons)
+	Verified  []uint32          `json:"verified_crcs,omitempty"` // Otras builds comprobadas sin cambios en el mapa
+	Files     map[string]string `json:"files"`                   // gltf/nav/places → sha256
+	CreatedAt string            `json:"created_at,omitempty"`
+}
+
+// Accepts indica si los assets valen para una demo con ese MapCRC
+func (f *MapFingerprint) Accepts(crc uint32) bool {
+	return crc != 0 && (crc == f.MapCRC || slices.Contains(f.Verified, crc))
+}
+
+// MapCheck es el resultado de comparar la demo con los assets locales
+type MapCheck struct {
+	Status   string   `json:"status"`
+	Reasons  []string `json:"reasons,omitempty"`
+	DemoCRC  uint32   `json:"demo_crc,omitempty"`
+	LocalCRC uint32   `json:"local_crc,omitempty"`
+}
+
+// Reliable indica si las métricas que dependen del mesh (visibilidad, wallbangs) son fiables.
+// Solo un mismatch confirmado las invalida: sin fingerprint local o con una build sin
+// verificar no se sabe.
+func (c MapCheck) Reliable() bool {
+	return c.Status != MapCheckMismatch
+}
+
+// FingerprintPath devuelve la ruta del fingerprint de un mapa
+func FingerprintPath(mapsDir, baseName string) string {
+	return filepath.Join(mapsDir, baseName, baseName+FingerprintFileSuffix)
+}
+
+// LoadFingerprint lee el fingerprint del mapa (ErrNoFingerprint si no existe)
+func LoadFingerprint(mapsDir, baseName string) (*MapFingerprint, error) {
+	data, err := os.ReadFile(FingerprintPath(mapsDir, baseName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoFingerprint
+	}
+	if err != nil {
+		return nil, err
+	}
+	var fp MapFingerprint
+	if err := json.Unmarshal(data, &fp); err != nil {
+		return nil, fmt.Errorf("failed to parse map fingerprint: %w", err)
+	}
+	return &fp, nil
+}
+
+// SaveFingerprint escribe el fingerprint del mapa
+func SaveFingerprint(mapsDir, baseName string, fp *MapFingerprint) error {
+	data, err := json.MarshalIndent(fp, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(FingerprintPath(mapsDir, baseName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write map fingerprint: %w", err)
+	}
+	return nil
+}
+
+// NewFingerprint crea el fingerprint de los assets actuales del mapa para la build dada
+func NewFingerprint(mapsDir, baseName string, buildNum int, addons string) (*MapFingerprint, error) {
+	files, err := HashMapFiles(mapsDir, baseName)
+	if err != nil {
+		return nil, err
+	}
+	return &MapFingerprint{
+		Map:       baseName,
+		BuildNum:  buildNum,
+		Addons:    addons,
+		MapCRC:    MapCRC(baseName, buildNum, addons),
+		Files:     files,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// HashMapFiles devuelve el sha256 de los assets del mapa que existan (mesh con sus buffers o ficheros del manifest, nav y places)
+func HashMapFiles(mapsDir, baseName string) (map[string]string, error) {
+	files := make(map[string]string)
+	if meshPath := FindMeshSource(mapsDir, baseName); meshPath != "" {
+		sum, err := geometry.HashMeshSource(meshPath)
+		if err != nil {
+			return nil, err
+		}
+		files[FingerprintGLTF] = hex.EncodeToString(sum[:])
+	}
+	for kind, path := range map[string]string{
+		FingerprintNav:    filepath.Join(mapsDir, baseName, baseName+".nav"),
+		FingerprintPlaces: findPlaces(mapsDir, baseName),
+	} {
+		if path == "" {
+			continue
+		}
+		sum, err := hashFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files[kind] = sum
+	}
+	return files, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CompareFingerprint compara la demo con el fingerprint local; files son los hashes
+// actuales de los assets (HashMapFiles), para detectar ficheros cambiados a mano.
+func CompareFingerprint(demo DemoMapFingerprint, local *MapFingerprint, files map[string]string) MapCheck {
+	check := MapCheck{Status: MapCheckMatch, DemoCRC: demo.CRC()}
+	if local == nil {
+		check.Status = MapCheckUnknown
+		check.Reasons = append(check.Reasons, "no local map fingerprint ("+demo.BaseName()+FingerprintFileSuffix+")")
+		return check
+	}
+	check.LocalCRC = local.MapCRC
+
+	if demo.MapName != "" && !strings.EqualFold(demo.BaseName(), local.Map) {
+		check.Status = MapCheckMismatch
+		check.Reasons = append(check.Reasons, fmt.Sprintf("demo map %s, local assets are %s", demo.BaseName(), local.Map))
+	}
+
+	for _, kind := range []string{FingerprintGLTF, FingerprintNav, FingerprintPlaces} {
+		want, recorded := local.Files[kind]
+		got, present := files[kind]
+		switch {
+		case recorded && !present:
+			check.Status = MapCheckMismatch
+			check.Reasons = append(check.Reasons, kind+" missing since the fingerprint was recorded")
+		case !recorded && present:
+			check.Status = MapCheckMismatch
+			check.Reasons = append(check.Reasons, kind+" added after the fingerprint was recorded")
+		case recorded && want != got:
+			check.Status = MapCheckMismatch
+			check.Reasons = append(check.Reasons, kind+" changed since the fingerprint was recorded")
+		}
+	}
+
+	switch {
+	case !demo.Known():
+		if check.Status == MapCheckMatch {
+			check.Status = MapCheckUnknown
+		}
+		check.Reasons = append(check.Reasons, "demo header has no build number")
+	case !local.Accepts(check.DemoCRC):
+		// El CRC incluye la build: cada parche da uno nuevo aunque no toque el mapa
+		if check.Status == MapCheckMatch {
+			check.Status = MapCheckUnverified
+		}
+		check.Reasons = append(check.Reasons, fmt.Sprintf("demo build %d not verified (assets exported from build %d)", demo.BuildNum, local.BuildNum))
+	}
+	return check
+}
@@ -1,9 +1,9 @@
 package maps
 
 import (
+	"errors"
 	"fmt"
 	"math"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -26,12 +26,62 @@ type VisibilityChecker interface {
 	RayCast(origin, dir r3.Vector, maxDist float64) (float64, r3.Vector)
 }
 
+// PenetrationChecker is implemented by checkers that can estimate wallbang damage.
+// Optional (type-assert from VisibilityChecker): heuristic checkers don't have geometry.
+type PenetrationChecker interface {
+	// EstimatePenetration returns the surfaces between start and end and the damage left; ok=false without a mesh
+	EstimatePenetration(start, end r3.Vector, b geometry.Ballistics) (result geometry.PenetrationResult, ok bool)
+}
+
+// PathFinder is implemented by checkers with a nav mesh (type-assert from VisibilityChecker)
+type PathFinder interface {
+	// FindPath returns the shortest walkable path between two positions; ok=false without nav mesh or path
+	FindPath(from, to r3.Vector) (path NavPath, ok bool)
+}
+
+// OverviewProvider is implemented by checkers that read the radar overview of the map
+// (type-assert from VisibilityChecker)
+type OverviewProvider interface {
+	// Overview returns the radar config of the loaded map, nil if the map has no <map>.txt
+	Overview() *Overview
+}
+
+// FingerprintChecker is implemented by checkers that can tell whether the local map assets
+// match the map version seen in the demo (type-assert from VisibilityChecker)
+type FingerprintChecker interface {
+	// CheckFingerprint compares the demo map with the fingerprint of the loaded assets
+	CheckFingerprint(demo DemoMapFingerprint) MapCheck
+}
+
+// CollisionChecker is implemented by checkers with a mesh that can trace against a single
+// physics group (bullets, players or grenades, see geometry.BlockMask). Type-assert from VisibilityChecker.
+type CollisionChecker interface {
+	// TraceFor returns the distance to the first surface that blocks mask and its normal (-1 if none); ok=false without a mesh
+	TraceFor(origin, dir r3.Vector, maxDist float64, mask geometry.BlockMask) (dist float64, normal r3.Vector, ok bool)
+	// BlockedFor returns true if a surface that blocks mask lies between start and end
+	BlockedFor(start, end r3.Vector, mask geometry.BlockMask) bool
+}
+
+// OcclusionChecker is implemented by checkers with a mesh and answers the cheap pre-checks
+// of multi-point visibility before casting one ray per point (type-assert from VisibilityChecker)
+type OcclusionChecker interface {
+	// PotentiallyVisible returns false only if the PVS guarantees that start and end can't see each other (true without PVS)
+	PotentiallyVisible(start, end r3.Vector) bool
+	// Blocker returns a mesh triangle between start and end (any hit); ok=false if clear or without a mesh
+	Blocker(start, end r3.Vector) (tri geometry.Triangle, ok bool)
+}
+
 // MapManager handles loading maps and performing visibility checks
 type MapManager struct {
 	mapsDir     string
-	currentMesh *geometry.Mesh // Only support GLTF meshes for CS2
+	currentMesh *geometry.Mesh // Physics mesh (GLTF/GLB/OBJ or asset manifest)
 	currentNav  *NavMesh       // Navigation Mesh for callouts
 	callouts    []Callout      // List of named callouts (from places.json)
+	calloutIdx  *CalloutIndex  // Spatial index over callouts (built once per map, shared)
+	currentPVS  *PVS           // Optional precomputed visibility (nil = always raycast)
+	overview    *Overview      // Radar config from <map>.txt (nil = not found)
+	fingerprint *MapFingerprint
+	fileHashes  map[string]string
 	mapName     string
 	mutex       sync.RWMutex
 	useFallback bool // If true, use heuristic (FOV/Smoke) only
@@ -50,8 +100,8 @@ func (m *MapManager) GetCallout(pos r3.Vector) string {
 	defer m.mutex.RUnlock()
 
 	// Priority 1: Check JSON callouts (CS2 specific)
-	if len(m.callouts) > 0 {
-		if name := FindCallout(pos, m.callouts); name != "" {
+	if m.calloutIdx.Len() > 0 {
+		if name := m.calloutIdx.Find(pos); name != "" {
 			return name
 		}
 	}
@@ -74,6 +124,86 @@ func (m *MapManager) RayCast(origin, dir r3.Vector, maxDist float64) (float64, r
 	return -1, r3.Vector{}
 }
 
+// EstimatePenetration estimates bullet penetration from start to end with the given weapon ballistics
+func (m *MapManager) EstimatePenetration(start, end r3.Vector, b geometry.Ballistics) (geometry.PenetrationResult, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.currentMesh == nil || m.useFallback {
+		return geometry.PenetrationResult{}, false
+	}
+	return m.currentMesh.EstimatePenetration(start, end, b), true
+}
+
+// TraceFor casts a ray that only hits surfaces blocking mask (see CollisionChecker)
+func (m *MapManager) TraceFor(origin, dir r3.Vector, maxDist float64, mask geometry.BlockMask) (float64, r3.Vector, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.currentMesh == nil || m.useFallback {
+		return -1, r3.Vector{}, false
+	}
+	dist, normal := m.currentMesh.RayCastFor(origin, dir, maxDist, mask)
+	return dist, normal, true
+}
+
+// BlockedFor returns true if a surface blocking mask lies between start and end (false without a mesh)
+func (m *MapManager) BlockedFor(start, end r3.Vector, mask geometry.BlockMask) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.currentMesh == nil || m.useFallback {
+		return false
+	}
+	return m.currentMesh.RayIntersectsFor(start, end, mask)
+}
+
+// FindPath returns the shortest nav mesh path from -> to, with the callouts it crosses
+func (m *MapManager) FindPath(from, to r3.Vector) (NavPath, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.currentNav == nil {
+		return NavPath{}, false
+	}
+	path, ok := m.currentNav.FindPath(from, to)
+	if !ok || m.calloutIdx.Len() == 0 {
+		return path, ok
+	}
+
+	// Callouts de places.json sobre el centro de cada área (los del .nav como fallback)
+	var callouts []string
+	for _, id := range path.Areas {
+		if area := m.currentNav.AreaByID(id); area != nil {
+			name := m.calloutIdx.Find(area.Center)
+			if name == "" {
+				name = m.currentNav.GetPlaceName(area.Center)
+			}
+			callouts = appendCallout(callouts, name)
+		}
+	}
+	path.Callouts = callouts
+	return path, true
+}
+
+// Overview returns the radar config of the loaded map (nil if there is no <map>.txt)
+func (m *MapManager) Overview() *Overview {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.overview
+}
+
+// CheckFingerprint compares the map version seen in the demo with the loaded assets
+func (m *MapManager) CheckFingerprint(demo DemoMapFingerprint) MapCheck {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.currentMesh == nil || m.useFallback {
+		return MapCheck{Status: MapCheckUnavailable, DemoCRC: demo.CRC()}
+	}
+	return CompareFingerprint(demo, m.fingerprint, m.fileHashes)
+}
+
 // IsLoaded returns true if a map is currently loaded
 func (m *MapManager) IsLoaded() bool {
 	m.mutex.RLock()
@@ -82,7 +212,9 @@ func (m *MapManager) IsLoaded() bool {
 }
 
 // LoadMap attempts to load a map file for the given map name
-// Only supports .gltf (CS2 Physics Hull)
+// The mesh is the CS2 physics hull as .gltf/.glb/.obj or a <map>_assets.json manifest
+// (see FindMeshSource and geometry.LoadMesh). Mesh, nav and callouts are shared by
+// every MapManager in the process (see cache.go), so only the first parse of a map pays the load.
 func (m *MapManager) LoadMap(mapName string) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -96,80 +228,38 @@ func (m *MapManager) LoadMap(mapName string) error {
 	// Remove extension if present (e.g. de_mirage.bsp -> de_mirage)
 	baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
 
-	// 1. Try loading .gltf (CS2)
-	// Priority 1: maps/mapName/mapName_physics.gltf (Source 2 Viewer export)
-	gltfPath := filepath.Join(m.mapsDir, baseName, baseName+"_physics.gltf")
-
-	if _, err := os.Stat(gltfPath); err != nil {
-		// Priority 2: maps/mapName/mapName.gltf
-		gltfPath = filepath.Join(m.mapsDir, baseName, baseName+".gltf")
-	}
-
-	if _, err := os.Stat(gltfPath); err != nil {
-		// Priority 3: maps/mapName.gltf (Flat structure)
-		gltfPath = filepath.Join(m.mapsDir, baseName+".gltf")
+	// El radar no depende de la geometría: se carga también en modo heurístico
+	m.overview = nil
+	if ov, err := LoadOverview(m.mapsDir, baseName); err == nil {
+		m.overview = ov
+		fmt.Printf("Overview loaded: %s (%d levels)\n", ov.Source, len(ov.Levels))
+	} else if !errors.Is(err, ErrNoOverview) {
+		fmt.Printf("⚠️  Overview could not be parsed: %v\n", err)
 	}
 
-	if _, err := os.Stat(gltfPath); err == nil {
-		fmt.Printf("Loading CS2 Mesh (GLTF): %s\n", gltfPath)
-		mesh, err := geometry.LoadGLTF(gltfPath)
-		if err == nil {
-			m.currentMesh = mesh
-			m.mapName = mapName
-			m.useFallback = false
-			fmt.Printf("CS2 Mesh loaded successfully: %s (%d triangles)\n", mapName, len(mesh.Triangles))
-
-			// Try loading .nav file
-			navPath := filepath.Join(m.mapsDir, baseName, baseName+".nav")
-			if _, err := os.Stat(navPath); err == nil {
-				fmt.Printf("Loading Nav Mesh: %s\n", navPath)
-				nav, err := LoadNavMesh(navPath)
-				if err == nil {
-					m.currentNav = nav
-					fmt.Printf("Nav Mesh loaded successfully: %d areas, %d places\n", len(nav.Areas), len(nav.Places))
-				} else {
-					// v36 parsing not fully supported yet - fallback to demo's LastPlaceName() will be used
-					fmt.Printf("Nav Mesh v36 not supported (using demo fallback): %v\n", err)
-				}
-			} else {
-				fmt.Printf("Nav file not found: %s\n", navPath)
-			}
-
-			// Try loading places.json (CS2 Callouts)
-			// Priority 1: maps/mapName/mapName_places.json
-			placesPath := filepath.Join(m.mapsDir, baseName, baseName+"_places.json")
-			if _, err := os.Stat(placesPath); err != nil {
-				// Priority 2: maps/mapName_places.json
-				placesPath = filepath.Join(m.mapsDir, baseName+"_places.json")
-			}
-
-			if _, err := os.Stat(placesPath); err == nil {
-				fmt.Printf("Loading Callouts JSON: %s\n", placesPath)
-				callouts, err := LoadCallouts(placesPath)
-				if err == nil {
-					m.callouts = callouts
-					fmt.Printf("Callouts loaded successfully: %d places\n", len(callouts))
-
-					// Map seeds to NavMesh if available
-					if m.currentNav != nil {
-						m.MapCalloutsToNavMesh(callouts)
-					}
-				} else {
-					fmt.Printf("Failed to load Callouts JSON: %v\n", err)
-				}
-			} else {
-				// No places.json - will use demo's LastPlaceName() as fallback
-				// fmt.Printf("Callouts file not found: %s (using demo fallback)\n", placesPath)
-			}
-
-			return nil
-		}
-		fmt.Printf("Failed to load GLTF: %v\n", err)
+	assets, err := getSharedAssets(m.mapsDir, baseName)
+	if err == nil {
+		m.currentMesh = assets.mesh
+		m.currentNav = assets.nav
+		m.callouts = assets.callouts
+		m.calloutIdx = assets.calloutIndex
+		m.currentPVS = assets.pvs
+		m.fingerprint = assets.fingerprint
+		m.fileHashes = assets.fileHashes
+		m.mapName = mapName
+		m.useFallback = false
+		return nil
 	}
 
 	// Fallback
 	m.useFallback = true
 	m.currentMesh = nil
+	m.currentNav = nil
+	m.callouts = nil
+	m.calloutIdx = nil
+	m.currentPVS = nil
+	m.fingerprint = nil
+	m.fileHashes = nil
 	m.mapName = mapName
 	fmt.Printf("Map file not found. Using Heuristic Mode.\n")
 	return fmt.Errorf("map file not found")
@@ -195,14 +285,43 @@ func (m *MapManager) TraceRay(start, end r3.Vector) bool {
 		return true // Should not happen if IsLoaded() is checked, but safe default
 	}
 
+	// PVS: reject pairs that can never see each other without casting a ray
+	if m.currentPVS != nil && !m.currentPVS.PotentiallyVisible(start, end) {
+		return false
+	}
+
 	// RayIntersects returns true if BLOCKED
 	// So IsVisible = !RayIntersects
 	return !m.currentMesh.RayIntersects(start, end)
 }
 
+// PotentiallyVisible consulta solo el PVS (true si no hay PVS cargado)
+func (m *MapManager) PotentiallyVisible(start, end r3.Vector) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.currentPVS == nil || m.currentPVS.PotentiallyVisible(start, end)
+}
+
+// Blocker devuelve un triángulo del mesh que corta el segmento start-end
+func (m *MapManager) Blocker(start, end r3.Vector) (geometry.Triangle, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.currentMesh == nil {
+		return geometry.Triangle{}, false
+	}
+	return m.currentMesh.RayBlocker(start, end)
+}
+
+// SmokeOccluder answers line-of-sight queries against smokes (see pkg/smoke.Tracker)
+type SmokeOccluder interface {
+	Blocks(from, to r3.Vector, tick int) bool
+}
+
 // HeuristicIsVisible implements the "Option 2" logic: FOV + Smoke + Flash
 // This is static and doesn't need the map file
-func HeuristicIsVisible(shooter, enemy *common.Player, activeSmokes []r3.Vector) bool {
+func HeuristicIsVisible(shooter, enemy *common.Player, smokes SmokeOccluder, tick int) bool {
 	// 1. Basic Radar Check (IsSpottedBy) - REMOVED
 	// We now use Raycasting for wall checks, so we don't rely on IsSpottedBy which can be flaky in CS2.
 	// if !enemy.IsSpottedBy(shooter) {
@@ -260,71 +379,45 @@ func HeuristicIsVisible(shooter, enemy *common.Player, activeSmokes []r3.Vector)
 	}
 
 	// 4. Smoke Check
-	// Simple sphere check: Is there a smoke grenade center close to the line of sight?
-	// Line segment: shooterPos to enemyPos
-	for _, smokePos := range activeSmokes {
-		if distancePointToSegment(smokePos, shooterPos, enemyPos) < 140.0 { // 140 units is approx smoke radius
-			return false
-		}
+	// Volumetric opacity along the line of sight (shooterPos to enemyPos)
+	if smokes != nil && smokes.Blocks(shooterPos, enemyPos, tick) {
+		return false
 	}
 
 	return true
 }
 
-func distancePointToSegment(p, a, b r3.Vector) float64 {
-	ab := b.Sub(a)
-	ap := p.Sub(a)
-
-	// Project p onto ab, computing parameterized position t
-	t := ap.Dot(ab) / ab.Dot(ab)
-
-	// Clamp t to segment [0, 1]
-	if t < 0.0 {
-		t = 0.0
-	} else if t > 1.0 {
-		t = 1.0
-	}
-
-	// Compute nearest point on segment
-	nearest := a.Add(ab.Mul(t))
-
-	// Distance
-	return p.Sub(nearest).Norm()
-}
-
-// MapCalloutsToNavMesh maps point-based callouts to NavMesh PlaceIDs
-func (m *MapManager) MapCalloutsToNavMesh(callouts []Callout) {
-	if m.currentNav == nil {
-		return
-	}
-
+// mapCalloutsToNavMesh maps point-based callouts to NavMesh PlaceIDs.
+// Modifica nav.Places: solo se llama una vez al cargar el mapa (loadMapAssets),
+// antes de que la nav se comparta entre parses.
+func mapCalloutsToNavMesh(nav *NavMesh, callouts []Callout) {
 	// 1. Find Max PlaceID
 	maxPlaceID := uint16(0)
-	for i := range m.currentNav.Areas {
-		if m.currentNav.Areas[i].PlaceID > maxPlaceID {
-			maxPlaceID = m.currentNav.Areas[i].PlaceID
+	for i := range nav.Areas {
+		if nav.Areas[i].PlaceID > maxPlaceID {
+			maxPlaceID = nav.Areas[i].PlaceID
 		}
 	}
 
 	// 2. Initialize Places array
-	if len(m.currentNav.Places) < int(maxPlaceID) {
+	if len(nav.Places) < int(maxPlaceID) {
 		newPlaces := make([]string, maxPlaceID)
-		copy(newPlaces, m.currentNav.Places)
-		m.currentNav.Places = newPlaces
+		copy(newPlaces, nav.Places)
+		nav.Places = newPlaces
 	}
 
 	// 3. Map Seeds to PlaceIDs
 	count := 0
 	for _, c := range callouts {
-		// Only process point seeds (where Min/Max are nil)
-		if c.Min == nil && c.Max == nil {
+		// Only process point seeds (no box / polygon)
+		if c.IsSeed() && c.Max == nil {
 			pos := r3.Vector{X: c.X, Y: c.Y, Z: c.Z}
-			area := m.currentNav.GetNearestArea(pos)
+			area := nav.GetNearestArea(pos)
 			if area != nil && area.PlaceID > 0 {
 				// PlaceID is 1-based
 				idx := int(area.PlaceID) - 1
-				if idx < len(m.currentNav.Places) {
-					m.currentNav.Places[idx] = c.Name
+				if idx < len(nav.Places) {
+					nav.Places[idx] = c.Name
 					count++
 				}
 			}
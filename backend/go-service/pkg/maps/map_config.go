@@ -0,0 +1,76 @@
+package maps
+
+// MapConfig contains the coordinate transformation values
+// Read from the map's overview file (<map>.txt, see ResolveMapConfig); the builtin
+// table below (from demoinfocs-golang examples/_assets/metadata/*.txt) is the fallback
+type MapConfig struct {
+	PosX   float64    `json:"pos_x"`
+	PosY   float64    `json:"pos_y"`
+	Scale  float64    `json:"scale"`
+	Rotate bool       `json:"rotate,omitempty"`
+	Levels []MapLevel `json:"levels,omitempty"` // Vertical sections, bottom to top (multi-level maps)
+	Source string     `json:"source"`           // "overview", "builtin" or "default" (unknown map: positions are approximate)
+}
+
+// MapLevel is a vertical section of the radar (e.g. Nuke "lower"): players with
+// altitude_min <= z < altitude_max are drawn on that level's radar image
+type MapLevel struct {
+	Name        string  `json:"name"`
+	AltitudeMin float64 `json:"altitude_min"`
+	AltitudeMax float64 `json:"altitude_max"`
+}
+
+// Map config sources
+const (
+	MapConfigSourceOverview = "overview"
+	MapConfigSourceBuiltin  = "builtin"
+	MapConfigSourceDefault  = "default"
+)
+
+// ========================================
+// MAP CONFIGS
+// Fallback when the maps directory has no overview file for the map
+// ========================================
+
+// MapConfigs contains pre-defined map metadata for coordinate translation
+var MapConfigs = map[string]MapConfig{
+	"de_dust2":    {PosX: -2476, PosY: 3239, Scale: 4.4},
+	"de_mirage":   {PosX: -3230, PosY: 1713, Scale: 5.0},
+	"de_inferno":  {PosX: -2087, PosY: 3870, Scale: 4.9},
+	"de_ancient":  {PosX: -2953, PosY: 2164, Scale: 5.0},
+	"de_anubis":   {PosX: -2796, PosY: 3328, Scale: 5.22},
+	"de_nuke":     {PosX: -3453, PosY: 2887, Scale: 7.0, Levels: []MapLevel{{Name: "lower", AltitudeMin: -10000, AltitudeMax: -495}, {Name: "default", AltitudeMin: -495, AltitudeMax: 10000}}},
+	"de_overpass": {PosX: -4831, PosY: 1781, Scale: 5.2},
+	"de_vertigo":  {PosX: -3168, PosY: 1762, Scale: 4.0, Levels: []MapLevel{{Name: "lower", AltitudeMin: -10000, AltitudeMax: 11700}, {Name: "default", AltitudeMin: 11700, AltitudeMax: 20000}}},
+	"de_train":    {PosX: -2308, PosY: 2078, Scale: 4.082077},
+	"de_cache":    {PosX: -2000, PosY: 3250, Scale: 5.5},
+}
+
+// GetMapConfig returns the builtin map config for a given map name
+// Returns a default config (Source "default") if map is not found
+func GetMapConfig(mapName string) MapConfig {
+	if config, ok := MapConfigs[mapName]; ok {
+		config.Source = MapConfigSourceBuiltin
+		return config
+	}
+	// Default fallback
+	return MapConfig{PosX: -2500, PosY: 3000, Scale: 5.0, Source: MapConfigSourceDefault}
+}
+
+// ResolveMapConfig returns the config from the map's overview file, or the builtin one if there is none
+func ResolveMapConfig(mapName string, overview *Overview) MapConfig {
+	if overview == nil {
+		return GetMapConfig(mapName)
+	}
+	config := MapConfig{
+		PosX:   overview.PosX,
+		PosY:   overview.PosY,
+		Scale:  overview.Scale,
+		Rotate: overview.Rotate,
+		Source: MapConfigSourceOverview,
+	}
+	for _, l := range overview.Levels {
+		config.Levels = append(config.Levels, MapLevel{Name: l.Name, AltitudeMin: l.AltitudeMin, AltitudeMax: l.AltitudeMax})
+	}
+	return config
+}
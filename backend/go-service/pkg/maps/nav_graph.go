@@ -0,0 +1,112 @@
+package maps
+
+// ============================================================================
+// NAV AREA GRAPH
+// Grafo dirigido de áreas a partir de las conexiones por arista y las
+// escaleras del .nav.
+// ============================================================================
+
+// NavLinkKind es el tipo de enlace entre dos áreas
+type NavLinkKind uint8
+
+const (
+	NavLinkWalk       NavLinkKind = iota // Conexión por arista
+	NavLinkLadderUp                      // Subir por una escalera
+	NavLinkLadderDown                    // Bajar por una escalera
+)
+
+// NavLink es un enlace saliente desde un área
+type NavLink struct {
+	AreaID   uint32
+	Kind     NavLinkKind
+	Edge     int    // Arista de salida (-1 en escaleras)
+	LadderID uint32 // Solo escaleras
+}
+
+// BuildIndex (re)construye el índice por ID y el grid espacial de las áreas.
+// LoadNavMesh ya lo hace; solo hace falta si se modifican las áreas a mano.
+func (nm *NavMesh) BuildIndex() {
+	nm.areaIndex = make(map[uint32]int, len(nm.Areas))
+	for i := range nm.Areas {
+		nm.areaIndex[nm.Areas[i].ID] = i
+	}
+	nm.buildAreaGrid()
+}
+
+// AreaByID devuelve el área con ese ID (nil si no existe)
+func (nm *NavMesh) AreaByID(id uint32) *NavArea {
+	if nm.areaIndex == nil {
+		nm.BuildIndex()
+	}
+	if i, ok := nm.areaIndex[id]; ok {
+		return &nm.Areas[i]
+	}
+	return nil
+}
+
+// LadderByID devuelve la escalera con ese ID (nil si no existe)
+func (nm *NavMesh) LadderByID(id uint32) *NavLadder {
+	for i := range nm.Ladders {
+		if nm.Ladders[i].ID == id {
+			return &nm.Ladders[i]
+		}
+	}
+	return nil
+}
+
+// Neighbors devuelve los enlaces salientes de un área: conexiones por arista
+// (que pueden ser de un solo sentido) y escaleras. Los enlaces a áreas que no
+// existen en el mesh se descartan.
+func (nm *NavMesh) Neighbors(areaID uint32) []NavLink {
+	area := nm.AreaByID(areaID)
+	if area == nil {
+		return nil
+	}
+
+	var links []NavLink
+	for edge, conns := range area.Connections {
+		for _, c := range conns {
+			if nm.AreaByID(c.AreaID) != nil {
+				links = append(links, NavLink{AreaID: c.AreaID, Kind: NavLinkWalk, Edge: edge})
+			}
+		}
+	}
+
+	// Escaleras: desde abajo se llega a las áreas de arriba y viceversa
+	for _, id := range area.LaddersAbove {
+		if l := nm.LadderByID(id); l != nil {
+			for _, top := range l.topAreas() {
+				if top != areaID && nm.AreaByID(top) != nil {
+					links = append(links, NavLink{AreaID: top, Kind: NavLinkLadderUp, Edge: -1, LadderID: id})
+				}
+			}
+		}
+	}
+	for _, id := range area.LaddersBelow {
+		if l := nm.LadderByID(id); l != nil && l.BottomAreaID != 0 && l.BottomAreaID != areaID && nm.AreaByID(l.BottomAreaID) != nil {
+			links = append(links, NavLink{AreaID: l.BottomAreaID, Kind: NavLinkLadderDown, Edge: -1, LadderID: id})
+		}
+	}
+	return links
+}
+
+// IsConnected indica si existe un enlace directo from → to
+func (nm *NavMesh) IsConnected(from, to uint32) bool {
+	for _, l := range nm.Neighbors(from) {
+		if l.AreaID == to {
+			return true
+		}
+	}
+	return false
+}
+
+// topAreas devuelve las áreas no vacías en lo alto de la escalera
+func (l *NavLadder) topAreas() []uint32 {
+	var out []uint32
+	for _, id := range []uint32{l.TopForwardAreaID, l.TopLeftAreaID, l.TopRightAreaID, l.TopBehindAreaID} {
+		if id != 0 {
+			out = append(out, id)
+		}
+	}
+	return out
+}
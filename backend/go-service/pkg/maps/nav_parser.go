@@ -2,495 +2,566 @@ package maps
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"io"
+	"math"
 	"os"
+	"sync"
 
 	"github.com/golang/geo/r3"
 )
 
+// ============================================================================
+// NAV MESH PARSER
+// Soporta CS:GO (Source 1, v16) y CS2 (Source 2, v30+). v36 es la última versión
+// conocida: las posteriores se leen con su layout (NewerThanKnown avisa de ello).
+//
+// Layout Source 2 (little-endian):
+//
+//	header:  magic u32, version u32, subversion u32, analyzed flags u32
+//	polys:   (v31+) corner count u32 + vec3[], polygon count u32 +
+//	         { u8 n, u32 idx[n], (v35+) u32 unk }
+//	unk:     (v32+) u32, (v35+) u32
+//	areas:   count u32 + { id u32, attr flags u64, hull u8,
+//	         polygon u32 (v31+) | corner count u32 + vec3[] (v30),
+//	         f32 unk, per edge { count u32 + (area u32, edge u32)[] },
+//	         legacy hiding spots, legacy encounter paths,
+//	         ladders above u32 + ids, ladders below u32 + ids }
+//	ladders: count u32 + { id u32, width f32, top vec3, bottom vec3,
+//	         length f32, direction u32, 5 x area u32 }
+//
+// Lo que va detrás de las escaleras (parámetros de generación en KV3) no se lee.
+// CS2 no guarda nombres de sitio en el .nav: los callouts vienen de places.json.
+// ============================================================================
+
+const (
+	navMagic = 0xFEEDFACE
+
+	NavVersionSource1   = 16 // CS:GO
+	NavMinVersionSource = 30 // Primera versión Source 2
+	NavMaxVersion       = 36 // Última versión conocida (las posteriores se leen con su layout)
+)
+
+// Errores del parser (usar errors.Is sobre el error devuelto por LoadNavMesh)
+var (
+	ErrInvalidNavMagic       = errors.New("invalid nav magic")
+	ErrUnsupportedNavVersion = errors.New("unsupported nav version")
+	ErrCorruptNav            = errors.New("corrupt nav file")
+)
+
+// NavParseError indica qué campo falló y dónde
+type NavParseError struct {
+	Offset int64  // Offset en bytes donde se produjo el error
+	Area   int    // Índice del área (-1 = fuera de la lista de áreas)
+	Field  string // Campo que se estaba leyendo
+	Err    error
+}
+
+func (e *NavParseError) Error() string {
+	if e.Area >= 0 {
+		return fmt.Sprintf("nav: area %d: %s at offset %d: %v", e.Area, e.Field, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("nav: %s at offset %d: %v", e.Field, e.Offset, e.Err)
+}
+
+func (e *NavParseError) Unwrap() error { return e.Err }
+
+// NavConnection es un enlace dirigido a otra área
+type NavConnection struct {
+	AreaID uint32
+	EdgeID uint32 // Arista de llegada en el área destino (solo Source 2)
+}
+
+// NavHidingSpot es un hiding spot (Source 1; en CS2 suele venir vacío)
+type NavHidingSpot struct {
+	ID       uint32
+	Position r3.Vector
+	Flags    uint8
+}
+
+// NavEncounterSpot es un punto a lo largo de un encounter path
+type NavEncounterSpot struct {
+	OrderID uint32
+	T       float64 // Distancia paramétrica (0-1)
+}
+
+// NavEncounterPath describe una ruta entrada→salida a través del área
+type NavEncounterPath struct {
+	FromAreaID uint32
+	FromDir    uint8
+	ToAreaID   uint32
+	ToDir      uint8
+	Spots      []NavEncounterSpot
+}
+
+// NavLadder es una escalera entre áreas
+type NavLadder struct {
+	ID               uint32
+	Width            float64
+	Length           float64
+	Top              r3.Vector
+	Bottom           r3.Vector
+	Direction        uint32
+	TopForwardAreaID uint32
+	TopLeftAreaID    uint32
+	TopRightAreaID   uint32
+	TopBehindAreaID  uint32
+	BottomAreaID     uint32
+}
+
 // NavArea represents a navigation area
 type NavArea struct {
-	ID      uint32
-	NW      r3.Vector // Used for v16 (AABB)
-	SE      r3.Vector // Used for v16 (AABB)
-	PlaceID uint16
+	ID        uint32
+	NW        r3.Vector // AABB min (v16: esquina NW)
+	SE        r3.Vector // AABB max (v16: esquina SE)
+	PlaceID   uint16    // 1-based; 0 = sin sitio
+	Flags     uint64    // Attribute flags
+	HullIndex uint8     // Source 2
 
-	// v35 Geometry
 	Corners []r3.Vector
 	Center  r3.Vector
+
+	// Connections[i] son los enlaces salientes por la arista i
+	// (Source 1: direcciones N, E, S, W)
+	Connections    [][]NavConnection
+	LaddersAbove   []uint32 // IDs de NavLadder
+	LaddersBelow   []uint32
+	HidingSpots    []NavHidingSpot
+	EncounterPaths []NavEncounterPath
 }
 
 // NavMesh represents the navigation mesh
 type NavMesh struct {
-	Places []string
-	Areas  []NavArea
+	Version    uint32
+	SubVersion uint32
+	IsAnalyzed bool
 
-	// Internal data for v35 reconstruction
-	corners  []r3.Vector
-	polygons []navPolygon
-}
+	Places  []string
+	Areas   []NavArea
+	Ladders []NavLadder
 
-type navPolygon struct {
-	indices []uint32
+	areaIndex map[uint32]int // Area ID -> índice en Areas
+	areaGrid  *grid2D        // Índice espacial de los polígonos (ver spatial.go)
+
+	// Caché de caminos (ver nav_path.go); el mesh se comparte entre parses
+	pathMu    sync.Mutex
+	pathCache map[[2]uint32][]navStep
 }
 
-// LoadNavMesh loads a .nav file (Supports CS:GO v16 and CS2 v35)
+// LoadNavMesh loads a .nav file (CS:GO v16 and CS2 v30+)
 func LoadNavMesh(path string) (*NavMesh, error) {
-	f, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	mesh := &NavMesh{}
+	return ParseNavMesh(data)
+}
 
-	// Debug: Print first 32 bytes
-	headerBuf := make([]byte, 32)
-	f.Read(headerBuf)
-	fmt.Printf("Header Hex: %x\n", headerBuf)
-	f.Seek(0, 0)
+// ParseNavMesh parsea el contenido de un .nav. No escribe nada en stdout; los
+// fallos se devuelven como *NavParseError (envolviendo ErrCorruptNav, etc.)
+func ParseNavMesh(data []byte) (*NavMesh, error) {
+	r := &navReader{data: data, area: -1}
 
-	// Read Magic
-	var magic uint32
-	if err := binary.Read(f, binary.LittleEndian, &magic); err != nil {
-		return nil, err
+	if magic := r.u32("magic"); r.err == nil && magic != navMagic {
+		return nil, r.fail("magic", fmt.Errorf("%w: %#x", ErrInvalidNavMagic, magic))
 	}
-	if magic != 0xFEEDFACE {
-		return nil, fmt.Errorf("invalid magic number: %x", magic)
+	mesh := &NavMesh{}
+	mesh.Version = r.u32("version")
+	if r.err != nil {
+		return nil, r.err
 	}
-
-	// Read Version
-	var version uint32
-	if err := binary.Read(f, binary.LittleEndian, &version); err != nil {
-		return nil, err
+	v := mesh.Version
+	if v != NavVersionSource1 && v < NavMinVersionSource {
+		return nil, r.fail("version", fmt.Errorf("%w: %d", ErrUnsupportedNavVersion, v))
 	}
-	fmt.Printf("Version: %d\n", version)
+	mesh.SubVersion = r.u32("subversion")
 
-	// SubVersion
-	var subVersion uint32
-	if err := binary.Read(f, binary.LittleEndian, &subVersion); err != nil {
-		return nil, err
+	var err error
+	if v == NavVersionSource1 {
+		err = parseSource1(r, mesh)
+	} else {
+		err = parseSource2(r, mesh)
 	}
-	fmt.Printf("SubVersion: %d\n", subVersion)
-
-	// BspSize
-	var bspSize uint32
-	if err := binary.Read(f, binary.LittleEndian, &bspSize); err != nil {
+	if err != nil {
+		if mesh.NewerThanKnown() {
+			return nil, fmt.Errorf("nav v%d is newer than v%d (read with its layout): %w", v, NavMaxVersion, err)
+		}
 		return nil, err
 	}
-	fmt.Printf("BspSize: %d\n", bspSize)
+	mesh.BuildIndex()
+	return mesh, nil
+}
 
-	// --- VERSION SPECIFIC PARSING ---
+// NewerThanKnown indica que la versión es posterior a NavMaxVersion: se ha leído con
+// el layout de NavMaxVersion y, aunque parsee, conviene revisar el resultado
+func (nm *NavMesh) NewerThanKnown() bool {
+	return nm.Version > NavMaxVersion
+}
 
-	if version >= 31 {
-		// CS2 (Source 2) Logic
-		// In v35, it seems IsAnalyzed and PlaceCount are NOT present immediately after BspSize.
-		// Or at least, the data following BspSize looks like CornerCount (0x10A9 = 4265).
-		// So we jump straight to version specific parsing.
-		return parseSource2(f, mesh, version)
-	}
+// --- Source 1 (CS:GO v16) ---
 
-	// IsAnalyzed (Only for v16 / Source 1)
-	var isAnalyzed uint8
-	if err := binary.Read(f, binary.LittleEndian, &isAnalyzed); err != nil {
-		return nil, err
-	}
-	fmt.Printf("IsAnalyzed: %d\n", isAnalyzed)
+func parseSource1(r *navReader, mesh *NavMesh) error {
+	r.u32("bsp size")
+	mesh.IsAnalyzed = r.u8("analyzed flag") != 0
 
-	// PlaceCount
-	var placeCount uint16
-	if err := binary.Read(f, binary.LittleEndian, &placeCount); err != nil {
-		return nil, err
-	}
-	fmt.Printf("Place Count: %d\n", placeCount)
-
-	// Places
+	placeCount := r.count16("place count", 2)
 	mesh.Places = make([]string, placeCount)
-	for i := 0; i < int(placeCount); i++ {
-		var len uint16
-		if err := binary.Read(f, binary.LittleEndian, &len); err != nil {
-			return nil, err
-		}
-
-		buf := make([]byte, len)
-		if err := binary.Read(f, binary.LittleEndian, &buf); err != nil {
-			return nil, err
-		}
+	for i := range mesh.Places {
+		n := r.count16("place name length", 1)
+		name := r.bytes("place name", n)
 		// Remove null terminator if present
-		if len > 0 && buf[len-1] == 0 {
-			mesh.Places[i] = string(buf[:len-1])
-		} else {
-			mesh.Places[i] = string(buf)
+		if len(name) > 0 && name[len(name)-1] == 0 {
+			name = name[:len(name)-1]
 		}
+		mesh.Places[i] = string(name)
 	}
+	r.u8("has unnamed areas")
 
-	if version == 16 {
-		// CS:GO (Source 1) Logic
-		return parseSource1(f, mesh)
+	areaCount := r.count32("area count", 4)
+	if r.err != nil {
+		return r.err
 	}
-
-	return nil, fmt.Errorf("unsupported nav version: %d", version)
-}
-
-func parseSource1(r io.Reader, mesh *NavMesh) (*NavMesh, error) {
-	// AreaCount
-	var areaCount uint32
-	if err := binary.Read(r, binary.LittleEndian, &areaCount); err != nil {
-		return nil, err
-	}
-
-	mesh.Areas = make([]NavArea, 0, areaCount)
-
-	for i := 0; i < int(areaCount); i++ {
-		area := NavArea{}
-
-		// ID
-		if err := binary.Read(r, binary.LittleEndian, &area.ID); err != nil {
-			return nil, err
-		}
-
-		// Flags
-		var flags uint32
-		if err := binary.Read(r, binary.LittleEndian, &flags); err != nil {
-			return nil, err
-		}
-
-		// NW, SE, NE Z, SW Z
-		var nwX, nwY, nwZ float32
-		var seX, seY, seZ float32
-		var neZ, swZ float32
-
-		binary.Read(r, binary.LittleEndian, &nwX)
-		binary.Read(r, binary.LittleEndian, &nwY)
-		binary.Read(r, binary.LittleEndian, &nwZ)
-		binary.Read(r, binary.LittleEndian, &seX)
-		binary.Read(r, binary.LittleEndian, &seY)
-		binary.Read(r, binary.LittleEndian, &seZ)
-		binary.Read(r, binary.LittleEndian, &neZ)
-		binary.Read(r, binary.LittleEndian, &swZ)
-
-		area.NW = r3.Vector{X: float64(nwX), Y: float64(nwY), Z: float64(nwZ)}
-		area.SE = r3.Vector{X: float64(seX), Y: float64(seY), Z: float64(seZ)}
-
-		// Calculate corners for unified interface
+	mesh.Areas = make([]NavArea, areaCount)
+	for i := range mesh.Areas {
+		r.area = i
+		area := &mesh.Areas[i]
+		area.ID = r.u32("id")
+		area.Flags = uint64(r.u32("attribute flags"))
+		nw := r.vec3("nw corner")
+		se := r.vec3("se corner")
+		neZ := float64(r.f32("ne z"))
+		swZ := float64(r.f32("sw z"))
+		area.NW, area.SE = nw, se
 		area.Corners = []r3.Vector{
-			{X: float64(nwX), Y: float64(nwY), Z: float64(nwZ)}, // NW
-			{X: float64(seX), Y: float64(nwY), Z: float64(neZ)}, // NE
-			{X: float64(seX), Y: float64(seY), Z: float64(seZ)}, // SE
-			{X: float64(nwX), Y: float64(seY), Z: float64(swZ)}, // SW
+			nw,                         // NW
+			{X: se.X, Y: nw.Y, Z: neZ}, // NE
+			se,                         // SE
+			{X: nw.X, Y: se.Y, Z: swZ}, // SW
 		}
 
 		// Connections (4 directions: N, E, S, W)
-		for dir := 0; dir < 4; dir++ {
-			var count uint32
-			binary.Read(r, binary.LittleEndian, &count)
-			for k := 0; k < int(count); k++ {
-				var connectID uint32
-				binary.Read(r, binary.LittleEndian, &connectID)
+		area.Connections = make([][]NavConnection, 4)
+		for dir := range area.Connections {
+			n := r.count32("connection count", 4)
+			conns := make([]NavConnection, n)
+			for k := range conns {
+				conns[k].AreaID = r.u32("connection area")
 			}
+			area.Connections[dir] = conns
 		}
 
-		// Hiding Spots
-		var hidingCount uint8
-		binary.Read(r, binary.LittleEndian, &hidingCount)
-		for k := 0; k < int(hidingCount); k++ {
-			var hidID uint32
-			binary.Read(r, binary.LittleEndian, &hidID)
-			var hidFlags uint8
-			binary.Read(r, binary.LittleEndian, &hidFlags)
-			var hx, hy, hz float32
-			binary.Read(r, binary.LittleEndian, &hx)
-			binary.Read(r, binary.LittleEndian, &hy)
-			binary.Read(r, binary.LittleEndian, &hz)
+		area.HidingSpots = readHidingSpots(r)
+		area.EncounterPaths = readEncounterPaths(r)
+		area.PlaceID = r.u16("place id")
+		area.LaddersAbove = r.ids("ladders up")
+		area.LaddersBelow = r.ids("ladders down")
+
+		r.skip("earliest occupy time", 2*4)
+		r.skip("light intensity", 4*4)
+		visible := r.count32("visible area count", 5)
+		r.skip("visible areas", visible*5)
+		r.u32("inherit visibility from")
+		custom := int(r.u8("custom data count"))
+		r.skip("custom data", custom*14)
+
+		if r.err != nil {
+			return r.err
 		}
+		area.Center = polygonCenter(area.Corners)
+	}
+	r.area = -1
 
-		// Encounter Paths
-		var encounterCount uint32
-		binary.Read(r, binary.LittleEndian, &encounterCount)
-		for k := 0; k < int(encounterCount); k++ {
-			var fromID, fromDir, toID, toDir uint32
-			var spotCount uint8
-			binary.Read(r, binary.LittleEndian, &fromID)
-			binary.Read(r, binary.LittleEndian, &fromDir)
-			binary.Read(r, binary.LittleEndian, &toID)
-			binary.Read(r, binary.LittleEndian, &toDir)
-			binary.Read(r, binary.LittleEndian, &spotCount)
-			for s := 0; s < int(spotCount); s++ {
-				var orderID uint32
-				var t float32
-				binary.Read(r, binary.LittleEndian, &orderID)
-				binary.Read(r, binary.LittleEndian, &t)
+	return readLadders(r, mesh)
+}
+
+// --- Source 2 (CS2 v30+) ---
+
+func parseSource2(r *navReader, mesh *NavMesh) error {
+	v := mesh.Version
+	mesh.IsAnalyzed = r.u32("analyzed flags")&1 != 0
+
+	var polygons [][]r3.Vector
+	if v >= 31 {
+		cornerCount := r.count32("corner count", 12)
+		corners := make([]r3.Vector, cornerCount)
+		for i := range corners {
+			corners[i] = r.vec3("corner")
+		}
+
+		polyCount := r.count32("polygon count", 1)
+		polygons = make([][]r3.Vector, polyCount)
+		for i := range polygons {
+			n := int(r.u8("polygon corner count"))
+			poly := make([]r3.Vector, n)
+			for k := range poly {
+				idx := r.u32("polygon corner index")
+				if r.err == nil && int(idx) >= len(corners) {
+					return r.fail("polygon corner index", fmt.Errorf("%w: corner %d of %d", ErrCorruptNav, idx, len(corners)))
+				}
+				if r.err == nil {
+					poly[k] = corners[idx]
+				}
+			}
+			if v >= 35 {
+				r.u32("polygon unk")
 			}
+			polygons[i] = poly
 		}
+	}
+	if v >= 32 {
+		r.u32("unk")
+	}
+	if v >= 35 {
+		r.u32("unk")
+	}
 
-		// PlaceID
-		if err := binary.Read(r, binary.LittleEndian, &area.PlaceID); err != nil {
-			return nil, err
+	areaCount := r.count32("area count", 4)
+	if r.err != nil {
+		return r.err
+	}
+	mesh.Areas = make([]NavArea, areaCount)
+	for i := range mesh.Areas {
+		r.area = i
+		area := &mesh.Areas[i]
+		area.ID = r.u32("id")
+		area.Flags = r.u64("attribute flags")
+		area.HullIndex = r.u8("hull index")
+
+		if v >= 31 {
+			idx := r.u32("polygon index")
+			if r.err == nil && int(idx) >= len(polygons) {
+				return r.fail("polygon index", fmt.Errorf("%w: polygon %d of %d", ErrCorruptNav, idx, len(polygons)))
+			}
+			if r.err == nil {
+				area.Corners = polygons[idx]
+			}
+		} else {
+			n := r.count32("corner count", 12)
+			area.Corners = make([]r3.Vector, n)
+			for k := range area.Corners {
+				area.Corners[k] = r.vec3("corner")
+			}
 		}
+		r.f32("unk")
 
-		// Skip Ladder Connections (2 directions)
-		for dir := 0; dir < 2; dir++ {
-			var count uint32
-			binary.Read(r, binary.LittleEndian, &count)
-			for k := 0; k < int(count); k++ {
-				var connectID uint32
-				binary.Read(r, binary.LittleEndian, &connectID)
+		// Connections (una lista por arista)
+		area.Connections = make([][]NavConnection, len(area.Corners))
+		for e := range area.Connections {
+			n := r.count32("connection count", 8)
+			conns := make([]NavConnection, n)
+			for k := range conns {
+				conns[k].AreaID = r.u32("connection area")
+				conns[k].EdgeID = r.u32("connection edge")
 			}
+			area.Connections[e] = conns
 		}
 
-		// Earliest Occupier (2 teams)
-		for team := 0; team < 2; team++ {
-			var time float32
-			binary.Read(r, binary.LittleEndian, &time)
-		}
+		area.HidingSpots = readHidingSpots(r)
+		area.EncounterPaths = readEncounterPaths(r)
+		area.LaddersAbove = r.ids("ladders above")
+		area.LaddersBelow = r.ids("ladders below")
 
-		mesh.Areas = append(mesh.Areas, area)
+		if r.err != nil {
+			return r.err
+		}
+		area.NW, area.SE = cornerBounds(area.Corners)
+		area.Center = polygonCenter(area.Corners)
 	}
+	r.area = -1
 
-	return mesh, nil
+	return readLadders(r, mesh)
 }
 
-func parseSource2(r io.Reader, mesh *NavMesh, version uint32) (*NavMesh, error) {
-	fmt.Printf("Parsing Source 2 Nav Mesh (Version %d)\n", version)
-	// 1. Read Corners
-	var cornerCount uint32
-	if err := binary.Read(r, binary.LittleEndian, &cornerCount); err != nil {
-		return nil, fmt.Errorf("failed to read corner count: %v", err)
-	}
-	fmt.Printf("Corner Count: %d\n", cornerCount)
+// --- Secciones comunes ---
 
-	mesh.corners = make([]r3.Vector, cornerCount)
-	for i := 0; i < int(cornerCount); i++ {
-		var x, y, z float32
-		if err := binary.Read(r, binary.LittleEndian, &x); err != nil {
-			return nil, fmt.Errorf("failed to read corner X at %d: %v", i, err)
-		}
-		if err := binary.Read(r, binary.LittleEndian, &y); err != nil {
-			return nil, fmt.Errorf("failed to read corner Y at %d: %v", i, err)
-		}
-		if err := binary.Read(r, binary.LittleEndian, &z); err != nil {
-			return nil, fmt.Errorf("failed to read corner Z at %d: %v", i, err)
-		}
-		mesh.corners[i] = r3.Vector{X: float64(x), Y: float64(y), Z: float64(z)}
+func readHidingSpots(r *navReader) []NavHidingSpot {
+	n := int(r.u8("hiding spot count"))
+	if n == 0 {
+		return nil
 	}
-
-	if f, ok := r.(*os.File); ok {
-		offset, _ := f.Seek(0, io.SeekCurrent)
-		fmt.Printf("Offset after corners: %d\n", offset)
+	spots := make([]NavHidingSpot, n)
+	for i := range spots {
+		spots[i].ID = r.u32("hiding spot id")
+		spots[i].Position = r.vec3("hiding spot position")
+		spots[i].Flags = r.u8("hiding spot flags")
 	}
+	return spots
+}
 
-	// 2. Read Polygons
-	var polygonCount uint32
-	if err := binary.Read(r, binary.LittleEndian, &polygonCount); err != nil {
-		return nil, fmt.Errorf("failed to read polygon count: %v", err)
+func readEncounterPaths(r *navReader) []NavEncounterPath {
+	n := r.count32("encounter path count", 11)
+	if n == 0 {
+		return nil
 	}
-	fmt.Printf("Polygon Count: %d\n", polygonCount)
-
-	mesh.polygons = make([]navPolygon, polygonCount)
-	for i := 0; i < int(polygonCount); i++ {
-		var polyCornerCount uint8
-		if err := binary.Read(r, binary.LittleEndian, &polyCornerCount); err != nil {
-			return nil, fmt.Errorf("failed to read poly corner count at %d: %v", i, err)
+	paths := make([]NavEncounterPath, n)
+	for i := range paths {
+		p := &paths[i]
+		p.FromAreaID = r.u32("encounter from area")
+		p.FromDir = r.u8("encounter from direction")
+		p.ToAreaID = r.u32("encounter to area")
+		p.ToDir = r.u8("encounter to direction")
+		spots := int(r.u8("encounter spot count"))
+		p.Spots = make([]NavEncounterSpot, spots)
+		for k := range p.Spots {
+			p.Spots[k].OrderID = r.u32("encounter spot id")
+			p.Spots[k].T = float64(r.u8("encounter spot t")) / 255
 		}
+	}
+	return paths
+}
 
-		indices := make([]uint32, polyCornerCount)
-		if err := binary.Read(r, binary.LittleEndian, &indices); err != nil {
-			return nil, fmt.Errorf("failed to read poly indices at %d: %v", i, err)
-		}
-		mesh.polygons[i] = navPolygon{indices: indices}
-
-		// Skip UnkPoly (Version >= 35)
-		if version >= 35 {
-			var unkPoly uint32
-			if err := binary.Read(r, binary.LittleEndian, &unkPoly); err != nil {
-				return nil, fmt.Errorf("failed to read unkPoly at %d: %v", i, err)
-			}
-		}
+// readLadders lee la lista final de escaleras (mismo layout en v16 y Source 2)
+func readLadders(r *navReader, mesh *NavMesh) error {
+	n := r.count32("ladder count", 60)
+	mesh.Ladders = make([]NavLadder, n)
+	for i := range mesh.Ladders {
+		l := &mesh.Ladders[i]
+		l.ID = r.u32("ladder id")
+		l.Width = float64(r.f32("ladder width"))
+		l.Top = r.vec3("ladder top")
+		l.Bottom = r.vec3("ladder bottom")
+		l.Length = float64(r.f32("ladder length"))
+		l.Direction = r.u32("ladder direction")
+		l.TopForwardAreaID = r.u32("ladder top forward area")
+		l.TopLeftAreaID = r.u32("ladder top left area")
+		l.TopRightAreaID = r.u32("ladder top right area")
+		l.TopBehindAreaID = r.u32("ladder top behind area")
+		l.BottomAreaID = r.u32("ladder bottom area")
 	}
+	return r.err
+}
 
-	// 3. Global Unknowns
-	if version >= 32 {
-		var unk2 uint32
-		if err := binary.Read(r, binary.LittleEndian, &unk2); err != nil {
-			return nil, fmt.Errorf("failed to read unk2: %v", err)
-		}
+func cornerBounds(corners []r3.Vector) (min, max r3.Vector) {
+	if len(corners) == 0 {
+		return
 	}
-	if version >= 35 {
-		var unk3 uint32
-		if err := binary.Read(r, binary.LittleEndian, &unk3); err != nil {
-			return nil, fmt.Errorf("failed to read unk3: %v", err)
-		}
+	min, max = corners[0], corners[0]
+	for _, c := range corners[1:] {
+		min = r3.Vector{X: math.Min(min.X, c.X), Y: math.Min(min.Y, c.Y), Z: math.Min(min.Z, c.Z)}
+		max = r3.Vector{X: math.Max(max.X, c.X), Y: math.Max(max.Y, c.Y), Z: math.Max(max.Z, c.Z)}
 	}
+	return
+}
 
-	// 4. Areas
-	var areaCount uint32
-	if err := binary.Read(r, binary.LittleEndian, &areaCount); err != nil {
-		return nil, fmt.Errorf("failed to read area count: %v", err)
+func polygonCenter(corners []r3.Vector) r3.Vector {
+	var c r3.Vector
+	if len(corners) == 0 {
+		return c
 	}
-	fmt.Printf("Area Count: %d\n", areaCount)
-
-	mesh.Areas = make([]NavArea, areaCount)
-	for i := 0; i < int(areaCount); i++ {
-		area := NavArea{}
-
-		// Determine Byte Order
-		// Area 0 is Little Endian. Area 1+ seems to be Big Endian in v35?
-		var order binary.ByteOrder = binary.LittleEndian
-		if i > 0 {
-			order = binary.BigEndian
-		}
-
-		// ID
-		if err := binary.Read(r, order, &area.ID); err != nil {
-			return nil, fmt.Errorf("failed to read area ID at %d: %v", i, err)
-		}
-
-		var flags uint64
-		var hullIndex uint32
-		var polygonIndex uint32
-		var unkArea uint32
-		var padding uint8
-
-		var flags32 uint32
-		if err := binary.Read(r, order, &flags32); err != nil {
-			return nil, fmt.Errorf("failed to read flags at %d: %v", i, err)
-		}
-		flags = uint64(flags32)
+	for _, p := range corners {
+		c = c.Add(p)
+	}
+	return c.Mul(1 / float64(len(corners)))
+}
 
-		if err := binary.Read(r, order, &hullIndex); err != nil {
-			return nil, fmt.Errorf("failed to read hullIndex at %d: %v", i, err)
-		}
+// --- Lector binario ---
 
-		if err := binary.Read(r, order, &polygonIndex); err != nil {
-			return nil, fmt.Errorf("failed to read polygonIndex at %d: %v", i, err)
-		}
+// navReader lee little-endian sobre el fichero en memoria. El primer error se
+// guarda (con campo y offset) y las lecturas posteriores devuelven cero.
+type navReader struct {
+	data []byte
+	off  int
+	area int // Área en curso para los errores (-1 = ninguna)
+	err  error
+}
 
-		if err := binary.Read(r, order, &unkArea); err != nil {
-			return nil, fmt.Errorf("failed to read unkArea at %d: %v", i, err)
-		}
+func (r *navReader) fail(field string, err error) error {
+	if r.err == nil {
+		r.err = &NavParseError{Offset: int64(r.off), Area: r.area, Field: field, Err: err}
+	}
+	return r.err
+}
 
-		// Padding (1 byte)
-		if err := binary.Read(r, binary.LittleEndian, &padding); err != nil {
-			return nil, fmt.Errorf("failed to read padding at %d: %v", i, err)
-		}
+func (r *navReader) next(field string, n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.data)-r.off < n {
+		r.fail(field, fmt.Errorf("%w: need %d bytes, %d left", ErrCorruptNav, n, len(r.data)-r.off))
+		return nil
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
 
-		// Reconstruct corners
-		if int(polygonIndex) < len(mesh.polygons) {
-			poly := mesh.polygons[polygonIndex]
-			area.Corners = make([]r3.Vector, len(poly.indices))
-			for k, idx := range poly.indices {
-				if int(idx) < len(mesh.corners) {
-					area.Corners[k] = mesh.corners[idx]
-				}
-			}
-			// Calculate AABB for compatibility
-			minX, minY, minZ := 1e9, 1e9, 1e9
-			maxX, maxY, maxZ := -1e9, -1e9, -1e9
-			for _, c := range area.Corners {
-				if c.X < minX {
-					minX = c.X
-				}
-				if c.Y < minY {
-					minY = c.Y
-				}
-				if c.Z < minZ {
-					minZ = c.Z
-				}
-				if c.X > maxX {
-					maxX = c.X
-				}
-				if c.Y > maxY {
-					maxY = c.Y
-				}
-				if c.Z > maxZ {
-					maxZ = c.Z
-				}
-			}
-			area.NW = r3.Vector{X: minX, Y: minY, Z: minZ}
-			area.SE = r3.Vector{X: maxX, Y: maxY, Z: maxZ}
-		}
+func (r *navReader) u8(field string) uint8 {
+	if b := r.next(field, 1); b != nil {
+		return b[0]
+	}
+	return 0
+}
 
-		// Skip UnkArea (Already read)
-		// var unkArea uint32
-		// if err := binary.Read(r, binary.LittleEndian, &unkArea); err != nil {
-		// 	return nil, fmt.Errorf("failed to read unkArea at %d: %v", i, err)
-		// }
+func (r *navReader) u16(field string) uint16 {
+	if b := r.next(field, 2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
 
-		if i < 5 {
-			fmt.Printf("Area %d: ID=%d Flags=%x Hull=%d Poly=%d Unk=%d\n", i, area.ID, flags, hullIndex, polygonIndex, unkArea)
-		}
+func (r *navReader) u32(field string) uint32 {
+	if b := r.next(field, 4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
 
-		// Connections (Per Edge)
-		edgeCount := len(area.Corners)
-		for k := 0; k < edgeCount; k++ {
-			var connCount uint32
-			if err := binary.Read(r, order, &connCount); err != nil {
-				return nil, fmt.Errorf("failed to read connCount at %d edge %d: %v", i, k, err)
-			}
-			for j := 0; j < int(connCount); j++ {
-				var targetID uint32
-				if err := binary.Read(r, order, &targetID); err != nil {
-					return nil, fmt.Errorf("failed to read targetID at %d edge %d conn %d: %v", i, k, j, err)
-				}
-			}
-		}
+func (r *navReader) u64(field string) uint64 {
+	if b := r.next(field, 8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
 
-		// Skip Legacy Data (2 bytes in v35?)
-		// Analysis of dump shows 2 bytes of zeros before LadderAboveCount
-		legacySkip := make([]byte, 2)
-		if err := binary.Read(r, binary.LittleEndian, &legacySkip); err != nil {
-			return nil, fmt.Errorf("failed to read legacySkip at %d: %v", i, err)
-		}
+func (r *navReader) f32(field string) float32 {
+	return math.Float32frombits(r.u32(field))
+}
 
-		// Ladders Above
-		var ladderAboveCount uint32
-		if err := binary.Read(r, order, &ladderAboveCount); err != nil {
-			return nil, fmt.Errorf("failed to read ladderAboveCount at %d: %v", i, err)
-		}
-		for k := 0; k < int(ladderAboveCount); k++ {
-			var id uint32
-			if err := binary.Read(r, order, &id); err != nil {
-				return nil, fmt.Errorf("failed to read ladderAboveID at %d: %v", i, err)
-			}
-		}
+func (r *navReader) vec3(field string) r3.Vector {
+	x, y, z := r.f32(field), r.f32(field), r.f32(field)
+	return r3.Vector{X: float64(x), Y: float64(y), Z: float64(z)}
+}
 
-		// Ladders Below
-		var ladderBelowCount uint32
-		if err := binary.Read(r, order, &ladderBelowCount); err != nil {
-			return nil, fmt.Errorf("failed to read ladderBelowCount at %d: %v", i, err)
-		}
-		for k := 0; k < int(ladderBelowCount); k++ {
-			var id uint32
-			if err := binary.Read(r, order, &id); err != nil {
-				return nil, fmt.Errorf("failed to read ladderBelowID at %d: %v", i, err)
-			}
-		}
+func (r *navReader) bytes(field string, n int) []byte {
+	return r.next(field, n)
+}
 
-		// PlaceID (Finally!)
-		if err := binary.Read(r, order, &area.PlaceID); err != nil {
-			return nil, fmt.Errorf("failed to read PlaceID at %d: %v", i, err)
-		}
+func (r *navReader) skip(field string, n int) {
+	r.next(field, n)
+}
 
-		// Earliest Occupier (2 teams)
-		for team := 0; team < 2; team++ {
-			var time float32
-			if err := binary.Read(r, order, &time); err != nil {
-				return nil, fmt.Errorf("failed to read time at %d team %d: %v", i, team, err)
-			}
-		}
+// count32 lee un contador y comprueba que quepan al menos minSize bytes por
+// elemento (evita reservar memoria absurda con ficheros corruptos)
+func (r *navReader) count32(field string, minSize int) int {
+	return r.checkCount(field, int(r.u32(field)), minSize)
+}
 
-		// DEBUG: Read 4 extra bytes to fix alignment?
-		// It seems we are 4 bytes short in Area 0 reading.
-		if i == 0 {
-			dummy := make([]byte, 4)
-			r.Read(dummy)
-		}
+func (r *navReader) count16(field string, minSize int) int {
+	return r.checkCount(field, int(r.u16(field)), minSize)
+}
 
-		mesh.Areas[i] = area
+func (r *navReader) checkCount(field string, n, minSize int) int {
+	if r.err != nil {
+		return 0
+	}
+	if left := len(r.data) - r.off; n*minSize > left {
+		r.fail(field, fmt.Errorf("%w: %d entries do not fit in %d bytes", ErrCorruptNav, n, left))
+		return 0
 	}
+	return n
+}
 
-	return mesh, nil
+// ids lee un contador u32 seguido de IDs u32
+func (r *navReader) ids(field string) []uint32 {
+	n := r.count32(field, 4)
+	if n == 0 {
+		return nil
+	}
+	out := make([]uint32, n)
+	for i := range out {
+		out[i] = r.u32(field)
+	}
+	return out
 }
 
 // GetNearestArea returns the navigation area closest to the given position
@@ -498,22 +569,26 @@ func (nm *NavMesh) GetNearestArea(pos r3.Vector) *NavArea {
 	var bestArea *NavArea
 	minZDist := 10000.0
 
+	if nm.areaGrid != nil {
+		// Solo las áreas de la celda (en orden: mismo resultado que el recorrido completo)
+		for _, i := range nm.areaGrid.at(pos.X, pos.Y) {
+			area := &nm.Areas[i]
+			if isPointInPolygon(pos, area.Corners) {
+				if zDist := math.Abs(pos.Z - area.Center.Z); zDist < minZDist {
+					minZDist = zDist
+					bestArea = area
+				}
+			}
+		}
+		return bestArea
+	}
+
 	for i := range nm.Areas {
 		area := &nm.Areas[i]
 		// Check if point is inside the polygon (2D)
 		if isPointInPolygon(pos, area.Corners) {
 			// Check Z distance
 			zDist := pos.Z - area.Center.Z
-			if len(area.Corners) > 0 {
-				// Use average Z of corners if center is not reliable
-				avgZ := 0.0
-				for _, c := range area.Corners {
-					avgZ += c.Z
-				}
-				avgZ /= float64(len(area.Corners))
-				zDist = pos.Z - avgZ
-			}
-
 			if zDist < 0 {
 				zDist = -zDist
 			}
@@ -0,0 +1,377 @@
+package maps
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/geo/r3"
+)
+
+// Los .nav de testdata/nav son sintéticos (tres áreas, una escalera) y se generan con
+// los writers de este fichero: go test ./pkg/maps -run TestNav -update
+var updateNavFixtures = flag.Bool("update", false, "regenerate the synthetic .nav fixtures in testdata/nav")
+
+// Escenario sintético:
+//
+//	A(1) <-> B(2) -> C(3)   (B→C es una caída de un solo sentido)
+//	A --escalera 7--> C
+var navSquares = [][2]float64{{0, 0}, {100, 0}, {200, 0}}
+
+func TestNavParseSource2(t *testing.T) {
+	for _, v := range []uint32{31, 35, 36, 37} {
+		t.Run(fmt.Sprintf("v%d", v), func(t *testing.T) {
+			nav, err := ParseNavMesh(navFixture(t, fmt.Sprintf("source2_v%d.nav", v), func() []byte { return writeSource2(v) }))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkNavGraph(t, nav)
+
+			if !nav.IsAnalyzed || nav.Version != v {
+				t.Errorf("header: version=%d analyzed=%v", nav.Version, nav.IsAnalyzed)
+			}
+			if nav.NewerThanKnown() != (v > NavMaxVersion) {
+				t.Errorf("NewerThanKnown() = %v for v%d", nav.NewerThanKnown(), v)
+			}
+			a := nav.AreaByID(1)
+			if len(a.HidingSpots) != 1 || a.HidingSpots[0].ID != 9 {
+				t.Errorf("hiding spots = %+v", a.HidingSpots)
+			}
+			if len(a.EncounterPaths) != 1 || a.EncounterPaths[0].ToAreaID != 2 || a.EncounterPaths[0].Spots[0].T != 1 {
+				t.Errorf("encounter paths = %+v", a.EncounterPaths)
+			}
+			if c := a.Connections[1][0]; c.AreaID != 2 || c.EdgeID != 3 {
+				t.Errorf("connection A→B = %+v", c)
+			}
+			if b := nav.AreaByID(2); b.Center.X != 150 || b.Center.Z != 16 || b.NW.X != 100 || b.SE.Y != 100 {
+				t.Errorf("area B geometry: center=%v nw=%v se=%v", b.Center, b.NW, b.SE)
+			}
+		})
+	}
+}
+
+func TestNavParseSource1(t *testing.T) {
+	nav, err := ParseNavMesh(navFixture(t, "source1_v16.nav", writeSource1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNavGraph(t, nav)
+	if got := nav.GetPlaceName(r3.Vector{X: 50, Y: 50, Z: 0}); got != "BombsiteA" {
+		t.Errorf("place at A = %q", got)
+	}
+}
+
+func TestNavFindPath(t *testing.T) {
+	nav, err := ParseNavMesh(navFixture(t, "source2_v36.nav", func() []byte { return writeSource2(36) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := r3.Vector{X: 50, Y: 50}, r3.Vector{X: 150, Y: 50, Z: 16}, r3.Vector{X: 250, Y: 50, Z: 32}
+
+	// C no puede bajar a B directamente (caída de un solo sentido): baja por la escalera hasta A
+	path, ok := nav.FindPath(c, b)
+	if !ok || fmt.Sprint(path.Areas) != "[3 1 2]" {
+		t.Fatalf("path C→B = %v (ok=%v)", path.Areas, ok)
+	}
+	if path.Length < c.Sub(b).Norm() {
+		t.Errorf("path C→B shorter than straight line: %.1f", path.Length)
+	}
+	if tm := path.RunTime(250); math.Abs(tm-path.Length/250) > 1e-9 {
+		t.Errorf("run time = %.3f", tm)
+	}
+
+	edge := r3.Vector{X: 100, Y: 50}
+	path, ok = nav.FindPath(a, b)
+	if !ok || fmt.Sprint(path.Areas) != "[1 2]" || math.Abs(path.Length-a.Sub(edge).Norm()-b.Sub(edge).Norm()) > 1e-6 {
+		t.Errorf("path A→B = %v len %.2f (ok=%v)", path.Areas, path.Length, ok)
+	}
+
+	// Fuera del mesh pero cerca: se asocia al área más cercana; lejos: sin camino
+	if _, ok := nav.FindPath(r3.Vector{X: -40, Y: 50}, b); !ok {
+		t.Error("snap to nearest area failed")
+	}
+	if _, ok := nav.FindPath(r3.Vector{X: 5000, Y: 5000}, b); ok {
+		t.Error("found path from outside the mesh")
+	}
+}
+
+func TestNavParseErrors(t *testing.T) {
+	var perr *NavParseError
+	cases := []struct {
+		name  string
+		build func() []byte
+		want  error
+		area  int // Área esperada en el *NavParseError (-2 = no comprobar)
+	}{
+		{"bad_magic.nav", func() []byte { return []byte{1, 2, 3, 4, 0, 0, 0, 0} }, ErrInvalidNavMagic, -1},
+		{"unsupported_v20.nav", func() []byte {
+			w := &navWriter{}
+			w.u32(navMagic, 20, 0)
+			return w.buf.Bytes()
+		}, ErrUnsupportedNavVersion, -1},
+		// Truncado a mitad de la lista de áreas
+		{"truncated_v35.nav", func() []byte {
+			w := &navWriter{}
+			w.u32(navMagic, 35, 0, 0, 0, 0, 0, 0, 1, 5)
+			return w.buf.Bytes()
+		}, ErrCorruptNav, 0},
+		// Contador absurdo: no debe reservar memoria
+		{"huge_count_v35.nav", func() []byte {
+			w := &navWriter{}
+			w.u32(navMagic, 35, 0, 0, math.MaxUint32)
+			return w.buf.Bytes()
+		}, ErrCorruptNav, -2},
+		// Versión futura con otro layout: error del parser, con la versión en el mensaje
+		{"truncated_v40.nav", func() []byte {
+			w := &navWriter{}
+			w.u32(navMagic, 40, 0, 0, 0, 0, 0, 0, 1, 5)
+			return w.buf.Bytes()
+		}, ErrCorruptNav, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ParseNavMesh(navFixture(t, c.name, c.build))
+			if !errors.Is(err, c.want) {
+				t.Fatalf("err = %v, want %v", err, c.want)
+			}
+			if c.area != -2 && (!errors.As(err, &perr) || perr.Area != c.area) {
+				t.Errorf("err = %v, want *NavParseError in area %d", err, c.area)
+			}
+		})
+	}
+}
+
+// checkNavGraph verifica los enlaces del escenario en cualquier versión
+func checkNavGraph(t *testing.T, nav *NavMesh) {
+	t.Helper()
+	if len(nav.Areas) != 3 || len(nav.Ladders) != 1 {
+		t.Fatalf("got %d areas, %d ladders", len(nav.Areas), len(nav.Ladders))
+	}
+	for _, c := range []struct {
+		from, to uint32
+		ok       bool
+	}{
+		{1, 2, true}, {2, 1, true}, {2, 3, true}, {3, 2, false}, {1, 3, true}, {3, 1, true},
+	} {
+		if nav.IsConnected(c.from, c.to) != c.ok {
+			t.Errorf("IsConnected(%d, %d) = %v", c.from, c.to, !c.ok)
+		}
+	}
+	var up bool
+	for _, l := range nav.Neighbors(1) {
+		up = up || (l.Kind == NavLinkLadderUp && l.AreaID == 3 && l.LadderID == 7)
+	}
+	if !up {
+		t.Errorf("ladder link 1→3 missing: %+v", nav.Neighbors(1))
+	}
+	if l := nav.Ladders[0]; l.Length != 32 || l.Top.X != 250 {
+		t.Errorf("ladder = %+v", l)
+	}
+}
+
+// navFixture lee testdata/nav/<name> (con -update lo regenera antes con build)
+func navFixture(t *testing.T, name string, build func() []byte) []byte {
+	t.Helper()
+	path := filepath.Join("testdata", "nav", name)
+	if *updateNavFixtures {
+		if err := os.WriteFile(path, build(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (regenerate with -update)", err)
+	}
+	return data
+}
+
+// --- Writers de los ficheros sintéticos ---
+
+func navSquare(x, y, z float64) []r3.Vector {
+	return []r3.Vector{{X: x, Y: y, Z: z}, {X: x + 100, Y: y, Z: z}, {X: x + 100, Y: y + 100, Z: z}, {X: x, Y: y + 100, Z: z}}
+}
+
+func writeSource2(version uint32) []byte {
+	w := &navWriter{}
+	w.u32(navMagic, version, 2, 1)
+
+	// Corners + polygons (un cuadrado por área, esquinas no compartidas)
+	var corners []r3.Vector
+	for i, s := range navSquares {
+		corners = append(corners, navSquare(s[0], s[1], float64(i)*16)...)
+	}
+	w.u32(uint32(len(corners)))
+	for _, c := range corners {
+		w.vec3(c)
+	}
+	w.u32(uint32(len(navSquares)))
+	for i := range navSquares {
+		w.u8(4)
+		for k := 0; k < 4; k++ {
+			w.u32(uint32(i*4 + k))
+		}
+		if version >= 35 {
+			w.u32(0)
+		}
+	}
+	if version >= 32 {
+		w.u32(0)
+	}
+	if version >= 35 {
+		w.u32(0)
+	}
+
+	// Conexiones por arista (arista 1 = este, arista 3 = oeste)
+	edges := map[uint32][4][][2]uint32{
+		1: {1: {{2, 3}}},
+		2: {1: {{3, 3}}, 3: {{1, 1}}},
+		3: {},
+	}
+	w.u32(3)
+	for id := uint32(1); id <= 3; id++ {
+		w.u32(id)
+		w.u64(0x10)
+		w.u8(0)
+		w.u32(id - 1)
+		w.f32(0)
+		for _, conns := range edges[id] {
+			w.u32(uint32(len(conns)))
+			for _, c := range conns {
+				w.u32(c[0], c[1])
+			}
+		}
+		if id == 1 {
+			// Un hiding spot y un encounter path (legacy)
+			w.u8(1)
+			w.u32(9)
+			w.vec3(r3.Vector{X: 50, Y: 50})
+			w.u8(2)
+			w.u32(1)
+			w.u32(2)
+			w.u8(1)
+			w.u32(2)
+			w.u8(3)
+			w.u8(1)
+			w.u32(9)
+			w.u8(255)
+		} else {
+			w.u8(0)
+			w.u32(0)
+		}
+		var above, below []uint32
+		if id == 1 {
+			above = []uint32{7}
+		}
+		if id == 3 {
+			below = []uint32{7}
+		}
+		w.ids(above)
+		w.ids(below)
+	}
+
+	w.u32(1)
+	writeNavLadder(w)
+	w.buf.WriteString("trailing kv3 data")
+	return w.buf.Bytes()
+}
+
+func writeSource1() []byte {
+	w := &navWriter{}
+	w.u32(navMagic, 16, 1, 12345)
+	w.u8(1)
+	w.u16(2)
+	for _, name := range []string{"BombsiteA", "Ramp"} {
+		w.u16(uint16(len(name) + 1))
+		w.buf.WriteString(name)
+		w.u8(0)
+	}
+	w.u8(0)
+
+	// Directions: 1 = East, 3 = West
+	dirs := map[uint32][4][]uint32{
+		1: {1: {2}},
+		2: {1: {3}, 3: {1}},
+		3: {},
+	}
+	w.u32(3)
+	for id := uint32(1); id <= 3; id++ {
+		s := navSquares[id-1]
+		z := float64(id-1) * 16
+		w.u32(id, 0)
+		w.vec3(r3.Vector{X: s[0], Y: s[1], Z: z})
+		w.vec3(r3.Vector{X: s[0] + 100, Y: s[1] + 100, Z: z})
+		w.f32(z, z)
+		for _, conns := range dirs[id] {
+			w.ids(conns)
+		}
+		w.u8(0)                   // hiding spots
+		w.u32(0)                  // encounter paths
+		w.u16(uint16(min(id, 2))) // A = BombsiteA, B y C = Ramp
+		if id == 1 {
+			w.ids([]uint32{7})
+		} else {
+			w.ids(nil)
+		}
+		if id == 3 {
+			w.ids([]uint32{7})
+		} else {
+			w.ids(nil)
+		}
+		w.f32(0, 0)       // earliest occupy
+		w.f32(1, 1, 1, 1) // light intensity
+		w.u32(1, 2)       // 1 visible area
+		w.u8(0)
+		w.u32(0) // inherit visibility
+		w.u8(1)  // custom data
+		w.buf.Write(make([]byte, 14))
+	}
+	w.u32(1)
+	writeNavLadder(w)
+	return w.buf.Bytes()
+}
+
+func writeNavLadder(w *navWriter) {
+	w.u32(7)
+	w.f32(32)
+	w.vec3(r3.Vector{X: 250, Y: 50, Z: 32})
+	w.vec3(r3.Vector{X: 50, Y: 50, Z: 0})
+	w.f32(32)
+	w.u32(0)
+	w.u32(3, 0, 0, 0, 1)
+}
+
+// navWriter escribe little-endian para generar los ficheros sintéticos
+type navWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *navWriter) u8(v uint8) { w.buf.WriteByte(v) }
+
+func (w *navWriter) u16(v uint16) { binary.Write(&w.buf, binary.LittleEndian, v) }
+
+func (w *navWriter) u32(vs ...uint32) {
+	for _, v := range vs {
+		binary.Write(&w.buf, binary.LittleEndian, v)
+	}
+}
+
+func (w *navWriter) u64(v uint64) { binary.Write(&w.buf, binary.LittleEndian, v) }
+
+func (w *navWriter) f32(vs ...float64) {
+	for _, v := range vs {
+		binary.Write(&w.buf, binary.LittleEndian, float32(v))
+	}
+}
+
+func (w *navWriter) vec3(v r3.Vector) { w.f32(v.X, v.Y, v.Z) }
+
+func (w *navWriter) ids(ids []uint32) {
+	w.u32(uint32(len(ids)))
+	w.u32(ids...)
+}
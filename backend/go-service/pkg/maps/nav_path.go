@@ -0,0 +1,246 @@
+package maps
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/golang/geo/r3"
+)
+
+// ============================================================================
+// NAV PATHFINDING
+// A* sobre el grafo de áreas (ver nav_graph.go). El coste de cada salto es
+// centro → portal (punto medio de la arista o extremo de la escalera) → centro,
+// y la heurística la distancia en línea recta (admisible).
+//
+// Las secuencias de áreas se cachean por par (origen, destino): los análisis
+// consultan una y otra vez los mismos pares durante una ronda.
+// ============================================================================
+
+const (
+	navSnapDistance   = 256.0  // Distancia máxima (2D) para asociar una posición fuera del mesh a un área
+	navPathCacheLimit = 200000 // Entradas máximas en la caché de caminos (se vacía al llenarse)
+)
+
+// NavPath es un camino entre dos posiciones sobre el nav mesh
+type NavPath struct {
+	Areas    []uint32    // Áreas recorridas (origen → destino)
+	Points   []r3.Vector // Polilínea: origen, portales entre áreas, destino
+	Length   float64     // Longitud de la polilínea (unidades)
+	Callouts []string    // Callouts atravesados, sin repetidos consecutivos
+}
+
+// RunTime devuelve los segundos para recorrer el camino a speed u/s
+func (p NavPath) RunTime(speed float64) float64 {
+	if speed <= 0 {
+		return 0
+	}
+	return p.Length / speed
+}
+
+// navStep es un salto del camino: área destino y los puntos por los que se entra
+type navStep struct {
+	area   uint32
+	portal []r3.Vector
+}
+
+// AreaForPosition devuelve el área que contiene pos o, si está fuera del mesh
+// (saltando, pegado a una pared...), la de centro más cercano en 2D
+func (nm *NavMesh) AreaForPosition(pos r3.Vector) *NavArea {
+	if area := nm.GetNearestArea(pos); area != nil {
+		return area
+	}
+	best, bestDist := -1, navSnapDistance*navSnapDistance
+	consider := func(i int) {
+		a := &nm.Areas[i]
+		dx, dy := a.Center.X-pos.X, a.Center.Y-pos.Y
+		d := dx*dx + dy*dy
+		if math.Abs(a.Center.Z-pos.Z) < navSnapDistance && (d < bestDist || (d == bestDist && best >= 0 && i < best)) {
+			best, bestDist = i, d
+		}
+	}
+	if nm.areaGrid != nil {
+		nm.areaGrid.visit(pos.X-navSnapDistance, pos.Y-navSnapDistance, pos.X+navSnapDistance, pos.Y+navSnapDistance, func(items []int32) {
+			for _, i := range items {
+				consider(int(i))
+			}
+		})
+	} else {
+		for i := range nm.Areas {
+			consider(i)
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	return &nm.Areas[best]
+}
+
+// FindPath busca el camino más corto de from a to. ok=false si alguna de las
+// posiciones no está sobre el mesh o no hay camino (p.ej. caída de un solo sentido).
+func (nm *NavMesh) FindPath(from, to r3.Vector) (NavPath, bool) {
+	start := nm.AreaForPosition(from)
+	goal := nm.AreaForPosition(to)
+	if start == nil || goal == nil {
+		return NavPath{}, false
+	}
+	steps, ok := nm.areaPath(start.ID, goal.ID)
+	if !ok {
+		return NavPath{}, false
+	}
+
+	path := NavPath{Areas: []uint32{start.ID}, Points: []r3.Vector{from}}
+	for _, s := range steps {
+		path.Areas = append(path.Areas, s.area)
+		path.Points = append(path.Points, s.portal...)
+	}
+	path.Points = append(path.Points, to)
+	for i := 1; i < len(path.Points); i++ {
+		path.Length += path.Points[i].Sub(path.Points[i-1]).Norm()
+	}
+	for _, id := range path.Areas {
+		if a := nm.AreaByID(id); a != nil && a.PlaceID > 0 && int(a.PlaceID) <= len(nm.Places) {
+			path.Callouts = appendCallout(path.Callouts, nm.Places[a.PlaceID-1])
+		}
+	}
+	return path, true
+}
+
+// PathLength devuelve solo la longitud del camino (-1 si no hay camino)
+func (nm *NavMesh) PathLength(from, to r3.Vector) float64 {
+	path, ok := nm.FindPath(from, to)
+	if !ok {
+		return -1
+	}
+	return path.Length
+}
+
+// areaPath devuelve los saltos start → goal (cacheados)
+func (nm *NavMesh) areaPath(start, goal uint32) ([]navStep, bool) {
+	key := [2]uint32{start, goal}
+	nm.pathMu.Lock()
+	steps, cached := nm.pathCache[key]
+	nm.pathMu.Unlock()
+	if cached {
+		return steps, steps != nil || start == goal
+	}
+
+	steps, ok := nm.astar(start, goal)
+
+	nm.pathMu.Lock()
+	if nm.pathCache == nil || len(nm.pathCache) >= navPathCacheLimit {
+		nm.pathCache = make(map[[2]uint32][]navStep)
+	}
+	nm.pathCache[key] = steps // nil = sin camino (o mismo área)
+	nm.pathMu.Unlock()
+	return steps, ok
+}
+
+func (nm *NavMesh) astar(start, goal uint32) ([]navStep, bool) {
+	if start == goal {
+		return nil, true
+	}
+	goalArea := nm.AreaByID(goal)
+	if goalArea == nil || nm.AreaByID(start) == nil {
+		return nil, false
+	}
+
+	type cameFrom struct {
+		prev uint32
+		step navStep
+	}
+	gScore := map[uint32]float64{start: 0}
+	parents := make(map[uint32]cameFrom)
+	closed := make(map[uint32]bool)
+	open := &navQueue{}
+	heap.Push(open, &navNode{area: start, f: nm.AreaByID(start).Center.Sub(goalArea.Center).Norm()})
+
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*navNode).area
+		if current == goal {
+			var steps []navStep
+			for id := goal; id != start; id = parents[id].prev {
+				steps = append(steps, parents[id].step)
+			}
+			for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+				steps[i], steps[j] = steps[j], steps[i]
+			}
+			return steps, true
+		}
+		if closed[current] {
+			continue
+		}
+		closed[current] = true
+
+		area := nm.AreaByID(current)
+		for _, link := range nm.Neighbors(current) {
+			if closed[link.AreaID] {
+				continue
+			}
+			next := nm.AreaByID(link.AreaID)
+			portal := nm.linkPortal(area, next, link)
+			cost := 0.0
+			prev := area.Center
+			for _, p := range portal {
+				cost += p.Sub(prev).Norm()
+				prev = p
+			}
+			cost += next.Center.Sub(prev).Norm()
+
+			g := gScore[current] + cost
+			if old, seen := gScore[link.AreaID]; seen && g >= old {
+				continue
+			}
+			gScore[link.AreaID] = g
+			parents[link.AreaID] = cameFrom{prev: current, step: navStep{area: link.AreaID, portal: portal}}
+			heap.Push(open, &navNode{area: link.AreaID, f: g + next.Center.Sub(goalArea.Center).Norm()})
+		}
+	}
+	return nil, false
+}
+
+// linkPortal devuelve los puntos de paso entre dos áreas enlazadas
+func (nm *NavMesh) linkPortal(from, to *NavArea, link NavLink) []r3.Vector {
+	switch link.Kind {
+	case NavLinkLadderUp, NavLinkLadderDown:
+		if l := nm.LadderByID(link.LadderID); l != nil {
+			if link.Kind == NavLinkLadderUp {
+				return []r3.Vector{l.Bottom, l.Top}
+			}
+			return []r3.Vector{l.Top, l.Bottom}
+		}
+	case NavLinkWalk:
+		if n := len(from.Corners); link.Edge >= 0 && n > 1 {
+			a, b := from.Corners[link.Edge%n], from.Corners[(link.Edge+1)%n]
+			return []r3.Vector{a.Add(b).Mul(0.5)}
+		}
+	}
+	return []r3.Vector{from.Center.Add(to.Center).Mul(0.5)}
+}
+
+func appendCallout(callouts []string, name string) []string {
+	if name == "" || (len(callouts) > 0 && callouts[len(callouts)-1] == name) {
+		return callouts
+	}
+	return append(callouts, name)
+}
+
+// --- Cola de prioridad (min-heap por f) ---
+
+type navNode struct {
+	area uint32
+	f    float64
+}
+
+type navQueue []*navNode
+
+func (q navQueue) Len() int            { return len(q) }
+func (q navQueue) Less(i, j int) bool  { return q[i].f < q[j].f }
+func (q navQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *navQueue) Push(x interface{}) { *q = append(*q, x.(*navNode)) }
+func (q *navQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,257 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// RADAR OVERVIEW
+// Lee los <mapa>.txt de resource/overviews (formato KeyValues de Valve) con la
+// transformación mundo → radar (pos_x, pos_y, scale) y las secciones
+// verticales de los mapas con varios niveles (Nuke, Vertigo...).
+// ============================================================================
+
+// ErrNoOverview indica que el mapa no tiene <mapa>.txt en el directorio de mapas
+var ErrNoOverview = errors.New("overview file not found")
+
+// Overview es la configuración del radar de un mapa
+type Overview struct {
+	PosX   float64 // Esquina superior izquierda del radar (unidades del mundo)
+	PosY   float64
+	Scale  float64         // Unidades del mundo por pixel (radar de 1024x1024)
+	Rotate bool            // Radar rotado (solo mapas antiguos)
+	Levels []OverviewLevel // Secciones verticales, de abajo arriba (vacío = un solo nivel)
+	Source string          // Fichero del que se leyó
+}
+
+// OverviewLevel es una sección vertical del radar ("default" es la imagen principal)
+type OverviewLevel struct {
+	Name        string
+	AltitudeMin float64
+	AltitudeMax float64
+}
+
+// LevelFor devuelve el nombre del nivel que contiene la altura z ("" si el mapa no tiene niveles)
+func (o *Overview) LevelFor(z float64) string {
+	if o == nil {
+		return ""
+	}
+	for _, l := range o.Levels {
+		if z >= l.AltitudeMin && z < l.AltitudeMax {
+			return l.Name
+		}
+	}
+	return ""
+}
+
+// LoadOverview carga el <mapa>.txt del directorio de mapas (ErrNoOverview si no existe)
+func LoadOverview(mapsDir, baseName string) (*Overview, error) {
+	path := findOverview(mapsDir, baseName)
+	if path == "" {
+		return nil, ErrNoOverview
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ov, err := ParseOverview(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	ov.Source = path
+	return ov, nil
+}
+
+// ParseOverview interpreta el contenido de un fichero de overview
+func ParseOverview(data []byte) (*Overview, error) {
+	root, err := parseKeyValues(string(data))
+	if err != nil {
+		return nil, err
+	}
+	// El fichero es un único bloque "<mapa>" { ... }
+	var body *kvNode
+	for _, n := range root.children {
+		if n.children != nil {
+			body = n
+			break
+		}
+	}
+	if body == nil {
+		return nil, fmt.Errorf("missing map block")
+	}
+
+	ov := &Overview{}
+	for _, field := range []struct {
+		key string
+		dst *float64
+	}{{"pos_x", &ov.PosX}, {"pos_y", &ov.PosY}, {"scale", &ov.Scale}} {
+		n := body.child(field.key)
+		if n == nil {
+			return nil, fmt.Errorf("missing %q", field.key)
+		}
+		v, err := strconv.ParseFloat(n.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q: %w", field.key, err)
+		}
+		*field.dst = v
+	}
+	if ov.Scale <= 0 {
+		return nil, fmt.Errorf("invalid scale %v", ov.Scale)
+	}
+	if n := body.child("rotate"); n != nil {
+		ov.Rotate = n.value == "1"
+	}
+
+	if sections := body.child("verticalsections"); sections != nil {
+		for _, s := range sections.children {
+			if s.children == nil {
+				continue
+			}
+			level := OverviewLevel{Name: s.key}
+			minN, maxN := s.child("AltitudeMin"), s.child("AltitudeMax")
+			if minN == nil || maxN == nil {
+				return nil, fmt.Errorf("vertical section %q without altitudes", s.key)
+			}
+			if level.AltitudeMin, err = strconv.ParseFloat(minN.value, 64); err != nil {
+				return nil, fmt.Errorf("vertical section %q: %w", s.key, err)
+			}
+			if level.AltitudeMax, err = strconv.ParseFloat(maxN.value, 64); err != nil {
+				return nil, fmt.Errorf("vertical section %q: %w", s.key, err)
+			}
+			ov.Levels = append(ov.Levels, level)
+		}
+		sort.SliceStable(ov.Levels, func(i, j int) bool { return ov.Levels[i].AltitudeMin < ov.Levels[j].AltitudeMin })
+	}
+	return ov, nil
+}
+
+// findOverview devuelve la ruta del <mapa>.txt (o "" si no existe)
+func findOverview(mapsDir, baseName string) string {
+	candidates := []string{
+		// Priority 1: maps/mapName/mapName.txt
+		filepath.Join(mapsDir, baseName, baseName+".txt"),
+		// Priority 2: maps/overviews/mapName.txt (copia directa de resource/overviews)
+		filepath.Join(mapsDir, "overviews", baseName+".txt"),
+		// Priority 3: maps/mapName.txt
+		filepath.Join(mapsDir, baseName+".txt"),
+	}
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// --- KeyValues ---
+
+// kvNode es un par clave/valor o un bloque (children != nil)
+type kvNode struct {
+	key      string
+	value    string
+	children []*kvNode
+}
+
+// child busca una clave (sin distinguir mayúsculas, como el motor)
+func (n *kvNode) child(key string) *kvNode {
+	for _, c := range n.children {
+		if strings.EqualFold(c.key, key) {
+			return c
+		}
+	}
+	return nil
+}
+
+// parseKeyValues lee el formato KeyValues: "clave" "valor", "clave" { ... },
+// comentarios // y condicionales [$X360] (se ignoran)
+func parseKeyValues(src string) (*kvNode, error) {
+	tokens, err := kvTokens(src)
+	if err != nil {
+		return nil, err
+	}
+	root := &kvNode{children: []*kvNode{}}
+	stack := []*kvNode{root}
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		top := stack[len(stack)-1]
+		switch {
+		case tok == "}":
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("unexpected '}'")
+			}
+			stack = stack[:len(stack)-1]
+		case tok == "{":
+			return nil, fmt.Errorf("block without key")
+		default:
+			if i+1 >= len(tokens) {
+				return nil, fmt.Errorf("key %q without value", tok.text())
+			}
+			next := tokens[i+1]
+			node := &kvNode{key: tok.text()}
+			if next == "{" {
+				node.children = []*kvNode{}
+				stack = append(stack, node)
+			} else if next == "}" {
+				return nil, fmt.Errorf("key %q without value", tok.text())
+			} else {
+				node.value = next.text()
+			}
+			top.children = append(top.children, node)
+			i++
+		}
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("unclosed block %q", stack[len(stack)-1].key)
+	}
+	return root, nil
+}
+
+// kvToken es un token; los entrecomillados conservan las comillas para distinguir "{" de {
+type kvToken string
+
+func (t kvToken) text() string { return strings.Trim(string(t), `"`) }
+
+func kvTokens(src string) ([]kvToken, error) {
+	var tokens []kvToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '{' || c == '}':
+			tokens = append(tokens, kvToken(c))
+			i++
+		case c == '"':
+			end := strings.IndexByte(src[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, kvToken(src[i:i+end+2]))
+			i += end + 2
+		case c == '[':
+			// Condicional de plataforma: se descarta
+			end := strings.IndexByte(src[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated conditional")
+			}
+			i += end + 1
+		default:
+			start := i
+			for i < len(src) && !strings.ContainsRune(" \t\r\n{}\"", rune(src[i])) {
+				i++
+			}
+			tokens = append(tokens, kvToken(src[start:i]))
+		}
+	}
+	return tokens, nil
+}
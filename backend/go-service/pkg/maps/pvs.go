@@ -0,0 +1,454 @@
+package maps
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"cs2-demo-service/pkg/geometry"
+
+	"github.com/golang/geo/r3"
+)
+
+// ============================================================================
+// PVS (Potentially Visible Set)
+// Vóxeles del espacio jugable (a partir de las áreas del nav mesh) y, para cada
+// par de vóxeles, si ALGÚN punto de uno puede ver a alguno del otro.
+// MapManager.IsVisible lo consulta antes de lanzar el rayo: un par marcado como
+// no visible se descarta sin raycast. Los puntos fuera de vóxeles conocidos
+// (saltando, fuera del nav) nunca se descartan.
+//
+// Es un muestreo, no una prueba: cada vóxel se representa con pocos puntos
+// (pvsMaxPoints, a partir de muestras de suelo del nav), así que un par en el que
+// solo se ven puntos no muestreados queda marcado como NO visible y ese rayo no se
+// lanza (falso negativo). La dilatación a los vóxeles vecinos reduce estos casos,
+// pero no los elimina.
+//
+// Fichero <mapa>.pvs junto al mesh (ver build_pvs.go). Formato (little endian):
+//   magic "CS2PVS\x00\x00" | uint16 version | [32]byte hash del mesh
+//   origin (3 x float64) | cellSize, cellHeight (float64) | dims (3 x uint32)
+//   uint32 voxel count | grid (nx*ny*nz x int32, -1 = vacío) | bitset (count x words x uint64)
+// ============================================================================
+
+// PVSVersion must be bumped whenever the file format or the build change
+const PVSVersion uint16 = 1
+
+var pvsMagic = [8]byte{'C', 'S', '2', 'P', 'V', 'S', 0, 0}
+
+// ErrPVSStale is returned when the PVS was built from another mesh or version
+var ErrPVSStale = errors.New("pvs is stale")
+
+// Alturas del cuerpo sobre el suelo usadas al vóxelizar
+const (
+	pvsBodyMin   = 8.0  // Pies
+	pvsBodyMax   = 72.0 // Cabeza de pie
+	pvsMaxPoints = 3    // Puntos representativos por vóxel
+)
+
+// PVSOptions controla la construcción del PVS
+type PVSOptions struct {
+	CellSize    float64 // Tamaño XY del vóxel (default 128)
+	CellHeight  float64 // Altura del vóxel (default 64)
+	MaxDistance float64 // Pares más lejanos se marcan visibles sin comprobar (default 4000)
+	Workers     int     // Goroutines (default GOMAXPROCS)
+	Progress    func(done, total int)
+}
+
+// PVS es la tabla de visibilidad potencial entre vóxeles
+type PVS struct {
+	Origin     r3.Vector
+	CellSize   float64
+	CellHeight float64
+	Dims       [3]int
+	grid       []int32  // Índice de vóxel (x + y*nx + z*nx*ny) -> id compacto, -1 = vacío
+	words      int      // uint64 por fila del bitset
+	bits       []uint64 // count x words
+}
+
+// VoxelCount devuelve el número de vóxeles jugables
+func (p *PVS) VoxelCount() int {
+	if p.words == 0 {
+		return 0
+	}
+	return len(p.bits) / p.words
+}
+
+// voxelID devuelve el id compacto del vóxel que contiene pos, o -1
+func (p *PVS) voxelID(pos r3.Vector) int32 {
+	x := int(math.Floor((pos.X - p.Origin.X) / p.CellSize))
+	y := int(math.Floor((pos.Y - p.Origin.Y) / p.CellSize))
+	z := int(math.Floor((pos.Z - p.Origin.Z) / p.CellHeight))
+	if x < 0 || y < 0 || z < 0 || x >= p.Dims[0] || y >= p.Dims[1] || z >= p.Dims[2] {
+		return -1
+	}
+	return p.grid[x+y*p.Dims[0]+z*p.Dims[0]*p.Dims[1]]
+}
+
+// PotentiallyVisible devuelve false solo si el PVS garantiza que start y end no se ven
+func (p *PVS) PotentiallyVisible(start, end r3.Vector) bool {
+	a, b := p.voxelID(start), p.voxelID(end)
+	if a < 0 || b < 0 {
+		return true // Fuera del espacio vóxelizado: hay que lanzar el rayo
+	}
+	return p.get(int(a), int(b))
+}
+
+func (p *PVS) get(a, b int) bool {
+	return p.bits[a*p.words+b/64]&(1<<(uint(b)%64)) != 0
+}
+
+func (p *PVS) set(a, b int) {
+	p.bits[a*p.words+b/64] |= 1 << (uint(b) % 64)
+}
+
+// BuildPVS vóxeliza las áreas del nav y calcula la visibilidad entre vóxeles con raycasts
+func BuildPVS(mesh *geometry.Mesh, nav *NavMesh, opts PVSOptions) (*PVS, error) {
+	if mesh == nil || nav == nil || len(nav.Areas) == 0 {
+		return nil, fmt.Errorf("pvs needs a mesh and a nav mesh with areas")
+	}
+	if opts.CellSize <= 0 {
+		opts.CellSize = 128
+	}
+	if opts.CellHeight <= 0 {
+		opts.CellHeight = 64
+	}
+	if opts.MaxDistance <= 0 {
+		opts.MaxDistance = 4000
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	// 1. Puntos de suelo sobre las áreas del nav
+	floor := navFloorSamples(nav, opts.CellSize/2)
+	if len(floor) == 0 {
+		return nil, fmt.Errorf("nav mesh has no area geometry")
+	}
+
+	bounds := geometry.AABB{Min: floor[0], Max: floor[0]}
+	for _, f := range floor {
+		bounds = bounds.Extend(f).Extend(r3.Vector{X: f.X, Y: f.Y, Z: f.Z + pvsBodyMax})
+	}
+	p := &PVS{
+		Origin:     bounds.Min,
+		CellSize:   opts.CellSize,
+		CellHeight: opts.CellHeight,
+	}
+	p.Dims = [3]int{
+		int((bounds.Max.X-bounds.Min.X)/opts.CellSize) + 1,
+		int((bounds.Max.Y-bounds.Min.Y)/opts.CellSize) + 1,
+		int((bounds.Max.Z-bounds.Min.Z)/opts.CellHeight) + 1,
+	}
+	p.grid = make([]int32, p.Dims[0]*p.Dims[1]*p.Dims[2])
+	for i := range p.grid {
+		p.grid[i] = -1
+	}
+
+	// 2. Vóxeles ocupados por el cuerpo de un jugador + puntos representativos
+	var points [][]r3.Vector
+	for _, f := range floor {
+		x := int((f.X - p.Origin.X) / opts.CellSize)
+		y := int((f.Y - p.Origin.Y) / opts.CellSize)
+		z0 := int((f.Z + pvsBodyMin - p.Origin.Z) / opts.CellHeight)
+		z1 := int((f.Z + pvsBodyMax - p.Origin.Z) / opts.CellHeight)
+		for z := z0; z <= z1 && z < p.Dims[2]; z++ {
+			idx := x + y*p.Dims[0] + z*p.Dims[0]*p.Dims[1]
+			id := p.grid[idx]
+			if id < 0 {
+				id = int32(len(points))
+				p.grid[idx] = id
+				points = append(points, nil)
+			}
+			if len(points[id]) >= pvsMaxPoints {
+				continue
+			}
+			// Punto del cuerpo dentro del rango Z del vóxel
+			lo := math.Max(f.Z+pvsBodyMin, p.Origin.Z+float64(z)*opts.CellHeight)
+			hi := math.Min(f.Z+pvsBodyMax, p.Origin.Z+float64(z+1)*opts.CellHeight)
+			points[id] = append(points[id], r3.Vector{X: f.X, Y: f.Y, Z: (lo + hi) / 2})
+		}
+	}
+
+	count := len(points)
+	p.words = (count + 63) / 64
+	p.bits = make([]uint64, count*p.words)
+
+	// 3. Visibilidad por pares (i <= j), repartida por filas entre workers
+	visible := make([][]int32, count) // visible[i] = j > i visibles desde i
+	var next, done atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= count {
+					return
+				}
+				var row []int32
+				for j := i + 1; j < count; j++ {
+					if voxelsVisible(mesh, points[i], points[j], opts.MaxDistance) {
+						row = append(row, int32(j))
+					}
+				}
+				visible[i] = row
+				if opts.Progress != nil {
+					opts.Progress(int(done.Add(1)), count)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < count; i++ {
+		p.set(i, i)
+		for _, j := range visible[i] {
+			p.set(i, int(j))
+			p.set(int(j), i)
+		}
+	}
+
+	// 4. Dilatación: si A ve a B, A puede ver a los vecinos de B (margen para el muestreo grueso)
+	p.dilate()
+	return p, nil
+}
+
+// voxelsVisible devuelve true si algún par de puntos representativos se ve
+func voxelsVisible(mesh *geometry.Mesh, a, b []r3.Vector, maxDist float64) bool {
+	if a[0].Sub(b[0]).Norm() > maxDist {
+		return true // Demasiado lejos para comprobar: no descartar
+	}
+	for _, pa := range a {
+		for _, pb := range b {
+			if !mesh.RayIntersects(pa, pb) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dilate añade a cada fila los vecinos (26-conectividad) de los vóxeles visibles: si A
+// ve a B, A y los vecinos de B se ven entre sí. Como la tabla sin dilatar es simétrica,
+// "X ve a algún vecino de Y" es el OR de las filas originales de los vecinos de X
+// (27 ORs de fila por vóxel); después se simetriza recorriendo solo los bits activos.
+func (p *PVS) dilate() {
+	orig := append([]uint64(nil), p.bits...)
+	nx, nxy := p.Dims[0], p.Dims[0]*p.Dims[1]
+	for idx, id := range p.grid {
+		if id < 0 {
+			continue
+		}
+		cx, cy, cz := idx%nx, (idx/nx)%p.Dims[1], idx/nxy
+		row := p.bits[int(id)*p.words : (int(id)+1)*p.words]
+		for dz := -1; dz <= 1; dz++ {
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					x, y, z := cx+dx, cy+dy, cz+dz
+					if x < 0 || y < 0 || z < 0 || x >= p.Dims[0] || y >= p.Dims[1] || z >= p.Dims[2] {
+						continue
+					}
+					n := int(p.grid[x+y*nx+z*nxy])
+					if n < 0 {
+						continue
+					}
+					for i, w := range orig[n*p.words : (n+1)*p.words] {
+						row[i] |= w
+					}
+				}
+			}
+		}
+	}
+
+	// Simetrizar: los bits que se añaden aquí ya están en la traspuesta, re-visitarlos no cambia nada
+	for a := 0; a < p.VoxelCount(); a++ {
+		for i, w := range p.bits[a*p.words : (a+1)*p.words] {
+			for ; w != 0; w &= w - 1 {
+				p.set(i*64+bits.TrailingZeros64(w), a)
+			}
+		}
+	}
+}
+
+// navFloorSamples devuelve puntos de suelo sobre cada área del nav (rejilla de paso step)
+func navFloorSamples(nav *NavMesh, step float64) []r3.Vector {
+	var samples []r3.Vector
+	for i := range nav.Areas {
+		corners := nav.Areas[i].Corners
+		if len(corners) < 3 {
+			continue
+		}
+		box := geometry.AABB{Min: corners[0], Max: corners[0]}
+		for _, c := range corners {
+			box = box.Extend(c)
+		}
+		samples = append(samples, nav.Areas[i].Center)
+		for x := box.Min.X + step/2; x < box.Max.X; x += step {
+			for y := box.Min.Y + step/2; y < box.Max.Y; y += step {
+				pt := r3.Vector{X: x, Y: y}
+				if isPointInPolygon(pt, corners) {
+					pt.Z = interpolateZ(pt, corners)
+					samples = append(samples, pt)
+				}
+			}
+		}
+	}
+	return samples
+}
+
+// interpolateZ estima la altura del suelo en pt (media ponderada por distancia a las esquinas)
+func interpolateZ(pt r3.Vector, corners []r3.Vector) float64 {
+	sumW, sumZ := 0.0, 0.0
+	for _, c := range corners {
+		d := math.Hypot(pt.X-c.X, pt.Y-c.Y)
+		if d < 1e-6 {
+			return c.Z
+		}
+		w := 1 / d
+		sumW += w
+		sumZ += w * c.Z
+	}
+	return sumZ / sumW
+}
+
+// PVSPath devuelve la ruta del PVS junto al mesh del mapa
+func PVSPath(meshPath string) string {
+	return meshPath[:len(meshPath)-len(filepath.Ext(meshPath))] + ".pvs"
+}
+
+// SavePVS escribe el PVS (atómico vía fichero temporal)
+func SavePVS(path string, p *PVS, meshHash [32]byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create pvs: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	w := bufio.NewWriterSize(tmp, 1<<20)
+	err = writePVS(w, p, meshHash)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write pvs: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write pvs: %w", err)
+	}
+	return nil
+}
+
+func writePVS(w io.Writer, p *PVS, meshHash [32]byte) error {
+	header := struct {
+		Magic      [8]byte
+		Version    uint16
+		Hash       [32]byte
+		Origin     [3]float64
+		CellSize   float64
+		CellHeight float64
+		Dims       [3]uint32
+		Count      uint32
+	}{
+		Magic:      pvsMagic,
+		Version:    PVSVersion,
+		Hash:       meshHash,
+		Origin:     [3]float64{p.Origin.X, p.Origin.Y, p.Origin.Z},
+		CellSize:   p.CellSize,
+		CellHeight: p.CellHeight,
+		Dims:       [3]uint32{uint32(p.Dims[0]), uint32(p.Dims[1]), uint32(p.Dims[2])},
+		Count:      uint32(p.VoxelCount()),
+	}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, p.grid); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, p.bits)
+}
+
+// LoadPVS lee un PVS. Devuelve ErrPVSStale si se construyó con otro mesh o versión.
+func LoadPVS(path string, meshHash [32]byte) (*PVS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 1<<20)
+
+	var header struct {
+		Magic      [8]byte
+		Version    uint16
+		Hash       [32]byte
+		Origin     [3]float64
+		CellSize   float64
+		CellHeight float64
+		Dims       [3]uint32
+		Count      uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read pvs header: %w", err)
+	}
+	if header.Magic != pvsMagic {
+		return nil, fmt.Errorf("not a pvs file: %s", path)
+	}
+	if header.Version != PVSVersion {
+		return nil, fmt.Errorf("%w: version %d, expected %d", ErrPVSStale, header.Version, PVSVersion)
+	}
+	if header.Hash != meshHash {
+		return nil, fmt.Errorf("%w: built from another mesh", ErrPVSStale)
+	}
+
+	// Las cuentas de la cabecera deben cuadrar con el tamaño del fichero antes de reservar nada
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat pvs: %w", err)
+	}
+	body := info.Size() - int64(binary.Size(header))
+	cells := int64(1)
+	for _, d := range header.Dims {
+		if d == 0 || cells > body/4/int64(d) {
+			return nil, fmt.Errorf("pvs corrupt: grid %v does not fit in %d bytes", header.Dims, info.Size())
+		}
+		cells *= int64(d)
+	}
+	count := int64(header.Count)
+	if count > cells || cells*4+count*((count+63)/64)*8 != body {
+		return nil, fmt.Errorf("pvs corrupt: %d voxels in a %v grid do not match %d bytes", count, header.Dims, info.Size())
+	}
+
+	p := &PVS{
+		Origin:     r3.Vector{X: header.Origin[0], Y: header.Origin[1], Z: header.Origin[2]},
+		CellSize:   header.CellSize,
+		CellHeight: header.CellHeight,
+		Dims:       [3]int{int(header.Dims[0]), int(header.Dims[1]), int(header.Dims[2])},
+		words:      int(count+63) / 64,
+	}
+	p.grid = make([]int32, p.Dims[0]*p.Dims[1]*p.Dims[2])
+	p.bits = make([]uint64, int(count)*p.words)
+	if err := binary.Read(r, binary.LittleEndian, p.grid); err != nil {
+		return nil, fmt.Errorf("pvs truncated: %w", err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, p.bits); err != nil {
+		return nil, fmt.Errorf("pvs truncated: %w", err)
+	}
+	for _, id := range p.grid {
+		if int64(id) >= count {
+			return nil, fmt.Errorf("pvs corrupt: voxel id %d >= %d", id, count)
+		}
+	}
+	return p, nil
+}
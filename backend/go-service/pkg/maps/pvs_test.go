@@ -0,0 +1,148 @@
+package maps
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/golang/geo/r3"
+)
+
+// testPVS crea un PVS 6x5x3 con ~2/3 de vóxeles ocupados y visibilidad simétrica aleatoria
+// (como la deja BuildPVS antes de dilatar)
+func testPVS(seed int64) *PVS {
+	rng := rand.New(rand.NewSource(seed))
+	p := &PVS{CellSize: 128, CellHeight: 64, Dims: [3]int{6, 5, 3}}
+	p.grid = make([]int32, 6*5*3)
+	count := 0
+	for i := range p.grid {
+		p.grid[i] = -1
+		if rng.Intn(3) > 0 {
+			p.grid[i] = int32(count)
+			count++
+		}
+	}
+	p.words = (count + 63) / 64
+	p.bits = make([]uint64, count*p.words)
+	for a := 0; a < count; a++ {
+		p.set(a, a)
+		for b := a + 1; b < count; b++ {
+			if rng.Intn(20) == 0 {
+				p.set(a, b)
+				p.set(b, a)
+			}
+		}
+	}
+	return p
+}
+
+// dilateNaive es la definición directa: si A ve a B, A y cada vecino de B se ven
+func dilateNaive(p *PVS) []uint64 {
+	out := append([]uint64(nil), p.bits...)
+	q := &PVS{Dims: p.Dims, grid: p.grid, words: p.words, bits: out}
+	for idx, b := range p.grid {
+		if b < 0 {
+			continue
+		}
+		cx, cy, cz := idx%p.Dims[0], (idx/p.Dims[0])%p.Dims[1], idx/(p.Dims[0]*p.Dims[1])
+		for a := 0; a < p.VoxelCount(); a++ {
+			if !p.get(a, int(b)) {
+				continue
+			}
+			for dz := -1; dz <= 1; dz++ {
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						x, y, z := cx+dx, cy+dy, cz+dz
+						if x < 0 || y < 0 || z < 0 || x >= p.Dims[0] || y >= p.Dims[1] || z >= p.Dims[2] {
+							continue
+						}
+						if n := p.grid[x+y*p.Dims[0]+z*p.Dims[0]*p.Dims[1]]; n >= 0 {
+							q.set(a, int(n))
+							q.set(int(n), a)
+						}
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+func TestPVSDilate(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		p := testPVS(seed)
+		want := dilateNaive(p)
+		p.dilate()
+		if !slices.Equal(p.bits, want) {
+			t.Fatalf("seed %d: dilate differs from the naive definition", seed)
+		}
+		for a := 0; a < p.VoxelCount(); a++ {
+			for b := 0; b < p.VoxelCount(); b++ {
+				if p.get(a, b) != p.get(b, a) {
+					t.Fatalf("seed %d: not symmetric at (%d, %d)", seed, a, b)
+				}
+			}
+		}
+	}
+}
+
+func TestPVSSaveLoad(t *testing.T) {
+	p := testPVS(7)
+	p.Origin = r3.Vector{X: -100, Y: 50, Z: 0}
+	path := filepath.Join(t.TempDir(), "de_test.pvs")
+	hash := [32]byte{1, 2, 3}
+	if err := SavePVS(path, p, hash); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadPVS(path, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Origin != p.Origin || got.Dims != p.Dims || !slices.Equal(got.grid, p.grid) || !slices.Equal(got.bits, p.bits) {
+		t.Error("loaded pvs differs from the saved one")
+	}
+	if _, err := LoadPVS(path, [32]byte{9}); !errors.Is(err, ErrPVSStale) {
+		t.Errorf("other mesh hash: err = %v, want ErrPVSStale", err)
+	}
+}
+
+func TestLoadPVSBadCounts(t *testing.T) {
+	p := testPVS(3)
+	path := filepath.Join(t.TempDir(), "de_test.pvs")
+	if err := SavePVS(path, p, [32]byte{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Offsets de la cabecera: magic(8) version(2) hash(32) origin(24) cell(16) dims(12) count(4)
+	const dimsOff, countOff = 82, 94
+	for name, mutate := range map[string]func([]byte) []byte{
+		"huge dims": func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[dimsOff:], 1<<31)
+			binary.LittleEndian.PutUint32(b[dimsOff+4:], 1<<31)
+			return b
+		},
+		"zero dim":   func(b []byte) []byte { binary.LittleEndian.PutUint32(b[dimsOff+8:], 0); return b },
+		"huge count": func(b []byte) []byte { binary.LittleEndian.PutUint32(b[countOff:], 1<<31); return b },
+		"truncated":  func(b []byte) []byte { return b[:len(b)-8] },
+		"trailing":   func(b []byte) []byte { return append(b, 0, 0, 0, 0) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			bad := filepath.Join(t.TempDir(), "bad.pvs")
+			if err := os.WriteFile(bad, mutate(slices.Clone(data)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadPVS(bad, [32]byte{}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
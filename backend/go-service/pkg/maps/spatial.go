@@ -0,0 +1,227 @@
+package maps
+
+import (
+	"math"
+
+	"github.com/golang/geo/r3"
+)
+
+// ============================================================================
+// SPATIAL INDEX
+// Grid uniforme en XY para no recorrer todos los callouts / áreas del nav en
+// cada consulta (se llaman por tick y por jugador). Cada celda guarda los
+// índices (en orden ascendente) de los elementos cuyo AABB la toca, así que
+// los resultados coinciden exactamente con el recorrido lineal.
+// ============================================================================
+
+const (
+	spatialCellSize = 256.0 // Tamaño de celda por defecto (unidades)
+	spatialMaxCells = 512   // Máximo de celdas por eje (se agranda la celda si hace falta)
+
+	calloutSeedRadius = 500.0 // Distancia máxima a un point seed (approx 12 meters)
+)
+
+// grid2D es un grid uniforme de índices sobre el plano XY
+type grid2D struct {
+	minX, minY float64
+	cell       float64
+	nx, ny     int
+	cells      [][]int32
+}
+
+// newGrid2D crea un grid que cubre [minX,maxX]x[minY,maxY]
+func newGrid2D(minX, minY, maxX, maxY, cell float64) *grid2D {
+	if cell <= 0 {
+		cell = spatialCellSize
+	}
+	span := math.Max(maxX-minX, maxY-minY)
+	if span/cell > spatialMaxCells {
+		cell = span / spatialMaxCells
+	}
+	g := &grid2D{minX: minX, minY: minY, cell: cell}
+	g.nx = int((maxX-minX)/cell) + 1
+	g.ny = int((maxY-minY)/cell) + 1
+	g.cells = make([][]int32, g.nx*g.ny)
+	return g
+}
+
+// cellRange devuelve el rango de celdas (recortado al grid) que toca el rectángulo
+func (g *grid2D) cellRange(minX, minY, maxX, maxY float64) (x0, y0, x1, y1 int) {
+	clamp := func(v float64, n int) int {
+		i := int(math.Floor(v / g.cell))
+		if i < 0 {
+			return 0
+		}
+		if i >= n {
+			return n - 1
+		}
+		return i
+	}
+	return clamp(minX-g.minX, g.nx), clamp(minY-g.minY, g.ny), clamp(maxX-g.minX, g.nx), clamp(maxY-g.minY, g.ny)
+}
+
+// insert añade idx a todas las celdas que toca el rectángulo (llamar con idx crecientes)
+func (g *grid2D) insert(idx int, minX, minY, maxX, maxY float64) {
+	x0, y0, x1, y1 := g.cellRange(minX, minY, maxX, maxY)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			c := y*g.nx + x
+			g.cells[c] = append(g.cells[c], int32(idx))
+		}
+	}
+}
+
+// at devuelve los índices de la celda que contiene (x, y); nil fuera del grid
+func (g *grid2D) at(x, y float64) []int32 {
+	fx, fy := (x-g.minX)/g.cell, (y-g.minY)/g.cell
+	if fx < 0 || fy < 0 || int(fx) >= g.nx || int(fy) >= g.ny {
+		return nil
+	}
+	return g.cells[int(fy)*g.nx+int(fx)]
+}
+
+// visit llama a fn con cada celda que toca el rectángulo (un índice puede repetirse entre celdas)
+func (g *grid2D) visit(minX, minY, maxX, maxY float64, fn func(items []int32)) {
+	if maxX < g.minX || maxY < g.minY || minX > g.minX+float64(g.nx)*g.cell || minY > g.minY+float64(g.ny)*g.cell {
+		return
+	}
+	x0, y0, x1, y1 := g.cellRange(minX, minY, maxX, maxY)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			fn(g.cells[y*g.nx+x])
+		}
+	}
+}
+
+// --- Callouts ---
+
+// CalloutIndex resuelve callouts (polígonos/cajas de places.json y point seeds) con
+// un grid. Mismo resultado que FindCallout: la forma de mayor prioridad que
+// contiene la posición (empate: la primera) y, si ninguna, el seed más cercano
+// a menos de 500 unidades.
+type CalloutIndex struct {
+	callouts []Callout
+	shapes   *grid2D // nil si no hay polígonos/cajas
+	seeds    *grid2D // nil si no hay seeds
+}
+
+// NewCalloutIndex construye el índice (los callouts no se copian: no modificarlos después)
+func NewCalloutIndex(callouts []Callout) *CalloutIndex {
+	ix := &CalloutIndex{callouts: callouts}
+
+	shapeMin, shapeMax := r3.Vector{X: math.Inf(1), Y: math.Inf(1)}, r3.Vector{X: math.Inf(-1), Y: math.Inf(-1)}
+	seedMin, seedMax := shapeMin, shapeMax
+	nShapes, nSeeds := 0, 0
+	for i := range callouts {
+		c := &callouts[i]
+		switch {
+		case c.IsPolygon() || c.IsBox():
+			lo, hi := c.Bounds()
+			shapeMin, shapeMax = minMax2D(shapeMin, shapeMax, lo.X, lo.Y, hi.X, hi.Y)
+			nShapes++
+		case c.IsSeed():
+			seedMin, seedMax = minMax2D(seedMin, seedMax, c.X, c.Y, c.X, c.Y)
+			nSeeds++
+		}
+	}
+
+	if nShapes > 0 {
+		ix.shapes = newGrid2D(shapeMin.X, shapeMin.Y, shapeMax.X, shapeMax.Y, spatialCellSize)
+	}
+	if nSeeds > 0 {
+		ix.seeds = newGrid2D(seedMin.X, seedMin.Y, seedMax.X, seedMax.Y, spatialCellSize)
+	}
+	for i := range callouts {
+		c := &callouts[i]
+		switch {
+		case c.IsPolygon() || c.IsBox():
+			lo, hi := c.Bounds()
+			ix.shapes.insert(i, lo.X, lo.Y, hi.X, hi.Y)
+		case c.IsSeed():
+			ix.seeds.insert(i, c.X, c.Y, c.X, c.Y)
+		}
+	}
+	return ix
+}
+
+// Len devuelve el número de callouts indexados
+func (ix *CalloutIndex) Len() int {
+	if ix == nil {
+		return 0
+	}
+	return len(ix.callouts)
+}
+
+// Find returns the name of the callout containing the position, or empty string
+func (ix *CalloutIndex) Find(pos r3.Vector) string {
+	if ix == nil {
+		return ""
+	}
+
+	// 1. Formas: mayor prioridad; empate, menor índice (celdas ordenadas)
+	if ix.shapes != nil {
+		best := int32(-1)
+		for _, i := range ix.shapes.at(pos.X, pos.Y) {
+			if ix.callouts[i].Contains(pos) && (best < 0 || ix.callouts[i].Priority > ix.callouts[best].Priority) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			return ix.callouts[best].Name
+		}
+	}
+
+	// 2. Seed más cercano (empates: menor índice, como el recorrido lineal)
+	if ix.seeds == nil {
+		return ""
+	}
+	best, bestDist := int32(-1), calloutSeedRadius
+	ix.seeds.visit(pos.X-calloutSeedRadius, pos.Y-calloutSeedRadius, pos.X+calloutSeedRadius, pos.Y+calloutSeedRadius, func(items []int32) {
+		for _, i := range items {
+			c := &ix.callouts[i]
+			dist := math.Sqrt((c.X-pos.X)*(c.X-pos.X) + (c.Y-pos.Y)*(c.Y-pos.Y) + (c.Z-pos.Z)*(c.Z-pos.Z))
+			if dist < bestDist || (dist == bestDist && best >= 0 && i < best) {
+				best, bestDist = i, dist
+			}
+		}
+	})
+	if best < 0 {
+		return ""
+	}
+	return ix.callouts[best].Name
+}
+
+// --- Nav areas ---
+
+// buildAreaGrid indexa los polígonos de las áreas por su AABB en XY
+func (nm *NavMesh) buildAreaGrid() {
+	if len(nm.Areas) == 0 {
+		nm.areaGrid = nil
+		return
+	}
+	min, max := r3.Vector{X: math.Inf(1), Y: math.Inf(1)}, r3.Vector{X: math.Inf(-1), Y: math.Inf(-1)}
+	for i := range nm.Areas {
+		if len(nm.Areas[i].Corners) == 0 {
+			continue
+		}
+		lo, hi := cornerBounds(nm.Areas[i].Corners)
+		min, max = minMax2D(min, max, lo.X, lo.Y, hi.X, hi.Y)
+	}
+	if math.IsInf(min.X, 1) {
+		nm.areaGrid = nil
+		return
+	}
+	g := newGrid2D(min.X, min.Y, max.X, max.Y, spatialCellSize)
+	for i := range nm.Areas {
+		if len(nm.Areas[i].Corners) == 0 {
+			continue
+		}
+		lo, hi := cornerBounds(nm.Areas[i].Corners)
+		g.insert(i, lo.X, lo.Y, hi.X, hi.Y)
+	}
+	nm.areaGrid = g
+}
+
+func minMax2D(min, max r3.Vector, x0, y0, x1, y1 float64) (r3.Vector, r3.Vector) {
+	return r3.Vector{X: math.Min(min.X, x0), Y: math.Min(min.Y, y0)}, r3.Vector{X: math.Max(max.X, x1), Y: math.Max(max.Y, y1)}
+}
@@ -0,0 +1,137 @@
+package maps
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/golang/geo/r3"
+)
+
+// Consultas de callout / área del nav: recorrido lineal vs. grid (CalloutIndex y el
+// grid de NavMesh) sobre callouts y áreas sintéticas.
+//
+//	go test ./pkg/maps -run '^$' -bench 'CalloutIndex|NavAreaLookup'
+
+const spatialItems = 2000 // Callouts y (~) áreas del nav sintéticos
+
+// spatialQueries devuelve posiciones aleatorias dentro del área cubierta (altura de
+// jugador); la mitad sobre centros de áreas del nav (si no, casi todas caen fuera)
+func spatialQueries(rng *rand.Rand, nav *NavMesh, n int) []r3.Vector {
+	queries := make([]r3.Vector, n)
+	for i := range queries {
+		queries[i] = r3.Vector{X: rng.Float64()*8000 - 4000, Y: rng.Float64()*8000 - 4000, Z: rng.Float64() * 300}
+	}
+	for i := 0; i < len(queries); i += 2 {
+		queries[i] = nav.Areas[rng.Intn(len(nav.Areas))].Center
+	}
+	return queries
+}
+
+func TestCalloutIndexMatchesLinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	callouts := syntheticCallouts(rng, spatialItems)
+	index := NewCalloutIndex(callouts)
+	for i, q := range spatialQueries(rng, syntheticNav(rng, spatialItems), 5000) {
+		if want, got := FindCallout(q, callouts), index.Find(q); got != want {
+			t.Fatalf("query %d %v: grid %q, linear %q", i, q, got, want)
+		}
+	}
+}
+
+func TestNavAreaGridMatchesLinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	nav := syntheticNav(rng, spatialItems)
+	linear := &NavMesh{Areas: nav.Areas} // Sin BuildIndex: recorrido completo
+	for i, q := range spatialQueries(rng, nav, 5000) {
+		if want, got := linear.GetNearestArea(q), nav.GetNearestArea(q); got != want {
+			t.Fatalf("query %d %v: grid %v, linear %v", i, q, got, want)
+		}
+	}
+}
+
+func BenchmarkCalloutIndex(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	callouts := syntheticCallouts(rng, spatialItems)
+	index := NewCalloutIndex(callouts)
+	queries := spatialQueries(rng, syntheticNav(rng, spatialItems), 20000)
+
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			FindCallout(queries[i%len(queries)], callouts)
+		}
+	})
+	b.Run("grid", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			index.Find(queries[i%len(queries)])
+		}
+	})
+}
+
+func BenchmarkNavAreaLookup(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	nav := syntheticNav(rng, spatialItems)
+	linear := &NavMesh{Areas: nav.Areas}
+	queries := spatialQueries(rng, nav, 20000)
+
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			linear.GetNearestArea(queries[i%len(queries)])
+		}
+	})
+	b.Run("grid", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			nav.GetNearestArea(queries[i%len(queries)])
+		}
+	})
+}
+
+// syntheticCallouts genera cajas, triángulos con prioridad y point seeds (un tercio de cada)
+func syntheticCallouts(rng *rand.Rand, n int) []Callout {
+	out := make([]Callout, 0, n)
+	for i := 0; i < n; i++ {
+		x, y, z := rng.Float64()*8000-4000, rng.Float64()*8000-4000, rng.Float64()*200
+		name := fmt.Sprintf("Callout%d", i)
+		w, h := 100+rng.Float64()*600, 100+rng.Float64()*600
+		switch i % 3 {
+		case 0:
+			out = append(out, Callout{Name: name,
+				Min: &Vector3{X: x, Y: y, Z: z - 100}, Max: &Vector3{X: x + w, Y: y + h, Z: z + 200}})
+		case 1:
+			zMin, zMax := z-100, z+200
+			out = append(out, Callout{Name: name, Priority: rng.Intn(3), ZMin: &zMin, ZMax: &zMax,
+				Polygon: []Vector2{{X: x, Y: y}, {X: x + w, Y: y}, {X: x + w/2, Y: y + h}}})
+		default:
+			out = append(out, Callout{Name: name, X: x, Y: y, Z: z})
+		}
+	}
+	return out
+}
+
+// syntheticNav genera ~n áreas cuadradas en un grid con dos pisos
+func syntheticNav(rng *rand.Rand, n int) *NavMesh {
+	nav := &NavMesh{}
+	side := 1
+	for side*side*2 < n {
+		side++
+	}
+	size := 8000.0 / float64(side)
+	for floor := 0; floor < 2; floor++ {
+		for y := 0; y < side; y++ {
+			for x := 0; x < side; x++ {
+				if floor == 1 && rng.Intn(3) != 0 {
+					continue // Segundo piso parcial
+				}
+				x0, y0, z := float64(x)*size-4000, float64(y)*size-4000, float64(floor)*200
+				corners := []r3.Vector{{X: x0, Y: y0, Z: z}, {X: x0 + size, Y: y0, Z: z}, {X: x0 + size, Y: y0 + size, Z: z}, {X: x0, Y: y0 + size, Z: z}}
+				nav.Areas = append(nav.Areas, NavArea{
+					ID:      uint32(len(nav.Areas) + 1),
+					Corners: corners,
+					Center:  r3.Vector{X: x0 + size/2, Y: y0 + size/2, Z: z},
+				})
+			}
+		}
+	}
+	nav.BuildIndex()
+	return nav
+}
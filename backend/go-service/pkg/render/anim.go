@@ -0,0 +1,253 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"math"
+)
+
+// ============================================================================
+// ANIMATION ENCODERS
+// GIF (paleta fija de 256 colores, sin dithering) y APNG (PNG animado, color
+// completo). Los frames se añaden de uno en uno: el APNG se escribe según
+// llegan; el GIF guarda los frames en paleta (1 byte/pixel) hasta Close.
+// ============================================================================
+
+// Formatos de animación
+const (
+	FormatGIF  = "gif"
+	FormatAPNG = "apng"
+)
+
+// ErrUnknownFormat se devuelve para formatos que no son gif ni apng
+var ErrUnknownFormat = errors.New("unknown animation format")
+
+// AnimationWriter recibe los frames de una animación
+type AnimationWriter interface {
+	AddFrame(img *image.RGBA) error
+	Close() error
+}
+
+// NewAnimationWriter crea el encoder del formato pedido. frames es el número total de
+// frames (el APNG lo declara en la cabecera) y delayMs el tiempo entre frames.
+func NewAnimationWriter(w io.Writer, format string, frames, delayMs int) (AnimationWriter, error) {
+	switch format {
+	case FormatGIF, "":
+		return &gifWriter{w: w, delay: int(math.Max(2, math.Round(float64(delayMs)/10)))}, nil
+	case FormatAPNG:
+		return &apngWriter{w: w, frames: frames, delayMs: delayMs}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// ContentType devuelve el MIME type del formato
+func ContentType(format string) string {
+	if format == FormatAPNG {
+		return "image/apng"
+	}
+	return "image/gif"
+}
+
+// --- GIF ---
+
+type gifWriter struct {
+	w     io.Writer
+	delay int // Centésimas de segundo
+	anim  gif.GIF
+}
+
+// gifPalette es Plan9 (256 colores bien repartidos); gifLookup cachea el índice por
+// color cuantizado a 5 bits por canal para no buscar en la paleta en cada pixel
+var (
+	gifPalette = color.Palette(palette.Plan9)
+	gifLookup  = buildGIFLookup()
+)
+
+func buildGIFLookup() []uint8 {
+	lookup := make([]uint8, 1<<15)
+	for i := range lookup {
+		r, g, b := uint8(i>>10)<<3|4, uint8(i>>5&31)<<3|4, uint8(i&31)<<3|4
+		lookup[i] = uint8(gifPalette.Index(color.RGBA{r, g, b, 255}))
+	}
+	return lookup
+}
+
+func (g *gifWriter) AddFrame(img *image.RGBA) error {
+	b := img.Bounds()
+	p := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), gifPalette)
+	for y := 0; y < b.Dy(); y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, b.Min.Y+y):]
+		out := p.Pix[y*p.Stride:]
+		for x := 0; x < b.Dx(); x++ {
+			r, gg, bb := row[x*4], row[x*4+1], row[x*4+2]
+			out[x] = gifLookup[int(r>>3)<<10|int(gg>>3)<<5|int(bb>>3)]
+		}
+	}
+	g.anim.Image = append(g.anim.Image, p)
+	g.anim.Delay = append(g.anim.Delay, g.delay)
+	return nil
+}
+
+func (g *gifWriter) Close() error {
+	if len(g.anim.Image) == 0 {
+		return errors.New("animation has no frames")
+	}
+	return gif.EncodeAll(g.w, &g.anim)
+}
+
+// --- APNG ---
+
+// apngWriter escribe un PNG animado (acTL/fcTL/fdAT de la especificación APNG).
+// Todos los frames se codifican aquí con el mismo formato fijo (RGBA de 8 bits,
+// filtro Sub por fila) en lugar de con image/png, que elige RGB o RGBA según la
+// opacidad de cada imagen: así el IHDR del primer frame vale para todos. Los
+// pixels se escriben tal cual (sin des-premultiplicar): los frames de Canvas son opacos.
+type apngWriter struct {
+	w       io.Writer
+	frames  int
+	delayMs int
+	written int
+	width   int
+	height  int
+	seq     uint32
+	err     error
+	row     []byte // Fila filtrada (1 byte de filtro + 4 por pixel), reutilizada
+}
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// PNG: color type 6 (RGBA), bit depth 8, filtro Sub
+const (
+	pngColorRGBA = 6
+	pngFilterSub = 1
+)
+
+func (a *apngWriter) AddFrame(img *image.RGBA) error {
+	if a.err != nil {
+		return a.err
+	}
+	if a.written >= a.frames {
+		return fmt.Errorf("apng: more frames than declared (%d)", a.frames)
+	}
+
+	b := img.Bounds()
+	if a.written == 0 {
+		a.width, a.height = b.Dx(), b.Dy()
+		a.write(pngSignature)
+		ihdr := make([]byte, 13)
+		binary.BigEndian.PutUint32(ihdr[0:], uint32(a.width))
+		binary.BigEndian.PutUint32(ihdr[4:], uint32(a.height))
+		ihdr[8] = 8 // Bit depth
+		ihdr[9] = pngColorRGBA
+		// compression, filter, interlace = 0
+		a.chunk("IHDR", ihdr)
+		actl := make([]byte, 8)
+		binary.BigEndian.PutUint32(actl[0:], uint32(a.frames))
+		binary.BigEndian.PutUint32(actl[4:], 0) // Repetir siempre
+		a.chunk("acTL", actl)
+	} else if b.Dx() != a.width || b.Dy() != a.height {
+		return fmt.Errorf("apng: frame %d is %dx%d, expected %dx%d", a.written, b.Dx(), b.Dy(), a.width, a.height)
+	}
+
+	data, err := a.encodeFrame(img)
+	if err != nil {
+		return err
+	}
+
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:], a.nextSeq())
+	binary.BigEndian.PutUint32(fctl[4:], uint32(a.width))
+	binary.BigEndian.PutUint32(fctl[8:], uint32(a.height))
+	binary.BigEndian.PutUint16(fctl[20:], uint16(a.delayMs))
+	binary.BigEndian.PutUint16(fctl[22:], 1000)
+	// x/y offset = 0, dispose_op = none, blend_op = source
+	a.chunk("fcTL", fctl)
+
+	if a.written == 0 {
+		a.chunk("IDAT", data)
+	} else {
+		fdat := make([]byte, 4+len(data))
+		binary.BigEndian.PutUint32(fdat, a.nextSeq())
+		copy(fdat[4:], data)
+		a.chunk("fdAT", fdat)
+	}
+	a.written++
+	return a.err
+}
+
+// encodeFrame devuelve el flujo zlib de los pixels RGBA del frame con el filtro Sub
+func (a *apngWriter) encodeFrame(img *image.RGBA) ([]byte, error) {
+	b := img.Bounds()
+	stride := a.width * 4
+	if len(a.row) != 1+stride {
+		a.row = make([]byte, 1+stride)
+	}
+	a.row[0] = pngFilterSub
+
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, zlib.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	for y := 0; y < a.height; y++ {
+		src := img.Pix[img.PixOffset(b.Min.X, b.Min.Y+y):][:stride]
+		out := a.row[1:]
+		copy(out[:4], src[:4])
+		for i := 4; i < stride; i++ {
+			out[i] = src[i] - src[i-4]
+		}
+		if _, err := zw.Write(a.row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (a *apngWriter) Close() error {
+	if a.err != nil {
+		return a.err
+	}
+	if a.written != a.frames {
+		return fmt.Errorf("apng: %d frames written, %d declared", a.written, a.frames)
+	}
+	a.chunk("IEND", nil)
+	return a.err
+}
+
+func (a *apngWriter) nextSeq() uint32 {
+	s := a.seq
+	a.seq++
+	return s
+}
+
+func (a *apngWriter) write(b []byte) {
+	if a.err == nil {
+		_, a.err = a.w.Write(b)
+	}
+}
+
+func (a *apngWriter) chunk(typ string, data []byte) {
+	head := make([]byte, 8)
+	binary.BigEndian.PutUint32(head, uint32(len(data)))
+	copy(head[4:], typ)
+	crc := crc32.NewIEEE()
+	crc.Write(head[4:])
+	crc.Write(data)
+	tail := make([]byte, 4)
+	binary.BigEndian.PutUint32(tail, crc.Sum32())
+	a.write(head)
+	a.write(data)
+	a.write(tail)
+}
@@ -0,0 +1,100 @@
+package render
+
+import (
+	"image/color"
+	"math"
+)
+
+// Point es una posición del mundo a pintar (Weight solo cuenta en heatmaps; 0 = 1)
+type Point struct {
+	X, Y, Z float64
+	Weight  float64
+	Color   color.RGBA // Solo plots (cero = color por defecto)
+}
+
+// Heatmap acumula un kernel gaussiano de radio radius (unidades del mundo) por punto
+// y lo pinta sobre el lienzo con una rampa azul → verde → amarillo → rojo.
+// La densidad se normaliza al máximo; las zonas con menos del 2% no se pintan.
+func (c *Canvas) Heatmap(points []Point, radius float64) {
+	size := c.Size()
+	if len(points) == 0 || size == 0 {
+		return
+	}
+	sigma := math.Max(c.Units(radius)/2, 1)
+	r := int(math.Ceil(sigma * 3))
+
+	// Kernel precalculado
+	kernel := make([]float64, (2*r+1)*(2*r+1))
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			kernel[(dy+r)*(2*r+1)+dx+r] = math.Exp(-float64(dx*dx+dy*dy) / (2 * sigma * sigma))
+		}
+	}
+
+	density := make([]float64, size*size)
+	for _, p := range points {
+		w := p.Weight
+		if w == 0 {
+			w = 1
+		}
+		fx, fy := c.Point(p.X, p.Y)
+		cx, cy := int(fx), int(fy)
+		for dy := -r; dy <= r; dy++ {
+			y := cy + dy
+			if y < 0 || y >= size {
+				continue
+			}
+			for dx := -r; dx <= r; dx++ {
+				x := cx + dx
+				if x < 0 || x >= size {
+					continue
+				}
+				density[y*size+x] += w * kernel[(dy+r)*(2*r+1)+dx+r]
+			}
+		}
+	}
+
+	peak := 0.0
+	for _, d := range density {
+		peak = math.Max(peak, d)
+	}
+	if peak == 0 {
+		return
+	}
+	for i, d := range density {
+		v := d / peak
+		if v < 0.02 {
+			continue
+		}
+		col := heatColor(v)
+		col.A = uint8(80 + 140*v)
+		c.Blend(i%size, i/size, col)
+	}
+}
+
+// heatColor devuelve el color de la rampa para v en [0, 1]
+func heatColor(v float64) color.RGBA {
+	stops := []struct {
+		at      float64
+		r, g, b float64
+	}{
+		{0, 40, 60, 220},
+		{0.35, 40, 200, 90},
+		{0.65, 250, 220, 40},
+		{1, 230, 30, 30},
+	}
+	for i := 1; i < len(stops); i++ {
+		if v <= stops[i].at {
+			a, b := stops[i-1], stops[i]
+			t := (v - a.at) / (b.at - a.at)
+			return color.RGBA{
+				R: uint8(a.r + (b.r-a.r)*t),
+				G: uint8(a.g + (b.g-a.g)*t),
+				B: uint8(a.b + (b.b-a.b)*t),
+				A: 255,
+			}
+		}
+	}
+	last := stops[len(stops)-1]
+	return color.RGBA{uint8(last.r), uint8(last.g), uint8(last.b), 255}
+}
@@ -0,0 +1,73 @@
+package render
+
+import (
+	"image/color"
+	"math"
+)
+
+// Colores por defecto de los plots
+var (
+	ColorCT      = color.RGBA{93, 160, 235, 255}
+	ColorT       = color.RGBA{235, 180, 60, 255}
+	ColorNeutral = color.RGBA{230, 230, 230, 255}
+)
+
+// Segment es una línea entre dos posiciones del mundo (p.ej. atacante → víctima)
+type Segment struct {
+	From, To Point
+	Color    color.RGBA
+}
+
+// Plot pinta cada punto como un círculo de radio radius pixels con borde oscuro
+func (c *Canvas) Plot(points []Point, radius float64) {
+	if radius <= 0 {
+		radius = 4
+	}
+	outline := color.RGBA{0, 0, 0, 200}
+	for _, p := range points {
+		col := p.Color
+		if col == (color.RGBA{}) {
+			col = ColorNeutral
+		}
+		x, y := c.Point(p.X, p.Y)
+		c.disc(x, y, radius+1, outline)
+		c.disc(x, y, radius, col)
+	}
+}
+
+// Lines pinta los segmentos (1 px, semitransparentes)
+func (c *Canvas) Lines(segments []Segment) {
+	for _, s := range segments {
+		col := s.Color
+		if col == (color.RGBA{}) {
+			col = ColorNeutral
+		}
+		col.A = 160
+		x0, y0 := c.Point(s.From.X, s.From.Y)
+		x1, y1 := c.Point(s.To.X, s.To.Y)
+		n := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+		for i := 0; i <= n; i++ {
+			t := float64(i) / float64(n)
+			c.Blend(int(x0+(x1-x0)*t), int(y0+(y1-y0)*t), col)
+		}
+	}
+}
+
+// disc rellena un círculo centrado en (x, y) (pixels del lienzo) con antialias en el borde
+func (c *Canvas) disc(x, y, radius float64, col color.RGBA) {
+	r := int(math.Ceil(radius))
+	cx, cy := int(x), int(y)
+	for dy := -r - 1; dy <= r+1; dy++ {
+		for dx := -r - 1; dx <= r+1; dx++ {
+			px, py := float64(cx+dx)+0.5, float64(cy+dy)+0.5
+			d := math.Hypot(px-x, py-y)
+			cover := math.Min(1, math.Max(0, radius+0.5-d))
+			if cover <= 0 {
+				continue
+			}
+			pc := col
+			pc.A = uint8(float64(col.A) * cover)
+			c.Blend(cx+dx, cy+dy, pc)
+		}
+	}
+}
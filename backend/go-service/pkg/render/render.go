@@ -0,0 +1,176 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// ============================================================================
+// RADAR RENDERING
+// Proyección mundo → imagen del radar con la transformación del overview
+// (pos_x, pos_y, scale; el radar de CS2 mide 1024x1024) y un lienzo RGBA sobre
+// el que se pintan heatmaps y puntos. Solo usa la librería estándar.
+// ============================================================================
+
+// RadarSize es el lado (px) del radar al que se refiere Transform.Scale
+const RadarSize = 1024
+
+// ErrNoRadar indica que el mapa no tiene imagen de radar en el directorio de mapas
+var ErrNoRadar = errors.New("radar image not found")
+
+// Transform es la transformación del overview del mapa
+type Transform struct {
+	PosX  float64 // Esquina superior izquierda (unidades del mundo)
+	PosY  float64
+	Scale float64 // Unidades del mundo por pixel del radar de RadarSize
+}
+
+// Project devuelve el pixel (en el radar de RadarSize) de una posición del mundo
+func (t Transform) Project(x, y float64) (float64, float64) {
+	return (x - t.PosX) / t.Scale, (t.PosY - y) / t.Scale
+}
+
+// Unproject es la inversa de Project
+func (t Transform) Unproject(px, py float64) (float64, float64) {
+	return t.PosX + px*t.Scale, t.PosY - py*t.Scale
+}
+
+// Crop devuelve la transformación de un recorte cuadrado centrado en el rectángulo del
+// mundo [minX, maxX] x [minY, maxY] (el lado es el mayor de los dos)
+func (t Transform) Crop(minX, minY, maxX, maxY float64) Transform {
+	side := math.Max(maxX-minX, maxY-minY)
+	cx, cy := (minX+maxX)/2, (minY+maxY)/2
+	return Transform{PosX: cx - side/2, PosY: cy + side/2, Scale: side / RadarSize}
+}
+
+// CropImage recorta bg (una imagen alineada con t, de cualquier tamaño) a la zona de
+// crop. Lo que queda fuera de bg es transparente.
+func CropImage(bg image.Image, t, crop Transform) image.Image {
+	factor := float64(bg.Bounds().Dx()) / RadarSize
+	x0, y0 := t.Project(crop.PosX, crop.PosY)
+	side := int(math.Round(crop.Scale * RadarSize / t.Scale * factor))
+	if side <= 0 {
+		return nil
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	origin := bg.Bounds().Min.Add(image.Pt(int(math.Round(x0*factor)), int(math.Round(y0*factor))))
+	draw.Draw(dst, dst.Bounds(), bg, origin, draw.Src)
+	return dst
+}
+
+// Canvas es una imagen alineada con el radar del mapa (de cualquier tamaño)
+type Canvas struct {
+	Img       *image.RGBA
+	Transform Transform
+	factor    float64 // Pixels del lienzo por pixel del radar de RadarSize
+}
+
+// NewCanvas crea un lienzo cuadrado de size px; background (opcional) se escala a ese tamaño
+func NewCanvas(t Transform, size int, background image.Image) *Canvas {
+	if size <= 0 {
+		size = RadarSize
+	}
+	c := &Canvas{
+		Img:       image.NewRGBA(image.Rect(0, 0, size, size)),
+		Transform: t,
+		factor:    float64(size) / RadarSize,
+	}
+	draw.Draw(c.Img, c.Img.Bounds(), &image.Uniform{color.RGBA{24, 24, 28, 255}}, image.Point{}, draw.Src)
+	if background != nil {
+		scaleInto(c.Img, background)
+	}
+	return c
+}
+
+// Size devuelve el lado del lienzo en pixels
+func (c *Canvas) Size() int { return c.Img.Bounds().Dx() }
+
+// CopyFrom sobrescribe los pixels con los de src (mismo tamaño), p.ej. para reutilizar
+// el fondo ya escalado en cada frame de una animación
+func (c *Canvas) CopyFrom(src *Canvas) {
+	copy(c.Img.Pix, src.Img.Pix)
+}
+
+// Point devuelve el pixel del lienzo de una posición del mundo
+func (c *Canvas) Point(x, y float64) (float64, float64) {
+	px, py := c.Transform.Project(x, y)
+	return px * c.factor, py * c.factor
+}
+
+// Units convierte una distancia del mundo a pixels del lienzo
+func (c *Canvas) Units(d float64) float64 {
+	return d / c.Transform.Scale * c.factor
+}
+
+// Blend mezcla col (con su alfa) sobre el pixel (x, y)
+func (c *Canvas) Blend(x, y int, col color.RGBA) {
+	if !(image.Point{X: x, Y: y}).In(c.Img.Rect) || col.A == 0 {
+		return
+	}
+	i := c.Img.PixOffset(x, y)
+	p := c.Img.Pix[i : i+4 : i+4]
+	a := uint32(col.A)
+	p[0] = uint8((uint32(col.R)*a + uint32(p[0])*(255-a)) / 255)
+	p[1] = uint8((uint32(col.G)*a + uint32(p[1])*(255-a)) / 255)
+	p[2] = uint8((uint32(col.B)*a + uint32(p[2])*(255-a)) / 255)
+	p[3] = 255
+}
+
+// EncodePNG escribe el lienzo como PNG
+func (c *Canvas) EncodePNG(w io.Writer) error {
+	return png.Encode(w, c.Img)
+}
+
+// scaleInto copia src escalado (vecino más cercano) sobre todo dst
+func scaleInto(dst *image.RGBA, src image.Image) {
+	sb := src.Bounds()
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	for y := 0; y < h; y++ {
+		sy := sb.Min.Y + y*sb.Dy()/h
+		for x := 0; x < w; x++ {
+			sx := sb.Min.X + x*sb.Dx()/w
+			r, g, b, a := src.At(sx, sy).RGBA()
+			if a == 0 {
+				continue
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r>>8), uint8(g>>8), uint8(b>>8), 255
+		}
+	}
+}
+
+// --- Fondos ---
+
+// LoadRadar carga el radar del mapa en PNG (level = "" o "default" para el principal,
+// p.ej. "lower" para de_nuke_lower_radar.png). Los .dds/.vtex_c del juego hay que
+// convertirlos antes a PNG (Source 2 Viewer).
+func LoadRadar(mapsDir, mapName, level string) (image.Image, error) {
+	name := mapName + "_radar.png"
+	if level != "" && level != "default" {
+		name = mapName + "_" + level + "_radar.png"
+	}
+	for _, p := range []string{
+		filepath.Join(mapsDir, mapName, name),
+		filepath.Join(mapsDir, "overviews", name),
+		filepath.Join(mapsDir, name),
+	} {
+		f, err := os.Open(p)
+		if err != nil {
+			continue
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		return img, nil
+	}
+	return nil, ErrNoRadar
+}
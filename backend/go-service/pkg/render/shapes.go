@@ -0,0 +1,112 @@
+package render
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+// ============================================================================
+// SHAPES
+// Primitivas en pixels del lienzo (usar Canvas.Point / Canvas.Units para pasar
+// de coordenadas del mundo) para los frames animados: círculos, líneas con
+// grosor, polígonos y conos de visión.
+// ============================================================================
+
+// FillCircle rellena un círculo (con antialias en el borde)
+func (c *Canvas) FillCircle(x, y, radius float64, col color.RGBA) {
+	c.disc(x, y, radius, col)
+}
+
+// Ring pinta una circunferencia de grosor width
+func (c *Canvas) Ring(x, y, radius, width float64, col color.RGBA) {
+	r := int(math.Ceil(radius + width))
+	cx, cy := int(x), int(y)
+	for dy := -r - 1; dy <= r+1; dy++ {
+		for dx := -r - 1; dx <= r+1; dx++ {
+			d := math.Hypot(float64(cx+dx)+0.5-x, float64(cy+dy)+0.5-y)
+			cover := math.Min(1, math.Max(0, width/2+0.5-math.Abs(d-radius)))
+			if cover <= 0 {
+				continue
+			}
+			pc := col
+			pc.A = uint8(float64(col.A) * cover)
+			c.Blend(cx+dx, cy+dy, pc)
+		}
+	}
+}
+
+// Line pinta un segmento de grosor width (<= 1: un pixel)
+func (c *Canvas) Line(x0, y0, x1, y1, width float64, col color.RGBA) {
+	n := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	if width <= 1 {
+		for i := 0; i <= n; i++ {
+			t := float64(i) / float64(n)
+			c.Blend(int(x0+(x1-x0)*t), int(y0+(y1-y0)*t), col)
+		}
+		return
+	}
+	// Grosor: rellenar el rectángulo del segmento como polígono
+	dx, dy := x1-x0, y1-y0
+	l := math.Hypot(dx, dy)
+	if l == 0 {
+		c.disc(x0, y0, width/2, col)
+		return
+	}
+	nx, ny := -dy/l*width/2, dx/l*width/2
+	c.FillPolygon([][2]float64{{x0 + nx, y0 + ny}, {x1 + nx, y1 + ny}, {x1 - nx, y1 - ny}, {x0 - nx, y0 - ny}}, col)
+}
+
+// FillPolygon rellena un polígono (scanline, par-impar) con vértices en pixels
+func (c *Canvas) FillPolygon(pts [][2]float64, col color.RGBA) {
+	if len(pts) < 3 {
+		return
+	}
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range pts {
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	size := c.Size()
+	y0, y1 := int(math.Max(0, math.Floor(minY))), int(math.Min(float64(size-1), math.Ceil(maxY)))
+
+	var xs []float64
+	for y := y0; y <= y1; y++ {
+		sy := float64(y) + 0.5
+		xs = xs[:0]
+		for i := range pts {
+			a, b := pts[i], pts[(i+1)%len(pts)]
+			if (a[1] <= sy) == (b[1] <= sy) {
+				continue
+			}
+			xs = append(xs, a[0]+(sy-a[1])/(b[1]-a[1])*(b[0]-a[0]))
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			for x := int(math.Ceil(xs[i] - 0.5)); float64(x)+0.5 <= xs[i+1]; x++ {
+				c.Blend(x, y, col)
+			}
+		}
+	}
+}
+
+// FillSector rellena un sector circular (cono de visión). angle en grados con 0 =
+// +X del mundo y sentido antihorario (yaw de CS); spread es la apertura total.
+func (c *Canvas) FillSector(x, y, radius, angle, spread float64, col color.RGBA) {
+	const steps = 12
+	pts := [][2]float64{{x, y}}
+	for i := 0; i <= steps; i++ {
+		a := (angle - spread/2 + spread*float64(i)/steps) * math.Pi / 180
+		// El eje Y de la imagen va hacia abajo
+		pts = append(pts, [2]float64{x + math.Cos(a)*radius, y - math.Sin(a)*radius})
+	}
+	c.FillPolygon(pts, col)
+}
+
+// FillRect rellena un rectángulo en pixels
+func (c *Canvas) FillRect(x0, y0, x1, y1 int, col color.RGBA) {
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			c.Blend(x, y, col)
+		}
+	}
+}
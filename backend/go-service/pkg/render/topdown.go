@@ -0,0 +1,223 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cs2-demo-service/pkg/geometry"
+)
+
+// ============================================================================
+// TOP-DOWN MAP IMAGE
+// Planta del physics mesh para los mapas sin radar: se rasterizan los suelos
+// (normal hacia arriba) de una sección vertical con z-buffer, se sombrean por
+// altura y se oscurecen los escalones/paredes. La imagen está alineada con la
+// transformación del overview, así que los plots caen en su sitio.
+//
+// Se cachea junto al mesh (<mesh>_topdown_<nivel>-<clave>.png); la clave
+// incluye el hash del mesh, la transformación, el rango Z y el tamaño, así que
+// si cambia el mesh (o el overview) se regenera sola.
+// ============================================================================
+
+const (
+	topDownMinNormalZ = 0.5  // Suelos: normal con Z mayor que esto (pendientes de hasta 60º)
+	topDownEdgeStep   = 24.0 // Diferencia de altura entre pixels vecinos que se pinta como borde
+)
+
+// Level es una sección vertical del mapa: suelos con Min <= z < Max
+type Level struct {
+	Name     string
+	Min, Max float64
+}
+
+// AllLevels es la sección de los mapas de un solo nivel
+var AllLevels = Level{Name: "default", Min: math.Inf(-1), Max: math.Inf(1)}
+
+// TopDown rasteriza en planta los suelos del mesh de la sección level, de size px,
+// alineado con t. Los pixels sin suelo quedan transparentes.
+func TopDown(mesh *geometry.Mesh, t Transform, size int, level Level) *image.RGBA {
+	if size <= 0 {
+		size = RadarSize
+	}
+	factor := float64(size) / RadarSize
+	project := func(x, y float64) (float64, float64) {
+		px, py := t.Project(x, y)
+		return px * factor, py * factor
+	}
+
+	// 1. Z-buffer con la altura del suelo más alto de la sección en cada pixel
+	height := make([]float64, size*size)
+	for i := range height {
+		height[i] = math.Inf(-1)
+	}
+	for _, tri := range mesh.Triangles {
+		if tri.Normal.Z < topDownMinNormalZ {
+			continue // Paredes y techos
+		}
+		z := (tri.V0.Z + tri.V1.Z + tri.V2.Z) / 3
+		if z < level.Min || z >= level.Max {
+			continue
+		}
+		ax, ay := project(tri.V0.X, tri.V0.Y)
+		bx, by := project(tri.V1.X, tri.V1.Y)
+		cx, cy := project(tri.V2.X, tri.V2.Y)
+		rasterTriangle(size, ax, ay, bx, by, cx, cy, func(i int, w0, w1, w2 float64) {
+			if h := w0*tri.V0.Z + w1*tri.V1.Z + w2*tri.V2.Z; h > height[i] {
+				height[i] = h
+			}
+		})
+	}
+
+	// 2. Rango de alturas pintadas (para el sombreado)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, h := range height {
+		if !math.IsInf(h, -1) {
+			lo, hi = math.Min(lo, h), math.Max(hi, h)
+		}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	if math.IsInf(lo, 1) {
+		return img
+	}
+	span := math.Max(hi-lo, 1)
+
+	// 3. Sombreado por altura (más claro = más alto) + bordes en los desniveles
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			h := height[y*size+x]
+			if math.IsInf(h, -1) {
+				continue
+			}
+			v := (h - lo) / span
+			col := color.RGBA{uint8(60 + 110*v), uint8(66 + 110*v), uint8(76 + 104*v), 255}
+			if isEdge(height, size, x, y, h) {
+				col = color.RGBA{col.R / 3, col.G / 3, col.B / 3, 255}
+			}
+			img.SetRGBA(x, y, col)
+		}
+	}
+	return img
+}
+
+// isEdge indica si algún vecino (4-conexo) no tiene suelo o está a otra altura
+func isEdge(height []float64, size, x, y int, h float64) bool {
+	for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+		nx, ny := x+d[0], y+d[1]
+		if nx < 0 || ny < 0 || nx >= size || ny >= size {
+			continue
+		}
+		n := height[ny*size+nx]
+		if math.IsInf(n, -1) || math.Abs(n-h) > topDownEdgeStep {
+			return true
+		}
+	}
+	return false
+}
+
+// rasterTriangle llama a fn con el índice y las coordenadas baricéntricas de cada
+// pixel (centro) dentro del triángulo
+func rasterTriangle(size int, ax, ay, bx, by, cx, cy float64, fn func(i int, w0, w1, w2 float64)) {
+	area := (bx-ax)*(cy-ay) - (by-ay)*(cx-ax)
+	if area == 0 {
+		return
+	}
+	minX, maxX := max(int(min(ax, bx, cx)), 0), min(int(max(ax, bx, cx))+1, size-1)
+	minY, maxY := max(int(min(ay, by, cy)), 0), min(int(max(ay, by, cy))+1, size-1)
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			w0 := ((bx-px)*(cy-py) - (by-py)*(cx-px)) / area
+			w1 := ((cx-px)*(ay-py) - (cy-py)*(ax-px)) / area
+			w2 := 1 - w0 - w1
+			if w0 >= 0 && w1 >= 0 && w2 >= 0 {
+				fn(y*size+x, w0, w1, w2)
+			}
+		}
+	}
+}
+
+// --- Caché en disco ---
+
+// TopDownPath devuelve la ruta de la imagen cacheada de una sección, junto al mesh
+func TopDownPath(meshPath string, t Transform, size int, level Level, meshHash [32]byte) string {
+	h := sha256.New()
+	h.Write(meshHash[:])
+	fmt.Fprintf(h, "|%g|%g|%g|%d|%g|%g", t.PosX, t.PosY, t.Scale, size, level.Min, level.Max)
+	key := hex.EncodeToString(h.Sum(nil)[:6])
+	return topDownPrefix(meshPath, level) + key + ".png"
+}
+
+func topDownPrefix(meshPath string, level Level) string {
+	base := strings.TrimSuffix(meshPath, filepath.Ext(meshPath))
+	return base + "_topdown_" + level.Name + "-"
+}
+
+// LoadTopDown devuelve la planta de la sección desde la caché junto al mesh o, si no
+// existe o está desfasada (otro mesh/overview), la genera y la guarda. El mesh se carga
+// (desde meshCacheDir, ver geometry.LoadMeshCached) solo si hay que regenerar.
+func LoadTopDown(meshPath, meshCacheDir string, t Transform, size int, level Level) (img image.Image, generated bool, err error) {
+	if size <= 0 {
+		size = RadarSize
+	}
+	hash, err := geometry.HashMeshSource(meshPath)
+	if err != nil {
+		return nil, false, err
+	}
+	path := TopDownPath(meshPath, t, size, level, hash)
+	if f, err := os.Open(path); err == nil {
+		img, err := png.Decode(f)
+		f.Close()
+		if err == nil {
+			return img, false, nil
+		}
+		fmt.Printf("⚠️  Regenerating corrupt top-down image %s: %v\n", path, err)
+	}
+
+	mesh, _, err := geometry.LoadMeshCached(meshPath, meshCacheDir)
+	if err != nil {
+		return nil, false, err
+	}
+	rgba := TopDown(mesh, t, size, level)
+	if err := saveTopDown(path, meshPath, level, rgba); err != nil {
+		// La imagen sirve igual aunque no se pueda cachear
+		fmt.Printf("⚠️  Could not cache top-down image %s: %v\n", path, err)
+	}
+	return rgba, true, nil
+}
+
+// saveTopDown escribe la imagen (atómico vía fichero temporal) y borra las versiones antiguas
+func saveTopDown(path, meshPath string, level Level, img image.Image) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create top-down image: %w", err)
+	}
+	if err := png.Encode(tmp, img); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to encode top-down image: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	stale, _ := filepath.Glob(topDownPrefix(meshPath, level) + "*.png")
+	for _, p := range stale {
+		if p != path {
+			os.Remove(p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package smoke
+
+import (
+	"math"
+	"sort"
+
+	"github.com/golang/geo/r3"
+)
+
+// ============================================================================
+// VOLUMETRIC SMOKE
+// Cada humo es un elipsoide achatado apoyado en el suelo con densidad que cae
+// hacia el borde, y un ciclo de vida (bloom → full → dissipating). Las HE
+// abren un hueco esférico que se vuelve a rellenar.
+//
+// Las consultas de oclusión integran la densidad a lo largo del segmento y
+// devuelven una opacidad parcial (0 = transparente, 1 = opaco).
+//
+// Solo hay humos detonados (Start): una granada de humo en vuelo no ocluye.
+//
+// NOTA: CS2 transmite la ocupación de vóxeles del humo (m_VoxelFrameData),
+// pero demoinfocs no la decodifica; la forma se aproxima analíticamente y no
+// tiene en cuenta paredes/puertas por las que el humo se expande.
+// ============================================================================
+
+// Forma y tiempos aproximados de un humo de CS2
+const (
+	HorizontalRadius = 144.0 // Radio horizontal con el humo completo
+	VerticalRadius   = 88.0  // Semieje vertical
+	CenterOffsetZ    = 56.0  // El centro queda por encima del punto de detonación
+
+	BloomSeconds      = 1.5  // Tiempo hasta alcanzar el tamaño completo
+	DissipateStart    = 18.5 // Segundos desde la detonación hasta que empieza a disiparse
+	DissipateSeconds  = 3.5  // Duración de la disipación (luego SmokeExpired)
+	HEHoleRadius      = 130.0
+	HEHoleRefillSecs  = 2.0  // Tiempo en que el hueco de una HE se vuelve a cerrar
+	OpacityLength     = 64.0 // Unidades de humo a densidad 1 para opacidad 1-1/e
+	sampleStep        = 8.0
+	BlockingOcclusion = 0.7 // Opacidad a partir de la cual se considera que tapa la visión
+)
+
+// Phase es la fase del ciclo de vida de un humo
+type Phase string
+
+const (
+	PhaseBloom       Phase = "bloom"
+	PhaseFull        Phase = "full"
+	PhaseDissipating Phase = "dissipating"
+	PhaseHECleared   Phase = "he_cleared" // Hueco de HE abierto (se está rellenando)
+	PhaseExpired     Phase = "expired"
+)
+
+// heHole es el hueco abierto por una HE dentro de un humo
+type heHole struct {
+	center r3.Vector
+	tick   int
+}
+
+// Smoke es un humo detonado
+type Smoke struct {
+	ID        int // Entity ID de la granada
+	Position  r3.Vector
+	StartTick int
+	holes     []heHole
+}
+
+// Tracker mantiene los humos activos de la ronda
+type Tracker struct {
+	TickRate float64
+	smokes   map[int]*Smoke
+}
+
+// NewTracker crea un tracker vacío (tickRate <= 0 = 64)
+func NewTracker(tickRate float64) *Tracker {
+	if tickRate <= 0 {
+		tickRate = 64
+	}
+	return &Tracker{TickRate: tickRate, smokes: make(map[int]*Smoke)}
+}
+
+// Start registra un humo detonado
+func (t *Tracker) Start(id int, pos r3.Vector, tick int) {
+	t.smokes[id] = &Smoke{ID: id, Position: pos, StartTick: tick}
+}
+
+// Expire elimina un humo (SmokeExpired)
+func (t *Tracker) Expire(id int) {
+	delete(t.smokes, id)
+}
+
+// Reset elimina todos los humos (inicio de ronda)
+func (t *Tracker) Reset() {
+	t.smokes = make(map[int]*Smoke)
+}
+
+// HEExplode abre un hueco en los humos alcanzados por la explosión
+func (t *Tracker) HEExplode(pos r3.Vector, tick int) {
+	for _, s := range t.smokes {
+		if s.center().Sub(pos).Norm() < HorizontalRadius+HEHoleRadius {
+			s.holes = append(s.holes, heHole{center: pos, tick: tick})
+		}
+	}
+}
+
+// Active devuelve los humos no expirados en tick, ordenados por ID (determinista)
+func (t *Tracker) Active(tick int) []*Smoke {
+	var out []*Smoke
+	for _, s := range t.smokes {
+		if t.Phase(s, tick) != PhaseExpired {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Phase devuelve la fase del humo en tick
+func (t *Tracker) Phase(s *Smoke, tick int) Phase {
+	secs := t.seconds(tick - s.StartTick)
+	switch {
+	case secs < 0:
+		return PhaseExpired
+	case secs < BloomSeconds:
+		return PhaseBloom
+	case secs >= DissipateStart+DissipateSeconds:
+		return PhaseExpired
+	}
+	for _, h := range s.holes {
+		if age := t.seconds(tick - h.tick); age >= 0 && age < HEHoleRefillSecs {
+			return PhaseHECleared
+		}
+	}
+	if secs < DissipateStart {
+		return PhaseFull
+	}
+	return PhaseDissipating
+}
+
+// Occlusion devuelve la opacidad (0-1) acumulada de todos los humos entre from y to
+func (t *Tracker) Occlusion(from, to r3.Vector, tick int) float64 {
+	depth := 0.0
+	for _, s := range t.Active(tick) {
+		depth += t.opticalDepth(s, from, to, tick)
+	}
+	return 1 - math.Exp(-depth)
+}
+
+// Blocks indica si los humos tapan la línea de visión from→to
+func (t *Tracker) Blocks(from, to r3.Vector, tick int) bool {
+	return t.Occlusion(from, to, tick) >= BlockingOcclusion
+}
+
+// opticalDepth integra la densidad del humo a lo largo del segmento
+func (t *Tracker) opticalDepth(s *Smoke, from, to r3.Vector, tick int) float64 {
+	secs := t.seconds(tick - s.StartTick)
+	scale, density := lifecycle(secs)
+	if scale <= 0 || density <= 0 {
+		return 0
+	}
+
+	// Intersección segmento / elipsoide (espacio escalado a esfera unidad)
+	radii := r3.Vector{X: HorizontalRadius * scale, Y: HorizontalRadius * scale, Z: VerticalRadius * scale}
+	center := s.center()
+	o := divVec(from.Sub(center), radii)
+	d := divVec(to.Sub(from), radii)
+	a := d.Dot(d)
+	if a == 0 {
+		return 0
+	}
+	b := 2 * o.Dot(d)
+	c := o.Dot(o) - 1
+	disc := b*b - 4*a*c
+	if disc <= 0 {
+		return 0
+	}
+	sq := math.Sqrt(disc)
+	t0 := math.Max(0, (-b-sq)/(2*a))
+	t1 := math.Min(1, (-b+sq)/(2*a))
+	if t1 <= t0 {
+		return 0
+	}
+
+	// Muestreo dentro del intervalo
+	segLen := to.Sub(from).Norm()
+	inside := (t1 - t0) * segLen
+	n := int(math.Ceil(inside / sampleStep))
+	if n < 1 {
+		n = 1
+	}
+	step := (t1 - t0) / float64(n)
+	depth := 0.0
+	for i := 0; i < n; i++ {
+		u := t0 + (float64(i)+0.5)*step
+		p := from.Add(to.Sub(from).Mul(u))
+		q := divVec(p.Sub(center), radii)
+		r2 := q.Dot(q)
+		local := density * (1 - r2*r2) // Borde difuso
+		local *= t.holeFactor(s, p, tick)
+		if local > 0 {
+			depth += local * step * segLen / OpacityLength
+		}
+	}
+	return depth
+}
+
+// lifecycle devuelve la escala del volumen y la densidad según el tiempo desde la detonación
+func lifecycle(secs float64) (scale, density float64) {
+	switch {
+	case secs < 0:
+		return 0, 0
+	case secs < BloomSeconds:
+		f := secs / BloomSeconds
+		return 0.3 + 0.7*f, f
+	case secs < DissipateStart:
+		return 1, 1
+	case secs < DissipateStart+DissipateSeconds:
+		return 1, 1 - (secs-DissipateStart)/DissipateSeconds
+	default:
+		return 0, 0
+	}
+}
+
+// holeFactor devuelve 0 dentro de un hueco de HE reciente y 1 fuera
+func (t *Tracker) holeFactor(s *Smoke, p r3.Vector, tick int) float64 {
+	factor := 1.0
+	for _, h := range s.holes {
+		age := t.seconds(tick - h.tick)
+		if age < 0 || age >= HEHoleRefillSecs {
+			continue
+		}
+		radius := HEHoleRadius * (1 - age/HEHoleRefillSecs)
+		// Núcleo despejado (70% del radio) y borde que se rellena gradualmente
+		if dist := p.Sub(h.center).Norm(); dist < radius {
+			factor = math.Min(factor, math.Max(0, (dist-0.7*radius)/(0.3*radius)))
+		}
+	}
+	return factor
+}
+
+func (s *Smoke) center() r3.Vector {
+	return r3.Vector{X: s.Position.X, Y: s.Position.Y, Z: s.Position.Z + CenterOffsetZ}
+}
+
+func (t *Tracker) seconds(ticks int) float64 {
+	return float64(ticks) / t.TickRate
+}
+
+func divVec(v, r r3.Vector) r3.Vector {
+	return r3.Vector{X: v.X / r.X, Y: v.Y / r.Y, Z: v.Z / r.Z}
+}
@@ -0,0 +1,413 @@
+package plots
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cs2-demo-service/models"
+	"cs2-demo-service/pkg/maps"
+	"cs2-demo-service/pkg/render"
+)
+
+// ============================================================================
+// MATCH PLOTS
+// Heatmaps y plots de posiciones (muertes, kills, granadas, tracking) sobre el
+// radar del mapa, a partir de los exports de un match (match_<id>/*.json).
+// Sin radar en PNG se usa la planta generada del physics mesh (ver
+// render.TopDown); sin mesh, fondo liso.
+// ============================================================================
+
+// Tipos de plot
+const (
+	KindDeaths    = "deaths"
+	KindKills     = "kills"
+	KindGrenades  = "grenades"
+	KindPositions = "positions"
+
+	ModeHeatmap = "heatmap"
+	ModePoints  = "points"
+)
+
+// ErrBadOptions se devuelve (envuelto) cuando las opciones no son válidas
+var ErrBadOptions = errors.New("invalid plot options")
+
+// Options describe qué pintar
+type Options struct {
+	MatchDir string // Directorio match_<id> de los exports
+	MapsDir  string // Directorio de mapas (overview, radar, mesh)
+
+	Kind    string // deaths, kills, grenades, positions
+	Mode    string // heatmap (por defecto) o points
+	SteamID string // Solo este jugador (vacío = todos)
+	Side    string // "CT" / "T" (vacío = ambos)
+	Round   int    // Solo esta ronda (0 = todas)
+	Grenade string // Solo este tipo de granada: smoke, flash, he, molotov, decoy
+	Level   string // Sección vertical del radar (vacío = nivel principal, sin filtrar por altura en mapas de un nivel)
+	Size    int    // Lado de la imagen en px (0 = 1024)
+}
+
+// Render pinta el plot pedido y lo escribe como PNG
+func Render(opts Options, w io.Writer) error {
+	canvas, err := Build(opts)
+	if err != nil {
+		return err
+	}
+	return canvas.EncodePNG(w)
+}
+
+// Build pinta el plot pedido y devuelve el lienzo
+func Build(opts Options) (*render.Canvas, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeHeatmap
+	}
+	if opts.Mode != ModeHeatmap && opts.Mode != ModePoints {
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrBadOptions, opts.Mode)
+	}
+	if opts.Side != "" && opts.Side != "CT" && opts.Side != "T" {
+		return nil, fmt.Errorf("%w: unknown side %q", ErrBadOptions, opts.Side)
+	}
+
+	var metadata models.AI_Metadata
+	if err := readJSON(filepath.Join(opts.MatchDir, "metadata.json"), &metadata); err != nil {
+		return nil, err
+	}
+	config := resolveConfig(opts.MapsDir, metadata.MapName)
+	level, err := selectLevel(config, opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []render.Point
+	var segments []render.Segment
+	switch opts.Kind {
+	case KindDeaths, KindKills:
+		points, segments, err = killPoints(opts)
+	case KindGrenades:
+		points, segments, err = grenadePoints(opts)
+	case KindPositions:
+		points, err = positionPoints(opts)
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrBadOptions, opts.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Mapas con varios niveles: solo los puntos del nivel pintado
+	if level != nil {
+		points = filterLevel(points, level)
+		segments = filterSegments(segments, level)
+	}
+
+	t := render.Transform{PosX: config.PosX, PosY: config.PosY, Scale: config.Scale}
+	bg, _ := background(opts.MapsDir, metadata.MapName, t, level)
+	canvas := render.NewCanvas(t, opts.Size, bg)
+	if opts.Mode == ModeHeatmap {
+		radius := 150.0
+		if opts.Kind == KindPositions {
+			radius = 100
+		}
+		canvas.Heatmap(points, radius)
+	} else {
+		canvas.Lines(segments)
+		canvas.Plot(points, math.Max(3, float64(canvas.Size())/256))
+	}
+	return canvas, nil
+}
+
+// resolveConfig lee el overview del mapa (o la tabla interna)
+func resolveConfig(mapsDir, mapName string) maps.MapConfig {
+	overview, err := maps.LoadOverview(mapsDir, mapName)
+	if err != nil {
+		overview = nil
+	}
+	return maps.ResolveMapConfig(mapName, overview)
+}
+
+// selectLevel devuelve la sección vertical a pintar (nil = mapa de un solo nivel)
+func selectLevel(config maps.MapConfig, name string) (*maps.MapLevel, error) {
+	if len(config.Levels) == 0 {
+		if name != "" && name != "default" {
+			return nil, fmt.Errorf("%w: map has no level %q", ErrBadOptions, name)
+		}
+		return nil, nil
+	}
+	if name == "" {
+		name = "default"
+	}
+	for i := range config.Levels {
+		if config.Levels[i].Name == name {
+			return &config.Levels[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: map has no level %q", ErrBadOptions, name)
+}
+
+func filterLevel(points []render.Point, level *maps.MapLevel) []render.Point {
+	out := points[:0]
+	for _, p := range points {
+		if p.Z >= level.AltitudeMin && p.Z < level.AltitudeMax {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func filterSegments(segments []render.Segment, level *maps.MapLevel) []render.Segment {
+	out := segments[:0]
+	for _, s := range segments {
+		if s.To.Z >= level.AltitudeMin && s.To.Z < level.AltitudeMax {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// --- Fondo ---
+
+// Origen de la imagen de fondo
+const (
+	BackgroundRadar   = "radar"
+	BackgroundTopDown = "topdown"
+)
+
+// topDowns cachea en memoria las plantas ya cargadas (clave: mesh|nivel|versión del mesh)
+var topDowns sync.Map
+
+// MapImage devuelve el fondo del mapa para una sección vertical ("" = principal): el
+// radar en PNG si existe o, si no, la planta generada del physics mesh (cacheada junto
+// al mesh). nil si no hay ninguno de los dos.
+func MapImage(mapsDir, mapName, levelName string) (image.Image, string, error) {
+	config := resolveConfig(mapsDir, mapName)
+	level, err := selectLevel(config, levelName)
+	if err != nil {
+		return nil, "", err
+	}
+	t := render.Transform{PosX: config.PosX, PosY: config.PosY, Scale: config.Scale}
+	img, source := background(mapsDir, mapName, t, level)
+	return img, source, nil
+}
+
+// background devuelve el radar del nivel o, si no hay, la planta del mesh (nil = fondo liso)
+func background(mapsDir, mapName string, t render.Transform, level *maps.MapLevel) (image.Image, string) {
+	section := render.AllLevels
+	if level != nil {
+		section = render.Level{Name: level.Name, Min: level.AltitudeMin, Max: level.AltitudeMax}
+	}
+	if img, err := render.LoadRadar(mapsDir, mapName, section.Name); err == nil {
+		return img, BackgroundRadar
+	} else if !errors.Is(err, render.ErrNoRadar) {
+		fmt.Printf("⚠️  Radar image for %s could not be decoded: %v\n", mapName, err)
+	}
+
+	meshPath := maps.FindMeshSource(mapsDir, mapName)
+	if meshPath == "" {
+		return nil, ""
+	}
+	stamp := maps.MeshSourceStamp(meshPath)
+	if stamp == "" {
+		return nil, ""
+	}
+	key := fmt.Sprintf("%s|%s|%g|%g|%g|%s", meshPath, section.Name, t.PosX, t.PosY, t.Scale, stamp)
+	if img, ok := topDowns.Load(key); ok {
+		return img.(image.Image), BackgroundTopDown
+	}
+	img, generated, err := render.LoadTopDown(meshPath, filepath.Join(mapsDir, maps.CacheDirName), t, render.RadarSize, section)
+	if err != nil {
+		fmt.Printf("⚠️  No background for %s: %v\n", mapName, err)
+		return nil, ""
+	}
+	if generated {
+		fmt.Printf("🗺️  Generated top-down image for %s (%s)\n", mapName, section.Name)
+	}
+	topDowns.Store(key, img)
+	return img, BackgroundTopDown
+}
+
+// --- Datos ---
+
+func readJSON(path string, v interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func sideColor(team string) color.RGBA {
+	switch team {
+	case "CT":
+		return render.ColorCT
+	case "T":
+		return render.ColorT
+	}
+	return render.ColorNeutral
+}
+
+func vectorPoint(v models.AI_Vector, col color.RGBA) render.Point {
+	return render.Point{X: v.X, Y: v.Y, Z: v.Z, Color: col}
+}
+
+// killPoints devuelve dónde murió cada víctima (deaths) o dónde estaba el asesino (kills),
+// con una línea asesino → víctima
+func killPoints(opts Options) ([]render.Point, []render.Segment, error) {
+	var combat models.AI_DuelExport
+	if err := readJSON(filepath.Join(opts.MatchDir, "combat.json"), &combat); err != nil {
+		return nil, nil, err
+	}
+	var points []render.Point
+	var segments []render.Segment
+	for _, round := range combat.Rounds {
+		if opts.Round > 0 && round.Round != opts.Round {
+			continue
+		}
+		for _, duel := range round.Duels {
+			if duel.Outcome == "damage" || duel.Attacker.Position == nil {
+				continue
+			}
+			for _, victim := range duel.Victims {
+				if victim.HealthAfter > 0 || victim.Position == nil {
+					continue
+				}
+				// El jugador/lado filtrado es el asesino en kills y la víctima en deaths
+				subject := victim
+				if opts.Kind == KindKills {
+					subject = duel.Attacker
+				}
+				if !matchesPlayer(opts, strconv.FormatUint(subject.SteamID, 10), subject.Team) {
+					continue
+				}
+				from := vectorPoint(*duel.Attacker.Position, sideColor(duel.Attacker.Team))
+				to := vectorPoint(*victim.Position, sideColor(victim.Team))
+				if opts.Kind == KindKills {
+					points = append(points, from)
+				} else {
+					points = append(points, to)
+				}
+				segments = append(segments, render.Segment{From: from, To: to, Color: sideColor(subject.Team)})
+			}
+		}
+	}
+	return points, segments, nil
+}
+
+// grenadePoints devuelve dónde cayó cada granada, con una línea desde donde se lanzó
+func grenadePoints(opts Options) ([]render.Point, []render.Segment, error) {
+	var grenades models.AI_GrenadesExport
+	if err := readJSON(filepath.Join(opts.MatchDir, "grenades.json"), &grenades); err != nil {
+		return nil, nil, err
+	}
+	// grenades.json identifica al lanzador por nombre
+	playerName := ""
+	if opts.SteamID != "" {
+		var summary models.AI_PlayersSummaryExport
+		if err := readJSON(filepath.Join(opts.MatchDir, "players_summary.json"), &summary); err != nil {
+			return nil, nil, err
+		}
+		for _, p := range summary.Players {
+			if p.SteamID == opts.SteamID {
+				playerName = p.Name
+			}
+		}
+		if playerName == "" {
+			return nil, nil, fmt.Errorf("%w: player %s not in match", ErrBadOptions, opts.SteamID)
+		}
+	}
+
+	var points []render.Point
+	var segments []render.Segment
+	for _, round := range grenades.Rounds {
+		if opts.Round > 0 && round.Round != opts.Round {
+			continue
+		}
+		for _, g := range round.Events {
+			if playerName != "" && g.Thrower != playerName {
+				continue
+			}
+			if opts.Side != "" && g.ThrowerSide != opts.Side {
+				continue
+			}
+			kind := grenadeKind(g.Type)
+			if opts.Grenade != "" && kind != opts.Grenade {
+				continue
+			}
+			to := vectorPoint(g.EndPosition, grenadeColor(kind))
+			points = append(points, to)
+			segments = append(segments, render.Segment{From: vectorPoint(g.StartPosition, to.Color), To: to, Color: to.Color})
+		}
+	}
+	return points, segments, nil
+}
+
+// positionPoints devuelve las muestras de tracking de jugadores vivos
+func positionPoints(opts Options) ([]render.Point, error) {
+	var tracking models.AI_TrackingExport
+	if err := readJSON(filepath.Join(opts.MatchDir, "tracking.json"), &tracking); err != nil {
+		return nil, err
+	}
+	var points []render.Point
+	for _, round := range tracking.Rounds {
+		if opts.Round > 0 && round.Round != opts.Round {
+			continue
+		}
+		for _, tick := range round.Ticks {
+			for _, p := range tick.Players {
+				if !p.IsAlive || !matchesPlayer(opts, strconv.FormatUint(p.PlayerSteamID, 10), p.Team) {
+					continue
+				}
+				points = append(points, vectorPoint(p.Position, sideColor(p.Team)))
+			}
+		}
+	}
+	return points, nil
+}
+
+func matchesPlayer(opts Options, steamID, team string) bool {
+	return (opts.SteamID == "" || opts.SteamID == steamID) && (opts.Side == "" || opts.Side == team)
+}
+
+// grenadeKind normaliza el tipo de granada de grenades.json
+func grenadeKind(t string) string {
+	t = strings.ToLower(t)
+	switch {
+	case strings.Contains(t, "smoke"):
+		return "smoke"
+	case strings.Contains(t, "flash"):
+		return "flash"
+	case strings.Contains(t, "molotov"), strings.Contains(t, "incendiary"):
+		return "molotov"
+	case strings.Contains(t, "decoy"):
+		return "decoy"
+	case t == "he" || strings.HasPrefix(t, "he "):
+		return "he"
+	}
+	return t
+}
+
+func grenadeColor(kind string) color.RGBA {
+	switch kind {
+	case "smoke":
+		return color.RGBA{200, 200, 200, 255}
+	case "flash":
+		return color.RGBA{250, 240, 120, 255}
+	case "he":
+		return color.RGBA{230, 70, 60, 255}
+	case "molotov":
+		return color.RGBA{250, 140, 30, 255}
+	}
+	return render.ColorNeutral
+}
@@ -0,0 +1,490 @@
+package plots
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"cs2-demo-service/models"
+	"cs2-demo-service/pkg/maps"
+	"cs2-demo-service/pkg/render"
+)
+
+// ============================================================================
+// ROUND RECAPS
+// Animación (GIF / APNG) de una ronda a partir de replay.json: jugadores con
+// su cono de visión, granadas en vuelo, humos y fuegos, disparos y estado de la
+// bomba sobre el radar. El tiempo de juego se acelera (Speed) y, con Crop, la
+// imagen se recorta a la zona donde pasó algo en la ronda.
+// ============================================================================
+
+// Valores por defecto / límites de los recaps
+const (
+	DefaultRecapSpeed = 4.0
+	DefaultRecapFPS   = 10
+	DefaultRecapSize  = 512
+
+	MaxRecapSpeed  = 32.0
+	MaxRecapFPS    = 30
+	MaxRecapSize   = 2048
+	MaxRecapFrames = 1500
+	// MaxRecapPixels limita frames × size² (el GIF guarda 1 byte/pixel de cada frame
+	// hasta el final): 512 px admite 1500 frames, 2048 px solo 128
+	MaxRecapPixels = 512 << 20
+
+	recapPadding = 300.0  // Margen (unidades) alrededor de la zona de acción
+	recapMinSide = 1500.0 // Lado mínimo del recorte (unidades)
+	recapKillFX  = 1.5    // Segundos que se ve la línea de una kill
+)
+
+// RecapOptions describe qué ronda animar y cómo
+type RecapOptions struct {
+	MatchDir string // Directorio match_<id> de los exports
+	MapsDir  string // Directorio de mapas (overview, radar, mesh)
+
+	Round  int     // Número de ronda (1-based)
+	Format string  // gif (por defecto) o apng
+	Speed  float64 // Segundos de juego por segundo de animación (0 = 4)
+	FPS    int     // Frames por segundo de la animación (0 = 10)
+	Size   int     // Lado de la imagen en px (0 = 512)
+	Crop   bool    // Recortar a la zona de acción de la ronda
+	Level  string  // Sección vertical del radar (vacío = nivel principal)
+}
+
+func (o RecapOptions) withDefaults() (RecapOptions, error) {
+	if o.Format == "" {
+		o.Format = render.FormatGIF
+	}
+	if o.Speed == 0 {
+		o.Speed = DefaultRecapSpeed
+	}
+	if o.FPS == 0 {
+		o.FPS = DefaultRecapFPS
+	}
+	if o.Size == 0 {
+		o.Size = DefaultRecapSize
+	}
+	switch {
+	case o.Format != render.FormatGIF && o.Format != render.FormatAPNG:
+		return o, fmt.Errorf("%w: unknown format %q (gif or apng)", ErrBadOptions, o.Format)
+	case o.Round < 1:
+		return o, fmt.Errorf("%w: round must be >= 1", ErrBadOptions)
+	case o.Speed < 0 || o.Speed > MaxRecapSpeed || math.IsNaN(o.Speed):
+		return o, fmt.Errorf("%w: speed must be in (0, %g]", ErrBadOptions, MaxRecapSpeed)
+	case o.FPS < 1 || o.FPS > MaxRecapFPS:
+		return o, fmt.Errorf("%w: fps must be in [1, %d]", ErrBadOptions, MaxRecapFPS)
+	case o.Size < 64 || o.Size > MaxRecapSize:
+		return o, fmt.Errorf("%w: size must be in [64, %d]", ErrBadOptions, MaxRecapSize)
+	}
+	return o, nil
+}
+
+// Recap es una ronda lista para animar: opciones validadas y número de frames ya
+// calculado, para conocer la memoria que necesita antes de renderizar
+type Recap struct {
+	Frames int // Frames de la animación
+
+	round      *models.ReplayRound
+	mapName    string
+	tickRate   float64
+	opts       RecapOptions
+	start, end int
+	step       float64 // Ticks de juego por frame
+}
+
+// LoadRecap lee la ronda de replay.json y prepara su animación
+func LoadRecap(opts RecapOptions) (*Recap, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	metadata, round, err := readReplayRound(filepath.Join(opts.MatchDir, "replay.json"), opts.Round)
+	if err != nil {
+		return nil, err
+	}
+	if round == nil {
+		return nil, fmt.Errorf("%w: round %d not in replay", ErrBadOptions, opts.Round)
+	}
+	return NewRecap(round, metadata.MapName, metadata.TickRate, opts)
+}
+
+// NewRecap prepara la animación de una ronda del replay (opts.MatchDir no se usa).
+// Devuelve ErrBadOptions si supera MaxRecapFrames o MaxRecapPixels.
+func NewRecap(round *models.ReplayRound, mapName string, tickRate float64, opts RecapOptions) (*Recap, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if len(round.Frames) == 0 {
+		return nil, fmt.Errorf("%w: round %d has no replay frames", ErrBadOptions, round.Round)
+	}
+	if tickRate <= 0 {
+		tickRate = 64
+	}
+
+	// Muestreo por tiempo de juego: cada frame de la animación avanza Speed/FPS segundos
+	r := &Recap{
+		round:    round,
+		mapName:  mapName,
+		tickRate: tickRate,
+		opts:     opts,
+		start:    round.Frames[0].Tick,
+		end:      round.Frames[len(round.Frames)-1].Tick,
+		step:     opts.Speed / float64(opts.FPS) * tickRate,
+	}
+	r.Frames = int(float64(r.end-r.start)/r.step) + 1
+	if r.Frames > MaxRecapFrames {
+		return nil, fmt.Errorf("%w: %d frames (max %d), raise speed or lower fps", ErrBadOptions, r.Frames, MaxRecapFrames)
+	}
+	if pixels := r.Frames * opts.Size * opts.Size; pixels > MaxRecapPixels {
+		return nil, fmt.Errorf("%w: %d frames of %dx%d px exceed the recap limit (%d Mpx), lower size or fps",
+			ErrBadOptions, r.Frames, opts.Size, opts.Size, MaxRecapPixels>>20)
+	}
+	return r, nil
+}
+
+// EstimatedBytes aproxima la memoria del render: dos lienzos RGBA y el radar, más los
+// frames en paleta (GIF, hasta Close) o la salida comprimida (APNG, ~1 byte/pixel)
+// y su copia en el buffer de salida
+func (r *Recap) EstimatedBytes() uint64 {
+	frame := uint64(r.opts.Size) * uint64(r.opts.Size)
+	canvases := 2*4*frame + 4*render.RadarSize*render.RadarSize
+	return canvases + 2*uint64(r.Frames)*frame
+}
+
+// RenderRecap lee la ronda de replay.json y escribe su animación
+func RenderRecap(opts RecapOptions, w io.Writer) error {
+	r, err := LoadRecap(opts)
+	if err != nil {
+		return err
+	}
+	return r.Render(w)
+}
+
+// RenderRound anima una ronda del replay (opts.MatchDir no se usa)
+func RenderRound(round *models.ReplayRound, mapName string, tickRate float64, opts RecapOptions, w io.Writer) error {
+	r, err := NewRecap(round, mapName, tickRate, opts)
+	if err != nil {
+		return err
+	}
+	return r.Render(w)
+}
+
+// Render escribe la animación
+func (rc *Recap) Render(w io.Writer) error {
+	opts, round := rc.opts, rc.round
+	config := resolveConfig(opts.MapsDir, rc.mapName)
+	level, err := selectLevel(config, opts.Level)
+	if err != nil {
+		return err
+	}
+	t := render.Transform{PosX: config.PosX, PosY: config.PosY, Scale: config.Scale}
+	bg, _ := background(opts.MapsDir, rc.mapName, t, level)
+	view := t
+	if opts.Crop {
+		if minX, minY, maxX, maxY, ok := actionArea(round); ok {
+			view = t.Crop(minX, minY, maxX, maxY)
+			if bg != nil {
+				bg = render.CropImage(bg, t, view)
+			}
+		}
+	}
+
+	anim, err := render.NewAnimationWriter(w, opts.Format, rc.Frames, 1000/opts.FPS)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadOptions, err)
+	}
+	base := render.NewCanvas(view, opts.Size, bg)
+	canvas := render.NewCanvas(view, opts.Size, nil)
+	r := recapFrame{canvas: canvas, level: level, tickRate: rc.tickRate, start: rc.start, end: rc.end, round: round}
+
+	next, prevTick := 0, rc.start-1
+	for i := 0; i < rc.Frames; i++ {
+		tick := rc.start + int(math.Round(float64(i)*rc.step))
+		// Último frame del replay con Tick <= tick y disparos desde el frame anterior
+		var shots []models.ReplayShot
+		for next < len(round.Frames) && round.Frames[next].Tick <= tick {
+			if round.Frames[next].Tick > prevTick {
+				shots = append(shots, round.Frames[next].Shots...)
+			}
+			next++
+		}
+		prevTick = tick
+
+		canvas.CopyFrom(base)
+		r.draw(&round.Frames[max(0, next-1)], tick, shots)
+		if err := anim.AddFrame(canvas.Img); err != nil {
+			return fmt.Errorf("failed to encode recap frame: %w", err)
+		}
+	}
+	return anim.Close()
+}
+
+// actionArea devuelve el rectángulo (con margen) que cubre jugadores vivos, granadas y
+// efectos de toda la ronda
+func actionArea(round *models.ReplayRound) (minX, minY, maxX, maxY float64, ok bool) {
+	minX, minY, maxX, maxY = math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	add := func(x, y, r float64) {
+		minX, minY = math.Min(minX, x-r), math.Min(minY, y-r)
+		maxX, maxY = math.Max(maxX, x+r), math.Max(maxY, y+r)
+		ok = true
+	}
+	for _, f := range round.Frames {
+		for _, p := range f.Players {
+			if p.Alive {
+				add(float64(p.X), float64(p.Y), 0)
+			}
+		}
+		for _, p := range f.Projectiles {
+			add(p.X, p.Y, 0)
+		}
+		for _, e := range f.ActiveEffects {
+			add(e.X, e.Y, e.Radius)
+		}
+	}
+	if !ok {
+		return
+	}
+	minX, minY, maxX, maxY = minX-recapPadding, minY-recapPadding, maxX+recapPadding, maxY+recapPadding
+	if grow := recapMinSide - (maxX - minX); grow > 0 {
+		minX, maxX = minX-grow/2, maxX+grow/2
+	}
+	if grow := recapMinSide - (maxY - minY); grow > 0 {
+		minY, maxY = minY-grow/2, maxY+grow/2
+	}
+	return
+}
+
+// --- Dibujo de un frame ---
+
+var (
+	colorSmoke   = color.RGBA{190, 190, 195, 170}
+	colorInferno = color.RGBA{240, 110, 20, 140}
+	colorBomb    = color.RGBA{230, 40, 40, 255}
+	colorDefuse  = color.RGBA{80, 170, 255, 255}
+	colorDead    = color.RGBA{150, 150, 150, 200}
+	colorOutline = color.RGBA{15, 15, 18, 230}
+)
+
+type recapFrame struct {
+	canvas     *render.Canvas
+	level      *maps.MapLevel
+	tickRate   float64
+	start, end int
+	round      *models.ReplayRound
+}
+
+func (r recapFrame) draw(f *models.ReplayFrame, tick int, shots []models.ReplayShot) {
+	c := r.canvas
+	unit := math.Max(3, float64(c.Size())/110) // Radio de un jugador en px
+
+	for _, e := range f.ActiveEffects {
+		x, y := c.Point(e.X, e.Y)
+		switch {
+		case e.Type == "smoke":
+			radius := e.Radius
+			if radius <= 0 {
+				radius = 144
+			}
+			c.FillCircle(x, y, c.Units(radius), colorSmoke)
+		case len(e.Hull) >= 6:
+			pts := make([][2]float64, 0, len(e.Hull)/2)
+			for i := 0; i+1 < len(e.Hull); i += 2 {
+				px, py := c.Point(e.Hull[i], e.Hull[i+1])
+				pts = append(pts, [2]float64{px, py})
+			}
+			c.FillPolygon(pts, colorInferno)
+		default:
+			c.FillCircle(x, y, c.Units(60), colorInferno)
+		}
+	}
+
+	// Kills recientes: línea del asesino a la víctima
+	for _, e := range r.round.Events {
+		if e.Type != models.ReplayEventKill || e.Tick > tick || float64(tick-e.Tick) > recapKillFX*r.tickRate {
+			continue
+		}
+		x0, y0 := c.Point(e.KillerX, e.KillerY)
+		x1, y1 := c.Point(e.VictimX, e.VictimY)
+		col := sideColor(e.KillerTeam)
+		col.A = 200
+		c.Line(x0, y0, x1, y1, math.Max(1.5, unit/3), col)
+	}
+
+	for _, s := range shots {
+		x0, y0 := c.Point(s.FromX, s.FromY)
+		x1, y1 := c.Point(s.ToX, s.ToY)
+		col := color.RGBA{255, 240, 170, 150}
+		if s.Hit {
+			col = color.RGBA{255, 90, 60, 220}
+		}
+		c.Line(x0, y0, x1, y1, 1, col)
+	}
+
+	for _, p := range f.Projectiles {
+		col := grenadeColor(grenadeKind(p.Type))
+		if !r.onLevel(p.Z) {
+			col.A = 90
+		}
+		trail := col
+		trail.A /= 2
+		px, py := c.Point(p.X, p.Y)
+		for i := 0; i+3 < len(p.Trajectory); i += 2 {
+			x0, y0 := c.Point(p.Trajectory[i], p.Trajectory[i+1])
+			x1, y1 := c.Point(p.Trajectory[i+2], p.Trajectory[i+3])
+			c.Line(x0, y0, x1, y1, 1, trail)
+		}
+		if n := len(p.Trajectory); n >= 2 {
+			x0, y0 := c.Point(p.Trajectory[n-2], p.Trajectory[n-1])
+			c.Line(x0, y0, px, py, 1, trail)
+		}
+		c.FillCircle(px, py, unit/2, col)
+	}
+
+	r.drawBomb(f.Bomb, tick, unit)
+
+	// Muertos debajo de los vivos
+	for _, p := range f.Players {
+		if p.Alive {
+			continue
+		}
+		x, y := c.Point(float64(p.X), float64(p.Y))
+		d := unit * 0.7
+		c.Line(x-d, y-d, x+d, y+d, 2, colorDead)
+		c.Line(x-d, y+d, x+d, y-d, 2, colorDead)
+	}
+	for _, p := range f.Players {
+		if !p.Alive {
+			continue
+		}
+		x, y := c.Point(float64(p.X), float64(p.Y))
+		col := sideColor(p.Team)
+		outline := colorOutline
+		if !r.onLevel(p.Z) {
+			col.A, outline.A = 110, 80
+		}
+		cone := col
+		cone.A /= 3
+		c.FillSector(x, y, unit*4, float64(p.Yaw), 90, cone)
+		c.FillCircle(x, y, unit+1, outline)
+		c.FillCircle(x, y, unit, col)
+		if p.FlashDuration > 0 {
+			c.Ring(x, y, unit+2, 2, color.RGBA{255, 255, 255, uint8(math.Min(1, p.FlashDuration/2) * 255)})
+		}
+		if p.HasC4 {
+			c.FillCircle(x, y, unit/2.5, colorBomb)
+		}
+		if p.IsDefusing {
+			c.Ring(x, y, unit+3, 2, colorDefuse)
+		}
+	}
+
+	r.drawProgress(tick)
+}
+
+func (r recapFrame) drawBomb(b *models.ReplayBombState, tick int, unit float64) {
+	if b == nil || b.State == "carried" {
+		return
+	}
+	c := r.canvas
+	x, y := c.Point(b.X, b.Y)
+	switch b.State {
+	case "planted", "defusing":
+		// Pulso cada segundo desde el plant
+		phase := math.Mod(float64(tick-b.PlantTick)/r.tickRate, 1)
+		pulse := colorBomb
+		pulse.A = uint8(200 * (1 - phase))
+		c.Ring(x, y, unit*(1+2*phase), 2, pulse)
+		if b.State == "defusing" {
+			c.Ring(x, y, unit*1.2, 2, colorDefuse)
+		}
+	case "exploded":
+		c.FillCircle(x, y, c.Units(500), color.RGBA{255, 150, 40, 90})
+	case "defused":
+		c.Ring(x, y, unit*1.2, 2, colorDefuse)
+	}
+	d := unit * 0.6
+	c.FillRect(int(x-d), int(y-d), int(x+d), int(y+d), colorBomb)
+}
+
+// drawProgress pinta la barra de tiempo de la ronda con una marca por kill
+func (r recapFrame) drawProgress(tick int) {
+	c := r.canvas
+	size := c.Size()
+	h := max(3, size/128)
+	c.FillRect(0, size-h, size, size, color.RGBA{0, 0, 0, 160})
+	span := float64(max(1, r.end-r.start))
+	c.FillRect(0, size-h, int(float64(size)*float64(tick-r.start)/span), size, color.RGBA{235, 235, 235, 220})
+	for _, e := range r.round.Events {
+		if e.Type != models.ReplayEventKill || e.Tick < r.start || e.Tick > r.end {
+			continue
+		}
+		x := int(float64(size) * float64(e.Tick-r.start) / span)
+		c.FillRect(x-1, size-2*h, x+1, size, sideColor(e.KillerTeam))
+	}
+}
+
+func (r recapFrame) onLevel(z float64) bool {
+	return r.level == nil || (z >= r.level.AltitudeMin && z < r.level.AltitudeMax)
+}
+
+// --- Lectura de replay.json ---
+
+// readReplayRound lee la cabecera de replay.json y decodifica solo la ronda pedida
+// (el fichero puede pesar cientos de MB). nil si la ronda no está.
+func readReplayRound(path string, number int) (models.ReplayMetadata, *models.ReplayRound, error) {
+	var metadata models.ReplayMetadata
+	f, err := os.Open(path)
+	if err != nil {
+		return metadata, nil, err
+	}
+	defer f.Close()
+
+	bad := func(err error) error {
+		return fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	dec := json.NewDecoder(f)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return metadata, nil, bad(fmt.Errorf("expected object"))
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return metadata, nil, bad(err)
+		}
+		switch key {
+		case "metadata":
+			if err := dec.Decode(&metadata); err != nil {
+				return metadata, nil, bad(err)
+			}
+		case "rounds":
+			tok, err := dec.Token()
+			if err != nil {
+				return metadata, nil, bad(err)
+			}
+			if tok == nil {
+				return metadata, nil, nil
+			}
+			for dec.More() {
+				var round models.ReplayRound
+				if err := dec.Decode(&round); err != nil {
+					return metadata, nil, bad(err)
+				}
+				if round.Round == number {
+					return metadata, &round, nil
+				}
+			}
+			return metadata, nil, nil
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return metadata, nil, bad(err)
+			}
+		}
+	}
+	return metadata, nil, nil
+}
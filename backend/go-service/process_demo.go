@@ -22,6 +22,7 @@ func main() {
 	}
 	defer f.Close()
 	log.SetOutput(f)
+	log.SetFlags(0) // Sin timestamps: debug_output.log comparable entre ejecuciones
 
 	fmt.Printf("Processing demo: %s\n", demoPath)
 	start := time.Now()
@@ -0,0 +1,46 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/parser"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// Regenera los exports de AI (y stats) desde la caché intermedia sin re-parsear la demo.
+// Uso: go run rebuild_exports.go -match <id> [-out ../data/exports] [-cache <path>] [-date <iso>]
+func main() {
+	matchID := flag.String("match", "", "match ID (required)")
+	outputDir := flag.String("out", filepath.Join("..", "data", "exports"), "exports base directory")
+	cachePath := flag.String("cache", "", "intermediate cache path (default: <out>/match_<id>/intermediate.bin)")
+	matchDate := flag.String("date", "", "match date (ISO 8601)")
+	flag.Parse()
+
+	if *matchID == "" {
+		log.Fatal("-match is required")
+	}
+	if *cachePath == "" {
+		*cachePath = parser.IntermediatePath(*outputDir, *matchID)
+	}
+
+	fmt.Printf("Rebuilding from: %s\n", *cachePath)
+	start := time.Now()
+
+	// 1. Load cache + re-run post-processing
+	ctx, err := parser.RebuildFromIntermediate(*cachePath)
+	if err != nil {
+		log.Fatalf("Error loading intermediate cache: %v", err)
+	}
+	fmt.Printf("Rebuild took: %v\n", time.Since(start))
+
+	// 2. Export
+	if err := parser.ExportAIModels(ctx, *matchID, *outputDir, *matchDate); err != nil {
+		log.Fatalf("Error exporting AI models: %v", err)
+	}
+
+	fmt.Printf("Done in %v\n", time.Since(start))
+}
@@ -0,0 +1,63 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/parser"
+	"cs2-demo-service/plots"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Genera un heatmap / plot de posiciones de un match (desde sus exports) en PNG.
+// Uso: go run render_plot.go -match <id> -kind deaths [-mode points] [-steam <id>] [-side CT] [-round 5] [-grenade smoke] [-level lower] [-o deaths.png]
+func main() {
+	matchID := flag.String("match", "", "match ID (required)")
+	exportsDir := flag.String("exports", filepath.Join("..", "data", "exports"), "exports base directory")
+	mapsDir := flag.String("maps", parser.DefaultMapsDir, "maps directory")
+	kind := flag.String("kind", plots.KindDeaths, "deaths, kills, grenades or positions")
+	mode := flag.String("mode", plots.ModeHeatmap, "heatmap or points")
+	steamID := flag.String("steam", "", "only this player (steam id)")
+	side := flag.String("side", "", "only this side (CT or T)")
+	round := flag.Int("round", 0, "only this round")
+	grenade := flag.String("grenade", "", "only this grenade type (smoke, flash, he, molotov, decoy)")
+	level := flag.String("level", "", "radar vertical section (multi-level maps)")
+	size := flag.Int("size", 1024, "image size in pixels")
+	out := flag.String("o", "", "output PNG (default <kind>_<mode>.png)")
+	flag.Parse()
+
+	if *matchID == "" {
+		log.Fatal("-match is required")
+	}
+	if *out == "" {
+		*out = fmt.Sprintf("%s_%s.png", *kind, *mode)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Error creating %s: %v", *out, err)
+	}
+	err = plots.Render(plots.Options{
+		MatchDir: filepath.Join(*exportsDir, fmt.Sprintf("match_%s", *matchID)),
+		MapsDir:  *mapsDir,
+		Kind:     *kind,
+		Mode:     *mode,
+		SteamID:  *steamID,
+		Side:     *side,
+		Round:    *round,
+		Grenade:  *grenade,
+		Level:    *level,
+		Size:     *size,
+	}, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*out)
+		log.Fatalf("Error rendering plot: %v", err)
+	}
+	fmt.Printf("🖼️  %s\n", *out)
+}
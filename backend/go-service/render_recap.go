@@ -0,0 +1,65 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/parser"
+	"cs2-demo-service/plots"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Genera la animación (GIF o APNG) de una ronda de un match a partir de su replay.json.
+// Uso: go run render_recap.go -match <id> -round 5 [-format apng] [-speed 4] [-fps 10] [-size 512] [-crop] [-level lower] [-o round5.gif]
+func main() {
+	matchID := flag.String("match", "", "match ID (required)")
+	exportsDir := flag.String("exports", filepath.Join("..", "data", "exports"), "exports base directory")
+	mapsDir := flag.String("maps", parser.DefaultMapsDir, "maps directory")
+	round := flag.Int("round", 0, "round number (required)")
+	format := flag.String("format", "gif", "gif or apng")
+	speed := flag.Float64("speed", plots.DefaultRecapSpeed, "game seconds per animation second")
+	fps := flag.Int("fps", plots.DefaultRecapFPS, "animation frames per second")
+	size := flag.Int("size", plots.DefaultRecapSize, "image size in pixels")
+	crop := flag.Bool("crop", false, "crop to the area where the round was played")
+	level := flag.String("level", "", "radar vertical section (multi-level maps)")
+	out := flag.String("o", "", "output file (default round<n>.<format>)")
+	flag.Parse()
+
+	if *matchID == "" || *round < 1 {
+		log.Fatal("-match and -round are required")
+	}
+	if *out == "" {
+		ext := *format
+		if ext == "apng" {
+			ext = "png"
+		}
+		*out = fmt.Sprintf("round%d.%s", *round, ext)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Error creating %s: %v", *out, err)
+	}
+	err = plots.RenderRecap(plots.RecapOptions{
+		MatchDir: filepath.Join(*exportsDir, fmt.Sprintf("match_%s", *matchID)),
+		MapsDir:  *mapsDir,
+		Round:    *round,
+		Format:   *format,
+		Speed:    *speed,
+		FPS:      *fps,
+		Size:     *size,
+		Crop:     *crop,
+		Level:    *level,
+	}, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*out)
+		log.Fatalf("Error rendering recap: %v", err)
+	}
+	fmt.Printf("🎞️  %s\n", *out)
+}
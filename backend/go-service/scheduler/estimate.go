@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"cs2-demo-service/handlers"
+	"cs2-demo-service/parser"
+)
+
+// Factores de estimación de memoria (bytes de RAM por byte de demo).
+// Medidos de forma aproximada con demos competitivas de 24-30 rondas (~80-150 MB):
+// el DemoContext retiene movement logs, tracking a 2Hz, replay frames a 16Hz y
+// raw combat events hasta el export, así que el coste crece lineal con el tamaño.
+const (
+	BaseBytesPerDemoByte = 3.0 // Parser + DemoContext + tracking
+
+	// Replay frames a 16Hz; escala con ParseOptions.ReplaySampleRateHz
+	ReplayBytesPerDemoByte = 1.0
+
+	// Con el volcado por ronda (ParseOptions.FlushDir) tracking/replay/combat no se acumulan
+	FlushedBaseBytesPerDemoByte = 1.5
+
+	// Coste adicional por analyzer activo
+	ReactionBytesPerDemoByte  = 1.5 // Visibility jobs + FirstSeen maps + ReactionTimes
+	SprayBytesPerDemoByte     = 0.5
+	MechanicsBytesPerDemoByte = 0.25
+	CrosshairBytesPerDemoByte = 0.25
+
+	// POV de una ronda (parser + humos + frames del jugador hasta el final de la ronda)
+	POVBytesPerDemoByte = 1.0
+
+	// Coste fijo por demo. Mesh + BVH + nav se comparten entre parses (cache de mapas del
+	// proceso), así que solo se reserva un margen por si la demo es la primera de su mapa.
+	MapOverheadBytes = 64 * 1024 * 1024
+)
+
+// analyzerCost asocia cada analyzer con su factor de memoria
+var analyzerCost = map[string]float64{
+	parser.AnalyzerReaction:  ReactionBytesPerDemoByte,
+	parser.AnalyzerSpray:     SprayBytesPerDemoByte,
+	parser.AnalyzerMechanics: MechanicsBytesPerDemoByte,
+	parser.AnalyzerCrosshair: CrosshairBytesPerDemoByte,
+}
+
+// EstimateMemory estima el pico de memoria de parsear una demo con las opciones dadas
+func EstimateMemory(demoSize int64, opts parser.ParseOptions) uint64 {
+	if demoSize < 0 {
+		demoSize = 0
+	}
+
+	factor := BaseBytesPerDemoByte
+	if opts.FlushDir != "" {
+		factor = FlushedBaseBytesPerDemoByte
+	} else {
+		hz := opts.ReplaySampleRateHz
+		if hz <= 0 {
+			hz = handlers.DefaultReplaySampleRateHz
+		}
+		factor += ReplayBytesPerDemoByte * float64(hz) / handlers.DefaultReplaySampleRateHz
+	}
+	for _, name := range parser.AllAnalyzers {
+		if opts.IsAnalyzerEnabled(name) {
+			factor += analyzerCost[name]
+		}
+	}
+
+	return uint64(float64(demoSize)*factor) + MapOverheadBytes
+}
+
+// EstimatePOVMemory estima el pico de memoria de exportar el POV de una ronda
+func EstimatePOVMemory(demoSize int64) uint64 {
+	if demoSize < 0 {
+		demoSize = 0
+	}
+	return uint64(float64(demoSize)*POVBytesPerDemoByte) + MapOverheadBytes
+}
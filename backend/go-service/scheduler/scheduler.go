@@ -0,0 +1,362 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime/metrics"
+	"strconv"
+	"sync"
+	"time"
+
+	"cs2-demo-service/models"
+	"cs2-demo-service/parser"
+)
+
+// ErrQueueFull se devuelve cuando la cola de demos pendientes está llena (backpressure)
+var ErrQueueFull = errors.New("scheduler queue is full")
+
+// heapMetric es la métrica de runtime usada para medir el heap vivo
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// Config controla la concurrencia y el presupuesto de memoria del scheduler
+type Config struct {
+	MaxConcurrent     int           // Máximo de demos parseándose a la vez
+	MemoryBudgetBytes uint64        // Suma máxima de memoria estimada de los jobs en ejecución
+	MaxQueue          int           // Máximo de jobs esperando (más allá se rechaza con ErrQueueFull)
+	SampleInterval    time.Duration // Intervalo de muestreo del heap para el reparto estimado por job
+}
+
+// DefaultConfig devuelve una configuración conservadora (2 demos, 4 GB)
+func DefaultConfig() Config {
+	return Config{
+		MaxConcurrent:     2,
+		MemoryBudgetBytes: 4 * 1024 * 1024 * 1024,
+		MaxQueue:          64,
+		SampleInterval:    250 * time.Millisecond,
+	}
+}
+
+// ConfigFromEnv lee la configuración de las variables de entorno
+// DEMO_MAX_CONCURRENT, DEMO_MEMORY_BUDGET_MB y DEMO_MAX_QUEUE (fallback a DefaultConfig)
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	if v, err := strconv.Atoi(os.Getenv("DEMO_MAX_CONCURRENT")); err == nil && v > 0 {
+		cfg.MaxConcurrent = v
+	}
+	if v, err := strconv.ParseUint(os.Getenv("DEMO_MEMORY_BUDGET_MB"), 10, 64); err == nil && v > 0 {
+		cfg.MemoryBudgetBytes = v * 1024 * 1024
+	}
+	if v, err := strconv.Atoi(os.Getenv("DEMO_MAX_QUEUE")); err == nil && v >= 0 {
+		cfg.MaxQueue = v
+	}
+	return cfg
+}
+
+// Job describe una demo a procesar, o un trabajo pesado arbitrario (Work) que debe
+// respetar el mismo presupuesto de memoria (recaps, POV...)
+type Job struct {
+	DemoPath  string
+	MatchID   string
+	MatchDate string
+	OutputDir string // Si está vacío no se exportan los AI models (ni se vuelca por ronda)
+	Options   parser.ParseOptions
+
+	// Work, si no es nil, sustituye al parse + export de la demo (Context queda nil)
+	Work func() error
+	// EstimatedBytes fija la memoria estimada del job; 0 = EstimateMemory del tamaño de DemoPath
+	EstimatedBytes uint64
+}
+
+// JobResult contiene el resultado de un job y sus métricas de memoria.
+// Si Context no es nil, el job sigue reservando su memoria estimada hasta que el
+// caller llama a Release (el DemoContext sigue vivo mientras se consume).
+type JobResult struct {
+	MatchID        string
+	Context        *models.DemoContext
+	EstimatedBytes uint64
+	// EstimatedPeakBytes es el pico del heap del proceso repartido entre los jobs en
+	// ejecución en proporción a su estimación: una aproximación, no una medida del job
+	EstimatedPeakBytes uint64
+	QueueWait          time.Duration
+	ParseDuration      time.Duration
+	ExportDuration     time.Duration
+	Err                error
+
+	release func() // Libera la reserva del job (nil si ya se liberó al terminar)
+}
+
+// Release devuelve al presupuesto la memoria reservada por el job. Hay que llamarlo
+// cuando se deja de usar Context; es idempotente y no hace nada si no hay reserva.
+func (r JobResult) Release() {
+	if r.release != nil {
+		r.release()
+	}
+}
+
+// Status es una foto del estado del scheduler
+type Status struct {
+	Running       int    `json:"running"`
+	Queued        int    `json:"queued"`
+	MaxConcurrent int    `json:"max_concurrent"`
+	ReservedBytes uint64 `json:"reserved_bytes"`
+	BudgetBytes   uint64 `json:"budget_bytes"`
+	HeapBytes     uint64 `json:"heap_bytes"`
+}
+
+type pendingJob struct {
+	job       Job
+	estimate  uint64
+	enqueued  time.Time
+	done      chan JobResult
+	peakShare uint64 // Parte estimada del pico de heap (protegido por Scheduler.mu)
+}
+
+// Scheduler ejecuta varias demos en paralelo sin superar el presupuesto de memoria.
+// Los jobs se admiten en orden FIFO: si el siguiente no cabe en el presupuesto,
+// esperan todos (evita que las demos grandes se queden sin turno).
+type Scheduler struct {
+	cfg Config
+
+	mu       sync.Mutex
+	queue    []*pendingJob
+	running  map[*pendingJob]struct{}
+	reserved uint64
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// New crea un scheduler y arranca el monitor de memoria
+func New(cfg Config) *Scheduler {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 1
+	}
+	if cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = DefaultConfig().SampleInterval
+	}
+
+	s := &Scheduler{
+		cfg:     cfg,
+		running: make(map[*pendingJob]struct{}),
+		stop:    make(chan struct{}),
+	}
+	go s.monitor()
+	return s
+}
+
+// Close detiene el monitor de memoria (los jobs en curso terminan normalmente)
+func (s *Scheduler) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// Submit encola un job y devuelve un canal con su resultado.
+// Devuelve ErrQueueFull si la cola ya tiene MaxQueue jobs esperando.
+func (s *Scheduler) Submit(job Job) (<-chan JobResult, error) {
+	// Con export activo, volcar cada ronda a disco para acotar la memoria
+	// (antes de estimar: con FlushDir el factor base es FlushedBaseBytesPerDemoByte)
+	if job.Work == nil && job.OutputDir != "" && job.Options.FlushDir == "" {
+		job.Options.FlushDir = parser.PartialDir(job.OutputDir, job.MatchID)
+	}
+
+	estimate := job.EstimatedBytes
+	if estimate == 0 {
+		info, err := os.Stat(job.DemoPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat demo: %w", err)
+		}
+		estimate = EstimateMemory(info.Size(), job.Options)
+	}
+
+	pj := &pendingJob{
+		job:      job,
+		estimate: estimate,
+		enqueued: time.Now(),
+		done:     make(chan JobResult, 1),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mustWait := len(s.queue) > 0 || !s.canStartLocked(pj)
+	if mustWait && len(s.queue) >= s.cfg.MaxQueue {
+		return nil, ErrQueueFull
+	}
+
+	s.queue = append(s.queue, pj)
+	log.Printf("📥 [scheduler] Job %s encolado (estimado %.0f MB, cola: %d, en ejecución: %d)",
+		job.MatchID, toMB(pj.estimate), len(s.queue), len(s.running))
+	s.dispatchLocked()
+
+	return pj.done, nil
+}
+
+// Run encola un job y espera a que termine
+func (s *Scheduler) Run(job Job) JobResult {
+	done, err := s.Submit(job)
+	if err != nil {
+		return JobResult{MatchID: job.MatchID, Err: err}
+	}
+	return <-done
+}
+
+// Status devuelve el estado actual del scheduler
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Status{
+		Running:       len(s.running),
+		Queued:        len(s.queue),
+		MaxConcurrent: s.cfg.MaxConcurrent,
+		ReservedBytes: s.reserved,
+		BudgetBytes:   s.cfg.MemoryBudgetBytes,
+		HeapBytes:     readHeapBytes(),
+	}
+}
+
+// canStartLocked indica si un job cabe ahora mismo en concurrencia y presupuesto.
+// Un job mayor que el presupuesto completo se admite cuando no hay nada más en ejecución.
+func (s *Scheduler) canStartLocked(pj *pendingJob) bool {
+	if len(s.running) >= s.cfg.MaxConcurrent {
+		return false
+	}
+	if len(s.running) == 0 {
+		return true
+	}
+	return s.reserved+pj.estimate <= s.cfg.MemoryBudgetBytes
+}
+
+// dispatchLocked arranca jobs de la cabeza de la cola mientras quepan
+func (s *Scheduler) dispatchLocked() {
+	for len(s.queue) > 0 {
+		head := s.queue[0]
+		if !s.canStartLocked(head) {
+			return
+		}
+		s.queue = s.queue[1:]
+		s.running[head] = struct{}{}
+		s.reserved += head.estimate
+		go s.execute(head)
+	}
+}
+
+// execute parsea (y exporta) la demo de un job, o ejecuta su Work. La reserva se
+// libera al terminar, salvo que el resultado lleve un DemoContext: entonces la libera
+// JobResult.Release.
+func (s *Scheduler) execute(pj *pendingJob) {
+	result := JobResult{
+		MatchID:        pj.job.MatchID,
+		EstimatedBytes: pj.estimate,
+		QueueWait:      time.Since(pj.enqueued),
+	}
+
+	if pj.job.Work != nil {
+		start := time.Now()
+		result.Err = pj.job.Work()
+		result.ParseDuration = time.Since(start)
+	} else {
+		runDemo(pj.job, &result)
+	}
+
+	s.sample()
+	s.finish(pj, &result)
+
+	log.Printf("📊 [scheduler] Job %s terminado: pico ~%.0f MB (reparto del heap), estimado %.0f MB, espera %v, parse %v",
+		result.MatchID, toMB(result.EstimatedPeakBytes), toMB(result.EstimatedBytes), result.QueueWait, result.ParseDuration)
+
+	pj.done <- result
+}
+
+// finish anota el pico estimado y libera la reserva del job, o la deja a cargo de
+// JobResult.Release si el resultado retiene un DemoContext
+func (s *Scheduler) finish(pj *pendingJob, result *JobResult) {
+	s.mu.Lock()
+	result.EstimatedPeakBytes = pj.peakShare
+	s.mu.Unlock()
+
+	if result.Context == nil {
+		s.releaseJob(pj)
+		return
+	}
+	var once sync.Once
+	result.release = func() { once.Do(func() { s.releaseJob(pj) }) }
+}
+
+// releaseJob quita el job de los que están en ejecución y admite los siguientes
+func (s *Scheduler) releaseJob(pj *pendingJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, pj)
+	s.reserved -= pj.estimate
+	s.dispatchLocked()
+}
+
+// runDemo parsea la demo del job y exporta los AI models si hay OutputDir
+func runDemo(job Job, result *JobResult) {
+	parseStart := time.Now()
+	parsed, err := parser.ParseDemoWithOptions(job.DemoPath, job.Options)
+	result.ParseDuration = time.Since(parseStart)
+	if err != nil {
+		result.Err = err
+		return
+	}
+
+	result.Context = parsed.Context
+	if job.OutputDir != "" {
+		exportStart := time.Now()
+		result.Err = parser.ExportAIModels(parsed.Context, job.MatchID, job.OutputDir, job.MatchDate)
+		result.ExportDuration = time.Since(exportStart)
+	}
+}
+
+// monitor muestrea el heap periódicamente mientras haya jobs en ejecución
+func (s *Scheduler) monitor() {
+	ticker := time.NewTicker(s.cfg.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+// sample lee el heap y actualiza la parte estimada del pico de cada job en ejecución.
+// Go no permite medir memoria por goroutine, así que el heap del proceso se reparte
+// entre los jobs en proporción a su memoria estimada (no es una medida por job).
+func (s *Scheduler) sample() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.running) == 0 || s.reserved == 0 {
+		return
+	}
+
+	heap := float64(readHeapBytes())
+	for pj := range s.running {
+		share := uint64(heap * float64(pj.estimate) / float64(s.reserved))
+		if share > pj.peakShare {
+			pj.peakShare = share
+		}
+	}
+}
+
+// readHeapBytes devuelve los bytes ocupados por objetos en el heap
+func readHeapBytes() uint64 {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+func toMB(bytes uint64) float64 {
+	return float64(bytes) / (1024 * 1024)
+}
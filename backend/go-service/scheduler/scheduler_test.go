@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cs2-demo-service/models"
+	"cs2-demo-service/parser"
+)
+
+// fakeDemo escribe un fichero de size bytes (el parse falla, solo importa el tamaño)
+func fakeDemo(t *testing.T, size int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "match.dem")
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSubmitEstimate(t *testing.T) {
+	const size = 1 << 20
+	demo := fakeDemo(t, size)
+	opts := parser.DefaultParseOptions()
+
+	flushed := opts
+	flushed.FlushDir = "partial"
+	for _, tc := range []struct {
+		name      string
+		outputDir string
+		want      uint64
+	}{
+		// Con export se vuelca por ronda: factor base reducido
+		{"export", t.TempDir(), EstimateMemory(size, flushed)},
+		{"no export", "", EstimateMemory(size, opts)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := New(DefaultConfig())
+			defer s.Close()
+
+			result := s.Run(Job{DemoPath: demo, MatchID: "m1", OutputDir: tc.outputDir, Options: opts})
+			if result.EstimatedBytes != tc.want {
+				t.Errorf("EstimatedBytes = %d, want %d", result.EstimatedBytes, tc.want)
+			}
+		})
+	}
+	if EstimateMemory(size, flushed) >= EstimateMemory(size, opts) {
+		t.Error("flushed estimate should be below the unflushed one")
+	}
+}
+
+func TestRunWork(t *testing.T) {
+	s := New(DefaultConfig())
+	defer s.Close()
+
+	errWork := errors.New("work failed")
+	ran := false
+	result := s.Run(Job{MatchID: "recap", EstimatedBytes: 1 << 20, Work: func() error {
+		ran = true
+		return errWork
+	}})
+	if !ran || !errors.Is(result.Err, errWork) || result.Context != nil {
+		t.Errorf("ran=%v err=%v context=%v", ran, result.Err, result.Context)
+	}
+	if result.EstimatedBytes != 1<<20 {
+		t.Errorf("EstimatedBytes = %d, want the job's own estimate", result.EstimatedBytes)
+	}
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	s := New(Config{MaxConcurrent: 1, MemoryBudgetBytes: 1 << 30, MaxQueue: 0})
+	defer s.Close()
+
+	block := make(chan struct{})
+	first, err := s.Submit(Job{MatchID: "a", EstimatedBytes: 1, Work: func() error { <-block; return nil }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Submit(Job{MatchID: "b", EstimatedBytes: 1, Work: func() error { return nil }}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("err = %v, want ErrQueueFull", err)
+	}
+	close(block)
+	<-first
+}
+
+func TestResultKeepsReservationUntilRelease(t *testing.T) {
+	s := New(Config{MaxConcurrent: 2, MemoryBudgetBytes: 100, MaxQueue: 4})
+	defer s.Close()
+
+	// Job en ejecución cuyo resultado retiene el DemoContext
+	pj := &pendingJob{job: Job{MatchID: "a"}, estimate: 80, done: make(chan JobResult, 1)}
+	s.mu.Lock()
+	s.running[pj] = struct{}{}
+	s.reserved += pj.estimate
+	s.mu.Unlock()
+
+	result := JobResult{Context: models.NewDemoContext(nil)}
+	s.finish(pj, &result)
+	if st := s.Status(); st.Running != 1 || st.ReservedBytes != 80 {
+		t.Fatalf("before Release: running=%d reserved=%d", st.Running, st.ReservedBytes)
+	}
+
+	// Un job que no cabe junto a la reserva espera a que se libere
+	second, err := s.Submit(Job{MatchID: "b", EstimatedBytes: 50, Work: func() error { return nil }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Status(); st.Queued != 1 {
+		t.Fatalf("second job should wait for the reservation, queued=%d", st.Queued)
+	}
+
+	result.Release()
+	result.Release() // Idempotente
+	<-second
+	if st := s.Status(); st.Running != 0 || st.ReservedBytes != 0 {
+		t.Errorf("after Release: running=%d reserved=%d", st.Running, st.ReservedBytes)
+	}
+}
@@ -0,0 +1,499 @@
+//go:build ignore
+
+package main
+
+import (
+	"cs2-demo-service/pkg/maps"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang/geo/r3"
+)
+
+// Valida un <mapa>_places.json contra el nav mesh:
+//   - formas inválidas (polígonos con <3 vértices, z_min > z_max, cajas invertidas)
+//   - solapes entre formas (ambiguos si tienen la misma prioridad)
+//   - huecos: espacio caminable del nav que no cae en ningún callout
+//   - seeds fuera del nav o no alcanzables desde el resto del mapa
+//
+// y genera un PNG de cobertura (vista cenital; cada callout un color, rojo = hueco,
+// magenta = solape ambiguo).
+// Uso:
+//
+//	go run validate_callouts.go -map de_nuke
+//	go run validate_callouts.go -map de_nuke -places my_places.json -png nuke.png -px 4
+func main() {
+	mapName := flag.String("map", "", "map name (e.g. de_nuke)")
+	mapsDir := flag.String("maps", "../data/maps", "maps directory")
+	placesPath := flag.String("places", "", "places file (default <maps>/<map>/<map>_places.json)")
+	step := flag.Float64("step", 16, "sampling step in units")
+	pngPath := flag.String("png", "", "coverage image (default <map>_callouts.png)")
+	px := flag.Float64("px", 4, "units per pixel in the image")
+	flag.Parse()
+
+	if *mapName == "" {
+		log.Fatal("-map is required")
+	}
+	if *placesPath == "" {
+		*placesPath = filepath.Join(*mapsDir, *mapName, *mapName+"_places.json")
+	}
+	if *pngPath == "" {
+		*pngPath = *mapName + "_callouts.png"
+	}
+
+	callouts, err := maps.LoadCallouts(*placesPath)
+	if err != nil {
+		log.Fatalf("Error loading %s: %v", *placesPath, err)
+	}
+	index := maps.NewCalloutIndex(callouts)
+
+	var nav *maps.NavMesh
+	navPath := filepath.Join(*mapsDir, *mapName, *mapName+".nav")
+	if nav, err = maps.LoadNavMesh(navPath); err != nil {
+		fmt.Printf("⚠️  No nav mesh (%v): gaps and seeds are not checked\n", err)
+		nav = nil
+	}
+
+	v := &validator{callouts: callouts, index: index, nav: nav, step: *step}
+	v.checkShapes()
+	v.checkOverlaps()
+	if nav != nil {
+		v.checkCoverage()
+		v.checkSeeds()
+	}
+
+	if err := v.render(*pngPath, *px); err != nil {
+		log.Fatalf("Error writing %s: %v", *pngPath, err)
+	}
+	fmt.Printf("🖼️  Coverage image: %s\n", *pngPath)
+
+	if v.errors > 0 {
+		fmt.Printf("❌ %d problems found\n", v.errors)
+		os.Exit(1)
+	}
+	fmt.Println("✅ Callouts OK")
+}
+
+type validator struct {
+	callouts []maps.Callout
+	index    *maps.CalloutIndex
+	nav      *maps.NavMesh
+	step     float64
+	errors   int
+
+	samples   []sample // Puntos caminables muestreados (con cobertura)
+	ambiguous []r3.Vector
+	badSeeds  map[int]bool
+}
+
+type sample struct {
+	pos     r3.Vector
+	callout string // "" = sin callout
+	bySeed  bool   // Solo cubierto por un point seed
+}
+
+func (v *validator) problem(format string, args ...interface{}) {
+	v.errors++
+	fmt.Printf("❌ "+format+"\n", args...)
+}
+
+func (v *validator) isShape(i int) bool {
+	return v.callouts[i].IsPolygon() || v.callouts[i].IsBox()
+}
+
+// --- 1. Formas ---
+
+func (v *validator) checkShapes() {
+	shapes, seeds := 0, 0
+	for i := range v.callouts {
+		c := &v.callouts[i]
+		switch {
+		case len(c.Polygon) > 0 && len(c.Polygon) < 3:
+			v.problem("%q: polygon with %d vertices", c.Name, len(c.Polygon))
+		case c.IsPolygon() && c.ZMin != nil && c.ZMax != nil && *c.ZMin > *c.ZMax:
+			v.problem("%q: z_min %.0f > z_max %.0f", c.Name, *c.ZMin, *c.ZMax)
+		case c.IsBox() && (c.Min.X > c.Max.X || c.Min.Y > c.Max.Y || c.Min.Z > c.Max.Z):
+			v.problem("%q: box min > max", c.Name)
+		case c.Min != nil && c.Max == nil && !c.IsPolygon():
+			v.problem("%q: box without max", c.Name)
+		}
+		if c.Name == "" {
+			v.problem("callout #%d has no name", i)
+		}
+		if v.isShape(i) {
+			shapes++
+		} else if c.IsSeed() {
+			seeds++
+		}
+	}
+	fmt.Printf("Callouts: %d shapes, %d seeds\n", shapes, seeds)
+}
+
+// --- 2. Solapes ---
+
+func (v *validator) checkOverlaps() {
+	type overlap struct {
+		a, b int
+		area float64
+	}
+	var found []overlap
+	for i := range v.callouts {
+		if !v.isShape(i) {
+			continue
+		}
+		for j := i + 1; j < len(v.callouts); j++ {
+			if !v.isShape(j) {
+				continue
+			}
+			if area, pts := v.overlapArea(i, j); area > 0 {
+				found = append(found, overlap{i, j, area})
+				if v.callouts[i].Priority == v.callouts[j].Priority {
+					v.ambiguous = append(v.ambiguous, pts...)
+				}
+			}
+		}
+	}
+
+	for _, o := range found {
+		a, b := &v.callouts[o.a], &v.callouts[o.b]
+		if a.Priority == b.Priority {
+			v.problem("ambiguous overlap %q / %q (~%.0f u², same priority %d; %q wins by file order)",
+				a.Name, b.Name, o.area, a.Priority, a.Name)
+		} else {
+			winner := a
+			if b.Priority > a.Priority {
+				winner = b
+			}
+			fmt.Printf("ℹ️  overlap %q / %q (~%.0f u²) resolved by priority -> %q\n", a.Name, b.Name, o.area, winner.Name)
+		}
+	}
+}
+
+// overlapArea estima por muestreo el área XY común de dos formas cuyos rangos Z se solapan
+func (v *validator) overlapArea(i, j int) (float64, []r3.Vector) {
+	aMin, aMax := v.callouts[i].Bounds()
+	bMin, bMax := v.callouts[j].Bounds()
+	lo := r3.Vector{X: math.Max(aMin.X, bMin.X), Y: math.Max(aMin.Y, bMin.Y), Z: math.Max(aMin.Z, bMin.Z)}
+	hi := r3.Vector{X: math.Min(aMax.X, bMax.X), Y: math.Min(aMax.Y, bMax.Y), Z: math.Min(aMax.Z, bMax.Z)}
+	if lo.X > hi.X || lo.Y > hi.Y || lo.Z > hi.Z {
+		return 0, nil
+	}
+	z := (lo.Z + hi.Z) / 2
+	if math.IsInf(z, 0) || math.IsNaN(z) {
+		z = math.Max(lo.Z, math.Min(hi.Z, 0)) // Rango Z abierto por un lado
+	}
+
+	var pts []r3.Vector
+	for x := lo.X + v.step/2; x <= hi.X; x += v.step {
+		for y := lo.Y + v.step/2; y <= hi.Y; y += v.step {
+			p := r3.Vector{X: x, Y: y, Z: z}
+			if v.callouts[i].Contains(p) && v.callouts[j].Contains(p) {
+				pts = append(pts, p)
+			}
+		}
+	}
+	return float64(len(pts)) * v.step * v.step, pts
+}
+
+// --- 3. Huecos sobre el nav ---
+
+func (v *validator) checkCoverage() {
+	type gap struct {
+		area      *maps.NavArea
+		uncovered int
+		total     int
+	}
+	var gaps []gap
+	covered, seedOnly := 0, 0
+	usedShapes := make(map[string]bool)
+
+	for i := range v.nav.Areas {
+		area := &v.nav.Areas[i]
+		pts := areaSamples(area, v.step)
+		g := gap{area: area, total: len(pts)}
+		for _, p := range pts {
+			s := sample{pos: p, callout: v.index.Find(p)}
+			if s.callout != "" {
+				if shape := v.shapeAt(p); shape == "" {
+					s.bySeed = true
+					seedOnly++
+				} else {
+					usedShapes[shape] = true
+				}
+				covered++
+			} else {
+				g.uncovered++
+			}
+			v.samples = append(v.samples, s)
+		}
+		if g.uncovered > 0 {
+			gaps = append(gaps, g)
+		}
+	}
+
+	total := len(v.samples)
+	if total == 0 {
+		fmt.Println("⚠️  Nav mesh has no walkable samples")
+		return
+	}
+	fmt.Printf("Walkable coverage: %.1f%% (%.1f%% only by seeds), %d samples\n",
+		100*float64(covered)/float64(total), 100*float64(seedOnly)/float64(total), total)
+
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].uncovered > gaps[j].uncovered })
+	for k, g := range gaps {
+		if k == 10 {
+			fmt.Printf("   ... and %d more areas with gaps\n", len(gaps)-k)
+			break
+		}
+		fmt.Printf("⚠️  gap: nav area %d at (%.0f, %.0f, %.0f): %.0f%% uncovered (~%.0f u²)\n",
+			g.area.ID, g.area.Center.X, g.area.Center.Y, g.area.Center.Z,
+			100*float64(g.uncovered)/float64(g.total), float64(g.uncovered)*v.step*v.step)
+	}
+
+	// Formas que no cubren nada caminable (normalmente un rango Z equivocado)
+	for i := range v.callouts {
+		if v.isShape(i) && !usedShapes[v.callouts[i].Name] {
+			v.problem("%q covers no walkable nav space (check its Z range)", v.callouts[i].Name)
+		}
+	}
+}
+
+// shapeAt devuelve el callout de forma (no seed) en p
+func (v *validator) shapeAt(p r3.Vector) string {
+	best := -1
+	for i := range v.callouts {
+		if v.isShape(i) && v.callouts[i].Contains(p) && (best < 0 || v.callouts[i].Priority > v.callouts[best].Priority) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return v.callouts[best].Name
+}
+
+// areaSamples muestrea el polígono del área en una rejilla (al menos el centro),
+// con la Z interpolada sobre el triángulo (abanico desde la esquina 0) que contiene el punto
+func areaSamples(area *maps.NavArea, step float64) []r3.Vector {
+	pts := []r3.Vector{area.Center}
+	c := area.Corners
+	if len(c) < 3 {
+		return pts
+	}
+	for x := area.NW.X + step/2; x < area.SE.X; x += step {
+		for y := area.NW.Y + step/2; y < area.SE.Y; y += step {
+			for k := 1; k+1 < len(c); k++ {
+				if z, ok := triangleZ(c[0], c[k], c[k+1], x, y); ok {
+					pts = append(pts, r3.Vector{X: x, Y: y, Z: z})
+					break
+				}
+			}
+		}
+	}
+	return pts
+}
+
+func triangleZ(a, b, c r3.Vector, x, y float64) (float64, bool) {
+	det := (b.Y-c.Y)*(a.X-c.X) + (c.X-b.X)*(a.Y-c.Y)
+	if det == 0 {
+		return 0, false
+	}
+	l1 := ((b.Y-c.Y)*(x-c.X) + (c.X-b.X)*(y-c.Y)) / det
+	l2 := ((c.Y-a.Y)*(x-c.X) + (a.X-c.X)*(y-c.Y)) / det
+	l3 := 1 - l1 - l2
+	if l1 < 0 || l2 < 0 || l3 < 0 {
+		return 0, false
+	}
+	return l1*a.Z + l2*b.Z + l3*c.Z, true
+}
+
+// --- 4. Seeds ---
+
+func (v *validator) checkSeeds() {
+	main := v.mainComponent()
+	v.badSeeds = make(map[int]bool)
+	for i := range v.callouts {
+		c := &v.callouts[i]
+		if !c.IsSeed() {
+			continue
+		}
+		pos := r3.Vector{X: c.X, Y: c.Y, Z: c.Z}
+		area := v.nav.AreaForPosition(pos)
+		switch {
+		case area == nil:
+			v.badSeeds[i] = true
+			v.problem("seed %q at (%.0f, %.0f, %.0f) is off the nav mesh", c.Name, c.X, c.Y, c.Z)
+		case !main[area.ID]:
+			v.badSeeds[i] = true
+			v.problem("seed %q is on nav area %d, unreachable from the rest of the map", c.Name, area.ID)
+		}
+	}
+}
+
+// mainComponent devuelve las áreas de la mayor componente conexa (ignorando el sentido de los enlaces)
+func (v *validator) mainComponent() map[uint32]bool {
+	adj := make(map[uint32][]uint32)
+	for _, a := range v.nav.Areas {
+		for _, l := range v.nav.Neighbors(a.ID) {
+			adj[a.ID] = append(adj[a.ID], l.AreaID)
+			adj[l.AreaID] = append(adj[l.AreaID], a.ID)
+		}
+	}
+	seen := make(map[uint32]bool)
+	var best map[uint32]bool
+	for _, a := range v.nav.Areas {
+		if seen[a.ID] {
+			continue
+		}
+		comp := map[uint32]bool{a.ID: true}
+		queue := []uint32{a.ID}
+		seen[a.ID] = true
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, n := range adj[id] {
+				if !seen[n] {
+					seen[n] = true
+					comp[n] = true
+					queue = append(queue, n)
+				}
+			}
+		}
+		if len(comp) > len(best) {
+			best = comp
+		}
+	}
+	return best
+}
+
+// --- 5. PNG ---
+
+func (v *validator) render(path string, unitsPerPixel float64) error {
+	// Bounds: nav + formas + seeds
+	min := r3.Vector{X: math.Inf(1), Y: math.Inf(1)}
+	max := r3.Vector{X: math.Inf(-1), Y: math.Inf(-1)}
+	grow := func(p r3.Vector) {
+		min.X, min.Y = math.Min(min.X, p.X), math.Min(min.Y, p.Y)
+		max.X, max.Y = math.Max(max.X, p.X), math.Max(max.Y, p.Y)
+	}
+	for _, s := range v.samples {
+		grow(s.pos)
+	}
+	for i := range v.callouts {
+		lo, hi := v.callouts[i].Bounds()
+		grow(lo)
+		grow(hi)
+	}
+	if math.IsInf(min.X, 1) {
+		return fmt.Errorf("nothing to render")
+	}
+	margin := 64.0
+	min.X, min.Y, max.X, max.Y = min.X-margin, min.Y-margin, max.X+margin, max.Y+margin
+	if span := math.Max(max.X-min.X, max.Y-min.Y); span/unitsPerPixel > 4096 {
+		unitsPerPixel = span / 4096
+	}
+	w, h := int((max.X-min.X)/unitsPerPixel)+1, int((max.Y-min.Y)/unitsPerPixel)+1
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 30
+		if i%4 == 3 {
+			img.Pix[i] = 255
+		}
+	}
+	// Y hacia arriba en el mapa = hacia arriba en la imagen
+	toPixel := func(x, y float64) (int, int) {
+		return int((x - min.X) / unitsPerPixel), h - 1 - int((y-min.Y)/unitsPerPixel)
+	}
+	fill := func(p r3.Vector, size float64, col color.RGBA) {
+		cx, cy := toPixel(p.X, p.Y)
+		r := int(size/unitsPerPixel/2) + 1
+		for y := cy - r; y < cy+r; y++ {
+			for x := cx - r; x < cx+r; x++ {
+				if x >= 0 && y >= 0 && x < w && y < h {
+					img.SetRGBA(x, y, col)
+				}
+			}
+		}
+	}
+
+	// Muestras caminables (de abajo arriba: el piso superior queda encima)
+	samples := append([]sample(nil), v.samples...)
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].pos.Z < samples[j].pos.Z })
+	for _, s := range samples {
+		col := color.RGBA{220, 30, 30, 255} // Hueco
+		if s.callout != "" {
+			col = calloutColor(s.callout, s.bySeed)
+		}
+		fill(s.pos, v.step, col)
+	}
+	for _, p := range v.ambiguous {
+		fill(p, v.step, color.RGBA{255, 0, 255, 255})
+	}
+
+	// Contornos de las formas
+	white := color.RGBA{240, 240, 240, 255}
+	for i := range v.callouts {
+		c := &v.callouts[i]
+		var poly []maps.Vector2
+		switch {
+		case c.IsPolygon():
+			poly = c.Polygon
+		case c.IsBox():
+			poly = []maps.Vector2{{X: c.Min.X, Y: c.Min.Y}, {X: c.Max.X, Y: c.Min.Y}, {X: c.Max.X, Y: c.Max.Y}, {X: c.Min.X, Y: c.Max.Y}}
+		default:
+			continue
+		}
+		for k := range poly {
+			a, b := poly[k], poly[(k+1)%len(poly)]
+			n := int(math.Hypot(b.X-a.X, b.Y-a.Y)/unitsPerPixel) + 1
+			for t := 0; t <= n; t++ {
+				f := float64(t) / float64(n)
+				x, y := toPixel(a.X+(b.X-a.X)*f, a.Y+(b.Y-a.Y)*f)
+				if x >= 0 && y >= 0 && x < w && y < h {
+					img.SetRGBA(x, y, white)
+				}
+			}
+		}
+	}
+
+	// Seeds (amarillo = no alcanzable / fuera del nav)
+	for i := range v.callouts {
+		c := &v.callouts[i]
+		if !c.IsSeed() {
+			continue
+		}
+		col := white
+		if v.badSeeds[i] {
+			col = color.RGBA{255, 220, 0, 255}
+		}
+		fill(r3.Vector{X: c.X, Y: c.Y}, 4*unitsPerPixel, col)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, img)
+}
+
+// calloutColor devuelve un color estable por nombre (más apagado si solo lo cubre un seed)
+func calloutColor(name string, dim bool) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	sum := h.Sum32()
+	c := color.RGBA{uint8(60 + sum%160), uint8(60 + (sum>>8)%160), uint8(60 + (sum>>16)%160), 255}
+	if dim {
+		c.R, c.G, c.B = c.R/2, c.G/2, c.B/2
+	}
+	return c
+}
@@ -66,6 +66,7 @@ app.use((req, res, next) => {
   const isGo = 
     url.startsWith('/process-demo') || 
     url.startsWith('/match-details') || 
+    url.startsWith('/map-image') || 
     url.startsWith('/health');
 
   if (isGo) {
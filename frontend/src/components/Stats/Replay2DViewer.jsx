@@ -204,6 +204,9 @@ const KillFeedItem = ({ kill, onComplete }) => {
       className="kill-feed-item"
     >
       <span className="kill-name killer" style={{ color: killerColor }}>{kill.killer_name?.substring(0, 10)}</span>
+      {kill.assister_name && (
+        <span className="kill-assist">+ {kill.assisted_flash ? '⚡' : ''}{kill.assister_name.substring(0, 8)}</span>
+      )}
       <div className="kill-weapon-container">
         {weaponIcon && weaponIcon.complete ? (
           <img src={weaponIcon.src} alt="" className="kill-weapon-icon" />
@@ -211,6 +214,11 @@ const KillFeedItem = ({ kill, onComplete }) => {
           <span className="kill-weapon-text">{kill.weapon || '?'}</span>
         )}
         {kill.headshot && <span className="headshot-badge">HS</span>}
+        {kill.wallbang && <span className="kill-flag-badge">WB</span>}
+        {kill.noscope && <span className="kill-flag-badge">NS</span>}
+        {kill.through_smoke && <span className="kill-flag-badge">SMK</span>}
+        {kill.attacker_blind && <span className="kill-flag-badge">BLIND</span>}
+        {kill.trade_of_id && <span className="kill-flag-badge">TRADE</span>}
       </div>
       <span className="kill-name victim" style={{ color: victimColor }}>{kill.victim_name?.substring(0, 10)}</span>
     </motion.div>
@@ -293,7 +301,9 @@ export default function Replay2DViewer({
   
   // Derived
   const mapName = metadata?.map_name;
-  const mapConfig = MAP_CONFIGS[mapName] || MAP_CONFIGS.de_mirage;
+  // Config del radar exportada por el parser (overview del mapa); la tabla local solo si no la conoce
+  const exportedConfig = metadata?.map_config?.source !== 'default' ? metadata?.map_config : null;
+  const mapConfig = exportedConfig || MAP_CONFIGS[mapName] || MAP_CONFIGS.de_mirage;
   const totalFrames = currentRoundData?.frames?.length || 0;
   const sampleRateMs = metadata?.sample_rate_ms || 62.5;
 
@@ -510,6 +520,10 @@ export default function Replay2DViewer({
     if (!mapName) return;
     const img = new Image();
     img.onload = () => { mapImageRef.current = img; };
+    // Sin radar local: planta generada por el servicio Go desde el mesh del mapa
+    img.onerror = () => {
+      if (!img.src.includes('/map-image/')) img.src = `/map-image/${mapName}`;
+    };
     img.src = `/maps/${mapName}_radar_psd.png`;
   }, [mapName]);
 